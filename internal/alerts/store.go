@@ -0,0 +1,209 @@
+// Package alerts ocenia alerty cenowe aktywów i dostarcza powiadomienia o ich zadziałaniu.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/notify"
+	"webwallet/internal/repository"
+	"webwallet/internal/webhooks"
+)
+
+// deliveryTimeout ogranicza czas wysyłki e-maila o jednym zadziałaniu alertu.
+const deliveryTimeout = 30 * time.Second
+
+// previousCloseWindow to okres, w którym szukamy ostatniego zamknięcia przed dzisiaj (weekendy, święta).
+const previousCloseWindow = 10
+
+// Store dekoruje magazyn danych: po każdym UpdateAssetCurrentPrice - z formularza ceny i z odświeżania
+// notowań - ocenia alerty cenowe aktywa. Zadziałanie alertu trafia do centrum powiadomień właściciela
+// portfela i jako zdarzenie alert.triggered na jego webhooki, a opcjonalnie także e-mailem
+// (wysyłanym w tle, żeby nie spowalniać zapisu ceny).
+type Store struct {
+	repository.Store
+	notifier   notify.Notifier // nil - e-maile wyłączone (brak serwera SMTP)
+	events     *webhooks.Dispatcher
+	deliveries sync.WaitGroup
+}
+
+// Sprawdzenie w czasie kompilacji, że dekorator nadal jest pełnym magazynem danych.
+var _ repository.Store = (*Store)(nil)
+
+// NewStore tworzy magazyn oceniający alerty cenowe. notifier może być nil - wtedy alerty z opcją e-mail
+// trafiają tylko do centrum powiadomień.
+func NewStore(store repository.Store, notifier notify.Notifier, events *webhooks.Dispatcher) *Store {
+	return &Store{Store: store, notifier: notifier, events: events}
+}
+
+// UpdateAssetCurrentPrice zapisuje cenę bieżącą aktywa i ocenia jego alerty. Błąd oceny alertów
+// nie cofa zmiany ceny, więc tylko go logujemy.
+func (s *Store) UpdateAssetCurrentPrice(ctx context.Context, portfolioID, assetID string, quote models.PriceQuote) error {
+	if err := s.Store.UpdateAssetCurrentPrice(ctx, portfolioID, assetID, quote); err != nil {
+		return err
+	}
+	if err := s.Evaluate(ctx, portfolioID, assetID); err != nil {
+		log.Printf("Price alerts: %v", err)
+	}
+	return nil
+}
+
+// PatchAsset zapisuje zmiany aktywa i - jeśli zmieniła się cena bieżąca - ocenia jego alerty,
+// tak jak UpdateAssetCurrentPrice.
+func (s *Store) PatchAsset(ctx context.Context, portfolioID string, version int64, assetID string, patch models.AssetPatch) error {
+	if err := s.Store.PatchAsset(ctx, portfolioID, version, assetID, patch); err != nil {
+		return err
+	}
+	if patch.Quote != nil {
+		if err := s.Evaluate(ctx, portfolioID, assetID); err != nil {
+			log.Printf("Price alerts: %v", err)
+		}
+	}
+	return nil
+}
+
+// Wait czeka na zakończenie wysyłek e-maili uruchomionych w tle (przy zamykaniu aplikacji).
+func (s *Store) Wait() {
+	s.deliveries.Wait()
+}
+
+// Evaluate ocenia alerty aktywa dla jego bieżącej ceny, zapisuje ich stan i dostarcza powiadomienia.
+func (s *Store) Evaluate(ctx context.Context, portfolioID, assetID string) error {
+	portfolio, err := s.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to load portfolio %s: %w", portfolioID, err)
+	}
+	asset, found := portfolio.FindAsset(assetID)
+	if !found || len(asset.Alerts) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	before := slices.Clone(asset.Alerts)
+	triggers := asset.EvaluateAlerts(s.previousClose(ctx, *asset), now)
+
+	// Stan alertu zapisujemy pod warunkiem, że nie zmienił się od wczytania. Gdy tę samą zmianę ceny
+	// oceniło równolegle inne wywołanie, zapis się nie uda i o zadziałaniu powiadomi tylko tamto.
+	saved := make(map[string]bool, len(asset.Alerts))
+	for i, alert := range asset.Alerts {
+		if alert.SameState(before[i]) {
+			continue
+		}
+		ok, err := s.UpdateAlertState(ctx, portfolioID, assetID, before[i], alert)
+		if err != nil {
+			return fmt.Errorf("failed to save alerts of %s: %w", asset.Name, err)
+		}
+		saved[alert.ID] = ok
+	}
+	triggers = slices.DeleteFunc(triggers, func(trigger models.AlertTrigger) bool { return !saved[trigger.Alert.ID] })
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	var owner *models.User
+	if portfolio.OwnerID != "" {
+		owner, err = s.GetUser(ctx, portfolio.OwnerID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to load owner of portfolio %s: %w", portfolioID, err)
+		}
+	}
+	for _, trigger := range triggers {
+		log.Printf("Price alert on %s triggered: %s", asset.Name, trigger.Message)
+		if owner != nil {
+			notification := models.NewAlertNotification(owner.ID, portfolioID, *asset, trigger, now)
+			if err := s.AddNotification(ctx, notification); err != nil {
+				log.Printf("Price alerts: %v", err)
+			}
+			s.events.Emit(ctx, owner.ID, portfolioID, models.WebhookAlertTriggered, newAlertPayload(*asset, trigger))
+		}
+		s.deliver(owner, portfolio, *asset, trigger)
+	}
+	return nil
+}
+
+// previousClose zwraca ostatnią cenę zamknięcia symbolu sprzed dzisiaj (zero, gdy jej nie ma
+// albo żaden alert aktywa jej nie potrzebuje).
+func (s *Store) previousClose(ctx context.Context, asset models.Asset) models.Decimal {
+	needed := slices.ContainsFunc(asset.Alerts, func(a models.PriceAlert) bool { return a.Condition == models.AlertDailyChange })
+	if !needed || models.NormalizeSymbol(asset.Symbol) == "" {
+		return models.Zero
+	}
+	yesterday := models.Today().AddDate(0, 0, -1)
+	points, err := s.PriceHistory(ctx, asset.Symbol, yesterday.AddDate(0, 0, -previousCloseWindow), yesterday)
+	if err != nil {
+		log.Printf("Price alerts: %v", err)
+		return models.Zero
+	}
+	if len(points) == 0 {
+		return models.Zero
+	}
+	return points[len(points)-1].Close
+}
+
+// alertPayload opisuje zadziałanie alertu w danych zdarzenia alert.triggered.
+type alertPayload struct {
+	Asset   alertEventAsset   `json:"asset"`
+	Alert   models.PriceAlert `json:"alert"`
+	Message string            `json:"message"`
+}
+
+// newAlertPayload opisuje zadziałanie alertu aktywa.
+func newAlertPayload(asset models.Asset, trigger models.AlertTrigger) alertPayload {
+	return alertPayload{
+		Asset: alertEventAsset{
+			ID:           asset.ID,
+			Name:         asset.Name,
+			Symbol:       asset.Symbol,
+			Currency:     asset.CurrencyCode(),
+			CurrentPrice: asset.CurrentPrice,
+			AvgCost:      asset.AvgCost,
+		},
+		Alert:   trigger.Alert,
+		Message: trigger.Message,
+	}
+}
+
+// alertEventAsset to dane aktywa dołączane do zdarzenia alert.triggered.
+type alertEventAsset struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Symbol       string         `json:"symbol"`
+	Currency     string         `json:"currency"`
+	CurrentPrice models.Decimal `json:"currentPrice"`
+	AvgCost      models.Decimal `json:"avgCost"`
+}
+
+// deliver wysyła w tle e-mail, jeśli alert go wymaga i skonfigurowano SMTP.
+func (s *Store) deliver(owner *models.User, portfolio *models.InvestmentPortfolio, asset models.Asset, trigger models.AlertTrigger) {
+	if !trigger.Alert.Email || s.notifier == nil || owner == nil {
+		return
+	}
+
+	s.deliveries.Add(1)
+	go func() {
+		defer s.deliveries.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		defer cancel()
+
+		if err := s.notifier.Notify(ctx, alertMessage(owner.Email, portfolio, asset, trigger)); err != nil {
+			log.Printf("Price alerts: failed to email %s: %v", owner.Email, err)
+		}
+	}()
+}
+
+// alertMessage układa treść e-maila o zadziałaniu alertu.
+func alertMessage(email string, portfolio *models.InvestmentPortfolio, asset models.Asset, trigger models.AlertTrigger) notify.Message {
+	body := trigger.Message + ".\n"
+	if portfolio.Name != "" {
+		body += "Portfel: " + portfolio.Name + "\n"
+	}
+	body += "\nAlert zadziała ponownie dopiero, gdy warunek przestanie być spełniony i znów zostanie przekroczony.\n" +
+		"Alerty (także drzemkę) ustawisz w aplikacji, w akcjach aktywa.\n"
+	return notify.Message{To: email, Subject: models.AlertTitle(asset), Body: body}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// alertSnoozeDays to dozwolone długości drzemki alertu (w dniach).
+var alertSnoozeDays = []int{1, 7, 30}
+
+// AssetAlertsHandler wyświetla alerty cenowe aktywa ("id") i obsługuje ich zmiany (POST):
+// action=add dodaje alert (pola "condition", "threshold", "email"), action=delete usuwa,
+// action=snooze wycisza alert "alert_id" na "days" dni, a action=unsnooze kończy drzemkę.
+func (h *AppHandler) AssetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	assetID := r.URL.Query().Get("id")
+	if r.Method == http.MethodPost {
+		assetID = r.FormValue("asset_id")
+	}
+	if assetID == "" {
+		http.Error(w, "Brak identyfikatora aktywa.", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for asset alerts: %v", err)
+		return
+	}
+	asset, found := portfolio.FindAsset(assetID)
+	if !found {
+		http.Error(w, "Nie znaleziono aktywa.", http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		h.renderAssetAlertsPage(w, r, *asset, portfolio.Version, "")
+		return
+	}
+
+	alerts := slices.Clone(asset.Alerts)
+	if message := changeAlerts(r, &alerts); message != "" {
+		h.renderAssetAlertsPage(w, r, *asset, portfolio.Version, message)
+		return
+	}
+
+	version, ok := formVersion(w, r)
+	if !ok {
+		return
+	}
+	err = h.portfolioRepo.UpdateAssetAlerts(ctx, currentPortfolioID(r), version, assetID, alerts)
+	if errors.Is(err, repository.ErrConflict) {
+		h.renderAssetAlertsPage(w, r, *asset, h.portfolioVersion(r), conflictMessage)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating alerts of asset %s: %v", assetID, err)
+		h.renderAssetAlertsPage(w, r, *asset, portfolio.Version, "Nie udało się zapisać alertów.")
+		return
+	}
+
+	log.Printf("Alerts of asset %s updated (%s).", asset.Name, r.FormValue("action"))
+	http.Redirect(w, r, "/asset-alerts?id="+url.QueryEscape(assetID), http.StatusSeeOther)
+}
+
+// changeAlerts stosuje do listy alertów operację z formularza. Zwraca komunikat dla użytkownika,
+// gdy dane są nieprawidłowe.
+func changeAlerts(r *http.Request, alerts *[]models.PriceAlert) string {
+	action := r.FormValue("action")
+	if action == "add" {
+		alert := models.PriceAlert{
+			ID:        models.GenerateID(),
+			Condition: models.AlertCondition(r.FormValue("condition")),
+			Email:     r.FormValue("email") != "",
+			CreatedAt: time.Now(),
+		}
+		threshold, err := models.ParseDecimal(r.FormValue("threshold"))
+		if err != nil {
+			return "Nieprawidłowy próg alertu. Podaj liczbę."
+		}
+		alert.Threshold = threshold
+		if !alert.Condition.IsValid() {
+			return "Nieprawidłowy warunek alertu. Wybierz jeden z listy."
+		}
+		if err := alert.Validate(); err != nil {
+			if alert.Condition == models.AlertProfitLoss {
+				return "Próg zysku/straty nie może być zerem (ujemny próg oznacza stratę)."
+			}
+			return "Próg alertu musi być większy od zera."
+		}
+		*alerts = append(*alerts, alert)
+		return ""
+	}
+
+	i := slices.IndexFunc(*alerts, func(a models.PriceAlert) bool { return a.ID == r.FormValue("alert_id") })
+	if i < 0 {
+		return "Nie znaleziono alertu."
+	}
+	switch action {
+	case "delete":
+		*alerts = slices.Delete(*alerts, i, i+1)
+	case "snooze":
+		days, err := strconv.Atoi(r.FormValue("days"))
+		if err != nil || !slices.Contains(alertSnoozeDays, days) {
+			return "Nieprawidłowa długość drzemki."
+		}
+		(*alerts)[i].SnoozedUntil = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+	case "unsnooze":
+		(*alerts)[i].SnoozedUntil = time.Time{}
+	default:
+		return "Nieznana operacja."
+	}
+	return ""
+}
+
+// renderAssetAlertsPage pomaga renderować komponent AssetAlertsPage.
+func (h *AppHandler) renderAssetAlertsPage(w http.ResponseWriter, r *http.Request, asset models.Asset, version int64, message string) {
+	if err := views.AssetAlertsPage(asset, version, alertSnoozeDays, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering asset alerts page", http.StatusInternalServerError)
+		log.Printf("Error rendering asset alerts page: %v", err)
+	}
+}
+
+// NotificationsHandler wyświetla centrum powiadomień (historię alertów) zalogowanego użytkownika
+// i oznacza wyświetlone powiadomienia jako przeczytane.
+func (h *AppHandler) NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	notifications, err := h.notifications.Notifications(ctx, user.ID, models.MaxAlertNotifications)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować powiadomień.", http.StatusInternalServerError)
+		log.Printf("Error loading notifications of %s: %v", user.Email, err)
+		return
+	}
+	if err := views.NotificationsPage(notifications).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering notifications page", http.StatusInternalServerError)
+		log.Printf("Error rendering notifications page: %v", err)
+		return
+	}
+	if err := h.notifications.MarkNotificationsRead(ctx, user.ID); err != nil {
+		log.Printf("Error marking notifications of %s as read: %v", user.Email, err)
+	}
+}
+
+// NotificationsBadgeHandler - endpoint HTMX zwracający liczbę nieprzeczytanych powiadomień do menu
+// (pusty, gdy wszystkie są przeczytane).
+func (h *AppHandler) NotificationsBadgeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		return
+	}
+	unread, err := h.notifications.UnreadNotifications(ctx, user.ID)
+	if err != nil {
+		log.Printf("Error counting notifications of %s: %v", user.Email, err)
+		return
+	}
+	if unread > 0 {
+		fmt.Fprintf(w, `<span class="notification-badge">%d</span>`, unread)
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// allocationGroup zwraca grupę docelowego podziału z parametru "group" (domyślnie typ portfela).
+func allocationGroup(r *http.Request) string {
+	group := r.FormValue("group")
+	if !models.IsValidAllocationGroup(group) {
+		return models.AllocationByWalletType
+	}
+	return group
+}
+
+// AllocationHandler wyświetla docelowy podział portfela w wybranej grupie, wykres odchyleń
+// od celu oraz listę transakcji przywracających cel. Parametry "cash" (gotówka do zainwestowania)
+// i "nosell" (bez sprzedaży) sterują propozycją transakcji.
+func (h *AppHandler) AllocationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for allocation page: %v", err)
+		return
+	}
+
+	group := allocationGroup(r)
+	message := r.URL.Query().Get("message")
+
+	cash := models.Zero
+	if value := strings.TrimSpace(r.URL.Query().Get("cash")); value != "" {
+		parsed, err := models.ParseDecimal(value)
+		if err != nil || parsed.IsNegative() {
+			message = "Nieprawidłowa kwota do zainwestowania. Musi być liczbą nieujemną."
+		} else {
+			cash = parsed
+		}
+	}
+	allowSell := r.URL.Query().Get("nosell") == ""
+
+	drifts := portfolio.AllocationDrifts(group)
+	plan := portfolio.Rebalance(group, cash, allowSell)
+	chartJSON := allocationDriftChart(group, drifts, middleware.GetTheme(ctx))
+
+	if err := views.AllocationPage(portfolio, group, drifts, plan, chartJSON, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering allocation page", http.StatusInternalServerError)
+		log.Printf("Error rendering allocation page: %v", err)
+	}
+}
+
+// AllocationTargetsHandler zapisuje docelowe udziały kategorii z jednej grupy.
+// Formularz wysyła pary pól "category" i "percent"; puste udziały pomijamy.
+func (h *AppHandler) AllocationTargetsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+	group := allocationGroup(r)
+	redirect := func(message string) {
+		target := "/allocation?group=" + url.QueryEscape(group)
+		if message != "" {
+			target += "&message=" + url.QueryEscape(message)
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	}
+
+	categories, percents := r.Form["category"], r.Form["percent"]
+	var targets []models.AllocationTarget
+	seen := make(map[string]bool)
+	for i, category := range categories {
+		category = strings.TrimSpace(category)
+		value := ""
+		if i < len(percents) {
+			value = strings.TrimSpace(percents[i])
+		}
+		if category == "" || value == "" {
+			continue
+		}
+		percent, err := models.ParseDecimal(value)
+		if err != nil {
+			redirect(fmt.Sprintf("Nieprawidłowy udział dla %q. Podaj liczbę od 0 do 100.", category))
+			return
+		}
+		if seen[category] {
+			redirect(fmt.Sprintf("Kategoria %q występuje dwa razy.", category))
+			return
+		}
+		seen[category] = true
+		targets = append(targets, models.AllocationTarget{Group: group, Category: category, Percent: percent})
+	}
+
+	// Sprawdzamy cele na kopii, żeby pokazać czytelny komunikat zamiast błędu zapisu
+	if err := models.NewInvestmentPortfolio().SetAllocationTargets(group, targets); err != nil {
+		redirect("Udziały muszą być liczbami od 0 do 100 i sumować się do 100%.")
+		return
+	}
+
+	if err := h.portfolioRepo.UpdateAllocationTargets(ctx, currentPortfolioID(r), group, targets); err != nil {
+		log.Printf("Error updating allocation targets: %v", err)
+		if errors.Is(err, repository.ErrConflict) {
+			redirect(conflictMessage)
+			return
+		}
+		http.Error(w, "Nie udało się zapisać docelowego podziału", http.StatusInternalServerError)
+		return
+	}
+	redirect("")
+}
+
+// allocationDriftChart buduje wykres słupkowy bieżącego i docelowego udziału kategorii (w procentach).
+func allocationDriftChart(group string, drifts []models.AllocationDrift, theme string) map[string]interface{} {
+	if len(drifts) == 0 {
+		return nil
+	}
+
+	labelColor := "#000000"
+	if theme == "dark" {
+		labelColor = "#b4b4b4ff"
+	}
+
+	categories := make([]string, 0, len(drifts))
+	current := make([]opts.BarData, 0, len(drifts))
+	target := make([]opts.BarData, 0, len(drifts))
+	for _, d := range drifts {
+		name := d.Category
+		if name == "" {
+			name = "Bez kategorii"
+		}
+		categories = append(categories, name)
+		current = append(current, opts.BarData{Value: fmt.Sprintf("%.2f", d.CurrentPercent)})
+		target = append(target, opts.BarData{Value: fmt.Sprintf("%.2f", d.TargetPercent)})
+	}
+
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Bieżący a docelowy podział",
+			Subtitle: "Udział w wartości portfela w % (" + models.AllocationGroupLabel(group) + ")",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{TextStyle: &opts.TextStyle{Color: labelColor}}),
+		charts.WithXAxisOpts(opts.XAxis{Data: categories}),
+	)
+	bar.SetXAxis(categories).
+		AddSeries("Bieżący", current).
+		AddSeries("Docelowy", target)
+	return bar.JSON()
+}
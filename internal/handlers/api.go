@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+// apiPrefix to początek adresów wersjonowanego JSON API.
+const apiPrefix = "/api/v1"
+
+// maxAPIBodySize ogranicza rozmiar treści żądania API (1 MB).
+const maxAPIBodySize = 1 << 20
+
+// apiDateLayout to format dat w parametrach i treści żądań API (np. "nextDue", "from", "to").
+const apiDateLayout = "2006-01-02"
+
+// Kody błędów JSON API (pole "error.code"). Komunikat ("error.message") jest po polsku, jak w formularzach.
+const (
+	apiCodeInvalidJSON      = "invalid_json"
+	apiCodeUnsupportedMedia = "unsupported_media_type"
+	apiCodeValidation       = "validation_failed"
+	apiCodeNotFound         = "not_found"
+	apiCodeMethodNotAllowed = "method_not_allowed"
+	apiCodeConflict         = "conflict"
+	apiCodePrecondition     = "precondition_failed"
+	apiCodeReadOnly         = "read_only"
+	apiCodeInternal         = "internal_error"
+)
+
+// apiError to treść odpowiedzi z błędem: {"error": {"code": "...", "message": "..."}}.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+// apiErrorDetail opisuje błąd żądania API.
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiPortfolio to portfel w odpowiedziach API - bez aktywów i subskrypcji, które mają własne zasoby.
+type apiPortfolio struct {
+	ID              string                 `json:"id"`
+	Name            string                 `json:"name"`
+	Default         bool                   `json:"default"`  // Portfel główny użytkownika (nie można go usunąć)
+	ReadOnly        bool                   `json:"readOnly"` // Widok zbiorczy "all" - tylko do odczytu
+	Version         int64                  `json:"version"`  // Do nagłówka If-Match przy zmianach
+	BaseCurrency    string                 `json:"baseCurrency"`
+	CostBasisMethod models.CostBasisMethod `json:"costBasisMethod"`
+	Totals          apiTotals              `json:"totals"`
+}
+
+// apiTotals to sumy portfela w jego walucie bazowej.
+type apiTotals struct {
+	BaseCurrency            string         `json:"baseCurrency"`
+	TotalValue              models.Decimal `json:"totalValue"`
+	TotalCost               models.Decimal `json:"totalCost"`
+	ProfitLoss              models.Decimal `json:"profitLoss"`
+	ProfitLossPercentage    float64        `json:"profitLossPercentage"`
+	RealizedProfitLoss      models.Decimal `json:"realizedProfitLoss"`
+	MonthlySubscriptionCost models.Decimal `json:"monthlySubscriptionCost"`
+	MissingFXRates          []string       `json:"missingFxRates,omitempty"` // Waluty bez kursu (pominięte w sumach)
+}
+
+// apiPortfolioInput to treść żądań zakładających portfel i zmieniających jego nazwę.
+type apiPortfolioInput struct {
+	Name string `json:"name"`
+}
+
+// APIHandler zwraca wersjonowane JSON API (/api/v1) do portfeli, aktywów, subskrypcji, cen i sum portfela.
+// Działa za AuthMiddleware i PortfolioMiddleware, tak jak strony HTML - uwierzytelnia je ciasteczko sesji z POST /login.
+// Zmiany przechodzą tę samą walidację co formularze; nagłówek If-Match z wersją portfela chroni przed nadpisaniem
+// zmian wprowadzonych w międzyczasie (odpowiedź 412; bez nagłówka równoległa zmiana tego samego elementu daje 409).
+func (h *AppHandler) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/portfolios", h.apiPortfolios)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}", h.apiPortfolio)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/totals", h.apiTotals)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/assets", h.apiAssets)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/assets/{asset}", h.apiAsset)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/subscriptions", h.apiSubscriptions)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/subscriptions/{subscription}", h.apiSubscription)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/prices", h.apiPrices)
+	mux.HandleFunc(apiPrefix+"/portfolios/{portfolio}/prices/{symbol}", h.apiPriceHistory)
+	mux.HandleFunc(apiPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "Nie ma takiego zasobu API.")
+	})
+	return mux
+}
+
+// writeJSON wysyła odpowiedź API z podanym kodem HTTP.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing API response: %v", err)
+	}
+}
+
+// writeAPIError wysyła odpowiedź z błędem w formacie apiError.
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// writeAPIStoreError zamienia błąd repozytorium na odpowiedź API (konflikt wersji, brak rekordu albo błąd serwera).
+func writeAPIStoreError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		writeAPIError(w, http.StatusConflict, apiCodeConflict, conflictMessage)
+	case errors.Is(err, repository.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "Nie znaleziono zasobu.")
+	default:
+		log.Printf("API: failed to %s: %v", action, err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "Błąd serwera. Spróbuj ponownie później.")
+	}
+}
+
+// writeAPIWriteError działa jak writeAPIStoreError dla zapisów z wersją z nagłówka If-Match: konflikt przy podanej
+// wersji oznacza, że warunek If-Match nie jest spełniony (412), a bez niej - równoległą zmianę tego samego elementu (409).
+func writeAPIWriteError(w http.ResponseWriter, err error, version int64, action string) {
+	if errors.Is(err, repository.ErrConflict) && version != repository.AnyVersion {
+		writeAPIError(w, http.StatusPreconditionFailed, apiCodePrecondition, conflictMessage)
+		return
+	}
+	writeAPIStoreError(w, err, action)
+}
+
+// allowMethods sprawdza metodę żądania. Dla innej metody odpowiada 405 z nagłówkiem Allow i zwraca false.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeAPIError(w, http.StatusMethodNotAllowed, apiCodeMethodNotAllowed, "Metoda niedozwolona")
+	return false
+}
+
+// decodeJSON odczytuje treść żądania do dst. Nieznane pola są błędem, żeby literówka nie była po cichu pomijana.
+// Gdy treść jest nieprawidłowa, odpowiada błędem i zwraca false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeAPIError(w, http.StatusUnsupportedMediaType, apiCodeUnsupportedMedia,
+			"Treść żądania musi być w formacie JSON (nagłówek Content-Type: application/json).")
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidJSON, fmt.Sprintf("Nieprawidłowe dane JSON: %v", err))
+		return false
+	}
+	if decoder.More() {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidJSON, "Nieprawidłowe dane JSON: oczekiwano jednego obiektu.")
+		return false
+	}
+	return true
+}
+
+// apiPortfolioID odczytuje z adresu ID portfela zalogowanego użytkownika. Widok zbiorczy ("all") jest
+// dostępny tylko do odczytu. Gdy portfel nie należy do użytkownika, odpowiada błędem i zwraca false.
+func apiPortfolioID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	portfolioID := r.PathValue("portfolio")
+	if portfolioID == models.AllPortfoliosID {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusBadRequest, apiCodeReadOnly, "Widok wszystkich portfeli jest tylko do odczytu. Wybierz konkretny portfel.")
+			return "", false
+		}
+		return portfolioID, true
+	}
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	if !selection.Owns(portfolioID) {
+		writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "Nie znaleziono portfela.")
+		return "", false
+	}
+	return portfolioID, true
+}
+
+// loadAPIPortfolio wczytuje portfel (albo widok zbiorczy). Gdy się nie uda, odpowiada błędem i zwraca false.
+func (h *AppHandler) loadAPIPortfolio(ctx context.Context, w http.ResponseWriter, r *http.Request, portfolioID string) (*models.InvestmentPortfolio, bool) {
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	portfolio, err := h.loadPortfolioView(ctx, selection, portfolioID)
+	if err != nil {
+		writeAPIStoreError(w, err, "load portfolio "+portfolioID)
+		return nil, false
+	}
+	return portfolio, true
+}
+
+// apiVersion odczytuje oczekiwaną wersję portfela z nagłówka If-Match (opcjonalnego; brak albo "*" - dowolna
+// wersja). Gdy nagłówek jest nieprawidłowy, odpowiada błędem i zwraca false.
+func apiVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	value := strings.Trim(strings.TrimPrefix(strings.TrimSpace(r.Header.Get("If-Match")), "W/"), `"`)
+	if value == "" || value == "*" {
+		return repository.AnyVersion, true
+	}
+	version, err := parseVersion(value)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Nieprawidłowy nagłówek If-Match. Podaj wersję portfela (pole \"version\").")
+		return 0, false
+	}
+	return version, true
+}
+
+// newAPIPortfolio opisuje portfel w odpowiedzi API.
+func newAPIPortfolio(portfolio *models.InvestmentPortfolio, info models.PortfolioInfo, selection middleware.PortfolioSelection) apiPortfolio {
+	return apiPortfolio{
+		ID:              info.ID,
+		Name:            info.Name,
+		Default:         info.ID == selection.DefaultID,
+		ReadOnly:        portfolio.IsAggregate(),
+		Version:         portfolio.Version,
+		BaseCurrency:    portfolio.GetBaseCurrency(),
+		CostBasisMethod: portfolio.CostBasisMethod,
+		Totals:          newAPITotals(portfolio),
+	}
+}
+
+// newAPITotals przelicza i zwraca sumy portfela.
+func newAPITotals(portfolio *models.InvestmentPortfolio) apiTotals {
+	portfolio.CalculateTotals()
+	return apiTotals{
+		BaseCurrency:            portfolio.GetBaseCurrency(),
+		TotalValue:              portfolio.TotalValue,
+		TotalCost:               portfolio.TotalCost,
+		ProfitLoss:              portfolio.TotalValue.Sub(portfolio.TotalCost),
+		ProfitLossPercentage:    portfolio.GetProfitLossPercentage(),
+		RealizedProfitLoss:      portfolio.RealizedProfitLoss,
+		MonthlySubscriptionCost: portfolio.MonthlySubscriptionCost,
+		MissingFXRates:          portfolio.MissingFXRates,
+	}
+}
+
+// apiPortfolioInfo zwraca nazwę i właściciela portfela z listy użytkownika (także dla widoku zbiorczego).
+func apiPortfolioInfo(selection middleware.PortfolioSelection, portfolioID string) models.PortfolioInfo {
+	if portfolioID == models.AllPortfoliosID {
+		return models.PortfolioInfo{ID: models.AllPortfoliosID, Name: models.AllPortfoliosName}
+	}
+	for _, info := range selection.Portfolios {
+		if info.ID == portfolioID {
+			return info
+		}
+	}
+	return models.PortfolioInfo{ID: portfolioID}
+}
+
+// apiPortfolios obsługuje /api/v1/portfolios: GET zwraca portfele użytkownika (główny na początku listy),
+// POST {"name": "..."} zakłada nowy portfel.
+func (h *AppHandler) apiPortfolios(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	if r.Method == http.MethodGet {
+		portfolios := make([]apiPortfolio, 0, len(selection.Portfolios))
+		for _, info := range selection.Portfolios {
+			portfolio, ok := h.loadAPIPortfolio(ctx, w, r, info.ID)
+			if !ok {
+				return
+			}
+			portfolios = append(portfolios, newAPIPortfolio(portfolio, info, selection))
+		}
+		writeJSON(w, http.StatusOK, portfolios)
+		return
+	}
+
+	var input apiPortfolioInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	name, err := models.NormalizePortfolioName(input.Name)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation,
+			fmt.Sprintf("Podaj nazwę portfela (maksymalnie %d znaków).", models.MaxPortfolioNameLength))
+		return
+	}
+	user, _ := middleware.GetUser(r.Context())
+	info := models.PortfolioInfo{ID: models.GenerateID(), Name: name, OwnerID: user.ID}
+	if err := h.portfolioRepo.CreatePortfolio(ctx, info); err != nil {
+		writeAPIStoreError(w, err, "create portfolio")
+		return
+	}
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, info.ID)
+	if !ok {
+		return
+	}
+	log.Printf("API: portfolio %s created by %s.", info.ID, user.Email)
+	w.Header().Set("Location", apiPrefix+"/portfolios/"+info.ID)
+	writeJSON(w, http.StatusCreated, newAPIPortfolio(portfolio, info, selection))
+}
+
+// apiPortfolio obsługuje /api/v1/portfolios/{portfolio}: GET zwraca portfel z sumami (także widok zbiorczy "all"),
+// PATCH {"name": "..."} zmienia jego nazwę, a DELETE usuwa go razem z aktywami i subskrypcjami (poza portfelem głównym).
+func (h *AppHandler) apiPortfolio(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	info := apiPortfolioInfo(selection, portfolioID)
+	switch r.Method {
+	case http.MethodPatch:
+		var input apiPortfolioInput
+		if !decodeJSON(w, r, &input) {
+			return
+		}
+		name, err := models.NormalizePortfolioName(input.Name)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Nieprawidłowa nazwa portfela.")
+			return
+		}
+		if err := h.portfolioRepo.RenamePortfolio(ctx, portfolioID, name); err != nil {
+			writeAPIStoreError(w, err, "rename portfolio "+portfolioID)
+			return
+		}
+		info.Name = name
+	case http.MethodDelete:
+		if portfolioID == selection.DefaultID {
+			writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Nie można usunąć portfela głównego.")
+			return
+		}
+		if err := h.portfolioRepo.DeletePortfolio(ctx, portfolioID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			writeAPIStoreError(w, err, "delete portfolio "+portfolioID)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, portfolio.Version))
+	writeJSON(w, http.StatusOK, newAPIPortfolio(portfolio, info, selection))
+}
+
+// apiTotals obsługuje GET /api/v1/portfolios/{portfolio}/totals - sumy portfela w jego walucie bazowej.
+func (h *AppHandler) apiTotals(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPITotals(portfolio))
+}
+
+// parseAPIDate odczytuje datę RRRR-MM-DD albo znacznik czasu RFC 3339 (z którego bierzemy sam dzień).
+func parseAPIDate(value string) (time.Time, error) {
+	if date, err := time.Parse(apiDateLayout, value); err == nil {
+		return date, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
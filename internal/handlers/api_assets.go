@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// apiAssetPatch to zmiany aktywa przyjmowane przez PATCH - te same, które pozwalają wprowadzić formularze
+// zmiany typu portfela i ceny. Ilość i koszt zmieniają się przez transakcje.
+type apiAssetPatch struct {
+	WalletType   *string         `json:"walletType"`
+	CurrentPrice *models.Decimal `json:"currentPrice"` // Zapisywana jako cena wpisana ręcznie (także do historii cen)
+}
+
+// apiPriceHistoryMaxDays ogranicza okres historii cen zwracanej przez API jednym żądaniem (10 lat).
+const apiPriceHistoryMaxDays = 10 * priceHistoryDays
+
+// apiPrice to bieżąca cena aktywa wraz ze źródłem notowania.
+type apiPrice struct {
+	AssetID   string         `json:"assetId"`
+	Name      string         `json:"name"`
+	Symbol    string         `json:"symbol"`
+	Price     models.Decimal `json:"price"`
+	Currency  string         `json:"currency"`
+	Source    string         `json:"source"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// apiAssets obsługuje /api/v1/portfolios/{portfolio}/assets: GET zwraca aktywa portfela, POST dodaje aktywo
+// (pola jak w models.Asset; początkowa ilość trafia do rejestru jako transakcja kupna, jak w formularzu).
+func (h *AppHandler) apiAssets(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+		if !ok {
+			return
+		}
+		assets := portfolio.Assets
+		if assets == nil {
+			assets = []models.Asset{}
+		}
+		writeJSON(w, http.StatusOK, assets)
+		return
+	}
+
+	var input models.Asset
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	asset, message := prepareNewAsset(input)
+	if message != "" {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, message)
+		return
+	}
+	version, ok := apiVersion(w, r)
+	if !ok {
+		return
+	}
+	if err := h.portfolioRepo.AddAsset(ctx, portfolioID, version, asset); err != nil {
+		writeAPIWriteError(w, err, version, "add asset")
+		return
+	}
+
+	log.Printf("API: asset %s added to portfolio %s.", asset.ID, portfolioID)
+	h.emitFor(ctx, r, portfolioID, models.WebhookAssetAdded, asset)
+	w.Header().Set("Location", fmt.Sprintf("%s/portfolios/%s/assets/%s", apiPrefix, portfolioID, asset.ID))
+	writeJSON(w, http.StatusCreated, asset)
+}
+
+// apiAsset obsługuje /api/v1/portfolios/{portfolio}/assets/{asset}: GET zwraca aktywo, PATCH zmienia typ portfela
+// ("walletType") i cenę bieżącą ("currentPrice"), a DELETE usuwa aktywo.
+func (h *AppHandler) apiAsset(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	assetID := r.PathValue("asset")
+	asset, ok := h.findAPIAsset(ctx, w, r, portfolioID, assetID)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, asset)
+	case http.MethodPatch:
+		h.patchAPIAsset(ctx, w, r, portfolioID, asset)
+	case http.MethodDelete:
+		version, ok := apiVersion(w, r)
+		if !ok {
+			return
+		}
+		if err := h.portfolioRepo.RemoveAsset(ctx, portfolioID, version, assetID); err != nil {
+			writeAPIWriteError(w, err, version, "remove asset "+assetID)
+			return
+		}
+		log.Printf("API: asset %s removed from portfolio %s.", assetID, portfolioID)
+		h.emitFor(ctx, r, portfolioID, models.WebhookAssetRemoved, asset)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// patchAPIAsset wprowadza zmiany z PATCH i odpowiada aktywem po zmianie.
+func (h *AppHandler) patchAPIAsset(ctx context.Context, w http.ResponseWriter, r *http.Request, portfolioID string, asset models.Asset) {
+	var patch apiAssetPatch
+	if !decodeJSON(w, r, &patch) {
+		return
+	}
+	if patch.WalletType == nil && patch.CurrentPrice == nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Brak zmian. Można zmienić pola \"walletType\" i \"currentPrice\".")
+		return
+	}
+	if patch.CurrentPrice != nil && patch.CurrentPrice.IsNegative() {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, priceMessage)
+		return
+	}
+	version, ok := apiVersion(w, r)
+	if !ok {
+		return
+	}
+
+	// Obie zmiany zapisujemy jednym wywołaniem, żeby nie zostawić aktywa zmienionego tylko częściowo
+	change := models.AssetPatch{WalletType: patch.WalletType}
+	if patch.CurrentPrice != nil {
+		quote := models.ManualQuote(*patch.CurrentPrice)
+		change.Quote = &quote
+	}
+	if err := h.portfolioRepo.PatchAsset(ctx, portfolioID, version, asset.ID, change); err != nil {
+		writeAPIWriteError(w, err, version, "update asset "+asset.ID)
+		return
+	}
+	if change.Quote != nil {
+		h.recordPrice(ctx, asset.Symbol, *change.Quote)
+		h.emitFor(ctx, r, portfolioID, models.WebhookPriceChanged, models.NewPriceChange(asset, *change.Quote))
+	}
+
+	updated, ok := h.findAPIAsset(ctx, w, r, portfolioID, asset.ID)
+	if !ok {
+		return
+	}
+	log.Printf("API: asset %s in portfolio %s updated.", asset.ID, portfolioID)
+	if patch.WalletType != nil {
+		h.emitFor(ctx, r, portfolioID, models.WebhookAssetUpdated, updated)
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// findAPIAsset wczytuje aktywo portfela. Gdy go nie ma, odpowiada błędem i zwraca false.
+func (h *AppHandler) findAPIAsset(ctx context.Context, w http.ResponseWriter, r *http.Request, portfolioID, assetID string) (models.Asset, bool) {
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return models.Asset{}, false
+	}
+	asset, found := portfolio.FindAsset(assetID)
+	if !found {
+		writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "Aktywo nie znalezione.")
+		return models.Asset{}, false
+	}
+	return *asset, true
+}
+
+// apiPrices obsługuje GET /api/v1/portfolios/{portfolio}/prices - bieżące ceny aktywów portfela
+// (cenę zmienia PATCH aktywa z polem "currentPrice").
+func (h *AppHandler) apiPrices(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return
+	}
+	prices := make([]apiPrice, 0, len(portfolio.Assets))
+	for _, asset := range portfolio.Assets {
+		prices = append(prices, apiPrice{
+			AssetID:   asset.ID,
+			Name:      asset.Name,
+			Symbol:    asset.Symbol,
+			Price:     asset.CurrentPrice,
+			Currency:  asset.CurrencyCode(),
+			Source:    asset.PriceSource,
+			UpdatedAt: asset.PriceUpdatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// apiPriceHistory obsługuje GET /api/v1/portfolios/{portfolio}/prices/{symbol} - historię cen zamknięcia symbolu
+// z portfela. Parametry "from" i "to" (RRRR-MM-DD) są opcjonalne; domyślnie jest to ostatni rok.
+// Dłuższy okres niż apiPriceHistoryMaxDays jest skracany do tylu dni przed "to".
+func (h *AppHandler) apiPriceHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return
+	}
+	symbol := models.NormalizeSymbol(r.PathValue("symbol"))
+	if !slices.Contains(priceHistorySymbols(portfolio), symbol) {
+		// Historię udostępniamy tylko dla symboli z portfela, tak jak na wykresie
+		writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "W portfelu nie ma aktywa o tym symbolu.")
+		return
+	}
+
+	to := models.Today()
+	if value := r.URL.Query().Get("to"); value != "" {
+		date, err := time.Parse(apiDateLayout, value)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Nieprawidłowa data 'to'. Użyj YYYY-MM-DD.")
+			return
+		}
+		to = date
+	}
+	from := to.AddDate(0, 0, -priceHistoryDays)
+	if value := r.URL.Query().Get("from"); value != "" {
+		date, err := time.Parse(apiDateLayout, value)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Nieprawidłowa data 'from'. Użyj YYYY-MM-DD.")
+			return
+		}
+		from = date
+	}
+	if from.After(to) {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, "Data 'from' nie może być późniejsza niż 'to'.")
+		return
+	}
+	if earliest := to.AddDate(0, 0, -apiPriceHistoryMaxDays); from.Before(earliest) {
+		from = earliest
+	}
+
+	points, err := h.priceHistory.PriceHistory(ctx, symbol, from, to)
+	if err != nil {
+		writeAPIStoreError(w, err, "load price history of "+symbol)
+		return
+	}
+	if points == nil {
+		points = []models.PricePoint{}
+	}
+	writeJSON(w, http.StatusOK, points)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// apiSubscriptionInput to subskrypcja w treści żądania API: pola jak w models.Subscription, ale termin "nextDue"
+// można podać jako samą datę RRRR-MM-DD. Historia terminów i płatności jest tylko do odczytu.
+type apiSubscriptionInput struct {
+	models.Subscription
+	NextDue string `json:"nextDue"`
+}
+
+// subscription sprawdza dane tak jak formularze subskrypcji i zwraca subskrypcję
+// albo komunikat dla użytkownika, gdy dane są nieprawidłowe.
+func (in apiSubscriptionInput) subscription() (models.Subscription, string) {
+	sub := in.Subscription
+	nextDue, err := parseAPIDate(in.NextDue)
+	if err != nil {
+		return sub, nextDueMessage
+	}
+	sub.NextDue = nextDue
+	if sub.Cost.IsNegative() {
+		return sub, subscriptionCostMessage
+	}
+	currency, err := models.NormalizeCurrency(sub.Currency)
+	if err != nil {
+		return sub, currencyMessage
+	}
+	sub.Currency = currency
+	return sub, checkSubscriptionSchedule(&sub)
+}
+
+// apiSubscriptions obsługuje /api/v1/portfolios/{portfolio}/subscriptions: GET zwraca subskrypcje portfela,
+// POST dodaje subskrypcję (termin z przeszłości od razu przesuwa się na najbliższą przyszłą płatność).
+func (h *AppHandler) apiSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+		if !ok {
+			return
+		}
+		subscriptions := portfolio.Subscriptions
+		if subscriptions == nil {
+			subscriptions = []models.Subscription{}
+		}
+		writeJSON(w, http.StatusOK, subscriptions)
+		return
+	}
+
+	var input apiSubscriptionInput
+	if !decodeJSON(w, r, &input) {
+		return
+	}
+	sub, message := input.subscription()
+	if message != "" {
+		writeAPIError(w, http.StatusBadRequest, apiCodeValidation, message)
+		return
+	}
+	sub.ID = models.GenerateID()
+	sub.PastCharges = nil
+	sub.Payments = nil
+	sub.RollOver(models.Today())
+
+	version, ok := apiVersion(w, r)
+	if !ok {
+		return
+	}
+	if err := h.portfolioRepo.AddSubscription(ctx, portfolioID, version, sub); err != nil {
+		writeAPIWriteError(w, err, version, "add subscription")
+		return
+	}
+
+	log.Printf("API: subscription %s added to portfolio %s.", sub.ID, portfolioID)
+	w.Header().Set("Location", fmt.Sprintf("%s/portfolios/%s/subscriptions/%s", apiPrefix, portfolioID, sub.ID))
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+// apiSubscription obsługuje /api/v1/portfolios/{portfolio}/subscriptions/{subscription}: GET zwraca subskrypcję,
+// PATCH zmienia podane pola (pozostałe zostają bez zmian), a DELETE usuwa subskrypcję.
+func (h *AppHandler) apiSubscription(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+	portfolioID, ok := apiPortfolioID(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	existing, ok := h.findAPISubscription(ctx, w, r, portfolioID, r.PathValue("subscription"))
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, existing)
+	case http.MethodPatch:
+		// Pola nieobecne w treści żądania zachowują obecne wartości
+		input := apiSubscriptionInput{Subscription: existing, NextDue: existing.NextDue.Format(apiDateLayout)}
+		if !decodeJSON(w, r, &input) {
+			return
+		}
+		sub, message := input.subscription()
+		if message != "" {
+			writeAPIError(w, http.StatusBadRequest, apiCodeValidation, message)
+			return
+		}
+		sub.ID = existing.ID
+
+		version, ok := apiVersion(w, r)
+		if !ok {
+			return
+		}
+		if err := h.portfolioRepo.UpdateSubscription(ctx, portfolioID, version, sub); err != nil {
+			writeAPIWriteError(w, err, version, "update subscription "+sub.ID)
+			return
+		}
+		// Termin z przeszłości przesuwa się w repozytorium, więc odpowiadamy subskrypcją po zapisie
+		updated, ok := h.findAPISubscription(ctx, w, r, portfolioID, sub.ID)
+		if !ok {
+			return
+		}
+		log.Printf("API: subscription %s in portfolio %s updated.", sub.ID, portfolioID)
+		writeJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		version, ok := apiVersion(w, r)
+		if !ok {
+			return
+		}
+		if err := h.portfolioRepo.RemoveSubscription(ctx, portfolioID, version, existing.ID); err != nil {
+			writeAPIWriteError(w, err, version, "remove subscription "+existing.ID)
+			return
+		}
+		log.Printf("API: subscription %s removed from portfolio %s.", existing.ID, portfolioID)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// findAPISubscription wczytuje subskrypcję portfela. Gdy jej nie ma, odpowiada błędem i zwraca false.
+func (h *AppHandler) findAPISubscription(ctx context.Context, w http.ResponseWriter, r *http.Request, portfolioID, subID string) (models.Subscription, bool) {
+	portfolio, ok := h.loadAPIPortfolio(ctx, w, r, portfolioID)
+	if !ok {
+		return models.Subscription{}, false
+	}
+	for _, s := range portfolio.Subscriptions {
+		if s.ID == subID {
+			return s, true
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, apiCodeNotFound, "Subskrypcja nie znaleziona.")
+	return models.Subscription{}, false
+}
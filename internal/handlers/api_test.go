@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+// apiRequest wysyła żądanie do JSON API w imieniu zalogowanego użytkownika, który ma jeden portfel P1.
+func apiRequest(h *AppHandler, user *models.User, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	ctx := middleware.WithUser(req.Context(), user)
+	ctx = middleware.WithPortfolioSelection(ctx, middleware.PortfolioSelection{
+		Portfolios: []models.PortfolioInfo{{ID: "P1", Name: "Test", OwnerID: user.ID}},
+		SelectedID: "P1",
+		DefaultID:  "P1",
+	})
+	rec := httptest.NewRecorder()
+	h.APIHandler().ServeHTTP(rec, req.WithContext(ctx))
+	return rec
+}
+
+// TestAPI sprawdza odpowiedzi /api/v1: mapowanie If-Match na 412, odrzucanie nieznanych pól JSON,
+// format treści błędów oraz sprawdzanie zakresu dat historii cen (odwrócony zakres i limit okresu).
+func TestAPI(t *testing.T) {
+	today := models.Today()
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		header  map[string]string
+		status  int
+		code    string // Oczekiwany error.code; pusty dla odpowiedzi bez błędu
+		allow   string // Oczekiwany nagłówek Allow
+		history int    // Oczekiwana liczba punktów historii cen (0 - nie sprawdzamy)
+	}{
+		{name: "get portfolio", method: http.MethodGet, target: "/api/v1/portfolios/P1", status: http.StatusOK},
+		{name: "add asset with current version", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body:   `{"name": "ETF", "type": "ETF", "walletType": "IKE", "quantity": "2", "avgCost": "10", "currentPrice": "11"}`,
+			header: map[string]string{"If-Match": `"1"`}, status: http.StatusCreated},
+		{name: "add asset with stale version", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body:   `{"name": "ETF", "type": "ETF", "walletType": "IKE", "quantity": "2", "avgCost": "10", "currentPrice": "11"}`,
+			header: map[string]string{"If-Match": `"0"`}, status: http.StatusPreconditionFailed, code: apiCodePrecondition},
+		{name: "add asset with invalid If-Match", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body:   `{"name": "ETF", "type": "ETF", "walletType": "IKE", "quantity": "2", "avgCost": "10", "currentPrice": "11"}`,
+			header: map[string]string{"If-Match": "abc"}, status: http.StatusBadRequest, code: apiCodeValidation},
+		{name: "add asset with unknown field", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body:   `{"name": "ETF", "type": "ETF", "walletType": "IKE", "quantity": "2", "avgCots": "10"}`,
+			status: http.StatusBadRequest, code: apiCodeInvalidJSON},
+		{name: "add asset with two objects", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body: `{"name": "ETF"} {"name": "ETF"}`, status: http.StatusBadRequest, code: apiCodeInvalidJSON},
+		{name: "add asset without JSON content type", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body: `{"name": "ETF"}`, header: map[string]string{"Content-Type": "text/plain"},
+			status: http.StatusUnsupportedMediaType, code: apiCodeUnsupportedMedia},
+		{name: "add invalid asset", method: http.MethodPost, target: "/api/v1/portfolios/P1/assets",
+			body: `{"name": "ETF", "type": "ETF", "walletType": "IKE", "quantity": "-2"}`, status: http.StatusBadRequest, code: apiCodeValidation},
+		{name: "delete asset with stale weak version", method: http.MethodDelete, target: "/api/v1/portfolios/P1/assets/A1",
+			header: map[string]string{"If-Match": `W/"0"`}, status: http.StatusPreconditionFailed, code: apiCodePrecondition},
+		{name: "delete asset with any version", method: http.MethodDelete, target: "/api/v1/portfolios/P1/assets/A1",
+			header: map[string]string{"If-Match": "*"}, status: http.StatusNoContent},
+		{name: "patch asset without changes", method: http.MethodPatch, target: "/api/v1/portfolios/P1/assets/A1",
+			body: `{}`, status: http.StatusBadRequest, code: apiCodeValidation},
+		{name: "unsupported method", method: http.MethodPut, target: "/api/v1/portfolios/P1/assets",
+			status: http.StatusMethodNotAllowed, code: apiCodeMethodNotAllowed, allow: "GET, POST"},
+		{name: "change the aggregate view", method: http.MethodPost, target: "/api/v1/portfolios/all/assets",
+			body: `{"name": "ETF"}`, status: http.StatusBadRequest, code: apiCodeReadOnly},
+		{name: "other user's portfolio", method: http.MethodGet, target: "/api/v1/portfolios/P9/assets",
+			status: http.StatusNotFound, code: apiCodeNotFound},
+		{name: "unknown resource", method: http.MethodGet, target: "/api/v1/unknown", status: http.StatusNotFound, code: apiCodeNotFound},
+		{name: "price history", method: http.MethodGet, target: "/api/v1/portfolios/P1/prices/cdr", status: http.StatusOK, history: 2},
+		{name: "price history capped to 10 years", method: http.MethodGet, target: "/api/v1/portfolios/P1/prices/CDR?from=1990-01-01",
+			status: http.StatusOK, history: 3},
+		{name: "reversed price history range", method: http.MethodGet, target: "/api/v1/portfolios/P1/prices/CDR?from=2026-10-10&to=2026-10-01",
+			status: http.StatusBadRequest, code: apiCodeValidation},
+		{name: "invalid price history date", method: http.MethodGet, target: "/api/v1/portfolios/P1/prices/CDR?to=jutro",
+			status: http.StatusBadRequest, code: apiCodeValidation},
+		{name: "price history of a symbol outside the portfolio", method: http.MethodGet, target: "/api/v1/portfolios/P1/prices/XYZ",
+			status: http.StatusNotFound, code: apiCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store, user := newTestHandler(t)
+			ctx := context.Background()
+			asset := models.Asset{ID: "A1", Name: "CD Projekt", Symbol: "CDR", Type: "Akcje", WalletType: "Zwykły",
+				Quantity: models.NewDecimal(1), AvgCost: models.NewDecimal(100), CurrentPrice: models.NewDecimal(100)}
+			if err := store.AddAsset(ctx, "P1", repository.AnyVersion, asset); err != nil {
+				t.Fatalf("AddAsset() unexpected error: %v", err)
+			}
+			// Punkty historii: dziś, miesiąc temu, 5 lat temu (poza domyślnym rokiem) i 11 lat temu (poza limitem 10 lat)
+			for _, date := range []time.Time{today, today.AddDate(0, 0, -30), today.AddDate(-5, 0, 0), today.AddDate(-11, 0, 0)} {
+				if err := store.RecordPrice(ctx, models.PricePoint{Symbol: "CDR", Date: date, Close: models.NewDecimal(100)}); err != nil {
+					t.Fatalf("RecordPrice() unexpected error: %v", err)
+				}
+			}
+
+			rec := apiRequest(h, user, tt.method, tt.target, tt.body, tt.header)
+			if rec.Code != tt.status {
+				t.Fatalf("%s %s: expected status %d, got %d: %s", tt.method, tt.target, tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.allow != "" && rec.Header().Get("Allow") != tt.allow {
+				t.Errorf("expected Allow %q, got %q", tt.allow, rec.Header().Get("Allow"))
+			}
+			if tt.code != "" {
+				if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+					t.Errorf("expected JSON error body, got Content-Type %q", contentType)
+				}
+				var body map[string]map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("error body is not {\"error\": {...}}: %v: %s", err, rec.Body.String())
+				}
+				if len(body) != 1 || body["error"]["code"] != tt.code || body["error"]["message"] == "" {
+					t.Errorf("expected error code %q with a message, got %s", tt.code, rec.Body.String())
+				}
+			}
+			if tt.history > 0 {
+				var points []models.PricePoint
+				if err := json.Unmarshal(rec.Body.Bytes(), &points); err != nil {
+					t.Fatalf("price history body: %v: %s", err, rec.Body.String())
+				}
+				if len(points) != tt.history {
+					t.Errorf("expected %d price history points, got %d: %s", tt.history, len(points), rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// TestWriteAPIWriteError sprawdza, że konflikt przy wersji z If-Match daje 412, a bez niej - 409.
+func TestWriteAPIWriteError(t *testing.T) {
+	tests := []struct {
+		version int64
+		err     error
+		status  int
+	}{
+		{version: 3, err: repository.ErrConflict, status: http.StatusPreconditionFailed},
+		{version: repository.AnyVersion, err: repository.ErrConflict, status: http.StatusConflict},
+		{version: 3, err: repository.ErrNotFound, status: http.StatusNotFound},
+		{version: 3, err: errors.New("disk full"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		writeAPIWriteError(rec, tt.err, tt.version, "test")
+		if rec.Code != tt.status {
+			t.Errorf("writeAPIWriteError(%v, version %d) status = %d, want %d", tt.err, tt.version, rec.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// RegisterHandler obsługuje formularz rejestracji (GET) i zakładanie konta (POST).
+// Po rejestracji użytkownik jest od razu zalogowany.
+func (h *AppHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		h.renderRegisterForm(w, r, "", "")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing register form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	password := r.FormValue("password")
+	if password != r.FormValue("password_confirm") {
+		h.renderRegisterForm(w, r, email, "Hasła nie są takie same.")
+		return
+	}
+
+	user, err := models.NewUser(email, password)
+	if err != nil {
+		h.renderRegisterForm(w, r, email, "Podaj poprawny adres e-mail i hasło o długości co najmniej 8 znaków.")
+		return
+	}
+
+	count, err := h.userRepo.CountUsers(ctx)
+	if err != nil {
+		log.Printf("Error counting users: %v", err)
+		h.renderRegisterForm(w, r, email, "Nie udało się utworzyć konta. Spróbuj ponownie.")
+		return
+	}
+
+	if err := h.userRepo.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			h.renderRegisterForm(w, r, email, "Konto z tym adresem e-mail już istnieje.")
+			return
+		}
+		log.Printf("Error creating user %s: %v", user.Email, err)
+		h.renderRegisterForm(w, r, email, "Nie udało się utworzyć konta. Spróbuj ponownie.")
+		return
+	}
+
+	log.Printf("User %s registered.", user.Email)
+	if count == 0 {
+		h.claimLegacyPortfolio(ctx, &user)
+	}
+	if err := h.startSession(ctx, w, user); err != nil {
+		log.Printf("Error creating session for %s: %v", user.Email, err)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// claimLegacyPortfolio przekazuje pierwszemu kontu portfel zapisany przed wprowadzeniem kont użytkowników.
+// Portfel przechodzi na właściciela tylko wtedy, gdy jeszcze go nie ma, więc przy równoczesnych
+// rejestracjach dostaje go dokładnie jedno konto; pozostałe zostają przy własnych, pustych portfelach.
+func (h *AppHandler) claimLegacyPortfolio(ctx context.Context, user *models.User) {
+	info := models.PortfolioInfo{ID: models.LegacyPortfolioID, Name: models.DefaultPortfolioName, OwnerID: user.ID}
+	claimed, err := h.portfolioRepo.ClaimPortfolio(ctx, info)
+	if err != nil {
+		log.Printf("Error claiming legacy portfolio for %s: %v", user.Email, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	if err := h.userRepo.SetUserPortfolio(ctx, user.ID, info.ID); err != nil {
+		// Portfel i tak należy już do użytkownika - zobaczy go na liście portfeli
+		log.Printf("Error making legacy portfolio the default of %s: %v", user.Email, err)
+		return
+	}
+	user.PortfolioID = info.ID
+	log.Printf("Legacy portfolio assigned to %s.", user.Email)
+}
+
+// LoginHandler obsługuje formularz logowania (GET) i logowanie (POST).
+func (h *AppHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		h.renderLoginForm(w, r, "", "")
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing login form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	email := r.FormValue("email")
+	// Ten sam komunikat dla nieznanego adresu i złego hasła - nie zdradzamy, które konta istnieją
+	const invalidCredentials = "Nieprawidłowy adres e-mail lub hasło."
+
+	normalized, err := models.NormalizeEmail(email)
+	if err != nil {
+		h.renderLoginForm(w, r, email, invalidCredentials)
+		return
+	}
+	user, err := h.userRepo.GetUserByEmail(ctx, normalized)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Error loading user %s: %v", normalized, err)
+		}
+		h.renderLoginForm(w, r, email, invalidCredentials)
+		return
+	}
+	if !user.CheckPassword(r.FormValue("password")) {
+		h.renderLoginForm(w, r, email, invalidCredentials)
+		return
+	}
+
+	if err := h.startSession(ctx, w, *user); err != nil {
+		log.Printf("Error creating session for %s: %v", user.Email, err)
+		h.renderLoginForm(w, r, email, "Nie udało się zalogować. Spróbuj ponownie.")
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// LogoutHandler kończy sesję i usuwa ciasteczko.
+func (h *AppHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if cookie, err := r.Cookie(middleware.SessionCookieName); err == nil {
+		if err := h.userRepo.DeleteSession(ctx, models.HashSessionToken(cookie.Value)); err != nil {
+			log.Printf("Error deleting session: %v", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1, // Usuń ciasteczko
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// startSession zapisuje nową sesję użytkownika i ustawia ciasteczko z jej tokenem.
+func (h *AppHandler) startSession(ctx context.Context, w http.ResponseWriter, user models.User) error {
+	session, token, err := models.NewSession(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := h.userRepo.CreateSession(ctx, session); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true, // Niedostępne dla JavaScriptu
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// renderLoginForm renderuje formularz logowania z komunikatem.
+func (h *AppHandler) renderLoginForm(w http.ResponseWriter, r *http.Request, email, message string) {
+	err := views.LoginForm(email, message).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering login form", http.StatusInternalServerError)
+		log.Printf("Error rendering login form: %v", err)
+	}
+}
+
+// renderRegisterForm renderuje formularz rejestracji z komunikatem.
+func (h *AppHandler) renderRegisterForm(w http.ResponseWriter, r *http.Request, email, message string) {
+	err := views.RegisterForm(email, message).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering register form", http.StatusInternalServerError)
+		log.Printf("Error rendering register form: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/views"
+)
+
+// Widoki kalendarza płatności.
+const (
+	calendarViewGrid = "grid" // Siatka miesiąca
+	calendarViewList = "list" // Lista terminów
+)
+
+// calendarParams odczytuje z zapytania miesiąc ("month", RRRR-MM) i widok ("view") kalendarza.
+// Miesiąc spoza okna models.CalendarMonths miesięcy od bieżącego zastępujemy bieżącym.
+func calendarParams(r *http.Request, first time.Time) (month time.Time, view string) {
+	view = r.URL.Query().Get("view")
+	if view != calendarViewList {
+		view = calendarViewGrid
+	}
+	month = first
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err == nil && !parsed.Before(first) && parsed.Before(first.AddDate(0, models.CalendarMonths, 0)) {
+			month = parsed
+		}
+	}
+	return month, view
+}
+
+// CalendarHandler wyświetla kalendarz płatności subskrypcji na najbliższe models.CalendarMonths miesięcy:
+// sumy miesięczne oraz siatkę lub listę terminów wybranego miesiąca. Żądania HTMX (nawigacja między
+// miesiącami i widokami) dostają sam fragment kalendarza.
+func (h *AppHandler) CalendarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for calendar: %v", err)
+		return
+	}
+
+	first := models.MonthStart(models.Today())
+	month, view := calendarParams(r, first)
+	calendar := portfolio.SubscriptionCalendar(first, models.CalendarMonths)
+
+	component := views.CalendarPage(portfolio, calendar, month, view)
+	// Przy przywracaniu historii HTMX potrzebuje całej strony, a nie samego fragmentu
+	if r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-History-Restore-Request") != "true" {
+		component = views.CalendarContent(portfolio, calendar, month, view)
+	}
+	if err := component.Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering calendar", http.StatusInternalServerError)
+		log.Printf("Error rendering calendar: %v", err)
+	}
+}
@@ -2,18 +2,21 @@ package handlers
 
 import (
 	"context"
-
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"webwallet/internal/middleware"
 	"webwallet/internal/models"
 	"webwallet/internal/repository"
 	"webwallet/internal/views" // Importujemy pakiet z komponentami templ
+	"webwallet/internal/webhooks"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/opts"
@@ -27,14 +30,21 @@ type PageData struct {
 	MonthlySubsCost      string
 	TotalPortfolioValue  string
 	ProfitLoss           string
-	ProfitLossRaw        float64
+	ProfitLossRaw        models.Decimal
 	ProfitLossPercentage float64
 }
 
 // AppHandler zawiera zależności (np. repozytorium bazy danych).
 // `tmpl` nie jest już *template.Template, bo używamy templ.Component.
+// Repozytorium jest interfejsem, więc handlery działają z MongoDB, pamięcią lub inną implementacją.
 type AppHandler struct {
-	portfolioRepo *repository.PortfolioRepo
+	portfolioRepo repository.PortfolioStore
+	userRepo      repository.UserStore
+	priceHistory  repository.PriceHistoryStore
+	snapshots     repository.SnapshotStore
+	notifications repository.NotificationStore
+	webhookRepo   repository.WebhookStore
+	events        *webhooks.Dispatcher
 }
 
 // ThemeToggleHandler zmienia wartość motywu w ciasteczku.
@@ -64,10 +74,73 @@ func (h *AppHandler) ThemeToggleHandler(w http.ResponseWriter, r *http.Request)
 }
 
 // NewAppHandler tworzy nową instancję AppHandler z zależnościami.
-func NewAppHandler(repo *repository.PortfolioRepo) *AppHandler {
+func NewAppHandler(store repository.Store, events *webhooks.Dispatcher) *AppHandler {
 	return &AppHandler{
-		portfolioRepo: repo,
+		portfolioRepo: store,
+		userRepo:      store,
+		priceHistory:  store,
+		snapshots:     store,
+		notifications: store,
+		webhookRepo:   store,
+		events:        events,
+	}
+}
+
+// currentPortfolioID zwraca ID portfela wybranego przez zalogowanego użytkownika (ustawionego przez PortfolioMiddleware).
+// W widoku zbiorczym zmiany trafiają do portfela głównego.
+func currentPortfolioID(r *http.Request) string {
+	if selection, ok := middleware.GetPortfolioSelection(r.Context()); ok {
+		return selection.ActiveID()
+	}
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		return ""
+	}
+	return user.PortfolioID
+}
+
+// conflictMessage to komunikat pokazywany, gdy portfel zmienił się od chwili wyświetlenia formularza.
+const conflictMessage = "Dane portfela zmieniły się w międzyczasie (np. w innej karcie). Sprawdź aktualne wartości i spróbuj ponownie."
+
+// Komunikaty walidacji wspólne dla formularzy i JSON API (/api/v1).
+const (
+	currencyMessage         = "Nieprawidłowy kod waluty. Użyj trzyliterowego kodu, np. PLN, USD, EUR."
+	subscriptionCostMessage = "Nieprawidłowa wartość 'Koszt'. Musi być liczbą nieujemną."
+	nextDueMessage          = "Nieprawidłowy format daty 'Następna Płatność'. Użyj YYYY-MM-DD."
+	priceMessage            = "Nieprawidłowa wartość 'Nowa Cena'. Musi być liczbą nieujemną."
+)
+
+// formVersion odczytuje wersję portfela z ukrytego pola "version" formularza. Repozytorium zapisuje zmianę
+// tylko wtedy, gdy portfel ma nadal tę wersję, a w przeciwnym razie zwraca repository.ErrConflict.
+// Każdy formularz zmieniający portfel musi przesłać wersję - gdy pola brak albo jest nieprawidłowe,
+// odpowiada błędem 400 i zwraca false.
+func formVersion(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	version, err := parseVersion(r.FormValue("version"))
+	if err != nil {
+		log.Printf("Rejected form without a valid portfolio version: %v", err)
+		http.Error(w, "Brak wersji portfela w formularzu. Odśwież stronę i spróbuj ponownie.", http.StatusBadRequest)
+		return 0, false
 	}
+	return version, true
+}
+
+// parseVersion odczytuje podaną wersję portfela (nieujemną liczbę całkowitą; pusta jest błędem).
+func parseVersion(versionStr string) (int64, error) {
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid portfolio version %q", versionStr)
+	}
+	return version, nil
+}
+
+// portfolioVersion zwraca bieżącą wersję portfela do ukrytego pola formularza (0, jeśli nie udało się go wczytać).
+func (h *AppHandler) portfolioVersion(r *http.Request) int64 {
+	portfolio, err := h.portfolioRepo.LoadPortfolio(r.Context(), currentPortfolioID(r))
+	if err != nil {
+		log.Printf("Error loading portfolio version: %v", err)
+		return 0
+	}
+	return portfolio.Version
 }
 
 // HomeHandler renderuje stronę główną portfela inwestycyjnego.
@@ -75,58 +148,90 @@ func (h *AppHandler) HomeHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
 	if err != nil {
 		http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
 		log.Printf("Error loading portfolio from DB: %v", err)
 		return
 	}
 
-	if len(portfolio.Assets) == 0 && len(portfolio.Subscriptions) == 0 {
+	// Przykładowe dane trafiają tylko do pustego portfela głównego - nowe portfele (np. "IKE") zostają puste
+	user, _ := middleware.GetUser(r.Context())
+	if user != nil && portfolio.ID == user.PortfolioID && len(portfolio.Assets) == 0 && len(portfolio.Subscriptions) == 0 {
 		log.Println("Database is empty, populating with initial sample data...")
-		portfolio.AddAsset(models.Asset{
-			ID:         "A1",
+		// Każdy zapis podbija wersję, więc dodanie subskrypcji oczekuje wersji po dodaniu aktywa
+		err := h.portfolioRepo.AddAsset(ctx, portfolio.ID, portfolio.Version, models.Asset{
+			ID:         models.GenerateID(),
 			Name:       "Akcje Testowe (DB)",
 			Symbol:     "DBG",
 			Type:       "Akcje",
-			Quantity:   10.0,
-			AvgCost:    100.00,
+			Quantity:   models.NewDecimal(10),
+			AvgCost:    models.NewDecimal(100),
 			WalletType: "Poduszka",
 		})
-		portfolio.AddSubscription(models.Subscription{
-			ID:        "S1",
-			Name:      "Miesięczna Sub (DB)",
-			Cost:      50.00,
-			Frequency: "Miesięcznie",
-			NextDue:   time.Now().AddDate(0, 1, 0),
-		})
-		if err := h.portfolioRepo.SavePortfolio(ctx, portfolio); err != nil {
+		if err == nil {
+			err = h.portfolioRepo.AddSubscription(ctx, portfolio.ID, portfolio.Version+1, models.Subscription{
+				ID:        models.GenerateID(),
+				Name:      "Miesięczna Sub (DB)",
+				Cost:      models.NewDecimal(50),
+				Frequency: models.FrequencyMonthly,
+				NextDue:   time.Now().AddDate(0, 1, 0),
+			})
+		}
+		if err != nil {
 			log.Printf("Could not save initial portfolio to DB: %v", err)
 		}
+		if portfolio, err = h.loadSelectedPortfolio(ctx, r); err != nil {
+			http.Error(w, "Failed to load portfolio", http.StatusInternalServerError)
+			log.Printf("Error loading portfolio from DB: %v", err)
+			return
+		}
 	}
 
+	h.renderHome(ctx, w, r, portfolio, "")
+}
+
+// renderHomeConflict wyświetla stronę główną z aktualnymi danymi portfela i komunikatem o konflikcie wersji -
+// tak jak formularze edycji, które po konflikcie wyświetlają się ponownie z komunikatem.
+func (h *AppHandler) renderHomeConflict(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, conflictMessage, http.StatusConflict)
+		log.Printf("Error loading portfolio after version conflict: %v", err)
+		return
+	}
+	h.renderHome(ctx, w, r, portfolio, conflictMessage)
+}
+
+// renderHome renderuje stronę główną portfela z opcjonalnym komunikatem dla użytkownika.
+func (h *AppHandler) renderHome(ctx context.Context, w http.ResponseWriter, r *http.Request, portfolio *models.InvestmentPortfolio, message string) {
 	rawProfitLoss := portfolio.GetProfitLoss()
+	rawRealizedProfitLoss := portfolio.GetRealizedProfitLoss()
 
 	// Tutaj tworzymy i renderujemy komponenty templ
 	homeComponent := views.Home(
 		"Przegląd Twoich aktywów i kosztów:",
 		portfolio,
-		models.FormatCurrency(portfolio.GetMonthlySubscriptionCost()),
-		models.FormatCurrency(portfolio.GetTotalValue()),
-		models.FormatCurrency(rawProfitLoss),
+		models.FormatCurrency(portfolio.GetMonthlySubscriptionCost(), portfolio.GetBaseCurrency()),
+		models.FormatCurrency(portfolio.GetTotalValue(), portfolio.GetBaseCurrency()),
+		models.FormatCurrency(rawProfitLoss, portfolio.GetBaseCurrency()),
 		rawProfitLoss,
 		portfolio.GetProfitLossPercentage(),
+		models.FormatCurrency(rawRealizedProfitLoss, portfolio.GetBaseCurrency()),
+		rawRealizedProfitLoss,
+		h.portfolioReturns(ctx, portfolio, time.Time{}, models.Today()).Portfolio,
+		message,
 	)
 
 	// Renderujemy komponent Home wewnątrz komponentu Layout
-	err = views.Layout(
+	err := views.Layout(
 		"Mój Portfel Inwestycyjny", // Tytuł dla Layout
 		homeComponent,              // Komponent content
 		portfolio,                  // Przekazywanie całego portfela do Layout (jeśli potrzebne w nagłówku/stopce)
-		models.FormatCurrency(portfolio.GetMonthlySubscriptionCost()),
-		models.FormatCurrency(portfolio.GetTotalValue()),
-		models.FormatCurrency(rawProfitLoss),
-		rawProfitLoss,
+		models.FormatCurrency(portfolio.GetMonthlySubscriptionCost(), portfolio.GetBaseCurrency()),
+		models.FormatCurrency(portfolio.GetTotalValue(), portfolio.GetBaseCurrency()),
+		models.FormatCurrency(rawProfitLoss, portfolio.GetBaseCurrency()),
+		rawProfitLoss.Float64(),
 		portfolio.GetProfitLossPercentage(),
 	).Render(r.Context(), w)
 
@@ -165,29 +270,27 @@ func (h *AppHandler) AddAssetHandler(w http.ResponseWriter, r *http.Request) {
 		walletType := r.FormValue("walletType")
 
 		// Walidacja i konwersja danych
-		quantity, err := strconv.ParseFloat(quantityStr, 64)
+		quantity, err := models.ParseDecimal(quantityStr)
 		if err != nil {
 			message = "Nieprawidłowa wartość 'Ilość'."
 			h.renderAddAssetForm(w, r, message)
 			return
 		}
-		avgCost, err := strconv.ParseFloat(avgCostStr, 64)
+		avgCost, err := models.ParseDecimal(avgCostStr)
 		if err != nil {
 			message = "Nieprawidłowa wartość 'Średni Koszt Zakupu'."
 			h.renderAddAssetForm(w, r, message)
 			return
 		}
 
-		currentPrice, err := strconv.ParseFloat(currentPriceStr, 64)
+		currentPrice, err := models.ParseDecimal(currentPriceStr)
 		if err != nil {
 			message = "Nieprawidłowa wartość 'Obecna cena'."
 			h.renderAddAssetForm(w, r, message)
 			return
 		}
 
-		// Utwórz nowe aktywo
-		newAsset := models.Asset{
-			ID:           models.GenerateID(), // Utwórz nową funkcję GenerateID w models
+		newAsset, message := prepareNewAsset(models.Asset{
 			Name:         name,
 			Symbol:       symbol,
 			Type:         assetType,
@@ -195,21 +298,23 @@ func (h *AppHandler) AddAssetHandler(w http.ResponseWriter, r *http.Request) {
 			AvgCost:      avgCost,
 			CurrentPrice: currentPrice,
 			WalletType:   walletType,
-		}
-
-		// Wczytaj aktualny portfel, dodaj aktywo i zapisz
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
-		if err != nil {
-			message = fmt.Sprintf("Błąd ładowania portfela: %v", err)
-			log.Printf("Error loading portfolio for asset addition: %v", err)
+			Currency:     r.FormValue("currency"),
+		})
+		if message != "" {
 			h.renderAddAssetForm(w, r, message)
 			return
 		}
 
-		portfolio.AddAsset(newAsset) // Dodaj nowe aktywo do portfela
-
-		if err := h.portfolioRepo.SavePortfolio(ctx, portfolio); err != nil {
+		// Dopisz aktywo do portfela (bez przepisywania pozostałych aktywów), o ile formularz nie jest nieaktualny
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		if err := h.portfolioRepo.AddAsset(ctx, currentPortfolioID(r), version, newAsset); err != nil {
 			message = fmt.Sprintf("Błąd zapisu portfela: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
 			log.Printf("Error saving portfolio after asset addition: %v", err)
 			h.renderAddAssetForm(w, r, message)
 			return
@@ -217,6 +322,7 @@ func (h *AppHandler) AddAssetHandler(w http.ResponseWriter, r *http.Request) {
 
 		message = "Aktywo dodane pomyślnie!"
 		log.Printf("Asset added: %+v", newAsset)
+		h.emit(ctx, r, models.WebhookAssetAdded, newAsset)
 		// Przekieruj na stronę główną lub wyświetl formularz z sukcesem
 		http.Redirect(w, r, "/", http.StatusSeeOther) // Przekieruj na główną stronę
 		return
@@ -226,9 +332,52 @@ func (h *AppHandler) AddAssetHandler(w http.ResponseWriter, r *http.Request) {
 	h.renderAddAssetForm(w, r, "")
 }
 
+// prepareNewAsset sprawdza dane nowego aktywa (z formularza albo z API) i zwraca aktywo gotowe do zapisu: z nowym ID,
+// znormalizowaną walutą i początkową ilością w rejestrze jako pierwszą transakcją kupna. Pozostałe pola wejścia
+// (np. transakcje, alerty) są pomijane. Zwraca komunikat dla użytkownika, gdy dane są nieprawidłowe.
+func prepareNewAsset(input models.Asset) (models.Asset, string) {
+	if !input.Quantity.IsPositive() {
+		return models.Asset{}, "Nieprawidłowa wartość 'Ilość'. Musi być liczbą większą od zera."
+	}
+	if input.AvgCost.IsNegative() {
+		return models.Asset{}, "Nieprawidłowa wartość 'Średni Koszt Zakupu'. Musi być liczbą nieujemną."
+	}
+	if input.CurrentPrice.IsNegative() {
+		return models.Asset{}, "Nieprawidłowa wartość 'Obecna cena'. Musi być liczbą nieujemną."
+	}
+	currency, err := models.NormalizeCurrency(input.Currency)
+	if err != nil {
+		return models.Asset{}, currencyMessage
+	}
+
+	opening := models.Transaction{
+		ID:       models.GenerateID(),
+		Type:     models.TransactionBuy,
+		Date:     models.Today(),
+		Quantity: input.Quantity,
+		Price:    input.AvgCost,
+		Note:     "Zakup początkowy",
+	}
+	if err := opening.Validate(); err != nil {
+		return models.Asset{}, fmt.Sprintf("Nieprawidłowa transakcja zakupu początkowego: %v", err)
+	}
+	return models.Asset{
+		ID:           models.GenerateID(),
+		Name:         input.Name,
+		Symbol:       input.Symbol,
+		Type:         input.Type,
+		Quantity:     input.Quantity,
+		AvgCost:      input.AvgCost,
+		CurrentPrice: input.CurrentPrice,
+		WalletType:   input.WalletType,
+		Currency:     currency,
+		Transactions: []models.Transaction{opening},
+	}, ""
+}
+
 // renderAddAssetForm pomaga renderować komponent AddAssetForm
 func (h *AppHandler) renderAddAssetForm(w http.ResponseWriter, r *http.Request, message string) {
-	err := views.AddAssetForm(message).Render(r.Context(), w)
+	err := views.AddAssetForm(h.portfolioVersion(r), message).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering add asset form", http.StatusInternalServerError)
 		log.Printf("Error rendering add asset form: %v", err)
@@ -253,7 +402,21 @@ func (h *AppHandler) DeleteAssetHandler(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	err := h.portfolioRepo.RemoveAsset(ctx, assetID)
+	// Zdarzenie asset.removed opisuje aktywo sprzed usunięcia
+	var removed *models.Asset
+	if portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r)); err == nil {
+		removed, _ = portfolio.FindAsset(assetID)
+	}
+
+	version, ok := formVersion(w, r)
+	if !ok {
+		return
+	}
+	err := h.portfolioRepo.RemoveAsset(ctx, currentPortfolioID(r), version, assetID)
+	if errors.Is(err, repository.ErrConflict) {
+		h.renderHomeConflict(ctx, w, r)
+		return
+	}
 	if err != nil {
 		log.Printf("Błąd usuwania aktywa (ID: %s): %v", assetID, err)
 		http.Error(w, fmt.Sprintf("Nie udało się usunąć aktywa: %v", err), http.StatusInternalServerError)
@@ -261,6 +424,9 @@ func (h *AppHandler) DeleteAssetHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	log.Printf("Aktywo o ID %s usunięte pomyślnie.", assetID)
+	if removed != nil {
+		h.emit(ctx, r, models.WebhookAssetRemoved, *removed)
+	}
 	http.Redirect(w, r, "/", http.StatusSeeOther) // Przekieruj z powrotem na stronę główną
 }
 
@@ -279,7 +445,7 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			message = fmt.Sprintf("Błąd parsowania formularza: %v", err)
 			log.Printf("Error parsing update asset form: %v", err)
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == r.FormValue("asset_id") {
@@ -302,11 +468,11 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		additionalQuantity, err := strconv.ParseFloat(additionalQuantityStr, 64)
-		if err != nil || additionalQuantity <= 0 {
+		additionalQuantity, err := models.ParseDecimal(additionalQuantityStr)
+		if err != nil || !additionalQuantity.IsPositive() {
 			message = "Nieprawidłowa wartość 'Dodatkowa Ilość'. Musi być liczbą większą od zera."
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == assetID {
@@ -319,11 +485,11 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		newPurchasePrice, err := strconv.ParseFloat(newPurchasePriceStr, 64)
-		if err != nil || newPurchasePrice <= 0 {
+		newPurchasePrice, err := models.ParseDecimal(newPurchasePriceStr)
+		if err != nil || !newPurchasePrice.IsPositive() {
 			message = "Nieprawidłowa wartość 'Cena Zakupu dla Nowej Ilości'. Musi być liczbą większą od zera."
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == assetID {
@@ -336,13 +502,20 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		// Wywołaj funkcję repozytorium do aktualizacji aktywa
-		err = h.portfolioRepo.UpdateAsset(ctx, assetID, additionalQuantity, newPurchasePrice)
+		// Wywołaj funkcję repozytorium do aktualizacji aktywa (o ile formularz nie jest nieaktualny)
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		err = h.portfolioRepo.UpdateAsset(ctx, currentPortfolioID(r), version, assetID, additionalQuantity, newPurchasePrice)
 		if err != nil {
 			message = fmt.Sprintf("Błąd aktualizacji aktywa: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
 			log.Printf("Error updating asset (ID: %s): %v", assetID, err)
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == assetID {
@@ -355,7 +528,8 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		log.Printf("Aktywo o ID %s zaktualizowane pomyślnie. Dodano %.2f sztuk po %.2f PLN.", assetID, additionalQuantity, newPurchasePrice)
+		log.Printf("Aktywo o ID %s zaktualizowane pomyślnie. Dodano %s sztuk po %s.", assetID, additionalQuantity, newPurchasePrice)
+		h.emitAssetEvent(ctx, r, models.WebhookAssetUpdated, assetID)
 		http.Redirect(w, r, "/", http.StatusSeeOther) // Przekieruj na stronę główną po sukcesie
 		return
 
@@ -367,7 +541,7 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 		if err != nil {
 			http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
 			log.Printf("Error loading portfolio for update asset form: %v", err)
@@ -394,7 +568,7 @@ func (h *AppHandler) UpdateAssetHandler(w http.ResponseWriter, r *http.Request)
 
 // renderUpdateAssetForm pomaga renderować komponent UpdateAssetForm
 func (h *AppHandler) renderUpdateAssetForm(w http.ResponseWriter, r *http.Request, asset models.Asset, message string) {
-	err := views.UpdateAssetForm(asset, message).Render(r.Context(), w)
+	err := views.UpdateAssetForm(asset, h.portfolioVersion(r), message).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering update asset form", http.StatusInternalServerError)
 		log.Printf("Error rendering update asset form: %v", err)
@@ -402,6 +576,476 @@ func (h *AppHandler) renderUpdateAssetForm(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// AssetTransactionsHandler wyświetla historię transakcji aktywa (GET) i dopisuje nową transakcję (POST).
+func (h *AppHandler) AssetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var message string
+	assetID := r.URL.Query().Get("id")
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			log.Printf("Error parsing transaction form: %v", err)
+			http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+			return
+		}
+		assetID = r.FormValue("asset_id")
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+
+		tx, err := parseTransactionForm(r)
+		if err != nil {
+			message = err.Error()
+		} else if err := h.portfolioRepo.AddTransaction(ctx, currentPortfolioID(r), version, assetID, tx); err != nil {
+			message = fmt.Sprintf("Błąd zapisu transakcji: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
+			log.Printf("Error adding transaction to asset (ID: %s): %v", assetID, err)
+		} else {
+			log.Printf("Transakcja %s dodana do aktywa o ID %s.", tx.Type, assetID)
+			h.emitAssetEvent(ctx, r, models.WebhookAssetUpdated, assetID)
+			http.Redirect(w, r, fmt.Sprintf("/asset-transactions?id=%s", assetID), http.StatusSeeOther)
+			return
+		}
+	}
+
+	if assetID == "" {
+		http.Error(w, "Brak identyfikatora aktywa.", http.StatusBadRequest)
+		return
+	}
+	h.renderAssetTransactions(ctx, w, r, assetID, message)
+}
+
+// renderAssetTransactions renderuje historię transakcji aktywa z aktualną wersją portfela i komunikatem.
+func (h *AppHandler) renderAssetTransactions(ctx context.Context, w http.ResponseWriter, r *http.Request, assetID, message string) {
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for transaction history: %v", err)
+		return
+	}
+
+	asset, found := portfolio.FindAsset(assetID)
+	if !found {
+		http.Error(w, "Aktywo nie znalezione.", http.StatusNotFound)
+		return
+	}
+
+	if err := views.AssetTransactionsPage(*asset, portfolio.CostBasisMethod, portfolio.Version, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering transaction history", http.StatusInternalServerError)
+		log.Printf("Error rendering transaction history: %v", err)
+	}
+}
+
+// parseTransactionForm odczytuje transakcję z formularza i zwraca komunikat błędu gotowy dla użytkownika.
+func parseTransactionForm(r *http.Request) (models.Transaction, error) {
+	tx := models.Transaction{
+		ID:   models.GenerateID(),
+		Type: models.TransactionType(r.FormValue("type")),
+		Note: r.FormValue("note"),
+	}
+
+	date, err := time.Parse("2006-01-02", r.FormValue("date"))
+	if err != nil {
+		return tx, fmt.Errorf("Nieprawidłowy format daty. Użyj YYYY-MM-DD.")
+	}
+	tx.Date = date
+
+	// Puste pola liczbowe traktujemy jako zero - nie każdy typ transakcji ich wymaga
+	parseOptional := func(field, label string) (models.Decimal, error) {
+		value := r.FormValue(field)
+		if value == "" {
+			return models.Zero, nil
+		}
+		parsed, err := models.ParseDecimal(value)
+		if err != nil {
+			return models.Zero, fmt.Errorf("Nieprawidłowa wartość '%s'.", label)
+		}
+		return parsed, nil
+	}
+
+	if tx.Quantity, err = parseOptional("quantity", "Ilość"); err != nil {
+		return tx, err
+	}
+	if tx.Price, err = parseOptional("price", "Cena"); err != nil {
+		return tx, err
+	}
+	if tx.Amount, err = parseOptional("amount", "Kwota"); err != nil {
+		return tx, err
+	}
+	if tx.Fee, err = parseOptional("fee", "Opłata"); err != nil {
+		return tx, err
+	}
+
+	if err := tx.Validate(); err != nil {
+		return tx, fmt.Errorf("Nieprawidłowa transakcja: %v", err)
+	}
+	return tx, nil
+}
+
+// DeleteTransactionHandler usuwa błędnie wprowadzoną transakcję z rejestru aktywa.
+func (h *AppHandler) DeleteTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Błąd parsowania formularza POST dla usuwania transakcji: %v", err)
+		http.Error(w, "Błąd wewnętrzny serwera", http.StatusInternalServerError)
+		return
+	}
+
+	assetID := r.FormValue("asset_id")
+	transactionID := r.FormValue("transaction_id")
+	if assetID == "" || transactionID == "" {
+		http.Error(w, "Brak identyfikatora aktywa lub transakcji.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	version, ok := formVersion(w, r)
+	if !ok {
+		return
+	}
+	err := h.portfolioRepo.RemoveTransaction(ctx, currentPortfolioID(r), version, assetID, transactionID)
+	if err != nil {
+		log.Printf("Błąd usuwania transakcji (ID: %s): %v", transactionID, err)
+		if errors.Is(err, repository.ErrConflict) {
+			h.renderAssetTransactions(ctx, w, r, assetID, conflictMessage)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Nie udało się usunąć transakcji: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Transakcja o ID %s usunięta pomyślnie.", transactionID)
+	h.emitAssetEvent(ctx, r, models.WebhookAssetUpdated, assetID)
+	http.Redirect(w, r, fmt.Sprintf("/asset-transactions?id=%s", assetID), http.StatusSeeOther)
+}
+
+// SellAssetHandler obsługuje sprzedaż (również częściową) posiadanego aktywa.
+func (h *AppHandler) SellAssetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var targetAsset models.Asset      // Aktywo, które sprzedajemy/wyświetlamy
+	var method models.CostBasisMethod // Metoda rozliczania partii portfela
+
+	// findAsset ładuje aktywo, żeby formularz z błędem nie był pusty
+	findAsset := func(assetID string) {
+		portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+		if loadErr == nil {
+			method = portfolio.CostBasisMethod
+			if a, ok := portfolio.FindAsset(assetID); ok {
+				targetAsset = *a
+			}
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		// --- Obsługa żądania POST (przetwarzanie formularza) ---
+		err := r.ParseForm()
+		if err != nil {
+			log.Printf("Error parsing sell asset form: %v", err)
+			findAsset(r.FormValue("asset_id"))
+			h.renderSellAssetForm(w, r, targetAsset, method, fmt.Sprintf("Błąd parsowania formularza: %v", err))
+			return
+		}
+
+		assetID := r.FormValue("asset_id")
+		if assetID == "" {
+			h.renderSellAssetForm(w, r, targetAsset, method, "Brak identyfikatora aktywa do sprzedaży.")
+			return
+		}
+
+		quantity, err := models.ParseDecimal(r.FormValue("quantity"))
+		if err != nil || !quantity.IsPositive() {
+			findAsset(assetID)
+			h.renderSellAssetForm(w, r, targetAsset, method, "Nieprawidłowa wartość 'Ilość'. Musi być liczbą większą od zera.")
+			return
+		}
+
+		price, err := models.ParseDecimal(r.FormValue("price"))
+		if err != nil || price.IsNegative() {
+			findAsset(assetID)
+			h.renderSellAssetForm(w, r, targetAsset, method, "Nieprawidłowa wartość 'Cena Sprzedaży'. Musi być liczbą nieujemną.")
+			return
+		}
+
+		fee := models.Zero
+		if feeStr := r.FormValue("fee"); feeStr != "" {
+			fee, err = models.ParseDecimal(feeStr)
+			if err != nil || fee.IsNegative() {
+				findAsset(assetID)
+				h.renderSellAssetForm(w, r, targetAsset, method, "Nieprawidłowa wartość 'Prowizja'. Musi być liczbą nieujemną.")
+				return
+			}
+		}
+
+		date, err := time.Parse("2006-01-02", r.FormValue("date"))
+		if err != nil {
+			findAsset(assetID)
+			h.renderSellAssetForm(w, r, targetAsset, method, "Nieprawidłowy format daty. Użyj YYYY-MM-DD.")
+			return
+		}
+
+		// Zapisz sprzedaż, o ile formularz nie jest nieaktualny (wersja portfela)
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		err = h.portfolioRepo.SellAsset(ctx, currentPortfolioID(r), version, assetID, quantity, price, fee, date)
+		if err != nil {
+			log.Printf("Error selling asset (ID: %s): %v", assetID, err)
+			message := fmt.Sprintf("Błąd sprzedaży aktywa: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
+			findAsset(assetID)
+			h.renderSellAssetForm(w, r, targetAsset, method, message)
+			return
+		}
+
+		log.Printf("Aktywo o ID %s sprzedane. Sprzedano %s sztuk po %s (prowizja %s).", assetID, quantity, price, fee)
+		h.emitAssetEvent(ctx, r, models.WebhookAssetUpdated, assetID)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// --- Obsługa żądania GET (wyświetlanie formularza) ---
+	assetID := r.URL.Query().Get("id")
+	if assetID == "" {
+		http.Error(w, "Brak identyfikatora aktywa do sprzedaży.", http.StatusBadRequest)
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for sell asset form: %v", err)
+		return
+	}
+
+	asset, found := portfolio.FindAsset(assetID)
+	if !found {
+		http.Error(w, "Aktywo nie znalezione.", http.StatusNotFound)
+		return
+	}
+
+	h.renderSellAssetForm(w, r, *asset, portfolio.CostBasisMethod, "")
+}
+
+// renderSellAssetForm pomaga renderować komponent SellAssetForm
+func (h *AppHandler) renderSellAssetForm(w http.ResponseWriter, r *http.Request, asset models.Asset, method models.CostBasisMethod, message string) {
+	err := views.SellAssetForm(asset, method, h.portfolioVersion(r), message).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Error rendering sell asset form", http.StatusInternalServerError)
+		log.Printf("Error rendering sell asset form: %v", err)
+		return
+	}
+}
+
+// CostBasisMethodHandler zmienia metodę rozliczania partii (FIFO, LIFO, średni koszt) dla portfela.
+func (h *AppHandler) CostBasisMethodHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Błąd parsowania formularza metody rozliczania: %v", err)
+		http.Error(w, "Błąd wewnętrzny serwera", http.StatusInternalServerError)
+		return
+	}
+
+	method := models.CostBasisMethod(r.FormValue("method"))
+	if !method.IsValid() {
+		http.Error(w, "Nieznana metoda rozliczania partii.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.UpdateCostBasisMethod(ctx, currentPortfolioID(r), method); err != nil {
+		log.Printf("Błąd zmiany metody rozliczania partii: %v", err)
+		if errors.Is(err, repository.ErrConflict) {
+			h.renderHomeConflict(ctx, w, r)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Nie udało się zmienić metody rozliczania: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Metoda rozliczania partii zmieniona na %s.", method)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// EmergencyFundHandler zapisuje miesięczne koszty życia i próg ostrzeżenia poduszki finansowej.
+// Puste pola oznaczają zero (brak kosztów poza subskrypcjami, brak ostrzeżenia).
+func (h *AppHandler) EmergencyFundHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing emergency fund form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	parseAmount := func(field string) (models.Decimal, error) {
+		value := strings.TrimSpace(r.FormValue(field))
+		if value == "" {
+			return models.Zero, nil
+		}
+		return models.ParseDecimal(value)
+	}
+	livingCost, livingErr := parseAmount("livingCost")
+	minMonths, monthsErr := parseAmount("minMonths")
+	settings := models.EmergencyFundSettings{MonthlyLivingCost: livingCost, MinMonths: minMonths}
+	if livingErr != nil || monthsErr != nil || settings.Validate() != nil {
+		http.Error(w, "Koszty życia i liczba miesięcy muszą być liczbami nieujemnymi.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.UpdateEmergencyFundSettings(ctx, currentPortfolioID(r), settings); err != nil {
+		log.Printf("Error updating emergency fund settings: %v", err)
+		if errors.Is(err, repository.ErrConflict) {
+			h.renderHomeConflict(ctx, w, r)
+			return
+		}
+		http.Error(w, "Nie udało się zapisać ustawień poduszki finansowej", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// FXRatesHandler wyświetla kursy walut i walutę bazową (GET) oraz zapisuje kurs dla pary walutowej (POST).
+func (h *AppHandler) FXRatesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var message string
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			log.Printf("Error parsing fx rate form: %v", err)
+			http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+			return
+		}
+
+		base, baseErr := models.NormalizeCurrency(r.FormValue("base"))
+		quote, quoteErr := models.NormalizeCurrency(r.FormValue("quote"))
+		rate, rateErr := models.ParseDecimal(r.FormValue("rate"))
+
+		switch {
+		case baseErr != nil || quoteErr != nil:
+			message = currencyMessage
+		case base == quote:
+			message = "Waluty w parze muszą się różnić."
+		case rateErr != nil || !rate.IsPositive():
+			message = "Nieprawidłowa wartość 'Kurs'. Musi być liczbą większą od zera."
+		default:
+			fxRate := models.FXRate{Base: base, Quote: quote, Rate: rate, UpdatedAt: time.Now()}
+			if err := h.portfolioRepo.SaveFXRate(ctx, fxRate); err != nil {
+				message = fmt.Sprintf("Błąd zapisu kursu: %v", err)
+				log.Printf("Error saving fx rate %s: %v", fxRate.Pair(), err)
+			} else {
+				http.Redirect(w, r, "/fx-rates", http.StatusSeeOther)
+				return
+			}
+		}
+	}
+
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for fx rates page: %v", err)
+		return
+	}
+	portfolio.CalculateTotals() // Ustala listę walut bez kursu
+
+	if err := views.FXRatesPage(portfolio, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering fx rates page", http.StatusInternalServerError)
+		log.Printf("Error rendering fx rates page: %v", err)
+	}
+}
+
+// DeleteFXRateHandler usuwa kurs dla pary walutowej.
+func (h *AppHandler) DeleteFXRateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Błąd parsowania formularza usuwania kursu: %v", err)
+		http.Error(w, "Błąd wewnętrzny serwera", http.StatusInternalServerError)
+		return
+	}
+
+	pair := r.FormValue("pair")
+	if pair == "" {
+		http.Error(w, "Brak pary walutowej.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.RemoveFXRate(ctx, pair); err != nil {
+		log.Printf("Błąd usuwania kursu %s: %v", pair, err)
+		http.Error(w, fmt.Sprintf("Nie udało się usunąć kursu: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/fx-rates", http.StatusSeeOther)
+}
+
+// BaseCurrencyHandler zmienia walutę bazową portfela, w której liczone są sumy.
+func (h *AppHandler) BaseCurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Błąd parsowania formularza waluty bazowej: %v", err)
+		http.Error(w, "Błąd wewnętrzny serwera", http.StatusInternalServerError)
+		return
+	}
+
+	currency, err := models.NormalizeCurrency(r.FormValue("currency"))
+	if err != nil {
+		http.Error(w, "Nieprawidłowy kod waluty.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.UpdateBaseCurrency(ctx, currentPortfolioID(r), currency); err != nil {
+		log.Printf("Błąd zmiany waluty bazowej: %v", err)
+		http.Error(w, fmt.Sprintf("Nie udało się zmienić waluty bazowej: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Waluta bazowa zmieniona na %s.", currency)
+	http.Redirect(w, r, "/fx-rates", http.StatusSeeOther)
+}
+
 // helper func for checking if string is in slice
 func isInSlice(s string, slice []string) bool {
 	for _, v := range slice {
@@ -426,7 +1070,7 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 			message = fmt.Sprintf("Błąd parsowania formularza: %v", err)
 			log.Printf("Error parsing update asset form: %v", err)
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == r.FormValue("asset_id") {
@@ -450,7 +1094,7 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 
 		// Sprawdź czy aktywo należy do dozwolonych typów
 		//if !isInSlice(newWalletType, allowedTypes) {
-		//	portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+		//	portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 		//	if loadErr == nil {
 		//		for _, a := range portfolio.Assets {
 		//			if a.ID == assetID {
@@ -463,13 +1107,20 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 		//	return
 		//}
 
-		// Wywołaj funkcję repozytorium do aktualizacji aktywa
-		err = h.portfolioRepo.UpdateAssetWalletType(ctx, assetID, newWalletType)
+		// Wywołaj funkcję repozytorium do aktualizacji aktywa (o ile formularz nie jest nieaktualny)
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		err = h.portfolioRepo.UpdateAssetWalletType(ctx, currentPortfolioID(r), version, assetID, newWalletType)
 		if err != nil {
 			message = fmt.Sprintf("Błąd aktualizacji aktywa: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
 			log.Printf("Error updating asset (ID: %s): %v", assetID, err)
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == assetID {
@@ -483,6 +1134,7 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 		}
 
 		log.Printf("Aktywo o ID %s zaktualizowane pomyślnie. Zmieniono typ portfela na %s.", assetID, newWalletType)
+		h.emitAssetEvent(ctx, r, models.WebhookAssetUpdated, assetID)
 		http.Redirect(w, r, "/", http.StatusSeeOther) // Przekieruj na stronę główną po sukcesie
 		return
 
@@ -494,7 +1146,7 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 		if err != nil {
 			http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
 			log.Printf("Error loading portfolio for update asset form: %v", err)
@@ -521,7 +1173,7 @@ func (h *AppHandler) UpdateWalletTypeHandler(w http.ResponseWriter, r *http.Requ
 
 // renderUpdateWalletTypeForm pomaga renderować komponent UpdateWalletTypeForm
 func (h *AppHandler) renderUpdateWalletTypeForm(w http.ResponseWriter, r *http.Request, asset models.Asset, message string) {
-	err := views.UpdateWalletTypeForm(asset, message).Render(r.Context(), w)
+	err := views.UpdateWalletTypeForm(asset, h.portfolioVersion(r), message).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering update asset form", http.StatusInternalServerError)
 		log.Printf("Error rendering update asset form: %v", err)
@@ -547,43 +1199,52 @@ func (h *AppHandler) AddSubscriptionHandler(w http.ResponseWriter, r *http.Reque
 
 		name := r.FormValue("name")
 		costStr := r.FormValue("cost")
-		frequency := r.FormValue("frequency")
 		nextDueStr := r.FormValue("nextDue")
 
-		cost, err := strconv.ParseFloat(costStr, 64)
-		if err != nil {
-			message = "Nieprawidłowa wartość 'Koszt'."
+		cost, err := models.ParseDecimal(costStr)
+		if err != nil || cost.IsNegative() {
+			message = subscriptionCostMessage
 			h.renderAddSubscriptionForm(w, r, message)
 			return
 		}
 
 		nextDue, err := time.Parse("2006-01-02", nextDueStr)
 		if err != nil {
-			message = "Nieprawidłowy format daty 'Następna Płatność'. Użyj YYYY-MM-DD."
+			message = nextDueMessage
 			h.renderAddSubscriptionForm(w, r, message)
 			return
 		}
 
-		newSub := models.Subscription{
-			ID:        models.GenerateID(),
-			Name:      name,
-			Cost:      cost,
-			Frequency: frequency,
-			NextDue:   nextDue,
-		}
-
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+		currency, err := models.NormalizeCurrency(r.FormValue("currency"))
 		if err != nil {
-			message = fmt.Sprintf("Błąd ładowania portfela: %v", err)
-			log.Printf("Error loading portfolio for subscription addition: %v", err)
+			message = currencyMessage
 			h.renderAddSubscriptionForm(w, r, message)
 			return
 		}
 
-		portfolio.AddSubscription(newSub)
+		newSub := models.Subscription{
+			ID:       models.GenerateID(),
+			Name:     name,
+			Cost:     cost,
+			NextDue:  nextDue,
+			Currency: currency,
+		}
+		if message = parseSubscriptionSchedule(r, &newSub); message != "" {
+			h.renderAddSubscriptionForm(w, r, message)
+			return
+		}
+		// Termin z przeszłości od razu przesuwamy na najbliższą przyszłą płatność
+		newSub.RollOver(models.Today())
 
-		if err := h.portfolioRepo.SavePortfolio(ctx, portfolio); err != nil {
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		if err := h.portfolioRepo.AddSubscription(ctx, currentPortfolioID(r), version, newSub); err != nil {
 			message = fmt.Sprintf("Błąd zapisu portfela: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
 			log.Printf("Error saving portfolio after subscription addition: %v", err)
 			h.renderAddSubscriptionForm(w, r, message)
 			return
@@ -597,9 +1258,53 @@ func (h *AppHandler) AddSubscriptionHandler(w http.ResponseWriter, r *http.Reque
 	h.renderAddSubscriptionForm(w, r, "")
 }
 
+// parseSubscriptionSchedule odczytuje z formularza częstotliwość ("frequency"), odstęp w dniach
+// ("intervalDays", tylko dla częstotliwości "Co N dni") oraz wyprzedzenie przypomnienia e-mail
+// ("reminderDays", puste - bez przypomnienia) i zapisuje je w subskrypcji.
+// Zwraca komunikat dla użytkownika, gdy dane są nieprawidłowe.
+func parseSubscriptionSchedule(r *http.Request, sub *models.Subscription) string {
+	sub.Frequency = models.SubscriptionFrequency(r.FormValue("frequency"))
+	sub.IntervalDays = 0
+	if sub.FrequencyCode() == models.FrequencyCustom {
+		days, err := strconv.Atoi(strings.TrimSpace(r.FormValue("intervalDays")))
+		if err != nil {
+			return intervalDaysMessage
+		}
+		sub.IntervalDays = days
+	}
+
+	sub.ReminderDays = 0
+	if value := strings.TrimSpace(r.FormValue("reminderDays")); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil {
+			return "Nieprawidłowe przypomnienie. Podaj liczbę dni przed terminem płatności."
+		}
+		sub.ReminderDays = days
+	}
+	return checkSubscriptionSchedule(sub)
+}
+
+// intervalDaysMessage to komunikat o nieprawidłowym odstępie płatności dla częstotliwości "Co N dni".
+const intervalDaysMessage = "Nieprawidłowy odstęp płatności. Podaj liczbę dni większą od zera."
+
+// checkSubscriptionSchedule sprawdza częstotliwość, odstęp w dniach i wyprzedzenie przypomnienia subskrypcji
+// (z formularza albo z API). Zwraca komunikat dla użytkownika, gdy są nieprawidłowe.
+func checkSubscriptionSchedule(sub *models.Subscription) string {
+	if sub.FrequencyCode() == models.FrequencyCustom && sub.IntervalDays < 1 {
+		return intervalDaysMessage
+	}
+	if err := sub.ValidateSchedule(); err != nil {
+		return "Nieprawidłowa częstotliwość. Wybierz jedną z listy."
+	}
+	if err := sub.ValidateReminder(); err != nil {
+		return fmt.Sprintf("Przypomnienie można ustawić od 0 do %d dni przed terminem płatności.", models.MaxReminderDays)
+	}
+	return ""
+}
+
 // renderAddSubscriptionForm pomaga renderować komponent AddSubscriptionForm
 func (h *AppHandler) renderAddSubscriptionForm(w http.ResponseWriter, r *http.Request, message string) {
-	err := views.AddSubscriptionForm(message).Render(r.Context(), w)
+	err := views.AddSubscriptionForm(h.portfolioVersion(r), message).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering add subscription form", http.StatusInternalServerError)
 		log.Printf("Error rendering add subscription form: %v", err)
@@ -630,7 +1335,15 @@ func (h *AppHandler) DeleteSubscriptionHandler(w http.ResponseWriter, r *http.Re
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	err = h.portfolioRepo.RemoveSubscription(ctx, subID)
+	version, ok := formVersion(w, r)
+	if !ok {
+		return
+	}
+	err = h.portfolioRepo.RemoveSubscription(ctx, currentPortfolioID(r), version, subID)
+	if errors.Is(err, repository.ErrConflict) {
+		h.renderHomeConflict(ctx, w, r)
+		return
+	}
 	if err != nil {
 		log.Printf("Błąd usuwania subskrypcji (ID: %s): %v", subID, err)
 		http.Error(w, fmt.Sprintf("Nie udało się usunąć subskrypcji: %v", err), http.StatusInternalServerError)
@@ -655,7 +1368,7 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 		if err != nil {
 			message = fmt.Sprintf("Błąd parsowania formularza: %v", err)
 			log.Printf("Error parsing update subscription form: %v", err)
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, s := range portfolio.Subscriptions {
 					if s.ID == r.FormValue("sub_id") {
@@ -671,7 +1384,6 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 		subID := r.FormValue("sub_id")
 		name := r.FormValue("name")
 		costStr := r.FormValue("cost")
-		frequency := r.FormValue("frequency")
 		nextDueStr := r.FormValue("nextDue")
 
 		if subID == "" {
@@ -680,10 +1392,10 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 			return
 		}
 
-		cost, err := strconv.ParseFloat(costStr, 64)
-		if err != nil || cost < 0 {
-			message = "Nieprawidłowa wartość 'Koszt'. Musi być liczbą nieujemną."
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+		cost, err := models.ParseDecimal(costStr)
+		if err != nil || cost.IsNegative() {
+			message = subscriptionCostMessage
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, s := range portfolio.Subscriptions {
 					if s.ID == subID {
@@ -698,8 +1410,24 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 
 		nextDue, err := time.Parse("2006-01-02", nextDueStr)
 		if err != nil {
-			message = "Nieprawidłowy format daty 'Następna Płatność'. Użyj YYYY-MM-DD."
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			message = nextDueMessage
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+			if loadErr == nil {
+				for _, s := range portfolio.Subscriptions {
+					if s.ID == subID {
+						targetSub = s
+						break
+					}
+				}
+			}
+			h.renderUpdateSubscriptionForm(w, r, targetSub, message)
+			return
+		}
+
+		currency, err := models.NormalizeCurrency(r.FormValue("currency"))
+		if err != nil {
+			message = currencyMessage
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, s := range portfolio.Subscriptions {
 					if s.ID == subID {
@@ -713,18 +1441,39 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 		}
 
 		updatedSub := models.Subscription{
-			ID:        subID, // Używamy istniejącego ID
-			Name:      name,
-			Cost:      cost,
-			Frequency: frequency,
-			NextDue:   nextDue,
+			ID:       subID, // Używamy istniejącego ID
+			Name:     name,
+			Cost:     cost,
+			NextDue:  nextDue,
+			Currency: currency,
+		}
+		if message = parseSubscriptionSchedule(r, &updatedSub); message != "" {
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+			if loadErr == nil {
+				for _, s := range portfolio.Subscriptions {
+					if s.ID == subID {
+						targetSub = s
+						break
+					}
+				}
+			}
+			h.renderUpdateSubscriptionForm(w, r, targetSub, message)
+			return
 		}
 
-		err = h.portfolioRepo.UpdateSubscription(ctx, updatedSub)
+		// Historii terminów i płatności formularz nie zawiera - repozytorium zmienia tylko edytowane pola
+		version, ok := formVersion(w, r)
+		if !ok {
+			return
+		}
+		err = h.portfolioRepo.UpdateSubscription(ctx, currentPortfolioID(r), version, updatedSub)
 		if err != nil {
 			message = fmt.Sprintf("Błąd aktualizacji subskrypcji: %v", err)
+			if errors.Is(err, repository.ErrConflict) {
+				message = conflictMessage
+			}
 			log.Printf("Error updating subscription (ID: %s): %v", subID, err)
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, s := range portfolio.Subscriptions {
 					if s.ID == subID {
@@ -749,7 +1498,7 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 			return
 		}
 
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 		if err != nil {
 			http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
 			log.Printf("Error loading portfolio for update subscription form: %v", err)
@@ -776,7 +1525,7 @@ func (h *AppHandler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Re
 
 // renderUpdateSubscriptionForm pomaga renderować komponent UpdateSubscriptionForm
 func (h *AppHandler) renderUpdateSubscriptionForm(w http.ResponseWriter, r *http.Request, sub models.Subscription, message string) {
-	err := views.UpdateSubscriptionForm(sub, message).Render(r.Context(), w)
+	err := views.UpdateSubscriptionForm(sub, h.portfolioVersion(r), message).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Error rendering update subscription form", http.StatusInternalServerError)
 		log.Printf("Error rendering update subscription form: %v", err)
@@ -799,7 +1548,7 @@ func (h *AppHandler) UpdateAssetPriceHandler(w http.ResponseWriter, r *http.Requ
 			message = fmt.Sprintf("Błąd parsowania formularza: %v", err)
 			log.Printf("Error parsing update asset form: %v", err)
 			// Spróbuj załadować aktywo, żeby formularz nie był pusty
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == r.FormValue("asset_id") {
@@ -814,11 +1563,11 @@ func (h *AppHandler) UpdateAssetPriceHandler(w http.ResponseWriter, r *http.Requ
 
 		assetID := r.FormValue("asset_id")
 		priceStr := r.FormValue("currentPrice")
-		newPrice, err := strconv.ParseFloat(priceStr, 64)
-		if err != nil || newPrice < 0 {
+		newPrice, err := models.ParseDecimal(priceStr)
+		if err != nil || newPrice.IsNegative() {
 			// Render form again with error
-			message := "Nieprawidłowa wartość 'Nowa Cena'. Musi być liczbą nieujemną."
-			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx)
+			message := priceMessage
+			portfolio, loadErr := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 			if loadErr == nil {
 				for _, a := range portfolio.Assets {
 					if a.ID == assetID {
@@ -831,7 +1580,14 @@ func (h *AppHandler) UpdateAssetPriceHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
-		err = h.portfolioRepo.UpdateAssetCurrentPrice(ctx, assetID, newPrice)
+		// Stan aktywa sprzed zmiany - do historii cen i zdarzenia price.changed (poprzednia cena)
+		var previous *models.Asset
+		if portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r)); err == nil {
+			previous, _ = portfolio.FindAsset(assetID)
+		}
+
+		quote := models.ManualQuote(newPrice)
+		err = h.portfolioRepo.UpdateAssetCurrentPrice(ctx, currentPortfolioID(r), assetID, quote)
 		if err != nil {
 			log.Printf("Błąd aktualizacji ceny aktywa (ID: %s): %v", assetID, err)
 			message := fmt.Sprintf("Nie udało się zaktualizować ceny: %v", err)
@@ -839,6 +1595,12 @@ func (h *AppHandler) UpdateAssetPriceHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
+		if previous != nil {
+			// Ręcznie wpisana cena trafia też do historii cen symbolu
+			h.recordPrice(ctx, previous.Symbol, quote)
+			h.emit(ctx, r, models.WebhookPriceChanged, models.NewPriceChange(*previous, quote))
+		}
+
 		log.Printf("Cena aktywa o ID %s zaktualizowana pomyślnie.", assetID)
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
@@ -850,7 +1612,7 @@ func (h *AppHandler) UpdateAssetPriceHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
-		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
 		if err != nil {
 			http.Error(w, "Nie udało się załadować portfela.", http.StatusInternalServerError)
 			log.Printf("Error loading portfolio for update asset form: %v", err)
@@ -897,7 +1659,7 @@ func (h *AppHandler) VisualizationsHandler(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
 	if err != nil {
 		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
 		return
@@ -932,7 +1694,7 @@ func (h *AppHandler) VisualizationsHandler(w http.ResponseWriter, r *http.Reques
 	sort.Strings(assetTypes)
 
 	// Renderuj całą stronę
-	views.VisualizationsPage(portfolioTypes, assetTypes, portfolio).Render(r.Context(), w)
+	views.VisualizationsPage(portfolioTypes, assetTypes, priceHistorySymbols(portfolio), portfolio).Render(r.Context(), w)
 }
 
 // GetVisualizationDataHandler - teraz renderuje cały panel filtrów i wykres
@@ -941,7 +1703,7 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 	defer cancel()
 	theme := middleware.GetTheme(ctx)
 
-	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx)
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
 	if err != nil {
 		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
 		return
@@ -962,6 +1724,10 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 	if chartType == "" {
 		chartType = "pie" // Domyślny typ wykresu
 	}
+	rangeKey := r.URL.Query().Get("range")
+	if !slices.Contains(models.SnapshotRanges, rangeKey) {
+		rangeKey = models.DefaultSnapshotRange
+	}
 
 	// 2. Filtruj aktywa w dwóch krokach
 	var tempAssets []models.Asset
@@ -989,9 +1755,9 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 	}
 
 	// 3. Przygotuj dane dla wykresu (bez zmian)
-	valueByAsset := make(map[string]float64)
+	valueByAsset := make(map[string]models.Decimal)
 	for _, asset := range filteredAssets {
-		valueByAsset[asset.Name] += asset.Quantity * asset.CurrentPrice
+		valueByAsset[asset.Name] = valueByAsset[asset.Name].Add(portfolio.ToBase(asset.Quantity.Mul(asset.CurrentPrice), asset.CurrencyCode()))
 	}
 
 	// 4. Utwórz i skonfiguruj wykres na podstawie parametru `chartType`
@@ -999,6 +1765,14 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 	chartID := "portfolio-chart"
 
 	switch chartType {
+	case "history":
+		// Wartość w czasie z dziennych zapisów portfela (filtr typu aktywa nie ma tu zastosowania)
+		chartJSON, err = h.netWorthChart(ctx, r, portfolio, portfolioType, rangeKey, theme)
+		if err != nil {
+			log.Printf("Error loading portfolio snapshots: %v", err)
+			http.Error(w, "Nie udało się załadować historii wartości portfela", http.StatusInternalServerError)
+			return
+		}
 	case "bar":
 		// Logic for the bar chart
 		xAxisData := make([]string, 0)
@@ -1006,7 +1780,7 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 
 		for name, value := range valueByAsset {
 			xAxisData = append(xAxisData, name)
-			barData = append(barData, opts.BarData{Value: fmt.Sprintf("%.2f", value)})
+			barData = append(barData, opts.BarData{Value: value.StringFixed(2)})
 		}
 
 		bar := charts.NewBar()
@@ -1039,7 +1813,7 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 		// Logika dla wykresu kołowego (jak wcześniej)
 		pieData := make([]opts.PieData, 0)
 		for name, value := range valueByAsset {
-			pieData = append(pieData, opts.PieData{Name: name, Value: fmt.Sprintf("%.2f", value)})
+			pieData = append(pieData, opts.PieData{Name: name, Value: value.StringFixed(2)})
 		}
 		pie := charts.NewPie()
 
@@ -1106,6 +1880,7 @@ func (h *AppHandler) GetVisualizationDataHandler(w http.ResponseWriter, r *http.
 		portfolioType,
 		assetType,
 		chartType,
+		rangeKey,
 		chartID,
 		chartJSON,
 	).Render(r.Context(), w)
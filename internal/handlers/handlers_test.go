@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/webhooks"
+)
+
+// newTestHandler tworzy handler na repozytorium w pamięci oraz zalogowanego użytkownika z pustym portfelem.
+func newTestHandler(t *testing.T) (*AppHandler, *repository.MemoryPortfolioRepo, *models.User) {
+	t.Helper()
+	store := repository.NewMemoryPortfolioRepo()
+	user := &models.User{ID: "U1", Email: "test@example.com", PortfolioID: "P1", CreatedAt: time.Now()}
+	if err := store.CreateUser(context.Background(), *user); err != nil {
+		t.Fatalf("CreateUser() unexpected error: %v", err)
+	}
+	if err := store.CreatePortfolio(context.Background(), models.PortfolioInfo{ID: "P1", Name: "Test", OwnerID: user.ID}); err != nil {
+		t.Fatalf("CreatePortfolio() unexpected error: %v", err)
+	}
+	return NewAppHandler(store, webhooks.NewDispatcher(store, time.Hour)), store, user
+}
+
+// postForm wysyła formularz POST do handlera w imieniu zalogowanego użytkownika.
+func postForm(handler http.HandlerFunc, user *models.User, target string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(middleware.WithUser(req.Context(), user))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// loadTestPortfolio wczytuje portfel użytkownika testowego.
+func loadTestPortfolio(t *testing.T, store repository.Store) *models.InvestmentPortfolio {
+	t.Helper()
+	portfolio, err := store.LoadPortfolio(context.Background(), "P1")
+	if err != nil {
+		t.Fatalf("LoadPortfolio() unexpected error: %v", err)
+	}
+	return portfolio
+}
+
+// TestAssetHandlers sprawdza dodawanie, dokupowanie i usuwanie aktywa przez formularze,
+// w tym odrzucenie zmian z nieaktualnego formularza (wersja portfela) z komunikatem zamiast pustej odpowiedzi.
+func TestAssetHandlers(t *testing.T) {
+	h, store, user := newTestHandler(t)
+	form := url.Values{
+		"name": {"Akcje Test"}, "symbol": {"TST"}, "type": {"Akcje"}, "quantity": {"10"},
+		"avgCost": {"100"}, "currentPrice": {"120"}, "walletType": {"Inwestycje"}, "currency": {"PLN"},
+	}
+
+	// Formularz bez wersji portfela jest odrzucany, zamiast zapisywać zmianę bez sprawdzania
+	rec := postForm(h.AddAssetHandler, user, "/add-asset", form)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("AddAssetHandler without version expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	form.Set("version", strconv.FormatInt(loadTestPortfolio(t, store).Version, 10))
+	rec = postForm(h.AddAssetHandler, user, "/add-asset", form)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("AddAssetHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	portfolio := loadTestPortfolio(t, store)
+	if len(portfolio.Assets) != 1 || portfolio.Assets[0].Quantity != models.NewDecimal(10) {
+		t.Fatalf("AddAssetHandler expected one asset with quantity 10, got %+v", portfolio.Assets)
+	}
+	assetID := portfolio.Assets[0].ID
+	stale := strconv.FormatInt(portfolio.Version, 10)
+
+	rec = postForm(h.UpdateAssetHandler, user, "/update-asset", url.Values{
+		"asset_id": {assetID}, "additional_quantity": {"5"}, "new_purchase_price": {"130"}, "version": {stale},
+	})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("UpdateAssetHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	portfolio = loadTestPortfolio(t, store)
+	if portfolio.Assets[0].Quantity != models.NewDecimal(15) {
+		t.Errorf("UpdateAssetHandler expected quantity 15, got %s", portfolio.Assets[0].Quantity)
+	}
+
+	// Formularz wyświetlony przed poprzednią zmianą ma już nieaktualną wersję portfela
+	rec = postForm(h.UpdateAssetHandler, user, "/update-asset", url.Values{
+		"asset_id": {assetID}, "additional_quantity": {"5"}, "new_purchase_price": {"130"}, "version": {stale},
+	})
+	if !strings.Contains(rec.Body.String(), conflictMessage) {
+		t.Errorf("UpdateAssetHandler with stale version expected conflict message, got status %d: %s", rec.Code, rec.Body)
+	}
+	if quantity := loadTestPortfolio(t, store).Assets[0].Quantity; quantity != models.NewDecimal(15) {
+		t.Errorf("UpdateAssetHandler with stale version should not change quantity, got %s", quantity)
+	}
+
+	rec = postForm(h.DeleteAssetHandler, user, "/delete-asset?id="+assetID, url.Values{"version": {stale}})
+	if !strings.Contains(rec.Body.String(), conflictMessage) {
+		t.Errorf("DeleteAssetHandler with stale version expected conflict message, got status %d: %s", rec.Code, rec.Body)
+	}
+	if assets := loadTestPortfolio(t, store).Assets; len(assets) != 1 {
+		t.Errorf("DeleteAssetHandler with stale version should keep the asset, got %d assets", len(assets))
+	}
+
+	current := strconv.FormatInt(loadTestPortfolio(t, store).Version, 10)
+	rec = postForm(h.DeleteAssetHandler, user, "/delete-asset?id="+assetID, url.Values{"version": {current}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("DeleteAssetHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	if assets := loadTestPortfolio(t, store).Assets; len(assets) != 0 {
+		t.Errorf("DeleteAssetHandler expected no assets, got %+v", assets)
+	}
+}
+
+// TestSubscriptionHandlers sprawdza dodawanie, edycję i usuwanie subskrypcji przez formularze. Edycja nie może
+// skasować historii płatności (formularz jej nie zawiera), a nieaktualny formularz kończy się konfliktem.
+func TestSubscriptionHandlers(t *testing.T) {
+	h, store, user := newTestHandler(t)
+	nextDue := models.Today().AddDate(0, 1, 0).Format("2006-01-02")
+
+	rec := postForm(h.AddSubscriptionHandler, user, "/add-subscription", url.Values{
+		"name": {"Siłownia"}, "cost": {"100"}, "nextDue": {nextDue}, "currency": {"PLN"}, "frequency": {"monthly"},
+		"version": {strconv.FormatInt(loadTestPortfolio(t, store).Version, 10)},
+	})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("AddSubscriptionHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	portfolio := loadTestPortfolio(t, store)
+	if len(portfolio.Subscriptions) != 1 || portfolio.GetMonthlySubscriptionCost() != models.NewDecimal(100) {
+		t.Fatalf("AddSubscriptionHandler expected one subscription costing 100 a month, got %+v", portfolio.Subscriptions)
+	}
+	subID := portfolio.Subscriptions[0].ID
+
+	payment := models.SubscriptionPayment{Date: models.Today(), Amount: models.NewDecimal(100)}
+	if err := store.RecordSubscriptionPayment(context.Background(), "P1", repository.AnyVersion, subID, payment, false); err != nil {
+		t.Fatalf("RecordSubscriptionPayment() unexpected error: %v", err)
+	}
+	stale := strconv.FormatInt(loadTestPortfolio(t, store).Version, 10)
+
+	rec = postForm(h.UpdateSubscriptionHandler, user, "/update-subscription", url.Values{
+		"sub_id": {subID}, "name": {"Siłownia Plus"}, "cost": {"120"}, "nextDue": {nextDue}, "currency": {"PLN"},
+		"frequency": {"monthly"}, "version": {stale},
+	})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("UpdateSubscriptionHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	sub := loadTestPortfolio(t, store).Subscriptions[0]
+	if sub.Name != "Siłownia Plus" || sub.Cost != models.NewDecimal(120) || len(sub.Payments) != 1 {
+		t.Errorf("UpdateSubscriptionHandler expected new name and cost with the payment kept, got %+v", sub)
+	}
+
+	rec = postForm(h.UpdateSubscriptionHandler, user, "/update-subscription", url.Values{
+		"sub_id": {subID}, "name": {"Stara nazwa"}, "cost": {"100"}, "nextDue": {nextDue}, "currency": {"PLN"},
+		"frequency": {"monthly"}, "version": {stale},
+	})
+	if !strings.Contains(rec.Body.String(), conflictMessage) {
+		t.Errorf("UpdateSubscriptionHandler with stale version expected conflict message, got status %d: %s", rec.Code, rec.Body)
+	}
+	if name := loadTestPortfolio(t, store).Subscriptions[0].Name; name != "Siłownia Plus" {
+		t.Errorf("UpdateSubscriptionHandler with stale version should not change the name, got %q", name)
+	}
+
+	rec = postForm(h.DeleteSubscriptionHandler, user, "/delete-subscription", url.Values{"sub_id": {subID}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("DeleteSubscriptionHandler without version expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = postForm(h.DeleteSubscriptionHandler, user, "/delete-subscription", url.Values{"sub_id": {subID}, "version": {stale}})
+	if !strings.Contains(rec.Body.String(), conflictMessage) {
+		t.Errorf("DeleteSubscriptionHandler with stale version expected conflict message, got status %d: %s", rec.Code, rec.Body)
+	}
+
+	current := strconv.FormatInt(loadTestPortfolio(t, store).Version, 10)
+	rec = postForm(h.DeleteSubscriptionHandler, user, "/delete-subscription", url.Values{"sub_id": {subID}, "version": {current}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("DeleteSubscriptionHandler expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body)
+	}
+	if subs := loadTestPortfolio(t, store).Subscriptions; len(subs) != 0 {
+		t.Errorf("DeleteSubscriptionHandler expected no subscriptions, got %+v", subs)
+	}
+}
+
+// TestPrepareNewAsset sprawdza walidację nowego aktywa wspólną dla formularza i API.
+func TestPrepareNewAsset(t *testing.T) {
+	valid := models.Asset{Name: "Akcje", Quantity: models.NewDecimal(10), AvgCost: models.NewDecimal(100), CurrentPrice: models.NewDecimal(120), Currency: "usd"}
+
+	tests := []struct {
+		name    string
+		change  func(a *models.Asset)
+		wantErr bool
+	}{
+		{"valid", func(a *models.Asset) {}, false},
+		{"zero avg cost", func(a *models.Asset) { a.AvgCost = models.Zero }, false},
+		{"zero quantity", func(a *models.Asset) { a.Quantity = models.Zero }, true},
+		{"negative quantity", func(a *models.Asset) { a.Quantity = models.NewDecimal(-1) }, true},
+		{"negative avg cost", func(a *models.Asset) { a.AvgCost = models.NewDecimal(-1) }, true},
+		{"negative current price", func(a *models.Asset) { a.CurrentPrice = models.NewDecimal(-1) }, true},
+		{"invalid currency", func(a *models.Asset) { a.Currency = "zł" }, true},
+	}
+	for _, tt := range tests {
+		input := valid
+		tt.change(&input)
+		asset, message := prepareNewAsset(input)
+		if (message != "") != tt.wantErr {
+			t.Errorf("prepareNewAsset(%s) expected error %v, got message %q", tt.name, tt.wantErr, message)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if asset.Currency != "USD" || len(asset.Transactions) != 1 {
+			t.Errorf("prepareNewAsset(%s) expected USD asset with an opening transaction, got %+v", tt.name, asset)
+			continue
+		}
+		if opening := asset.Transactions[0]; opening.Amount != input.Quantity.Mul(input.AvgCost) {
+			t.Errorf("prepareNewAsset(%s) expected opening amount %s, got %s", tt.name, input.Quantity.Mul(input.AvgCost), opening.Amount)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// calendarFeedURL zwraca adres kanału iCalendar z tokenem dla serwera, który obsłużył żądanie.
+func calendarFeedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/subscriptions.ics?token=" + url.QueryEscape(token)
+}
+
+// userPortfolios wczytuje wszystkie portfele użytkownika (portfel główny jako pierwszy).
+func (h *AppHandler) userPortfolios(ctx context.Context, user *models.User) ([]*models.InvestmentPortfolio, error) {
+	infos, err := h.portfolioRepo.ListPortfolios(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{user.PortfolioID}
+	for _, info := range infos {
+		if info.ID != user.PortfolioID {
+			ids = append(ids, info.ID)
+		}
+	}
+
+	portfolios := make([]*models.InvestmentPortfolio, 0, len(ids))
+	for _, id := range ids {
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+	return portfolios, nil
+}
+
+// SubscriptionsICSHandler udostępnia kanał iCalendar z terminami subskrypcji wszystkich portfeli
+// użytkownika. Kalendarze w telefonach nie mają ciasteczka sesji, więc użytkownika rozpoznajemy
+// po tajnym tokenie z parametru "token". Kanał budujemy przy każdym żądaniu, dlatego dodanie,
+// zmiana lub usunięcie subskrypcji widać przy następnym odświeżeniu kalendarza.
+func (h *AppHandler) SubscriptionsICSHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	user, err := h.userRepo.GetUserByCalendarToken(ctx, models.HashSessionToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading user for calendar feed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	portfolios, err := h.userPortfolios(ctx, user)
+	if err != nil {
+		log.Printf("Error loading portfolios for calendar feed of %s: %v", user.Email, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="subscriptions.ics"`)
+	w.Header().Set("Cache-Control", "no-cache")
+	if _, err := w.Write(models.SubscriptionsICS(portfolios, time.Now())); err != nil {
+		log.Printf("Error writing calendar feed: %v", err)
+	}
+}
+
+// CalendarFeedHandler pokazuje stan kanału iCalendar zalogowanego użytkownika. POST z action=generate
+// tworzy nowy adres (poprzedni przestaje działać) i pokazuje go jeden raz, a action=disable wyłącza kanał.
+func (h *AppHandler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		h.renderCalendarFeedPage(w, r, "", user.CalendarTokenHash != "", "")
+		return
+	}
+
+	switch r.FormValue("action") {
+	case "generate":
+		token, hash, err := models.NewCalendarToken()
+		if err == nil {
+			err = h.userRepo.SetCalendarToken(ctx, user.ID, hash)
+		}
+		if err != nil {
+			log.Printf("Error creating calendar token for %s: %v", user.Email, err)
+			http.Error(w, "Nie udało się utworzyć adresu kalendarza", http.StatusInternalServerError)
+			return
+		}
+		h.renderCalendarFeedPage(w, r, calendarFeedURL(r, token), true, "")
+	case "disable":
+		if err := h.userRepo.SetCalendarToken(ctx, user.ID, ""); err != nil {
+			log.Printf("Error disabling calendar feed of %s: %v", user.Email, err)
+			http.Error(w, "Nie udało się wyłączyć kanału kalendarza", http.StatusInternalServerError)
+			return
+		}
+		h.renderCalendarFeedPage(w, r, "", false, "Kanał kalendarza wyłączony - dotychczasowy adres przestał działać.")
+	default:
+		http.Error(w, "Nieznana operacja", http.StatusBadRequest)
+	}
+}
+
+// renderCalendarFeedPage pomaga renderować komponent CalendarFeedPage.
+func (h *AppHandler) renderCalendarFeedPage(w http.ResponseWriter, r *http.Request, feedURL string, enabled bool, message string) {
+	if err := views.CalendarFeedPage(feedURL, enabled, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering calendar feed page", http.StatusInternalServerError)
+		log.Printf("Error rendering calendar feed page: %v", err)
+	}
+}
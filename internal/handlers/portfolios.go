@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// loadSelectedPortfolio wczytuje portfel wybrany w przełączniku. Dla widoku "Wszystkie portfele"
+// wczytuje każdy portfel użytkownika i łączy je w jeden portfel zbiorczy (tylko do odczytu).
+func (h *AppHandler) loadSelectedPortfolio(ctx context.Context, r *http.Request) (*models.InvestmentPortfolio, error) {
+	selection, ok := middleware.GetPortfolioSelection(r.Context())
+	if !ok || !selection.IsAggregate() {
+		return h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	}
+	return h.loadPortfolioView(ctx, selection, models.AllPortfoliosID)
+}
+
+// loadPortfolioView wczytuje portfel użytkownika o podanym ID, a dla models.AllPortfoliosID - portfel zbiorczy
+// ze wszystkich portfeli z listy selection.
+func (h *AppHandler) loadPortfolioView(ctx context.Context, selection middleware.PortfolioSelection, portfolioID string) (*models.InvestmentPortfolio, error) {
+	if portfolioID != models.AllPortfoliosID {
+		return h.portfolioRepo.LoadPortfolio(ctx, portfolioID)
+	}
+
+	portfolios := make([]*models.InvestmentPortfolio, 0, len(selection.Portfolios))
+	for _, info := range selection.Portfolios {
+		portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, info.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load portfolio %s: %w", info.Name, err)
+		}
+		portfolios = append(portfolios, portfolio)
+	}
+	return models.MergePortfolios(portfolios), nil
+}
+
+// setPortfolioCookie zapamiętuje w ciasteczku portfel wybrany w przełączniku.
+func setPortfolioCookie(w http.ResponseWriter, portfolioID string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.PortfolioCookieName,
+		Value:    portfolioID,
+		Path:     "/",
+		MaxAge:   3600 * 24 * 365, // Ważne przez rok
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// SelectPortfolioHandler przełącza wybrany portfel (lub widok zbiorczy) i wraca na poprzednią stronę.
+func (h *AppHandler) SelectPortfolioHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing portfolio selection form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	portfolioID := r.FormValue("portfolio_id")
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	if portfolioID != models.AllPortfoliosID && !selection.Owns(portfolioID) {
+		http.Error(w, "Nie znaleziono portfela.", http.StatusNotFound)
+		return
+	}
+
+	setPortfolioCookie(w, portfolioID)
+
+	redirect := r.Header.Get("Referer")
+	if redirect == "" {
+		redirect = "/"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// PortfoliosHandler wyświetla listę portfeli użytkownika (GET) i zakłada nowy portfel (POST).
+// Nowo utworzony portfel jest od razu wybierany w przełączniku.
+func (h *AppHandler) PortfoliosHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	var message string
+
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			log.Printf("Error parsing portfolio form: %v", err)
+			http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+			return
+		}
+
+		name, err := models.NormalizePortfolioName(r.FormValue("name"))
+		if err != nil {
+			message = fmt.Sprintf("Podaj nazwę portfela (maksymalnie %d znaków).", models.MaxPortfolioNameLength)
+		} else {
+			user, _ := middleware.GetUser(r.Context())
+			info := models.PortfolioInfo{ID: models.GenerateID(), Name: name, OwnerID: user.ID}
+			if err := h.portfolioRepo.CreatePortfolio(ctx, info); err != nil {
+				message = fmt.Sprintf("Błąd tworzenia portfela: %v", err)
+				log.Printf("Error creating portfolio %s: %v", name, err)
+			} else {
+				setPortfolioCookie(w, info.ID)
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+				return
+			}
+		}
+	}
+
+	if err := views.PortfoliosPage(selection, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering portfolios page", http.StatusInternalServerError)
+		log.Printf("Error rendering portfolios page: %v", err)
+	}
+}
+
+// RenamePortfolioHandler zmienia nazwę portfela użytkownika.
+func (h *AppHandler) RenamePortfolioHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing portfolio rename form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	portfolioID := r.FormValue("portfolio_id")
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	if !selection.Owns(portfolioID) {
+		http.Error(w, "Nie znaleziono portfela.", http.StatusNotFound)
+		return
+	}
+	name, err := models.NormalizePortfolioName(r.FormValue("name"))
+	if err != nil {
+		http.Error(w, "Nieprawidłowa nazwa portfela.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.RenamePortfolio(ctx, portfolioID, name); err != nil {
+		log.Printf("Error renaming portfolio %s: %v", portfolioID, err)
+		http.Error(w, fmt.Sprintf("Nie udało się zmienić nazwy portfela: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/portfolios", http.StatusSeeOther)
+}
+
+// DeletePortfolioHandler usuwa portfel użytkownika razem z aktywami i subskrypcjami.
+// Portfela głównego nie można usunąć - to on jest używany w widoku zbiorczym do zmian.
+func (h *AppHandler) DeletePortfolioHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		log.Printf("Error parsing portfolio delete form: %v", err)
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+
+	portfolioID := r.FormValue("portfolio_id")
+	selection, _ := middleware.GetPortfolioSelection(r.Context())
+	if !selection.Owns(portfolioID) {
+		http.Error(w, "Nie znaleziono portfela.", http.StatusNotFound)
+		return
+	}
+	if portfolioID == selection.DefaultID {
+		http.Error(w, "Nie można usunąć portfela głównego.", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.portfolioRepo.DeletePortfolio(ctx, portfolioID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Error deleting portfolio %s: %v", portfolioID, err)
+		http.Error(w, fmt.Sprintf("Nie udało się usunąć portfela: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Usunięty portfel nie może pozostać wybrany - wracamy do portfela głównego
+	if selection.SelectedID == portfolioID {
+		setPortfolioCookie(w, selection.DefaultID)
+	}
+	http.Redirect(w, r, "/portfolios", http.StatusSeeOther)
+}
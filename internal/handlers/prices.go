@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/views"
+)
+
+// priceHistoryDays to okres pokazywany na wykresie historii cen.
+const priceHistoryDays = 365
+
+// recordPrice dopisuje notowanie do historii cen symbolu. Błąd zapisu historii nie cofa
+// zmiany ceny, więc tylko go logujemy.
+func (h *AppHandler) recordPrice(ctx context.Context, symbol string, quote models.PriceQuote) {
+	if models.NormalizeSymbol(symbol) == "" {
+		return
+	}
+	if err := h.priceHistory.RecordPrice(ctx, models.NewPricePoint(symbol, quote)); err != nil {
+		log.Printf("Error recording price history of %s: %v", symbol, err)
+	}
+}
+
+// priceHistorySymbols zwraca posortowane, unikalne symbole aktywów portfela (aktywa bez symbolu nie mają historii cen).
+func priceHistorySymbols(portfolio *models.InvestmentPortfolio) []string {
+	symbols := []string{}
+	for _, asset := range portfolio.Assets {
+		symbol := models.NormalizeSymbol(asset.Symbol)
+		if symbol != "" && !slices.Contains(symbols, symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// PriceHistoryHandler - endpoint HTMX renderujący wykres liniowy historii cen wybranego symbolu z ostatniego roku.
+func (h *AppHandler) PriceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
+		return
+	}
+
+	symbols := priceHistorySymbols(portfolio)
+	symbol := models.NormalizeSymbol(r.URL.Query().Get("symbol"))
+	if !slices.Contains(symbols, symbol) {
+		// Historię pokazujemy tylko dla symboli z wybranego portfela
+		symbol = ""
+	}
+
+	var chartJSON map[string]interface{}
+	if symbol != "" {
+		to := models.Today()
+		points, err := h.priceHistory.PriceHistory(ctx, symbol, to.AddDate(0, 0, -priceHistoryDays), to)
+		if err != nil {
+			log.Printf("Error loading price history of %s: %v", symbol, err)
+			http.Error(w, "Nie udało się załadować historii cen", http.StatusInternalServerError)
+			return
+		}
+		if len(points) > 0 {
+			chartJSON = priceHistoryChart(symbol, points, middleware.GetTheme(ctx))
+		}
+	}
+
+	if err := views.PriceHistoryChart(symbols, symbol, chartJSON).Render(r.Context(), w); err != nil {
+		log.Printf("Error rendering price history chart: %v", err)
+	}
+}
+
+// priceHistoryChart buduje konfigurację wykresu liniowego cen zamknięcia symbolu.
+func priceHistoryChart(symbol string, points []models.PricePoint, theme string) map[string]interface{} {
+	dates := make([]string, 0, len(points))
+	closes := make([]opts.LineData, 0, len(points))
+	for _, p := range points {
+		dates = append(dates, p.Date.Format("2006-01-02"))
+		closes = append(closes, opts.LineData{Value: p.Close.StringFixed(2)})
+	}
+
+	labelColor := "#000000"
+	if theme == "dark" {
+		labelColor = "#b4b4b4ff"
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Historia cen: " + symbol,
+			Subtitle: "Ceny zamknięcia z ostatnich 12 miesięcy",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{TextStyle: &opts.TextStyle{Color: labelColor}}),
+		charts.WithYAxisOpts(opts.YAxis{Scale: opts.Bool(true)}),
+	)
+	line.SetXAxis(dates).AddSeries(symbol, closes)
+	return line.JSON()
+}
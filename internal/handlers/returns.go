@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/views"
+)
+
+// loadPriceHistories wczytuje pełną historię cen wszystkich symboli portfela - potrzebną do wyceny
+// pozycji w przeszłych dniach przy liczeniu stóp zwrotu.
+func (h *AppHandler) loadPriceHistories(ctx context.Context, portfolio *models.InvestmentPortfolio) (map[string][]models.PricePoint, error) {
+	prices := make(map[string][]models.PricePoint)
+	for _, symbol := range priceHistorySymbols(portfolio) {
+		points, err := h.priceHistory.PriceHistory(ctx, symbol, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		prices[symbol] = points
+	}
+	return prices, nil
+}
+
+// portfolioReturns wylicza stopy zwrotu portfela w okresie. Gdy historii cen nie da się wczytać,
+// liczymy dalej tylko na cenach z transakcji - stopy zwrotu nie są warte przerwania strony.
+func (h *AppHandler) portfolioReturns(ctx context.Context, portfolio *models.InvestmentPortfolio, from, to time.Time) models.ReturnsReport {
+	prices, err := h.loadPriceHistories(ctx, portfolio)
+	if err != nil {
+		log.Printf("Error loading price history for returns: %v", err)
+	}
+	return portfolio.Returns(from, to, prices)
+}
+
+// ReturnsHandler wyświetla tabelę stóp zwrotu TWR i XIRR wybranego portfela, jego typów portfela
+// i aktywów w okresie z parametrów "from" i "to" (RRRR-MM-DD). Bez "from" liczymy od pierwszej transakcji,
+// a bez "to" - do dzisiaj.
+func (h *AppHandler) ReturnsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for returns page: %v", err)
+		return
+	}
+
+	var message string
+	today := models.Today()
+	from, to := time.Time{}, today
+	if value := r.URL.Query().Get("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			message = "Nieprawidłowa data początkowa. Użyj formatu RRRR-MM-DD."
+			from = time.Time{}
+		}
+	}
+	if value := r.URL.Query().Get("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			message = "Nieprawidłowa data końcowa. Użyj formatu RRRR-MM-DD."
+			to = today
+		}
+	}
+	if to.After(today) {
+		to = today
+	}
+	if !from.IsZero() && !from.Before(to) {
+		message = "Data początkowa musi być wcześniejsza niż końcowa."
+		from = time.Time{}
+	}
+
+	report := h.portfolioReturns(ctx, portfolio, from, to)
+	if err := views.ReturnsPage(portfolio, report, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering returns page", http.StatusInternalServerError)
+		log.Printf("Error rendering returns page: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+)
+
+// loadSnapshots wczytuje dzienne zapisy wartości wybranego portfela z podanego zakresu.
+// W widoku "Wszystkie portfele" sumuje zapisy wszystkich portfeli użytkownika w walucie bazowej portfela zbiorczego.
+func (h *AppHandler) loadSnapshots(ctx context.Context, r *http.Request, portfolio *models.InvestmentPortfolio, rangeKey string) ([]models.PortfolioSnapshot, error) {
+	today := models.Today()
+	from := models.SnapshotRangeStart(rangeKey, today)
+
+	selection, ok := middleware.GetPortfolioSelection(r.Context())
+	if !ok || !selection.IsAggregate() {
+		return h.snapshots.Snapshots(ctx, currentPortfolioID(r), from, today)
+	}
+
+	var all []models.PortfolioSnapshot
+	for _, info := range selection.Portfolios {
+		snapshots, err := h.snapshots.Snapshots(ctx, info.ID, from, today)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, snapshots...)
+	}
+	return models.MergeSnapshots(all, portfolio.GetBaseCurrency(), portfolio.ToBase), nil
+}
+
+// netWorthChart buduje wykres warstwowy wartości portfela w czasie. Dla "Wszystkie" pokazuje
+// wartość i koszt całego portfela, a dla wybranego typu portfela - wartość aktywów tego typu.
+// Zwraca nil, jeśli w zakresie nie ma jeszcze żadnych zapisów.
+func (h *AppHandler) netWorthChart(ctx context.Context, r *http.Request, portfolio *models.InvestmentPortfolio, portfolioType, rangeKey, theme string) (map[string]interface{}, error) {
+	snapshots, err := h.loadSnapshots(ctx, r, portfolio, rangeKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	dates := make([]string, 0, len(snapshots))
+	values := make([]opts.LineData, 0, len(snapshots))
+	costs := make([]opts.LineData, 0, len(snapshots))
+	for _, s := range snapshots {
+		dates = append(dates, s.Date.Format("2006-01-02"))
+		if portfolioType == "Wszystkie" {
+			values = append(values, opts.LineData{Value: s.TotalValue.StringFixed(2)})
+			costs = append(costs, opts.LineData{Value: s.TotalCost.StringFixed(2)})
+		} else {
+			values = append(values, opts.LineData{Value: s.WalletValues[portfolioType].StringFixed(2)})
+		}
+	}
+
+	labelColor := "#000000"
+	if theme == "dark" {
+		labelColor = "#b4b4b4ff"
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Wartość portfela w czasie",
+			Subtitle: "Dzienne zapisy w " + portfolio.GetBaseCurrency() + " (" + portfolioType + ")",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{TextStyle: &opts.TextStyle{Color: labelColor}}),
+		charts.WithYAxisOpts(opts.YAxis{Scale: opts.Bool(true)}),
+	)
+	line.SetXAxis(dates).AddSeries("Wartość", values, charts.WithAreaStyleOpts(opts.AreaStyle{Opacity: opts.Float(0.3)}))
+	if portfolioType == "Wszystkie" {
+		line.AddSeries("Koszt zakupu", costs)
+	}
+	return line.JSON(), nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// findSubscription zwraca subskrypcję o podanym ID z bieżącego portfela.
+func (h *AppHandler) findSubscription(ctx context.Context, r *http.Request, subID string) (models.Subscription, bool) {
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		log.Printf("Error loading portfolio for subscription %s: %v", subID, err)
+		return models.Subscription{}, false
+	}
+	for _, s := range portfolio.Subscriptions {
+		if s.ID == subID {
+			return s, true
+		}
+	}
+	return models.Subscription{}, false
+}
+
+// SubscriptionPaymentHandler zapisuje faktyczną płatność subskrypcji. Pola "date" (RRRR-MM-DD)
+// i "amount" są opcjonalne - domyślnie dzisiaj i koszt subskrypcji. Gdy "markPaid" jest ustawione,
+// płatność rozlicza bieżący termin i NextDue przesuwa się na kolejny. Formularz ze strony edycji
+// subskrypcji (from=edit) wraca na tę stronę, przycisk z tabeli subskrypcji - na stronę główną.
+func (h *AppHandler) SubscriptionPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Metoda niedozwolona", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+		return
+	}
+	subID := r.FormValue("sub_id")
+	if subID == "" {
+		http.Error(w, "Brak identyfikatora subskrypcji w formularzu.", http.StatusBadRequest)
+		return
+	}
+	sub, ok := h.findSubscription(ctx, r, subID)
+	if !ok {
+		http.Error(w, "Nie znaleziono subskrypcji.", http.StatusNotFound)
+		return
+	}
+
+	fromEdit := r.FormValue("from") == "edit"
+	fail := func(message string, status int) {
+		if fromEdit {
+			h.renderUpdateSubscriptionForm(w, r, sub, message)
+			return
+		}
+		http.Error(w, message, status)
+	}
+
+	payment := models.SubscriptionPayment{
+		ID:     models.GenerateID(),
+		Date:   models.Today(),
+		Amount: sub.Cost,
+		Note:   strings.TrimSpace(r.FormValue("note")),
+	}
+	if value := strings.TrimSpace(r.FormValue("date")); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			fail("Nieprawidłowy format daty płatności. Użyj YYYY-MM-DD.", http.StatusBadRequest)
+			return
+		}
+		payment.Date = date
+	}
+	if value := strings.TrimSpace(r.FormValue("amount")); value != "" {
+		amount, err := models.ParseDecimal(value)
+		if err != nil || amount.IsNegative() {
+			fail("Nieprawidłowa kwota płatności. Musi być liczbą nieujemną.", http.StatusBadRequest)
+			return
+		}
+		payment.Amount = amount
+	}
+	markPaid := r.FormValue("markPaid") != ""
+
+	version, ok := formVersion(w, r)
+	if !ok {
+		return
+	}
+	err := h.portfolioRepo.RecordSubscriptionPayment(ctx, currentPortfolioID(r), version, subID, payment, markPaid)
+	if errors.Is(err, repository.ErrConflict) {
+		if !fromEdit {
+			h.renderHomeConflict(ctx, w, r)
+			return
+		}
+		fail(conflictMessage, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error recording payment of subscription %s: %v", subID, err)
+		fail("Nie udało się zapisać płatności.", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Payment of subscription %s recorded: %s on %s.", sub.Name, payment.Amount, payment.Date.Format("2006-01-02"))
+	if fromEdit {
+		http.Redirect(w, r, "/update-subscription?id="+url.QueryEscape(subID), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// SubscriptionReportHandler wyświetla roczne zestawienie prognozowanych i faktycznych wydatków
+// na subskrypcje wybranego portfela. Rok podaje parametr "year" (domyślnie bieżący).
+func (h *AppHandler) SubscriptionReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	portfolio, err := h.loadSelectedPortfolio(ctx, r)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować portfela", http.StatusInternalServerError)
+		log.Printf("Error loading portfolio for subscription report: %v", err)
+		return
+	}
+
+	var message string
+	year := models.Today().Year()
+	if value := r.URL.Query().Get("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1900 || parsed > 9999 {
+			message = "Nieprawidłowy rok. Pokazujemy bieżący rok."
+		} else {
+			year = parsed
+		}
+	}
+
+	report := portfolio.SubscriptionSpendReport(year)
+	if err := views.SubscriptionReportPage(portfolio, report, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering subscription report", http.StatusInternalServerError)
+		log.Printf("Error rendering subscription report: %v", err)
+	}
+}
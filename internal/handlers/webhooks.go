@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"webwallet/internal/middleware"
+	"webwallet/internal/models"
+	"webwallet/internal/notify"
+	"webwallet/internal/repository"
+	"webwallet/internal/views"
+)
+
+// WebhooksHandler wyświetla adresy webhooków użytkownika i dziennik doręczeń oraz obsługuje zmiany (POST):
+// action=add dodaje adres (pola "url" i "events" - brak zaznaczonych zdarzeń oznacza wszystkie),
+// action=toggle włącza lub wyłącza adres "endpoint_id", action=test wysyła na niego wiadomość testową,
+// a action=delete go usuwa.
+func (h *AppHandler) WebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var message string
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			log.Printf("Error parsing webhook form: %v", err)
+			http.Error(w, "Błąd parsowania formularza", http.StatusBadRequest)
+			return
+		}
+		message = h.changeWebhooks(ctx, r, user.ID)
+		if message == "" {
+			log.Printf("Webhooks of %s updated (%s).", user.Email, r.FormValue("action"))
+			http.Redirect(w, r, "/webhooks", http.StatusSeeOther)
+			return
+		}
+	}
+
+	endpoints, err := h.webhookRepo.WebhookEndpoints(ctx, user.ID)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować webhooków.", http.StatusInternalServerError)
+		log.Printf("Error loading webhook endpoints of %s: %v", user.Email, err)
+		return
+	}
+	deliveries, err := h.webhookRepo.WebhookDeliveries(ctx, user.ID, models.MaxWebhookDeliveries)
+	if err != nil {
+		http.Error(w, "Nie udało się załadować dziennika webhooków.", http.StatusInternalServerError)
+		log.Printf("Error loading webhook deliveries of %s: %v", user.Email, err)
+		return
+	}
+	if err := views.WebhooksPage(endpoints, deliveries, message).Render(r.Context(), w); err != nil {
+		http.Error(w, "Error rendering webhooks page", http.StatusInternalServerError)
+		log.Printf("Error rendering webhooks page: %v", err)
+	}
+}
+
+// changeWebhooks wykonuje operację z formularza webhooków. Zwraca komunikat dla użytkownika, gdy się nie udała.
+func (h *AppHandler) changeWebhooks(ctx context.Context, r *http.Request, userID string) string {
+	endpoints, err := h.webhookRepo.WebhookEndpoints(ctx, userID)
+	if err != nil {
+		log.Printf("Error loading webhook endpoints: %v", err)
+		return "Nie udało się załadować webhooków."
+	}
+
+	action := r.FormValue("action")
+	if action == "add" {
+		if len(endpoints) >= models.MaxWebhookEndpoints {
+			return fmt.Sprintf("Można dodać najwyżej %d adresów webhooków.", models.MaxWebhookEndpoints)
+		}
+		var events []models.WebhookEvent
+		for _, value := range r.Form["events"] {
+			events = append(events, models.WebhookEvent(value))
+		}
+		url := strings.TrimSpace(r.FormValue("url"))
+		if err := notify.CheckWebhookURL(ctx, url); err != nil {
+			log.Printf("Rejected webhook URL %s: %v", url, err)
+			switch {
+			case errors.Is(err, models.ErrNonPublicAddress):
+				return "Adres webhooka musi wskazywać na publiczny serwer - adresy lokalne i sieci prywatne są niedozwolone."
+			case models.ValidateWebhookURL(url) != nil:
+				return "Nieprawidłowy adres webhooka. Podaj pełny adres http:// lub https://."
+			default:
+				return "Nie udało się odnaleźć serwera webhooka. Sprawdź adres."
+			}
+		}
+		endpoint, err := models.NewWebhookEndpoint(userID, url, events)
+		if err != nil {
+			log.Printf("Error creating webhook endpoint: %v", err)
+			return "Nieprawidłowe zdarzenia webhooka. Wybierz je z listy."
+		}
+		if err := h.webhookRepo.SaveWebhookEndpoint(ctx, endpoint); err != nil {
+			log.Printf("Error saving webhook endpoint: %v", err)
+			return "Nie udało się zapisać webhooka."
+		}
+		return ""
+	}
+
+	i := slices.IndexFunc(endpoints, func(e models.WebhookEndpoint) bool { return e.ID == r.FormValue("endpoint_id") })
+	if i < 0 {
+		return "Nie znaleziono webhooka."
+	}
+	endpoint := endpoints[i]
+	switch action {
+	case "toggle":
+		endpoint.Active = !endpoint.Active
+		err = h.webhookRepo.SaveWebhookEndpoint(ctx, endpoint)
+	case "test":
+		err = h.events.Ping(ctx, endpoint)
+	case "delete":
+		err = h.webhookRepo.DeleteWebhookEndpoint(ctx, userID, endpoint.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return "Nie znaleziono webhooka."
+		}
+	default:
+		return "Nieznana operacja."
+	}
+	if err != nil {
+		log.Printf("Error changing webhook endpoint %s (%s): %v", endpoint.ID, action, err)
+		return "Nie udało się zmienić webhooka."
+	}
+	return ""
+}
+
+// emit wysyła zdarzenie bieżącego portfela zalogowanego użytkownika na jego webhooki.
+func (h *AppHandler) emit(ctx context.Context, r *http.Request, event models.WebhookEvent, data any) {
+	h.emitFor(ctx, r, currentPortfolioID(r), event, data)
+}
+
+// emitFor wysyła zdarzenie portfela o podanym ID (np. z adresu żądania API) na webhooki zalogowanego użytkownika.
+func (h *AppHandler) emitFor(ctx context.Context, r *http.Request, portfolioID string, event models.WebhookEvent, data any) {
+	user, ok := middleware.GetUser(r.Context())
+	if !ok {
+		return
+	}
+	h.events.Emit(ctx, user.ID, portfolioID, event, data)
+}
+
+// emitAssetEvent wysyła zdarzenie z aktualnym stanem aktywa (po udanej zmianie).
+func (h *AppHandler) emitAssetEvent(ctx context.Context, r *http.Request, event models.WebhookEvent, assetID string) {
+	portfolio, err := h.portfolioRepo.LoadPortfolio(ctx, currentPortfolioID(r))
+	if err != nil {
+		log.Printf("Error loading portfolio for %s webhook: %v", event, err)
+		return
+	}
+	if asset, found := portfolio.FindAsset(assetID); found {
+		h.emit(ctx, r, event, *asset)
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+const userKey = contextKey("user")
+
+// SessionCookieName to nazwa ciasteczka z tokenem sesji.
+const SessionCookieName = "session"
+
+// AuthMiddleware przepuszcza tylko zalogowanych użytkowników. Odczytuje ciasteczko sesji,
+// sprawdza sesję w bazie i dodaje użytkownika do kontekstu żądania.
+// Niezalogowani są przekierowywani na stronę logowania (dla żądań HTMX przez nagłówek HX-Redirect),
+// a żądania JSON API (/api/) dostają odpowiedź 401 z błędem w formacie JSON.
+func AuthMiddleware(users repository.UserStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, err := userFromSession(ctx, users, r)
+		if err != nil {
+			if err != repository.ErrNotFound {
+				log.Printf("Error checking session: %v", err)
+			}
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeUnauthorized(w)
+				return
+			}
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", "/login")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		// Dodaj użytkownika do kontekstu i przekaż żądanie dalej.
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+// writeUnauthorized odpowiada błędem 401 w tym samym formacie co błędy handlerów JSON API.
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	body := map[string]map[string]string{"error": {
+		"code":    "unauthorized",
+		"message": "Zaloguj się (POST /login), aby korzystać z API.",
+	}}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error writing API response: %v", err)
+	}
+}
+
+// userFromSession zwraca użytkownika przypisanego do sesji z ciasteczka.
+func userFromSession(ctx context.Context, users repository.UserStore, r *http.Request) (*models.User, error) {
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, repository.ErrNotFound
+	}
+	session, err := users.GetSession(ctx, models.HashSessionToken(cookie.Value))
+	if err != nil {
+		return nil, err
+	}
+	return users.GetUser(ctx, session.UserID)
+}
+
+// WithUser zwraca kontekst z zalogowanym użytkownikiem.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// GetUser odczytuje zalogowanego użytkownika z kontekstu (false, jeśli nikt nie jest zalogowany).
+func GetUser(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userKey).(*models.User)
+	return user, ok && user != nil
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+const portfolioKey = contextKey("portfolio")
+
+// PortfolioCookieName to nazwa ciasteczka z ID portfela wybranego w przełączniku.
+const PortfolioCookieName = "portfolio"
+
+// PortfolioSelection opisuje portfele zalogowanego użytkownika i portfel wybrany w przełączniku.
+type PortfolioSelection struct {
+	Portfolios []models.PortfolioInfo // Portfele użytkownika, portfel główny zawsze na początku listy
+	SelectedID string                 // ID wybranego portfela albo models.AllPortfoliosID dla widoku zbiorczego
+	DefaultID  string                 // ID portfela głównego (User.PortfolioID)
+}
+
+// IsAggregate mówi, czy wybrano widok zbiorczy "Wszystkie portfele".
+func (s PortfolioSelection) IsAggregate() bool {
+	return s.SelectedID == models.AllPortfoliosID
+}
+
+// ActiveID zwraca ID portfela, na którym wykonywane są zmiany. W widoku zbiorczym jest to portfel główny.
+func (s PortfolioSelection) ActiveID() string {
+	if s.IsAggregate() {
+		return s.DefaultID
+	}
+	return s.SelectedID
+}
+
+// Owns mówi, czy portfel o podanym ID należy do użytkownika.
+func (s PortfolioSelection) Owns(portfolioID string) bool {
+	for _, p := range s.Portfolios {
+		if p.ID == portfolioID {
+			return true
+		}
+	}
+	return false
+}
+
+// PortfolioMiddleware wczytuje listę portfeli zalogowanego użytkownika i odczytuje z ciasteczka,
+// który z nich jest wybrany. Musi działać po AuthMiddleware. Portfel główny użytkownika
+// jest zakładany przy pierwszym żądaniu, jeśli jeszcze nie ma go na liście.
+// Ciasteczko wskazujące cudzy lub usunięty portfel jest ignorowane.
+func PortfolioMiddleware(store repository.PortfolioStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUser(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		selection, err := loadSelection(ctx, store, user, r)
+		if err != nil {
+			log.Printf("Error loading portfolios of user %s: %v", user.Email, err)
+			http.Error(w, "Nie udało się załadować listy portfeli.", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPortfolioSelection(r.Context(), selection)))
+	})
+}
+
+// loadSelection składa PortfolioSelection dla użytkownika na podstawie bazy i ciasteczka.
+func loadSelection(ctx context.Context, store repository.PortfolioStore, user *models.User, r *http.Request) (PortfolioSelection, error) {
+	portfolios, err := store.ListPortfolios(ctx, user.ID)
+	if err != nil {
+		return PortfolioSelection{}, err
+	}
+
+	// Portfel główny na początku listy; zakładamy go, jeśli jeszcze nie ma właściciela
+	defaultInfo := models.PortfolioInfo{ID: user.PortfolioID, Name: models.DefaultPortfolioName, OwnerID: user.ID}
+	others := make([]models.PortfolioInfo, 0, len(portfolios))
+	found := false
+	for _, p := range portfolios {
+		if p.ID == user.PortfolioID {
+			defaultInfo = p
+			found = true
+			continue
+		}
+		others = append(others, p)
+	}
+	if !found {
+		if err := store.CreatePortfolio(ctx, defaultInfo); err != nil {
+			return PortfolioSelection{}, err
+		}
+	}
+
+	selection := PortfolioSelection{
+		Portfolios: append([]models.PortfolioInfo{defaultInfo}, others...),
+		SelectedID: user.PortfolioID,
+		DefaultID:  user.PortfolioID,
+	}
+	if cookie, err := r.Cookie(PortfolioCookieName); err == nil {
+		if cookie.Value == models.AllPortfoliosID || selection.Owns(cookie.Value) {
+			selection.SelectedID = cookie.Value
+		}
+	}
+	return selection, nil
+}
+
+// WithPortfolioSelection zwraca kontekst z listą portfeli i wybranym portfelem.
+func WithPortfolioSelection(ctx context.Context, selection PortfolioSelection) context.Context {
+	return context.WithValue(ctx, portfolioKey, selection)
+}
+
+// GetPortfolioSelection odczytuje wybór portfela z kontekstu (false poza PortfolioMiddleware, np. na stronie logowania).
+func GetPortfolioSelection(ctx context.Context) (PortfolioSelection, bool) {
+	selection, ok := ctx.Value(portfolioKey).(PortfolioSelection)
+	return selection, ok
+}
@@ -0,0 +1,306 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AlertCondition to rodzaj warunku alertu cenowego.
+type AlertCondition string
+
+const (
+	AlertPriceAbove  AlertCondition = "price_above"  // Cena bieżąca co najmniej równa progowi
+	AlertPriceBelow  AlertCondition = "price_below"  // Cena bieżąca co najwyżej równa progowi
+	AlertDailyChange AlertCondition = "daily_change" // Zmiana względem ostatniego zamknięcia o co najmniej próg % (w górę lub w dół)
+	AlertProfitLoss  AlertCondition = "profit_loss"  // Zysk/strata względem AvgCost: próg dodatni - zysk co najmniej próg %, ujemny - strata co najmniej |próg| %
+)
+
+// MaxAlertNotifications to liczba ostatnich powiadomień pokazywanych w centrum powiadomień.
+const MaxAlertNotifications = 100
+
+// AlertConditions zwraca warunki alertów w kolejności wyświetlania w formularzu.
+func AlertConditions() []AlertCondition {
+	return []AlertCondition{AlertPriceAbove, AlertPriceBelow, AlertDailyChange, AlertProfitLoss}
+}
+
+// Label zwraca polską nazwę warunku do wyświetlenia.
+func (c AlertCondition) Label() string {
+	switch c {
+	case AlertPriceAbove:
+		return "Cena powyżej"
+	case AlertPriceBelow:
+		return "Cena poniżej"
+	case AlertDailyChange:
+		return "Zmiana dzienna (%)"
+	case AlertProfitLoss:
+		return "Zysk/strata vs średni koszt (%)"
+	default:
+		return string(c)
+	}
+}
+
+// IsValid mówi, czy warunek jest jednym z obsługiwanych.
+func (c AlertCondition) IsValid() bool {
+	for _, known := range AlertConditions() {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceAlert to alert cenowy zdefiniowany dla aktywa. Alert powiadamia o przekroczeniu progu:
+// po powiadomieniu czeka (Triggered), aż warunek przestanie być spełniony, i dopiero wtedy może zadziałać ponownie.
+type PriceAlert struct {
+	ID        string         `json:"id" bson:"_id"`
+	Condition AlertCondition `json:"condition" bson:"condition"`
+	Threshold Decimal        `json:"threshold" bson:"threshold"` // Cena (w walucie aktywa) albo procent - zależnie od warunku
+	Email     bool           `json:"email" bson:"email"`         // Wysyłać też e-mail do właściciela portfela
+
+	SnoozedUntil    time.Time `json:"snoozedUntil" bson:"snoozedUntil"`       // Do tej chwili alert nie powiadamia (drzemka)
+	Triggered       bool      `json:"triggered" bson:"triggered"`             // Warunek był spełniony przy ostatniej ocenie
+	LastTriggeredAt time.Time `json:"lastTriggeredAt" bson:"lastTriggeredAt"` // Kiedy alert ostatnio powiadomił
+	CreatedAt       time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Validate sprawdza warunek i próg alertu.
+func (a PriceAlert) Validate() error {
+	if !a.Condition.IsValid() {
+		return fmt.Errorf("unknown alert condition %q", a.Condition)
+	}
+	switch a.Condition {
+	case AlertPriceAbove, AlertPriceBelow, AlertDailyChange:
+		if !a.Threshold.IsPositive() {
+			return fmt.Errorf("alert threshold must be positive")
+		}
+	case AlertProfitLoss:
+		if a.Threshold.IsZero() {
+			return fmt.Errorf("profit/loss alert threshold must not be zero")
+		}
+	}
+	return nil
+}
+
+// ErrNonPublicAddress oznacza, że adres webhooka wskazuje na serwer lokalny albo sieć prywatną.
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+// ValidateWebhookURL sprawdza, czy adres webhooka jest pełnym adresem http:// lub https://
+// i nie wskazuje wprost na serwer lokalny ani sieć prywatną (np. localhost, 127.0.0.1, 10.0.0.1).
+// Nazwę hosta rozwiązuje dopiero notify.CheckWebhookURL, a wysyłka sprawdza adres jeszcze raz przy łączeniu.
+func ValidateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("webhook URL %q: %w", rawURL, ErrNonPublicAddress)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddr(addr) {
+		return fmt.Errorf("webhook URL %q: %w", rawURL, ErrNonPublicAddress)
+	}
+	return nil
+}
+
+// nonPublicPrefixes to zakresy, których nie obejmują metody netip.Addr: "ta sieć" 0.0.0.0/8,
+// współdzielona przestrzeń operatorów (CGNAT) 100.64.0.0/10 i lokalny prefiks NAT64 64:ff9b:1::/48.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// Prefiksy IPv6, w których zaszyty jest adres IPv4: NAT64 (RFC 6052) i 6to4 (RFC 3056).
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// IsPublicAddr mówi, czy adres może być celem webhooka. Odrzuca adresy pętli zwrotnej, sieci prywatnych,
+// CGNAT, link-local (np. 169.254.169.254 z metadanymi chmury), nieokreślone i multicast, żeby webhook
+// nie posłużył do wysyłania żądań do sieci wewnętrznej serwera. Adresy NAT64 i 6to4 oceniamy
+// po zaszytym w nich adresie IPv4.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if embedded, ok := embeddedIPv4(addr); ok {
+		return IsPublicAddr(embedded)
+	}
+	return true
+}
+
+// embeddedIPv4 wyciąga adres IPv4 zaszyty w adresie NAT64 (ostatnie 4 bajty) lub 6to4 (bajty 2-5).
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	b := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}), true
+	case sixToFour.Contains(addr):
+		return netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]}), true
+	}
+	return netip.Addr{}, false
+}
+
+// SameState mówi, czy alerty mają ten sam stan oceny (Triggered i LastTriggeredAt).
+func (a PriceAlert) SameState(other PriceAlert) bool {
+	return a.Triggered == other.Triggered && a.LastTriggeredAt.Equal(other.LastTriggeredAt)
+}
+
+// IsSnoozed mówi, czy alert jest wyciszony (drzemka) w podanej chwili.
+func (a PriceAlert) IsSnoozed(now time.Time) bool {
+	return now.Before(a.SnoozedUntil)
+}
+
+// Description opisuje warunek alertu, np. "Cena powyżej 200.00 USD".
+func (a PriceAlert) Description(currency string) string {
+	switch a.Condition {
+	case AlertPriceAbove:
+		return "Cena powyżej " + FormatCurrency(a.Threshold, currency)
+	case AlertPriceBelow:
+		return "Cena poniżej " + FormatCurrency(a.Threshold, currency)
+	case AlertDailyChange:
+		return fmt.Sprintf("Zmiana dzienna o co najmniej %s%%", a.Threshold.StringFixed(2))
+	case AlertProfitLoss:
+		if a.Threshold.IsNegative() {
+			return fmt.Sprintf("Strata co najmniej %s%% względem średniego kosztu", a.Threshold.Abs().StringFixed(2))
+		}
+		return fmt.Sprintf("Zysk co najmniej %s%% względem średniego kosztu", a.Threshold.StringFixed(2))
+	default:
+		return a.Condition.Label()
+	}
+}
+
+// AlertTrigger to zadziałanie alertu przy ocenie nowej ceny aktywa.
+type AlertTrigger struct {
+	Alert   PriceAlert
+	Message string // Opis zdarzenia dla użytkownika, np. "Cena powyżej 200.00 USD - cena 201.50 USD"
+}
+
+// percentChange zwraca zmianę from -> to w procentach (0, gdy from jest zerem).
+func percentChange(from, to Decimal) Decimal {
+	if from.IsZero() {
+		return Zero
+	}
+	return to.Sub(from).Div(from).MulInt(100)
+}
+
+// formatPercentChange zapisuje zmianę procentową ze znakiem, np. "+6.20%".
+func formatPercentChange(change Decimal) string {
+	sign := ""
+	if change.IsPositive() {
+		sign = "+"
+	}
+	return sign + change.StringFixed(2) + "%"
+}
+
+// checkAlert sprawdza warunek alertu dla bieżącej ceny aktywa. ok jest false, gdy brak danych
+// do oceny (np. brak poprzedniego zamknięcia albo średniego kosztu) - stan alertu się wtedy nie zmienia.
+func (a Asset) checkAlert(alert PriceAlert, previousClose Decimal) (met bool, detail string, ok bool) {
+	currency := a.CurrencyCode()
+	price := FormatCurrency(a.CurrentPrice, currency)
+	switch alert.Condition {
+	case AlertPriceAbove:
+		return a.CurrentPrice.Cmp(alert.Threshold) >= 0, "cena " + price, true
+	case AlertPriceBelow:
+		return a.CurrentPrice.Cmp(alert.Threshold) <= 0, "cena " + price, true
+	case AlertDailyChange:
+		if !previousClose.IsPositive() {
+			return false, "", false
+		}
+		change := percentChange(previousClose, a.CurrentPrice)
+		return change.Abs().Cmp(alert.Threshold) >= 0, fmt.Sprintf("zmiana dzienna %s (cena %s)", formatPercentChange(change), price), true
+	case AlertProfitLoss:
+		if !a.AvgCost.IsPositive() {
+			return false, "", false
+		}
+		change := percentChange(a.AvgCost, a.CurrentPrice)
+		met := change.Cmp(alert.Threshold) >= 0
+		if alert.Threshold.IsNegative() {
+			met = change.Cmp(alert.Threshold) <= 0
+		}
+		return met, fmt.Sprintf("wynik %s względem średniego kosztu (cena %s)", formatPercentChange(change), price), true
+	default:
+		return false, "", false
+	}
+}
+
+// EvaluateAlerts ocenia alerty aktywa po zmianie ceny bieżącej i zwraca te, które właśnie zadziałały.
+// previousClose to ostatnia cena zamknięcia sprzed dzisiaj (potrzebna do zmiany dziennej; zero - brak).
+// Alert działa tylko przy przekroczeniu progu: gdy warunek był już spełniony przy poprzedniej ocenie,
+// nie powiadamia ponownie. Alert wyciszony drzemką zapamiętuje przekroczenie, ale nie powiadamia.
+// Metoda aktualizuje stan alertów (Triggered, LastTriggeredAt) - wywołujący powinien go zapisać.
+func (a *Asset) EvaluateAlerts(previousClose Decimal, now time.Time) []AlertTrigger {
+	triggers := []AlertTrigger{}
+	for i := range a.Alerts {
+		alert := &a.Alerts[i]
+		met, detail, ok := a.checkAlert(*alert, previousClose)
+		if !ok {
+			continue
+		}
+		if !met {
+			alert.Triggered = false
+			continue
+		}
+		if alert.Triggered {
+			continue
+		}
+		alert.Triggered = true
+		if alert.IsSnoozed(now) {
+			continue
+		}
+		alert.LastTriggeredAt = now
+		triggers = append(triggers, AlertTrigger{
+			Alert:   *alert,
+			Message: alert.Description(a.CurrencyCode()) + " - " + detail,
+		})
+	}
+	return triggers
+}
+
+// Notification to wpis w centrum powiadomień użytkownika (np. zadziałanie alertu cenowego).
+// Lista powiadomień jest jednocześnie historią alertów.
+type Notification struct {
+	ID          string    `json:"id" bson:"_id"`
+	UserID      string    `json:"userId" bson:"userId"`
+	PortfolioID string    `json:"portfolioId" bson:"portfolioId"`
+	AssetID     string    `json:"assetId" bson:"assetId"`
+	AlertID     string    `json:"alertId" bson:"alertId"`
+	Title       string    `json:"title" bson:"title"`
+	Message     string    `json:"message" bson:"message"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	Read        bool      `json:"read" bson:"read"`
+}
+
+// AlertTitle zwraca tytuł powiadomienia o alercie aktywa, np. "Alert: Apple (AAPL)".
+func AlertTitle(asset Asset) string {
+	if asset.Symbol == "" {
+		return "Alert: " + asset.Name
+	}
+	return fmt.Sprintf("Alert: %s (%s)", asset.Name, asset.Symbol)
+}
+
+// NewAlertNotification tworzy powiadomienie o zadziałaniu alertu aktywa dla właściciela portfela.
+func NewAlertNotification(userID, portfolioID string, asset Asset, trigger AlertTrigger, now time.Time) Notification {
+	return Notification{
+		ID:          GenerateID(),
+		UserID:      userID,
+		PortfolioID: portfolioID,
+		AssetID:     asset.ID,
+		AlertID:     trigger.Alert.ID,
+		Title:       AlertTitle(asset),
+		Message:     trigger.Message,
+		CreatedAt:   now,
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// TestPriceAlerts sprawdza, że alerty działają przy przekroczeniu progu, nie powtarzają się, dopóki
+// warunek nie ustąpi, a drzemka wycisza powiadomienie.
+func TestPriceAlerts(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	asset := Asset{
+		Name: "Apple", Symbol: "AAPL", Currency: "USD", AvgCost: dec(100), CurrentPrice: dec(190),
+		Alerts: []PriceAlert{
+			{ID: "above", Condition: AlertPriceAbove, Threshold: dec(200)},
+			{ID: "daily", Condition: AlertDailyChange, Threshold: dec(5)},
+			{ID: "loss", Condition: AlertProfitLoss, Threshold: dec(-10)},
+		},
+	}
+
+	if triggers := asset.EvaluateAlerts(dec(185), now); len(triggers) != 0 {
+		t.Fatalf("EvaluateAlerts() expected no triggers below thresholds, got %+v", triggers)
+	}
+
+	asset.CurrentPrice = dec(201)
+	triggers := asset.EvaluateAlerts(dec(190), now)
+	if len(triggers) != 2 || triggers[0].Alert.ID != "above" || triggers[1].Alert.ID != "daily" {
+		t.Fatalf("EvaluateAlerts() expected above and daily triggers, got %+v", triggers)
+	}
+	if triggers[0].Message != "Cena powyżej 200.00 USD - cena 201.00 USD" {
+		t.Errorf("EvaluateAlerts() unexpected message %q", triggers[0].Message)
+	}
+	if !asset.Alerts[0].LastTriggeredAt.Equal(now) {
+		t.Errorf("EvaluateAlerts() expected LastTriggeredAt to be set")
+	}
+
+	asset.CurrentPrice = dec(205)
+	if triggers := asset.EvaluateAlerts(dec(201), now); len(triggers) != 0 {
+		t.Errorf("EvaluateAlerts() expected no repeated triggers, got %+v", triggers)
+	}
+
+	asset.CurrentPrice = dec(195)
+	asset.EvaluateAlerts(dec(201), now)
+	asset.Alerts[0].SnoozedUntil = now.Add(24 * time.Hour)
+	asset.CurrentPrice = dec(202)
+	if triggers := asset.EvaluateAlerts(dec(201), now); len(triggers) != 0 {
+		t.Errorf("EvaluateAlerts() expected snoozed alert to stay silent, got %+v", triggers)
+	}
+
+	asset.CurrentPrice = dec(89)
+	triggers = asset.EvaluateAlerts(Zero, now)
+	if len(triggers) != 1 || triggers[0].Alert.ID != "loss" {
+		t.Fatalf("EvaluateAlerts() expected loss trigger, got %+v", triggers)
+	}
+
+	if err := (PriceAlert{Condition: AlertPriceBelow, Threshold: dec(-1)}).Validate(); err == nil {
+		t.Errorf("Validate() expected error for negative price threshold")
+	}
+}
+
+// TestIsPublicAddr sprawdza, że webhook nie może celować w sieci wewnętrzne, także przez CGNAT,
+// "tę sieć" 0.0.0.0/8 i adresy IPv4 zaszyte w NAT64 lub 6to4.
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"169.254.169.254", false},
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"100.128.0.1", true},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},       // NAT64 -> 10.0.0.1
+		{"64:ff9b::a9fe:a9fe", false},   // NAT64 -> 169.254.169.254
+		{"64:ff9b::5db8:d822", true},    // NAT64 -> 93.184.216.34
+		{"64:ff9b:1::5db8:d822", false}, // lokalny prefiks NAT64
+		{"2002:7f00:1::", false},        // 6to4 -> 127.0.0.1
+		{"2002:c0a8:101::1", false},     // 6to4 -> 192.168.1.1
+		{"2002:5db8:d822::1", true},     // 6to4 -> 93.184.216.34
+		{"fd00::1", false},
+		{"fe80::1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Grupy, dla których można ustawić docelowy podział portfela.
+const (
+	AllocationByWalletType = "walletType" // Według typu portfela (np. "Poduszka Finansowa")
+	AllocationByAssetType  = "assetType"  // Według typu aktywa (np. "Akcje", "Obligacje")
+)
+
+// AllocationGroups zwraca grupy docelowego podziału w kolejności wyświetlania.
+func AllocationGroups() []string {
+	return []string{AllocationByWalletType, AllocationByAssetType}
+}
+
+// AllocationGroupLabel zwraca polską nazwę grupy docelowego podziału.
+func AllocationGroupLabel(group string) string {
+	switch group {
+	case AllocationByWalletType:
+		return "Typ portfela"
+	case AllocationByAssetType:
+		return "Typ aktywa"
+	default:
+		return group
+	}
+}
+
+// IsValidAllocationGroup mówi, czy grupa docelowego podziału jest obsługiwana.
+func IsValidAllocationGroup(group string) bool {
+	return group == AllocationByWalletType || group == AllocationByAssetType
+}
+
+// AllocationTarget to docelowy udział jednej kategorii (typu portfela lub typu aktywa) w wartości portfela.
+type AllocationTarget struct {
+	Group    string  `json:"group" bson:"group"`       // AllocationByWalletType lub AllocationByAssetType
+	Category string  `json:"category" bson:"category"` // Np. "Poduszka Finansowa" albo "Akcje"
+	Percent  Decimal `json:"percent" bson:"percent"`   // Docelowy udział w procentach
+}
+
+// assetCategory zwraca kategorię aktywa w podanej grupie.
+func assetCategory(a Asset, group string) string {
+	if group == AllocationByAssetType {
+		return a.Type
+	}
+	return a.WalletType
+}
+
+// TargetsFor zwraca docelowe udziały kategorii z podanej grupy.
+func (p *InvestmentPortfolio) TargetsFor(group string) map[string]Decimal {
+	targets := make(map[string]Decimal)
+	for _, t := range p.AllocationTargets {
+		if t.Group == group {
+			targets[t.Category] = t.Percent
+		}
+	}
+	return targets
+}
+
+// SetAllocationTargets zastępuje docelowe udziały w podanej grupie. Udziały muszą mieścić się
+// w przedziale 0-100 i sumować do 100%; pusta lista (lub same zera) usuwa cele grupy.
+func (p *InvestmentPortfolio) SetAllocationTargets(group string, targets []AllocationTarget) error {
+	if !IsValidAllocationGroup(group) {
+		return fmt.Errorf("unknown allocation group %q", group)
+	}
+
+	sum := Zero
+	var kept []AllocationTarget
+	for _, t := range targets {
+		if t.Percent.IsNegative() || t.Percent.GreaterThan(NewDecimal(100)) {
+			return fmt.Errorf("target for %q must be between 0 and 100", t.Category)
+		}
+		if t.Percent.IsZero() {
+			continue
+		}
+		t.Group = group
+		sum = sum.Add(t.Percent)
+		kept = append(kept, t)
+	}
+	if len(kept) > 0 && sum != NewDecimal(100) {
+		return fmt.Errorf("targets must add up to 100%%, got %s%%", sum)
+	}
+
+	var updated []AllocationTarget
+	for _, t := range p.AllocationTargets {
+		if t.Group != group {
+			updated = append(updated, t)
+		}
+	}
+	p.AllocationTargets = append(updated, kept...)
+	return nil
+}
+
+// AllocationDrift porównuje bieżący udział kategorii w wartości portfela z docelowym.
+type AllocationDrift struct {
+	Category       string
+	Value          Decimal // Bieżąca wartość kategorii w walucie bazowej
+	CurrentPercent float64
+	TargetPercent  float64
+	HasTarget      bool
+}
+
+// Drift zwraca odchylenie bieżącego udziału od docelowego w punktach procentowych (dodatnie - nadwaga).
+func (d AllocationDrift) Drift() float64 {
+	return d.CurrentPercent - d.TargetPercent
+}
+
+// AllocationDrifts zwraca bieżący i docelowy udział każdej kategorii z podanej grupy -
+// zarówno kategorii, które mają aktywa, jak i tych, które mają tylko cel. Wynik jest posortowany po nazwie.
+func (p *InvestmentPortfolio) AllocationDrifts(group string) []AllocationDrift {
+	values, total := p.categoryValues(group)
+	targets := p.TargetsFor(group)
+
+	drifts := make(map[string]*AllocationDrift)
+	for category, value := range values {
+		d := &AllocationDrift{Category: category, Value: value}
+		if total.IsPositive() {
+			d.CurrentPercent = value.Div(total).Float64() * 100
+		}
+		drifts[category] = d
+	}
+	for category, percent := range targets {
+		d, ok := drifts[category]
+		if !ok {
+			d = &AllocationDrift{Category: category, Value: Zero}
+			drifts[category] = d
+		}
+		d.TargetPercent = percent.Float64()
+		d.HasTarget = true
+	}
+
+	result := make([]AllocationDrift, 0, len(drifts))
+	for _, d := range drifts {
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result
+}
+
+// categoryValues sumuje wartość aktywów (w walucie bazowej) według kategorii z podanej grupy.
+func (p *InvestmentPortfolio) categoryValues(group string) (map[string]Decimal, Decimal) {
+	values := make(map[string]Decimal)
+	total := Zero
+	for _, a := range p.Assets {
+		value := p.ToBase(a.Quantity.Mul(a.CurrentPrice), a.CurrencyCode())
+		category := assetCategory(a, group)
+		values[category] = values[category].Add(value)
+		total = total.Add(value)
+	}
+	return values, total
+}
+
+// RebalanceTrade to pojedyncza propozycja zakupu (dodatnia kwota) lub sprzedaży (ujemna kwota).
+// Dla kategorii bez aktywów AssetID jest pusty - trzeba wybrać, co kupić.
+type RebalanceTrade struct {
+	Category string
+	AssetID  string
+	Name     string
+	Symbol   string
+	Amount   Decimal // Kwota w walucie bazowej
+	Quantity Decimal // Przybliżona liczba jednostek po cenie bieżącej (0, gdy cena nieznana)
+}
+
+// IsBuy mówi, czy propozycja to zakup.
+func (t RebalanceTrade) IsBuy() bool {
+	return t.Amount.IsPositive()
+}
+
+// RebalancePlan to lista transakcji przywracających docelowy podział portfela.
+type RebalancePlan struct {
+	Group     string
+	Cash      Decimal // Gotówka do zainwestowania
+	AllowSell bool
+	Trades    []RebalanceTrade
+}
+
+// Rebalance wylicza transakcje, które przywracają docelowy podział w podanej grupie po dołożeniu
+// gotówki cash. Gdy allowSell jest false, niczego nie sprzedajemy - gotówkę dzielimy między kategorie
+// z niedowagą proporcjonalnie do ich niedoboru. Kwotę kategorii dzielimy na jej aktywa proporcjonalnie
+// do ich bieżącej wartości. Bez celów w grupie plan jest pusty.
+func (p *InvestmentPortfolio) Rebalance(group string, cash Decimal, allowSell bool) RebalancePlan {
+	plan := RebalancePlan{Group: group, Cash: cash, AllowSell: allowSell}
+	targets := p.TargetsFor(group)
+	if len(targets) == 0 {
+		return plan
+	}
+
+	values, total := p.categoryValues(group)
+	total = total.Add(cash)
+
+	// Różnica między wartością docelową a bieżącą dla każdej kategorii (z celem lub z aktywami)
+	differences := make(map[string]Decimal)
+	for category, value := range values {
+		differences[category] = targets[category].Mul(total).DivInt(100).Sub(value)
+	}
+	for category, percent := range targets {
+		if _, ok := differences[category]; !ok {
+			differences[category] = percent.Mul(total).DivInt(100)
+		}
+	}
+
+	if !allowSell {
+		deficit := Zero
+		for _, diff := range differences {
+			if diff.IsPositive() {
+				deficit = deficit.Add(diff)
+			}
+		}
+		for category, diff := range differences {
+			switch {
+			case !diff.IsPositive() || !cash.IsPositive():
+				differences[category] = Zero
+			case deficit.GreaterThan(cash):
+				differences[category] = diff.Mul(cash).Div(deficit)
+			}
+		}
+	}
+
+	categories := make([]string, 0, len(differences))
+	for category := range differences {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		amount := differences[category].Round(2)
+		if amount.IsZero() {
+			continue
+		}
+		plan.Trades = append(plan.Trades, p.categoryTrades(group, category, amount)...)
+	}
+	return plan
+}
+
+// categoryTrades rozdziela kwotę kategorii na jej aktywa proporcjonalnie do ich wartości
+// (po równo, gdy wszystkie są warte zero).
+func (p *InvestmentPortfolio) categoryTrades(group, category string, amount Decimal) []RebalanceTrade {
+	var assets []Asset
+	total := Zero
+	for _, a := range p.Assets {
+		if assetCategory(a, group) == category {
+			assets = append(assets, a)
+			total = total.Add(p.ToBase(a.Quantity.Mul(a.CurrentPrice), a.CurrencyCode()))
+		}
+	}
+	if len(assets) == 0 {
+		return []RebalanceTrade{{Category: category, Amount: amount, Quantity: Zero}}
+	}
+
+	trades := make([]RebalanceTrade, 0, len(assets))
+	allocated := Zero
+	for i, a := range assets {
+		share := amount.DivInt(int64(len(assets)))
+		if total.IsPositive() {
+			share = amount.Mul(p.ToBase(a.Quantity.Mul(a.CurrentPrice), a.CurrencyCode())).Div(total)
+		}
+		share = share.Round(2)
+		if i == len(assets)-1 {
+			// Ostatnie aktywo dostaje resztę, żeby suma zgadzała się co do grosza
+			share = amount.Sub(allocated)
+		}
+		allocated = allocated.Add(share)
+		if share.IsZero() {
+			continue
+		}
+
+		quantity := Zero
+		if unit := p.ToBase(a.CurrentPrice, a.CurrencyCode()); unit.IsPositive() {
+			quantity = share.Div(unit).Round(4)
+		}
+		trades = append(trades, RebalanceTrade{
+			Category: category,
+			AssetID:  a.ID,
+			Name:     a.Name,
+			Symbol:   a.Symbol,
+			Amount:   share,
+			Quantity: quantity,
+		})
+	}
+	return trades
+}
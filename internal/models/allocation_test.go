@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+// TestRebalance sprawdza docelowy podział portfela i propozycje transakcji (ze sprzedażą i bez).
+func TestRebalance(t *testing.T) {
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddAsset(Asset{ID: "A1", Name: "ETF", WalletType: "Długoterminowy", Quantity: dec(80), AvgCost: dec(10), CurrentPrice: dec(10)})
+	portfolio.AddAsset(Asset{ID: "A2", Name: "Lokata", WalletType: "Poduszka", Quantity: dec(200), AvgCost: dec(1), CurrentPrice: dec(1)})
+
+	if err := portfolio.SetAllocationTargets(AllocationByWalletType, []AllocationTarget{
+		{Category: "Długoterminowy", Percent: dec(50)},
+		{Category: "Poduszka", Percent: dec(40)},
+	}); err == nil {
+		t.Error("SetAllocationTargets() should reject targets that do not add up to 100%")
+	}
+	err := portfolio.SetAllocationTargets(AllocationByWalletType, []AllocationTarget{
+		{Category: "Długoterminowy", Percent: dec(50)},
+		{Category: "Poduszka", Percent: dec(40)},
+		{Category: "Krótkoterminowy", Percent: dec(10)},
+	})
+	if err != nil {
+		t.Fatalf("SetAllocationTargets() failed: %v", err)
+	}
+
+	drifts := portfolio.AllocationDrifts(AllocationByWalletType)
+	if len(drifts) != 3 || drifts[0].Category != "Długoterminowy" || drifts[0].Drift() != 30 {
+		t.Errorf("AllocationDrifts() expected 3 categories with +30 p.p. drift of Długoterminowy, got %+v", drifts)
+	}
+
+	// Ze sprzedażą: wartość 1000, cel 500/400/100
+	plan := portfolio.Rebalance(AllocationByWalletType, Zero, true)
+	amounts := make(map[string]Decimal)
+	for _, trade := range plan.Trades {
+		amounts[trade.Category] = trade.Amount
+	}
+	if amounts["Długoterminowy"] != dec(-300) || amounts["Poduszka"] != dec(200) || amounts["Krótkoterminowy"] != dec(100) {
+		t.Errorf("Rebalance() unexpected trades %+v", plan.Trades)
+	}
+	if plan.Trades[0].AssetID != "A1" || plan.Trades[0].Quantity != dec(-30) {
+		t.Errorf("Rebalance() expected selling 30 units of ETF, got %+v", plan.Trades[0])
+	}
+
+	// Bez sprzedaży: wartość 1300, cel 650/520/130 - 300 gotówki dzielone proporcjonalnie do niedoborów 320 i 130
+	plan = portfolio.Rebalance(AllocationByWalletType, dec(300), false)
+	total := Zero
+	for _, trade := range plan.Trades {
+		if !trade.IsBuy() {
+			t.Errorf("Rebalance(no-sell) proposed a sale %+v", trade)
+		}
+		total = total.Add(trade.Amount)
+	}
+	if total != dec(300) || len(plan.Trades) != 2 || plan.Trades[1].Amount != dec(213.33) {
+		t.Errorf("Rebalance(no-sell) expected 300 split 86.67/213.33, got %+v", plan.Trades)
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// CalendarMonths to liczba miesięcy (licząc od bieżącego), dla których kalendarz pokazuje płatności subskrypcji.
+const CalendarMonths = 12
+
+// SubscriptionCharge to pojedynczy termin płatności subskrypcji w kalendarzu.
+type SubscriptionCharge struct {
+	Date           time.Time
+	SubscriptionID string
+	Name           string
+	Currency       string
+	Amount         Decimal // Koszt subskrypcji w jej walucie
+	AmountBase     Decimal // Koszt w walucie bazowej portfela
+}
+
+// UpcomingCharges zwraca terminy płatności wszystkich subskrypcji z przedziału [from, to),
+// wyliczone z ich harmonogramów, posortowane po dacie i nazwie.
+func (p *InvestmentPortfolio) UpcomingCharges(from, to time.Time) []SubscriptionCharge {
+	var charges []SubscriptionCharge
+	for _, s := range p.Subscriptions {
+		for _, date := range s.ChargesBetween(from, to) {
+			charges = append(charges, SubscriptionCharge{
+				Date:           date,
+				SubscriptionID: s.ID,
+				Name:           s.Name,
+				Currency:       s.CurrencyCode(),
+				Amount:         s.Cost,
+				AmountBase:     p.ToBase(s.Cost, s.CurrencyCode()),
+			})
+		}
+	}
+	sort.SliceStable(charges, func(i, j int) bool {
+		if !charges[i].Date.Equal(charges[j].Date) {
+			return charges[i].Date.Before(charges[j].Date)
+		}
+		return charges[i].Name < charges[j].Name
+	})
+	return charges
+}
+
+// CalendarMonth to płatności subskrypcji z jednego miesiąca.
+type CalendarMonth struct {
+	Month   time.Time // Pierwszy dzień miesiąca
+	Charges []SubscriptionCharge
+	Total   Decimal // Suma płatności w walucie bazowej
+}
+
+// CalendarDay to jedna komórka siatki miesiąca.
+type CalendarDay struct {
+	Date    time.Time
+	InMonth bool // false dla dni z sąsiednich miesięcy, które dopełniają pierwszy i ostatni tydzień
+	Charges []SubscriptionCharge
+}
+
+// MonthStart zwraca pierwszy dzień miesiąca podanej daty.
+func MonthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// SubscriptionCalendar zwraca płatności subskrypcji w kolejnych miesiącach, zaczynając od miesiąca daty start.
+func (p *InvestmentPortfolio) SubscriptionCalendar(start time.Time, months int) []CalendarMonth {
+	first := MonthStart(start)
+	charges := p.UpcomingCharges(first, first.AddDate(0, months, 0))
+
+	calendar := make([]CalendarMonth, months)
+	for i := range calendar {
+		calendar[i] = CalendarMonth{Month: first.AddDate(0, i, 0), Total: Zero}
+	}
+	for _, charge := range charges {
+		i := (charge.Date.Year()-first.Year())*12 + int(charge.Date.Month()) - int(first.Month())
+		calendar[i].Charges = append(calendar[i].Charges, charge)
+		calendar[i].Total = calendar[i].Total.Add(charge.AmountBase)
+	}
+	return calendar
+}
+
+// Weeks dzieli miesiąc na tygodnie od poniedziałku do niedzieli. Pierwszy i ostatni tydzień
+// dopełniają dni z sąsiednich miesięcy (bez płatności).
+func (m CalendarMonth) Weeks() [][]CalendarDay {
+	offset := (int(m.Month.Weekday()) + 6) % 7 // Liczba dni od poniedziałku
+	day := m.Month.AddDate(0, 0, -offset)
+	next := m.Month.AddDate(0, 1, 0)
+
+	var weeks [][]CalendarDay
+	for day.Before(next) {
+		week := make([]CalendarDay, 7)
+		for i := range week {
+			week[i] = CalendarDay{Date: day, InMonth: day.Month() == m.Month.Month()}
+			for _, charge := range m.Charges {
+				if sameDay(charge.Date, day) {
+					week[i].Charges = append(week[i].Charges, charge)
+				}
+			}
+			day = day.AddDate(0, 0, 1)
+		}
+		weeks = append(weeks, week)
+	}
+	return weeks
+}
+
+// sameDay mówi, czy dwie daty przypadają na ten sam dzień kalendarzowy.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSubscriptionCalendar sprawdza rozkład terminów subskrypcji na miesiące, sumy miesięczne i siatkę tygodni.
+func TestSubscriptionCalendar(t *testing.T) {
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddSubscription(Subscription{ID: "S1", Name: "Prasa", Cost: dec(10), Frequency: FrequencyWeekly,
+		NextDue: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)})
+	portfolio.AddSubscription(Subscription{ID: "S2", Name: "Ubezpieczenie", Cost: dec(300), Frequency: FrequencyQuarterly,
+		NextDue: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)})
+
+	calendar := portfolio.SubscriptionCalendar(time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), 4)
+	if len(calendar) != 4 || !calendar[0].Month.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("SubscriptionCalendar() expected 4 months from March, got %+v", calendar)
+	}
+	// Marzec: 5 poniedziałków (2, 9, 16, 23, 30) po 10 i kwartalna płatność 31-go
+	if len(calendar[0].Charges) != 6 || calendar[0].Total != dec(350) {
+		t.Errorf("SubscriptionCalendar() expected 6 charges worth 350 in March, got %d worth %s", len(calendar[0].Charges), calendar[0].Total)
+	}
+	// Kwiecień: 4 poniedziałki; czerwiec: 5 poniedziałków i płatność 30-go (przycięta z 31-go)
+	if calendar[1].Total != dec(40) || calendar[3].Total != dec(350) {
+		t.Errorf("SubscriptionCalendar() expected April 40 and June 350, got %s and %s", calendar[1].Total, calendar[3].Total)
+	}
+
+	weeks := calendar[0].Weeks()
+	if len(weeks) != 6 || weeks[0][6].Date.Day() != 1 || weeks[0][0].InMonth {
+		t.Errorf("Weeks() expected March 2026 to start on Sunday of the first of 6 weeks, got %d weeks", len(weeks))
+	}
+	if day := weeks[5][1]; day.Date.Day() != 31 || len(day.Charges) != 1 || day.Charges[0].Name != "Ubezpieczenie" {
+		t.Errorf("Weeks() expected the quarterly charge on March 31, got %+v", day)
+	}
+}
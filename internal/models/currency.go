@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency to waluta przyjmowana dla danych zapisanych przed wprowadzeniem walut.
+const DefaultCurrency = "PLN"
+
+// NormalizeCurrency sprawdza kod waluty (ISO 4217, np. "USD") i zwraca go wielkimi literami.
+// Pusty kod oznacza walutę domyślną.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q: expected 3 letters", code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q: expected 3 letters", code)
+		}
+	}
+	return code, nil
+}
+
+// FXRate to kurs wymiany: 1 jednostka waluty Base kosztuje Rate jednostek waluty Quote.
+type FXRate struct {
+	Base      string    `json:"base" bson:"base"`
+	Quote     string    `json:"quote" bson:"quote"`
+	Rate      Decimal   `json:"rate" bson:"rate"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Pair zwraca identyfikator pary walutowej w formacie "USD/PLN".
+func (r FXRate) Pair() string {
+	return r.Base + "/" + r.Quote
+}
+
+// FXRates przechowuje znane kursy i pozwala przeliczać kwoty pomiędzy walutami.
+type FXRates struct {
+	rates map[string]FXRate
+}
+
+// NewFXRates buduje tabelę kursów z listy (np. wczytanej z bazy danych).
+func NewFXRates(list []FXRate) FXRates {
+	rates := FXRates{rates: make(map[string]FXRate, len(list))}
+	for _, r := range list {
+		rates.rates[r.Pair()] = r
+	}
+	return rates
+}
+
+// List zwraca wszystkie kursy posortowane według pary walutowej.
+func (r FXRates) List() []FXRate {
+	list := make([]FXRate, 0, len(r.rates))
+	for _, rate := range r.rates {
+		list = append(list, rate)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Pair() < list[j].Pair() })
+	return list
+}
+
+// Rate zwraca kurs wymiany z waluty from na walutę to.
+// Szuka kursu bezpośredniego, odwrotnego, a na końcu kursu krzyżowego przez trzecią walutę.
+func (r FXRates) Rate(from, to string) (Decimal, bool) {
+	if from == to {
+		return NewDecimal(1), true
+	}
+	if rate, ok := r.direct(from, to); ok {
+		return rate, true
+	}
+	for _, known := range r.rates {
+		for _, via := range []string{known.Base, known.Quote} {
+			if via == from || via == to {
+				continue
+			}
+			first, ok1 := r.direct(from, via)
+			second, ok2 := r.direct(via, to)
+			if ok1 && ok2 {
+				return first.Mul(second), true
+			}
+		}
+	}
+	return Zero, false
+}
+
+// direct zwraca kurs bezpośredni lub odwrotność kursu zapisanego w przeciwną stronę.
+func (r FXRates) direct(from, to string) (Decimal, bool) {
+	if rate, ok := r.rates[from+"/"+to]; ok && rate.Rate.IsPositive() {
+		return rate.Rate, true
+	}
+	if rate, ok := r.rates[to+"/"+from]; ok && rate.Rate.IsPositive() {
+		return NewDecimal(1).Div(rate.Rate), true
+	}
+	return Zero, false
+}
+
+// Convert przelicza kwotę z waluty from na walutę to. Zwraca false, jeśli kurs jest nieznany.
+func (r FXRates) Convert(amount Decimal, from, to string) (Decimal, bool) {
+	rate, divide, ok := r.Factor(from, to)
+	if !ok {
+		return amount, false
+	}
+	if divide {
+		return amount.Div(rate), true
+	}
+	return amount.Mul(rate), true
+}
+
+// Factor mówi, jak Convert przelicza kwotę z waluty from na walutę to: mnoży ją przez rate albo,
+// gdy divide jest true, dzieli przez rate. Zwraca false, jeśli kurs jest nieznany.
+func (r FXRates) Factor(from, to string) (rate Decimal, divide bool, ok bool) {
+	if from == to {
+		return NewDecimal(1), false, true
+	}
+	// Przy kursie odwrotnym dzielimy kwotę zamiast mnożyć przez zaokrągloną odwrotność kursu
+	if inverse, ok := r.rates[to+"/"+from]; ok && inverse.Rate.IsPositive() {
+		if _, hasDirect := r.rates[from+"/"+to]; !hasDirect {
+			return inverse.Rate, true, true
+		}
+	}
+	rate, ok = r.Rate(from, to)
+	return rate, false, ok
+}
@@ -0,0 +1,43 @@
+package models
+
+import "testing"
+
+// TestMultiCurrencyTotals sprawdza przeliczanie sum portfela na walutę bazową.
+func TestMultiCurrencyTotals(t *testing.T) {
+	portfolio := NewInvestmentPortfolio()
+	portfolio.FXRates = NewFXRates([]FXRate{
+		{Base: "USD", Quote: "PLN", Rate: dec(4.0)},
+		{Base: "EUR", Quote: "USD", Rate: dec(1.1)},
+	})
+
+	portfolio.AddAsset(Asset{ID: "C1", Name: "PLN", Quantity: dec(100), AvgCost: dec(1), CurrentPrice: dec(1)})
+	portfolio.AddAsset(Asset{ID: "C2", Name: "ETF USD", Currency: "USD", Quantity: dec(10), AvgCost: dec(10), CurrentPrice: dec(12)})
+	portfolio.AddAsset(Asset{ID: "C3", Name: "Obligacja EUR", Currency: "EUR", Quantity: dec(1), AvgCost: dec(100), CurrentPrice: dec(100)})
+
+	expectedValue := dec(100.0 + 10*12*4.0 + 100*1.1*4.0) // kurs krzyżowy EUR -> USD -> PLN
+	if portfolio.GetTotalValue() != expectedValue {
+		t.Errorf("GetTotalValue() expected %s, got %s", expectedValue, portfolio.GetTotalValue())
+	}
+	if len(portfolio.MissingFXRates) != 0 {
+		t.Errorf("expected no missing fx rates, got %v", portfolio.MissingFXRates)
+	}
+
+	// Zmiana waluty bazowej na USD
+	portfolio.BaseCurrency = "USD"
+	expectedUSD := dec(100.0/4.0 + 10*12 + 100*1.1)
+	if portfolio.GetTotalValue() != expectedUSD {
+		t.Errorf("GetTotalValue() in USD expected %s, got %s", expectedUSD, portfolio.GetTotalValue())
+	}
+
+	// Waluta bez kursu jest raportowana jako brakująca, a jej kwoty nie trafiają do sum
+	portfolio.AddAsset(Asset{ID: "C4", Name: "Akcje CHF", Currency: "CHF", Quantity: dec(1), AvgCost: dec(10), CurrentPrice: dec(10)})
+	if len(portfolio.MissingFXRates) != 1 || portfolio.MissingFXRates[0] != "CHF" {
+		t.Errorf("expected CHF to be reported as missing fx rate, got %v", portfolio.MissingFXRates)
+	}
+	if portfolio.GetTotalValue() != expectedUSD {
+		t.Errorf("GetTotalValue() should skip the CHF asset, expected %s, got %s", expectedUSD, portfolio.GetTotalValue())
+	}
+	if portfolio.HasFXRate("CHF") || !portfolio.HasFXRate("EUR") {
+		t.Error("HasFXRate() should report only CHF as missing")
+	}
+}
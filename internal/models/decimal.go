@@ -0,0 +1,450 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DecimalPlaces to liczba miejsc po przecinku przechowywana dokładnie (tyle, ile potrzeba dla kryptowalut).
+const DecimalPlaces = 8
+
+// decimalScale to 10^DecimalPlaces - mnożnik pomiędzy wartością a przechowywanymi jednostkami.
+const decimalScale int64 = 100_000_000
+
+// Decimal to liczba stałoprzecinkowa używana do kwot, cen i ilości zamiast float64.
+// Wartość przechowywana jest jako całkowita liczba jednostek 10^-8, więc dodawanie
+// i odejmowanie są dokładne, a mnożenie i dzielenie zaokrąglają tylko ostatnią cyfrę.
+// Wartość zerowa typu to 0.
+type Decimal struct {
+	units int64
+}
+
+// Zero to stała dla wartości 0.
+var Zero = Decimal{}
+
+// NewDecimal tworzy liczbę z części całkowitej (np. NewDecimal(12) == 12.0).
+// Wartości spoza zakresu są ograniczane do największej (najmniejszej) możliwej liczby.
+func NewDecimal(value int64) Decimal {
+	return Decimal{units: value}.MulInt(decimalScale)
+}
+
+// NewDecimalFromFloat zamienia float64 na Decimal, zaokrąglając do DecimalPlaces miejsc.
+// Używana tylko na granicy z kodem, który liczy na float64 (np. wykresy, stopy zwrotu).
+// NaN daje 0, a nieskończoność i wartości spoza zakresu - największą (najmniejszą) możliwą liczbę.
+func NewDecimalFromFloat(value float64) Decimal {
+	if math.IsNaN(value) {
+		return Zero
+	}
+	scaled := math.Round(value * float64(decimalScale))
+	switch {
+	case scaled >= math.MaxInt64:
+		return Decimal{units: math.MaxInt64}
+	case scaled <= math.MinInt64:
+		return Decimal{units: math.MinInt64}
+	}
+	return Decimal{units: int64(scaled)}
+}
+
+// parseDecimalFloat zamienia float64 odczytany z danych wejściowych na Decimal.
+// W przeciwieństwie do NewDecimalFromFloat odrzuca NaN, nieskończoność i wartości spoza zakresu.
+func parseDecimalFloat(value float64) (Decimal, error) {
+	scaled := math.Round(value * float64(decimalScale))
+	if math.IsNaN(scaled) || scaled >= math.MaxInt64 || scaled < math.MinInt64 {
+		return Zero, fmt.Errorf("decimal value %v is out of range", value)
+	}
+	return Decimal{units: int64(scaled)}, nil
+}
+
+// ParseDecimal odczytuje liczbę zapisaną tekstowo (np. z formularza) bez pośrednictwa float64.
+// Akceptuje zarówno kropkę, jak i przecinek jako separator dziesiętny.
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, ",", "."))
+	if s == "" {
+		return Zero, fmt.Errorf("empty decimal value")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("invalid decimal value %q", s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+	if len(fracPart) > DecimalPlaces {
+		// Nadmiarowe cyfry muszą być zerami - nie zaokrąglamy po cichu danych wejściowych
+		if strings.Trim(fracPart[DecimalPlaces:], "0") != "" {
+			return Zero, fmt.Errorf("decimal value %q has more than %d decimal places", s, DecimalPlaces)
+		}
+		fracPart = fracPart[:DecimalPlaces]
+	}
+	fracPart += strings.Repeat("0", DecimalPlaces-len(fracPart))
+
+	for _, part := range []string{intPart, fracPart} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return Zero, fmt.Errorf("invalid decimal value %q", s)
+			}
+		}
+	}
+
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	frac, _ := strconv.ParseInt(fracPart, 10, 64)
+	// whole*decimalScale + frac musi zmieścić się w int64
+	if err != nil || whole > (math.MaxInt64-frac)/decimalScale {
+		return Zero, fmt.Errorf("decimal value %q is out of range", s)
+	}
+
+	units := whole*decimalScale + frac
+	if negative {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+// MustParseDecimal działa jak ParseDecimal, ale panikuje przy błędzie. Przydatne w testach i stałych.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Add zwraca d + other. Wynik spoza zakresu jest ograniczany do największej (najmniejszej) możliwej liczby.
+func (d Decimal) Add(other Decimal) Decimal {
+	sum := d.units + other.units
+	// Przepełnienie: składniki mają ten sam znak, a suma - przeciwny
+	if (d.units >= 0) == (other.units >= 0) && (sum >= 0) != (d.units >= 0) {
+		return saturated(d.units >= 0)
+	}
+	return Decimal{units: sum}
+}
+
+// Sub zwraca d - other. Wynik spoza zakresu jest ograniczany do największej (najmniejszej) możliwej liczby.
+func (d Decimal) Sub(other Decimal) Decimal {
+	difference := d.units - other.units
+	// Przepełnienie: odjemna i odjemnik mają różne znaki, a różnica ma znak odjemnika
+	if (d.units >= 0) != (other.units >= 0) && (difference >= 0) != (d.units >= 0) {
+		return saturated(d.units >= 0)
+	}
+	return Decimal{units: difference}
+}
+
+// saturated zwraca największą (positive) albo najmniejszą możliwą liczbę - wynik działania spoza zakresu.
+func saturated(positive bool) Decimal {
+	if positive {
+		return Decimal{units: math.MaxInt64}
+	}
+	return Decimal{units: math.MinInt64}
+}
+
+// Neg zwraca -d (dla najmniejszej możliwej liczby - największą).
+func (d Decimal) Neg() Decimal {
+	if d.units == math.MinInt64 {
+		return Decimal{units: math.MaxInt64}
+	}
+	return Decimal{units: -d.units}
+}
+
+// Abs zwraca wartość bezwzględną.
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Mul zwraca d * other zaokrąglone (połówki od zera) do DecimalPlaces miejsc.
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(other.units))
+	return Decimal{units: roundedQuotient(product, big.NewInt(decimalScale))}
+}
+
+// MulInt zwraca d * n (dokładnie, a wynik spoza zakresu - ograniczony jak w roundedQuotient).
+func (d Decimal) MulInt(n int64) Decimal {
+	product := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(n))
+	return Decimal{units: roundedQuotient(product, big.NewInt(1))}
+}
+
+// Div zwraca d / other zaokrąglone do DecimalPlaces miejsc. Dzielenie przez zero zwraca 0.
+func (d Decimal) Div(other Decimal) Decimal {
+	if other.units == 0 {
+		return Zero
+	}
+	numerator := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(decimalScale))
+	return Decimal{units: roundedQuotient(numerator, big.NewInt(other.units))}
+}
+
+// DivInt zwraca d / n zaokrąglone do DecimalPlaces miejsc. Dzielenie przez zero zwraca 0.
+func (d Decimal) DivInt(n int64) Decimal {
+	if n == 0 {
+		return Zero
+	}
+	return Decimal{units: roundedQuotient(big.NewInt(d.units), big.NewInt(n))}
+}
+
+// roundedQuotient dzieli a/b z zaokrągleniem połówek od zera.
+// Wynik spoza zakresu int64 jest ograniczany do największej (najmniejszej) możliwej wartości.
+func roundedQuotient(a, b *big.Int) int64 {
+	quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+	// |2 * reszta| >= |dzielnik| oznacza, że trzeba zaokrąglić w górę (od zera)
+	twice := new(big.Int).Abs(new(big.Int).Lsh(remainder, 1))
+	if twice.Cmp(new(big.Int).Abs(b)) >= 0 {
+		if (a.Sign() < 0) != (b.Sign() < 0) {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	if !quotient.IsInt64() {
+		if quotient.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return quotient.Int64()
+}
+
+// Round zaokrągla do podanej liczby miejsc po przecinku (połówki od zera).
+func (d Decimal) Round(places int) Decimal {
+	if places >= DecimalPlaces || places < 0 {
+		return d
+	}
+	step := int64(math.Pow10(DecimalPlaces - places))
+	return Decimal{units: roundedQuotient(big.NewInt(d.units), big.NewInt(step))}.MulInt(step)
+}
+
+// Cmp porównuje liczby: -1 gdy d < other, 0 gdy równe, 1 gdy d > other.
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.units < other.units:
+		return -1
+	case d.units > other.units:
+		return 1
+	}
+	return 0
+}
+
+// Sign zwraca -1, 0 lub 1 w zależności od znaku liczby.
+func (d Decimal) Sign() int {
+	return d.Cmp(Zero)
+}
+
+// IsZero mówi, czy liczba jest równa 0.
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// IsPositive mówi, czy liczba jest większa od 0.
+func (d Decimal) IsPositive() bool {
+	return d.units > 0
+}
+
+// IsNegative mówi, czy liczba jest mniejsza od 0.
+func (d Decimal) IsNegative() bool {
+	return d.units < 0
+}
+
+// LessThan zwraca d < other.
+func (d Decimal) LessThan(other Decimal) bool {
+	return d.units < other.units
+}
+
+// GreaterThan zwraca d > other.
+func (d Decimal) GreaterThan(other Decimal) bool {
+	return d.units > other.units
+}
+
+// Min zwraca mniejszą z dwóch liczb.
+func (d Decimal) Min(other Decimal) Decimal {
+	if other.units < d.units {
+		return other
+	}
+	return d
+}
+
+// Float64 zamienia liczbę na float64 (np. dla wykresów i procentów).
+func (d Decimal) Float64() float64 {
+	return float64(d.units) / float64(decimalScale)
+}
+
+// String zwraca pełną reprezentację liczby bez zbędnych zer na końcu (np. "12.5").
+func (d Decimal) String() string {
+	s := d.StringFixed(DecimalPlaces)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// StringFixed zwraca liczbę z dokładnie podaną liczbą miejsc po przecinku (np. do wyświetlenia kwoty).
+func (d Decimal) StringFixed(places int) string {
+	if places < 0 {
+		places = 0
+	}
+	if places > DecimalPlaces {
+		places = DecimalPlaces
+	}
+	rounded := d.Round(places)
+
+	units := rounded.units
+	sign := ""
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	whole := units / decimalScale
+	frac := units % decimalScale
+	if places == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fracStr := fmt.Sprintf("%08d", frac)[:places]
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracStr)
+}
+
+// MarshalJSON zapisuje liczbę jako liczbę JSON (bez utraty precyzji po stronie serwera).
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON przyjmuje liczbę JSON lub tekst z liczbą.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		*d = Zero
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw = s
+	}
+	// Liczby JSON mogą być zapisane w notacji wykładniczej - wtedy przechodzimy przez float64
+	if strings.ContainsAny(raw, "eE") {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid decimal value %q", raw)
+		}
+		parsed, err := parseDecimalFloat(f)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+	parsed, err := ParseDecimal(raw)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalBSONValue zapisuje liczbę w MongoDB jako Decimal128, więc baza przechowuje wartość dokładną.
+func (d Decimal) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value, err := primitive.ParseDecimal128(d.String())
+	if err != nil {
+		return 0, nil, fmt.Errorf("cannot encode decimal %s: %w", d.String(), err)
+	}
+	return bson.MarshalValue(value)
+}
+
+// UnmarshalBSONValue odczytuje Decimal128, a także liczby zapisane przed migracją (double, int32, int64).
+func (d *Decimal) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Decimal128:
+		value := raw.Decimal128().String()
+		parsed, err := ParseDecimal(value)
+		if err != nil {
+			// Decimal128 może mieć więcej miejsc niż obsługujemy - zaokrąglamy przez float64,
+			// ale NaN, nieskończoności i wartości spoza zakresu są błędem, a nie zerem czy skrajną wartością
+			f, floatErr := strconv.ParseFloat(value, 64)
+			if floatErr != nil {
+				return fmt.Errorf("cannot decode decimal %s: %w", value, floatErr)
+			}
+			if parsed, err = parseDecimalFloat(f); err != nil {
+				return err
+			}
+		}
+		*d = parsed
+	case bsontype.Double:
+		*d = NewDecimalFromFloat(raw.Double())
+	case bsontype.Int32:
+		*d = NewDecimal(int64(raw.Int32()))
+	case bsontype.Int64:
+		*d = NewDecimal(raw.Int64())
+	case bsontype.String:
+		parsed, err := ParseDecimal(raw.StringValue())
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case bsontype.Null, bsontype.Undefined:
+		*d = Zero
+	default:
+		return fmt.Errorf("cannot decode %s into decimal", t)
+	}
+	return nil
+}
+
+// Value zapisuje liczbę w bazie SQL jako tekst, żeby nie tracić precyzji (interfejs driver.Valuer).
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan odczytuje liczbę z bazy SQL - z tekstu albo (dla starszych danych) z liczby (interfejs sql.Scanner).
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+	case string:
+		parsed, err := ParseDecimal(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case []byte:
+		parsed, err := ParseDecimal(string(v))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case int64:
+		*d = NewDecimal(v)
+	case float64:
+		*d = NewDecimalFromFloat(v)
+	default:
+		return fmt.Errorf("cannot scan %T into decimal", src)
+	}
+	return nil
+}
+
+// SumDecimals sumuje listę liczb.
+func SumDecimals(values ...Decimal) Decimal {
+	total := Zero
+	for _, v := range values {
+		total = total.Add(v)
+	}
+	return total
+}
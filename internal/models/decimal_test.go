@@ -0,0 +1,127 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestDecimalArithmetic sprawdza dokładność działań na kwotach i ilościach.
+func TestDecimalArithmetic(t *testing.T) {
+	// 0.1 + 0.2 na float64 daje 0.30000000000000004
+	if sum := MustParseDecimal("0.1").Add(MustParseDecimal("0.2")); sum != MustParseDecimal("0.3") {
+		t.Errorf("0.1 + 0.2 expected 0.3, got %s", sum)
+	}
+	if parsed, err := ParseDecimal("1 234,5"); err == nil {
+		t.Errorf("ParseDecimal() expected error for %q, got %s", "1 234,5", parsed)
+	}
+	if parsed, err := ParseDecimal("12,5"); err != nil || parsed != dec(12.5) {
+		t.Errorf("ParseDecimal(\"12,5\") expected 12.5, got %s (%v)", parsed, err)
+	}
+	if _, err := ParseDecimal("0.000000001"); err == nil {
+		t.Errorf("ParseDecimal() expected error for more than %d decimal places", DecimalPlaces)
+	}
+	if rounded := MustParseDecimal("2.345").StringFixed(2); rounded != "2.35" {
+		t.Errorf("StringFixed(2) expected 2.35, got %s", rounded)
+	}
+	if third := NewDecimal(1).DivInt(3); third.String() != "0.33333333" {
+		t.Errorf("1/3 expected 0.33333333, got %s", third)
+	}
+
+	// Wartości spoza zakresu są odrzucane przy odczycie, a wyniki działań ograniczane zamiast przekręcać się
+	if parsed, err := ParseDecimal("92233720368.99999999"); err == nil {
+		t.Errorf("ParseDecimal() expected out of range error, got %s", parsed)
+	}
+	var fromJSON Decimal
+	if err := fromJSON.UnmarshalJSON([]byte("1e15")); err == nil {
+		t.Errorf("UnmarshalJSON(1e15) expected out of range error, got %s", fromJSON)
+	}
+	huge := MustParseDecimal("90000000000")
+	if product := huge.Mul(huge); !product.IsPositive() || product.LessThan(huge) {
+		t.Errorf("Mul() overflow expected to saturate, got %s", product)
+	}
+	if product := huge.Neg().Mul(huge); !product.IsNegative() {
+		t.Errorf("Mul() negative overflow expected to saturate, got %s", product)
+	}
+	if nan := NewDecimalFromFloat(math.NaN()); !nan.IsZero() {
+		t.Errorf("NewDecimalFromFloat(NaN) expected 0, got %s", nan)
+	}
+	if inf := NewDecimalFromFloat(math.Inf(-1)); !inf.IsNegative() {
+		t.Errorf("NewDecimalFromFloat(-Inf) expected negative limit, got %s", inf)
+	}
+
+	// Wielokrotne zakupy ułamkowych ilości kryptowaluty sumują się bez błędów zaokrągleń
+	asset := Asset{ID: "BTC"}
+	for i := 0; i < 10; i++ {
+		if err := asset.AddTransaction(Transaction{Type: TransactionBuy, Date: time.Now(), Quantity: MustParseDecimal("0.00000001"), Price: MustParseDecimal("250000.10")}, CostBasisFIFO); err != nil {
+			t.Fatalf("AddTransaction() unexpected error: %v", err)
+		}
+	}
+	if asset.Quantity != MustParseDecimal("0.0000001") {
+		t.Errorf("expected Quantity 0.0000001, got %s", asset.Quantity)
+	}
+}
+
+// TestDecimalSaturation sprawdza, że dodawanie, odejmowanie i mnożenie przez liczbę całkowitą nie przekręcają się
+// przy przepełnieniu, tylko zatrzymują na największej (najmniejszej) możliwej liczbie.
+func TestDecimalSaturation(t *testing.T) {
+	largest := Decimal{units: math.MaxInt64}
+	smallest := Decimal{units: math.MinInt64}
+	one := NewDecimal(1)
+
+	tests := []struct {
+		name string
+		got  Decimal
+		want Decimal
+	}{
+		{"max + 1", largest.Add(one), largest},
+		{"min + (-1)", smallest.Add(one.Neg()), smallest},
+		{"max - (-1)", largest.Sub(one.Neg()), largest},
+		{"min - 1", smallest.Sub(one), smallest},
+		{"0 - min", Zero.Sub(smallest), largest},
+		{"-min", smallest.Neg(), largest},
+		{"max * 2", largest.MulInt(2), largest},
+		{"max * -2", largest.MulInt(-2), smallest},
+		{"NewDecimal(MaxInt64)", NewDecimal(math.MaxInt64), largest},
+		{"NewDecimal(MinInt64)", NewDecimal(math.MinInt64), smallest},
+		{"max rounded to 2 places", largest.Round(2), largest},
+		{"1 + 2", one.Add(NewDecimal(2)), NewDecimal(3)},
+		{"1 - 2", one.Sub(NewDecimal(2)), NewDecimal(-1)},
+		{"1.5 * -3", dec(1.5).MulInt(-3), dec(-4.5)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s expected %s, got %s", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+// TestDecimalUnmarshalBSON sprawdza odczyt Decimal128 z nadmiarowymi miejscami po przecinku
+// oraz odrzucenie wartości, których nie da się zamienić na liczbę (NaN, nieskończoność, poza zakresem).
+func TestDecimalUnmarshalBSON(t *testing.T) {
+	decode := func(s string) (Decimal, error) {
+		value, err := primitive.ParseDecimal128(s)
+		if err != nil {
+			t.Fatalf("ParseDecimal128(%q) unexpected error: %v", s, err)
+		}
+		typ, data, err := bson.MarshalValue(value)
+		if err != nil {
+			t.Fatalf("MarshalValue(%q) unexpected error: %v", s, err)
+		}
+		var d Decimal
+		err = d.UnmarshalBSONValue(typ, data)
+		return d, err
+	}
+
+	if d, err := decode("12.3456789012"); err != nil || d != MustParseDecimal("12.3456789") {
+		t.Errorf("UnmarshalBSONValue(12.3456789012) expected 12.3456789, got %s (%v)", d, err)
+	}
+	for _, s := range []string{"NaN", "Infinity", "1E+30"} {
+		if d, err := decode(s); err == nil {
+			t.Errorf("UnmarshalBSONValue(%s) expected error, got %s", s, d)
+		}
+	}
+}
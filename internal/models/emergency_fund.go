@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmergencyFundWalletType to typ portfela, którego aktywa tworzą poduszkę finansową.
+// Pasują też nazwy zaczynające się od niego, np. "Poduszka Finansowa".
+const EmergencyFundWalletType = "Poduszka"
+
+// IsEmergencyFundWalletType mówi, czy aktywa z danym typem portfela należą do poduszki finansowej.
+func IsEmergencyFundWalletType(walletType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(walletType)), strings.ToLower(EmergencyFundWalletType))
+}
+
+// EmergencyFundSettings to ustawienia poduszki finansowej portfela.
+type EmergencyFundSettings struct {
+	MonthlyLivingCost Decimal `json:"monthlyLivingCost" bson:"monthlyLivingCost"` // Miesięczne koszty życia poza subskrypcjami (w walucie bazowej)
+	MinMonths         Decimal `json:"minMonths" bson:"minMonths"`                 // Poniżej tylu miesięcy pokrycia pokazujemy ostrzeżenie (0 - bez ostrzeżenia)
+}
+
+// Validate sprawdza, czy ustawienia poduszki finansowej są nieujemne.
+func (s EmergencyFundSettings) Validate() error {
+	if s.MonthlyLivingCost.IsNegative() {
+		return fmt.Errorf("monthly living cost cannot be negative")
+	}
+	if s.MinMonths.IsNegative() {
+		return fmt.Errorf("minimum coverage cannot be negative")
+	}
+	return nil
+}
+
+// EmergencyFundCoverage mówi, na ile miesięcy wydatków wystarczy poduszka finansowa.
+type EmergencyFundCoverage struct {
+	FundValue       Decimal // Wartość aktywów poduszki (w walucie bazowej)
+	Subscriptions   Decimal // Miesięczny koszt subskrypcji
+	LivingCost      Decimal // Miesięczne koszty życia wpisane ręcznie
+	MonthlyExpenses Decimal // Subscriptions + LivingCost
+	Months          float64 // Liczba miesięcy pokrycia (ważna, gdy HasExpenses)
+	HasExpenses     bool    // false, gdy miesięczne wydatki są zerowe - pokrycia nie da się policzyć
+	MinMonths       float64
+	BelowThreshold  bool // Pokrycie jest niższe niż MinMonths
+}
+
+// EmergencyFundCoverage wylicza pokrycie miesięcznych wydatków (subskrypcje i koszty życia)
+// wartością aktywów poduszki finansowej. Wymaga aktualnych sum (CalculateTotals).
+func (p *InvestmentPortfolio) EmergencyFundCoverage() EmergencyFundCoverage {
+	coverage := EmergencyFundCoverage{
+		FundValue:     Zero,
+		Subscriptions: p.GetMonthlySubscriptionCost(),
+		LivingCost:    p.EmergencyFund.MonthlyLivingCost,
+		MinMonths:     p.EmergencyFund.MinMonths.Float64(),
+	}
+	for _, a := range p.Assets {
+		if IsEmergencyFundWalletType(a.WalletType) {
+			coverage.FundValue = coverage.FundValue.Add(p.ToBase(a.Quantity.Mul(a.CurrentPrice), a.CurrencyCode()))
+		}
+	}
+	coverage.MonthlyExpenses = coverage.Subscriptions.Add(coverage.LivingCost)
+
+	if coverage.MonthlyExpenses.IsPositive() {
+		coverage.HasExpenses = true
+		coverage.Months = coverage.FundValue.Div(coverage.MonthlyExpenses).Float64()
+		coverage.BelowThreshold = coverage.MinMonths > 0 && coverage.Months < coverage.MinMonths
+	}
+	return coverage
+}
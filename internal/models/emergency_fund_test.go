@@ -0,0 +1,21 @@
+package models
+
+import "testing"
+
+// TestEmergencyFundCoverage sprawdza pokrycie wydatków poduszką finansową i próg ostrzeżenia.
+func TestEmergencyFundCoverage(t *testing.T) {
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddAsset(Asset{ID: "A1", Name: "Lokata", WalletType: "Poduszka Finansowa", Quantity: dec(12000), AvgCost: dec(1), CurrentPrice: dec(1)})
+	portfolio.AddAsset(Asset{ID: "A2", Name: "ETF", WalletType: "Długoterminowy", Quantity: dec(10), AvgCost: dec(100), CurrentPrice: dec(100)})
+	portfolio.AddSubscription(Subscription{ID: "S1", Name: "Czynsz", Cost: dec(1000), Frequency: "Miesięcznie"})
+
+	if coverage := portfolio.EmergencyFundCoverage(); !coverage.HasExpenses || coverage.Months != 12 || coverage.BelowThreshold {
+		t.Errorf("EmergencyFundCoverage() expected 12 months without warning, got %+v", coverage)
+	}
+
+	portfolio.EmergencyFund = EmergencyFundSettings{MonthlyLivingCost: dec(2000), MinMonths: dec(6)}
+	coverage := portfolio.EmergencyFundCoverage()
+	if coverage.FundValue != dec(12000) || coverage.MonthlyExpenses != dec(3000) || coverage.Months != 4 || !coverage.BelowThreshold {
+		t.Errorf("EmergencyFundCoverage() expected 4 months below the 6-month threshold, got %+v", coverage)
+	}
+}
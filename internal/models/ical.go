@@ -0,0 +1,132 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsLineLimit to maksymalna długość linii iCalendar w bajtach (bez CRLF) - dłuższe linie zawijamy (RFC 5545, 3.1).
+const icsLineLimit = 75
+
+// NewCalendarToken tworzy losowy token do adresu kanału iCalendar. Zwraca token do pokazania
+// użytkownikowi oraz jego skrót do zapisania w bazie - sam token nie jest nigdzie przechowywany.
+func NewCalendarToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashSessionToken(token), nil
+}
+
+// RRule zwraca regułę powtarzania (RRULE, RFC 5545) odpowiadającą harmonogramowi subskrypcji,
+// licząc od NextDue. Dni 29-31 zamieniamy na "ostatni istniejący z dni 28..N" (BYSETPOS=-1), tak jak
+// NextChargeAfter przycina terminy do końca krótszego miesiąca. Nieznana częstotliwość daje pusty tekst.
+func (s Subscription) RRule() string {
+	months := 0
+	switch s.FrequencyCode() {
+	case FrequencyWeekly:
+		return "FREQ=WEEKLY"
+	case FrequencyCustom:
+		if s.IntervalDays < 1 {
+			return ""
+		}
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", s.IntervalDays)
+	case FrequencyMonthly:
+		months = 1
+	case FrequencyQuarterly:
+		months = 3
+	case FrequencySemiAnnual:
+		months = 6
+	case FrequencyYearly:
+		months = 12
+	default:
+		return ""
+	}
+
+	rule := "FREQ=MONTHLY"
+	if months == 12 {
+		rule = "FREQ=YEARLY"
+	} else if months > 1 {
+		rule += fmt.Sprintf(";INTERVAL=%d", months)
+	}
+	if day := s.anchorDay(s.NextDue); day > 28 {
+		if months == 12 {
+			rule += fmt.Sprintf(";BYMONTH=%d", int(s.NextDue.Month()))
+		}
+		days := make([]string, 0, day-27)
+		for d := 28; d <= day; d++ {
+			days = append(days, fmt.Sprint(d))
+		}
+		rule += ";BYMONTHDAY=" + strings.Join(days, ",") + ";BYSETPOS=-1"
+	}
+	return rule
+}
+
+// SubscriptionsICS buduje kanał iCalendar (RFC 5545) z terminami płatności subskrypcji podanych portfeli:
+// jedno całodniowe wydarzenie na subskrypcję, od NextDue, powtarzane według RRule. SEQUENCE to wersja
+// portfela, więc po każdej zmianie subskrypcji kalendarze traktują wydarzenia jako zaktualizowane.
+func SubscriptionsICS(portfolios []*InvestmentPortfolio, now time.Time) []byte {
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//webwallet//Subskrypcje//PL")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:Subskrypcje")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, portfolio := range portfolios {
+		for _, s := range portfolio.Subscriptions {
+			if s.NextDue.IsZero() {
+				continue
+			}
+			writeICSLine(&b, "BEGIN:VEVENT")
+			writeICSLine(&b, "UID:"+s.ID+"@webwallet")
+			writeICSLine(&b, "DTSTAMP:"+stamp)
+			writeICSLine(&b, fmt.Sprintf("SEQUENCE:%d", portfolio.Version))
+			writeICSLine(&b, "DTSTART;VALUE=DATE:"+s.NextDue.Format("20060102"))
+			writeICSLine(&b, "DTEND;VALUE=DATE:"+s.NextDue.AddDate(0, 0, 1).Format("20060102"))
+			if rule := s.RRule(); rule != "" {
+				writeICSLine(&b, "RRULE:"+rule)
+			}
+			writeICSLine(&b, "SUMMARY:"+escapeICSText(fmt.Sprintf("%s: %s", s.Name, FormatCurrency(s.Cost, s.CurrencyCode()))))
+			description := "Częstotliwość: " + s.FrequencyLabel()
+			if portfolio.Name != "" {
+				description += "\nPortfel: " + portfolio.Name
+			}
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(description))
+			writeICSLine(&b, "TRANSP:TRANSPARENT")
+			writeICSLine(&b, "END:VEVENT")
+		}
+	}
+	writeICSLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// escapeICSText zamienia znaki specjalne wartości tekstowej iCalendar na sekwencje ucieczki.
+func escapeICSText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// writeICSLine dopisuje linię zakończoną CRLF, zawijając ją co icsLineLimit bajtów
+// (kontynuacja zaczyna się od spacji) bez dzielenia znaków UTF-8.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1 // Spacja na początku kontynuacji też się liczy
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestSubscriptionsICS sprawdza reguły powtarzania subskrypcji i format kanału iCalendar.
+func TestSubscriptionsICS(t *testing.T) {
+	due := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	rules := []struct {
+		sub  Subscription
+		want string
+	}{
+		{Subscription{Frequency: FrequencyWeekly, NextDue: due}, "FREQ=WEEKLY"},
+		{Subscription{Frequency: FrequencyCustom, IntervalDays: 10, NextDue: due}, "FREQ=DAILY;INTERVAL=10"},
+		{Subscription{Frequency: FrequencyQuarterly, NextDue: due.AddDate(0, 0, -16)}, "FREQ=MONTHLY;INTERVAL=3"},
+		{Subscription{Frequency: FrequencyMonthly, NextDue: due}, "FREQ=MONTHLY;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+		{Subscription{Frequency: "Rocznie", NextDue: due}, "FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=28,29,30,31;BYSETPOS=-1"},
+		{Subscription{Frequency: "co jakiś czas", NextDue: due}, ""},
+	}
+	for _, c := range rules {
+		if got := c.sub.RRule(); got != c.want {
+			t.Errorf("RRule() of %s expected %q, got %q", c.sub.FrequencyLabel(), c.want, got)
+		}
+	}
+
+	portfolio := NewInvestmentPortfolio()
+	portfolio.Version = 7
+	portfolio.AddSubscription(Subscription{ID: "S1", Name: "Prąd; gaz, woda i bardzo długa nazwa, która nie zmieści się w jednej linii", Cost: dec(250),
+		Frequency: FrequencyMonthly, NextDue: time.Date(2026, 11, 10, 0, 0, 0, 0, time.UTC)})
+	ics := string(SubscriptionsICS([]*InvestmentPortfolio{portfolio}, time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)))
+
+	for _, want := range []string{"BEGIN:VCALENDAR\r\n", "UID:S1@webwallet\r\n", "SEQUENCE:7\r\n", "DTSTART;VALUE=DATE:20261110\r\n", "RRULE:FREQ=MONTHLY\r\n", `SUMMARY:Prąd\; gaz\, woda`} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("SubscriptionsICS() expected %q in feed:\n%s", want, ics)
+		}
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 || !utf8.ValidString(line) {
+			t.Errorf("SubscriptionsICS() line not folded on a character boundary within 75 octets: %q", line)
+		}
+	}
+}
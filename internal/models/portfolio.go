@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// AllPortfoliosID to specjalny identyfikator widoku zbiorczego, łączącego wszystkie portfele użytkownika.
+const AllPortfoliosID = "all"
+
+// AllPortfoliosName to nazwa widoku zbiorczego w przełączniku portfeli.
+const AllPortfoliosName = "Wszystkie portfele"
+
+// DefaultPortfolioName to nazwa nadawana głównemu portfelowi użytkownika (User.PortfolioID).
+const DefaultPortfolioName = "Główny"
+
+// MaxPortfolioNameLength to maksymalna długość nazwy portfela (w znakach).
+const MaxPortfolioNameLength = 50
+
+// PortfolioInfo to podstawowe dane portfela potrzebne w przełączniku - bez aktywów i subskrypcji.
+type PortfolioInfo struct {
+	ID      string `json:"id" bson:"_id"`
+	Name    string `json:"name" bson:"name"`
+	OwnerID string `json:"ownerId" bson:"ownerId"`
+}
+
+// NormalizePortfolioName sprawdza nazwę portfela i zwraca ją bez zbędnych spacji.
+func NormalizePortfolioName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("portfolio name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxPortfolioNameLength {
+		return "", fmt.Errorf("portfolio name must be at most %d characters long", MaxPortfolioNameLength)
+	}
+	return name, nil
+}
+
+// Info zwraca podstawowe dane portfela.
+func (p *InvestmentPortfolio) Info() PortfolioInfo {
+	return PortfolioInfo{ID: p.ID, Name: p.Name, OwnerID: p.OwnerID}
+}
+
+// IsAggregate mówi, czy portfel jest widokiem zbiorczym (tylko do odczytu) utworzonym przez MergePortfolios.
+func (p *InvestmentPortfolio) IsAggregate() bool {
+	return p.ID == AllPortfoliosID
+}
+
+// MergePortfolios łączy portfele w jeden widok zbiorczy "Wszystkie portfele".
+// Aktywa i subskrypcje wszystkich portfeli trafiają do jednej listy, a sumy są przeliczane
+// na walutę bazową pierwszego portfela (kursy walut są wspólne dla wszystkich portfeli).
+// Pozycje i zrealizowany zysk/strata każdego aktywa liczone są metodą rozliczania partii jego portfela
+// (CostBasisMethod widoku jest ustawiona tylko wtedy, gdy wszystkie portfele mają tę samą metodę).
+// Koszty życia poduszki finansowej są sumą kosztów portfeli, a próg ostrzeżenia - najwyższym z progów.
+func MergePortfolios(portfolios []*InvestmentPortfolio) *InvestmentPortfolio {
+	merged := NewInvestmentPortfolio()
+	merged.ID = AllPortfoliosID
+	merged.Name = AllPortfoliosName
+	merged.assetMethods = make(map[string]CostBasisMethod)
+
+	if len(portfolios) > 0 {
+		first := portfolios[0]
+		merged.OwnerID = first.OwnerID
+		merged.BaseCurrency = first.GetBaseCurrency()
+		merged.CostBasisMethod = first.CostBasisMethod
+		merged.FXRates = first.FXRates
+	}
+	for _, p := range portfolios {
+		merged.Assets = append(merged.Assets, p.Assets...)
+		merged.Subscriptions = append(merged.Subscriptions, p.Subscriptions...)
+		for _, a := range p.Assets {
+			merged.assetMethods[a.ID] = p.CostBasisMethod
+		}
+		if p.CostBasisMethod != merged.CostBasisMethod {
+			merged.CostBasisMethod = ""
+		}
+
+		settings := p.EmergencyFund
+		merged.EmergencyFund.MonthlyLivingCost = merged.EmergencyFund.MonthlyLivingCost.Add(merged.ToBase(settings.MonthlyLivingCost, p.GetBaseCurrency()))
+		if settings.MinMonths.GreaterThan(merged.EmergencyFund.MinMonths) {
+			merged.EmergencyFund.MinMonths = settings.MinMonths
+		}
+	}
+
+	merged.CalculateTotals()
+	return merged
+}
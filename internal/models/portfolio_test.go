@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMergePortfolios sprawdza widok zbiorczy łączący kilka portfeli użytkownika.
+func TestMergePortfolios(t *testing.T) {
+	rates := NewFXRates([]FXRate{{Base: "USD", Quote: "PLN", Rate: dec(4.0)}})
+
+	ike := NewInvestmentPortfolio()
+	ike.Name = "IKE"
+	ike.FXRates = rates
+	ike.AddAsset(Asset{ID: "M1", Name: "ETF", Quantity: dec(10), AvgCost: dec(100), CurrentPrice: dec(110)})
+
+	broker := NewInvestmentPortfolio()
+	broker.Name = "Maklerski"
+	broker.BaseCurrency = "USD"
+	broker.FXRates = rates
+	broker.AddAsset(Asset{ID: "M2", Name: "Akcje USD", Currency: "USD", Quantity: dec(5), AvgCost: dec(20), CurrentPrice: dec(30)})
+	broker.AddSubscription(Subscription{ID: "S1", Name: "Dane rynkowe", Cost: dec(10), Frequency: "Miesięcznie", Currency: "USD"})
+
+	merged := MergePortfolios([]*InvestmentPortfolio{ike, broker})
+	if !merged.IsAggregate() || merged.Name != AllPortfoliosName {
+		t.Errorf("MergePortfolios() expected aggregate portfolio, got ID %q name %q", merged.ID, merged.Name)
+	}
+	if len(merged.Assets) != 2 || len(merged.Subscriptions) != 1 {
+		t.Fatalf("MergePortfolios() expected 2 assets and 1 subscription, got %d and %d", len(merged.Assets), len(merged.Subscriptions))
+	}
+	// Sumy w walucie bazowej pierwszego portfela (PLN)
+	if expected := dec(10*110 + 5*30*4.0); merged.GetTotalValue() != expected {
+		t.Errorf("GetTotalValue() expected %s, got %s", expected, merged.GetTotalValue())
+	}
+	if expected := dec(10 * 4.0); merged.GetMonthlySubscriptionCost() != expected {
+		t.Errorf("GetMonthlySubscriptionCost() expected %s, got %s", expected, merged.GetMonthlySubscriptionCost())
+	}
+
+	if _, err := NormalizePortfolioName("   "); err == nil {
+		t.Errorf("NormalizePortfolioName() expected error for empty name")
+	}
+	if name, err := NormalizePortfolioName("  IKZE "); err != nil || name != "IKZE" {
+		t.Errorf("NormalizePortfolioName() expected %q, got %q (%v)", "IKZE", name, err)
+	}
+}
+
+// TestMergePortfoliosSettings sprawdza, że widok zbiorczy liczy zrealizowany zysk każdego aktywa metodą
+// jego portfela, a koszty życia poduszki finansowej sumuje (w walucie bazowej widoku).
+func TestMergePortfoliosSettings(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	rates := NewFXRates([]FXRate{{Base: "USD", Quote: "PLN", Rate: dec(4.0)}})
+	newPortfolio := func(id string, method CostBasisMethod, base string, settings EmergencyFundSettings) *InvestmentPortfolio {
+		p := NewInvestmentPortfolio()
+		p.CostBasisMethod = method
+		p.BaseCurrency = base
+		p.FXRates = rates
+		p.EmergencyFund = settings
+		p.AddAsset(Asset{ID: id, Name: "Akcje " + id, Transactions: []Transaction{
+			{ID: id + "B1", Type: TransactionBuy, Date: day(1), Quantity: dec(10), Price: dec(100)},
+			{ID: id + "B2", Type: TransactionBuy, Date: day(10), Quantity: dec(10), Price: dec(200)},
+			{ID: id + "S1", Type: TransactionSell, Date: day(20), Quantity: dec(15), Price: dec(300)},
+		}})
+		return p
+	}
+	fifo := newPortfolio("F", CostBasisFIFO, "PLN", EmergencyFundSettings{MonthlyLivingCost: dec(3000), MinMonths: dec(3)})
+	lifo := newPortfolio("L", CostBasisLIFO, "USD", EmergencyFundSettings{MonthlyLivingCost: dec(500), MinMonths: dec(6)})
+
+	merged := MergePortfolios([]*InvestmentPortfolio{fifo, lifo})
+	// Aktywa są w PLN: FIFO sprzedaje partie po 100 i 200, LIFO - po 200 i 100
+	expected := dec(15*300 - (10*100 + 5*200) + 15*300 - (10*200 + 5*100))
+	if got := merged.GetRealizedProfitLoss(); got != expected {
+		t.Errorf("GetRealizedProfitLoss() expected %s (FIFO + LIFO), got %s", expected, got)
+	}
+	if merged.CostBasisMethod != "" {
+		t.Errorf("MergePortfolios() expected no common cost basis method, got %q", merged.CostBasisMethod)
+	}
+	if expected := dec(3000 + 500*4.0); merged.EmergencyFund.MonthlyLivingCost != expected {
+		t.Errorf("MergePortfolios() expected living cost %s, got %s", expected, merged.EmergencyFund.MonthlyLivingCost)
+	}
+	if merged.EmergencyFund.MinMonths != dec(6) {
+		t.Errorf("MergePortfolios() expected the highest threshold 6, got %s", merged.EmergencyFund.MinMonths)
+	}
+}
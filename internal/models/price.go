@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PriceSourceManual oznacza cenę wpisaną ręcznie w formularzu aktualizacji ceny.
+const PriceSourceManual = "manual"
+
+// PriceQuote to notowanie aktywa: cena, źródło, z którego pochodzi (np. "manual", "file:ceny.json")
+// i chwila, z której pochodzi cena.
+type PriceQuote struct {
+	Price  Decimal
+	Source string
+	Time   time.Time
+}
+
+// ManualQuote tworzy notowanie dla ceny wpisanej ręcznie przez użytkownika.
+func ManualQuote(price Decimal) PriceQuote {
+	return PriceQuote{Price: price, Source: PriceSourceManual, Time: time.Now()}
+}
+
+// ApplyQuote ustawia cenę bieżącą aktywa wraz z informacją, skąd i kiedy pochodzi.
+func (a *Asset) ApplyQuote(quote PriceQuote) {
+	a.CurrentPrice = quote.Price
+	a.PriceSource = quote.Source
+	a.PriceUpdatedAt = quote.Time
+}
+
+// PriceOrigin opisuje dla użytkownika, skąd i kiedy pochodzi cena bieżąca aktywa
+// (pusty tekst dla cen zapisanych przed wprowadzeniem źródeł notowań).
+func (a Asset) PriceOrigin() string {
+	if a.PriceUpdatedAt.IsZero() {
+		return ""
+	}
+	source := a.PriceSource
+	if source == PriceSourceManual {
+		source = "wpisana ręcznie"
+	}
+	return fmt.Sprintf("Źródło ceny: %s, %s", source, a.PriceUpdatedAt.Local().Format("02.01.2006 15:04"))
+}
+
+// PricePoint to cena zamknięcia symbolu w danym dniu - jeden punkt historii notowań.
+// Każda kolejna cena z tego samego dnia nadpisuje poprzednią (zostaje ostatnia znana cena).
+type PricePoint struct {
+	Symbol string    `json:"symbol" bson:"symbol"` // Symbol aktywa zapisany wielkimi literami
+	Date   time.Time `json:"date" bson:"date"`     // Dzień notowania (północ UTC)
+	Close  Decimal   `json:"close" bson:"close"`
+	Source string    `json:"source" bson:"source"`
+}
+
+// NormalizeSymbol sprowadza symbol aktywa do postaci, pod którą przechowujemy jego historię cen.
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// NewPricePoint tworzy punkt historii cen z notowania symbolu.
+func NewPricePoint(symbol string, quote PriceQuote) PricePoint {
+	return PricePoint{
+		Symbol: NormalizeSymbol(symbol),
+		Date:   quote.Time.UTC().Truncate(24 * time.Hour),
+		Close:  quote.Price,
+		Source: quote.Source,
+	}
+}
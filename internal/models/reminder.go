@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultReminderDays to liczba dni przed terminem płatności, proponowana dla nowych subskrypcji.
+const DefaultReminderDays = 3
+
+// MaxReminderDays to najdłuższe wyprzedzenie przypomnienia o płatności subskrypcji (w dniach).
+const MaxReminderDays = 60
+
+// SubscriptionReminder to przypomnienie o zbliżającej się płatności subskrypcji.
+type SubscriptionReminder struct {
+	PortfolioID   string
+	PortfolioName string
+	Subscription  Subscription
+	DaysLeft      int // Ile dni zostało do NextDue (0 - płatność dzisiaj)
+}
+
+// Key identyfikuje przypomnienie o konkretnym terminie subskrypcji - po przesunięciu NextDue
+// na kolejny termin powstaje nowy klucz, więc przypomnienie zostanie wysłane ponownie.
+func (r SubscriptionReminder) Key() string {
+	return fmt.Sprintf("%s/%s/%s", r.PortfolioID, r.Subscription.ID, r.Subscription.NextDue.Format("2006-01-02"))
+}
+
+// SentReminder to zapis wysłanego przypomnienia - dzięki niemu restart aplikacji nie powtarza wysyłki.
+type SentReminder struct {
+	Key            string    `json:"key" bson:"_id"` // SubscriptionReminder.Key albo WebhookKey
+	PortfolioID    string    `json:"portfolioId" bson:"portfolioId"`
+	SubscriptionID string    `json:"subscriptionId" bson:"subscriptionId"`
+	DueDate        time.Time `json:"dueDate" bson:"dueDate"`
+	Email          string    `json:"email" bson:"email"` // Pusty dla zdarzenia webhooka
+	SentAt         time.Time `json:"sentAt" bson:"sentAt"`
+}
+
+// ValidateReminder sprawdza, czy wyprzedzenie przypomnienia mieści się w zakresie 0..MaxReminderDays (0 - bez przypomnienia).
+func (s Subscription) ValidateReminder() error {
+	if s.ReminderDays < 0 || s.ReminderDays > MaxReminderDays {
+		return fmt.Errorf("reminder must be between 0 and %d days before the due date", MaxReminderDays)
+	}
+	return nil
+}
+
+// DueReminders zwraca przypomnienia o subskrypcjach, których NextDue przypada między today
+// a today + ReminderDays. Przypomnienie przychodzi więc ReminderDays dni przed terminem,
+// a jeśli wtedy nie udało się go wysłać (np. aplikacja nie działała) - w kolejnych dniach aż do terminu.
+func (p *InvestmentPortfolio) DueReminders(today time.Time) []SubscriptionReminder {
+	today = today.UTC().Truncate(24 * time.Hour)
+	reminders := []SubscriptionReminder{}
+	for _, s := range p.Subscriptions {
+		if s.ReminderDays < 1 || s.NextDue.IsZero() {
+			continue
+		}
+		daysLeft := int(s.NextDue.UTC().Truncate(24*time.Hour).Sub(today).Hours() / 24)
+		if daysLeft < 0 || daysLeft > s.ReminderDays {
+			continue
+		}
+		reminders = append(reminders, SubscriptionReminder{
+			PortfolioID:   p.ID,
+			PortfolioName: p.Name,
+			Subscription:  s,
+			DaysLeft:      daysLeft,
+		})
+	}
+	return reminders
+}
+
+// WebhookKey identyfikuje zdarzenie subscription.due o konkretnym terminie w dzienniku wysłanych przypomnień,
+// obok e-maili z przypomnieniami.
+func (r SubscriptionReminder) WebhookKey() string {
+	return "webhook/" + r.Key()
+}
+
+// SubscriptionsDue zwraca subskrypcje, których termin płatności (NextDue) przypada w podanym dniu.
+func (p *InvestmentPortfolio) SubscriptionsDue(day time.Time) []SubscriptionReminder {
+	day = day.UTC().Truncate(24 * time.Hour)
+	due := []SubscriptionReminder{}
+	for _, s := range p.Subscriptions {
+		if !s.NextDue.IsZero() && s.NextDue.UTC().Truncate(24*time.Hour).Equal(day) {
+			due = append(due, SubscriptionReminder{PortfolioID: p.ID, PortfolioName: p.Name, Subscription: s})
+		}
+	}
+	return due
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDueReminders sprawdza, które subskrypcje wymagają przypomnienia i jak wygląda klucz dziennika wysyłek.
+func TestDueReminders(t *testing.T) {
+	today := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	portfolio := NewInvestmentPortfolio()
+	portfolio.ID = "P1"
+	for _, s := range []Subscription{
+		{ID: "today", NextDue: today, ReminderDays: 1},
+		{ID: "in3", NextDue: today.AddDate(0, 0, 3), ReminderDays: 3},
+		{ID: "in4", NextDue: today.AddDate(0, 0, 4), ReminderDays: 3},
+		{ID: "off", NextDue: today.AddDate(0, 0, 1)},
+		{ID: "past", NextDue: today.AddDate(0, 0, -1), ReminderDays: 5},
+	} {
+		s.Frequency = FrequencyMonthly
+		portfolio.AddSubscription(s)
+	}
+
+	reminders := portfolio.DueReminders(today.Add(15 * time.Hour))
+	if len(reminders) != 2 {
+		t.Fatalf("DueReminders() expected 2 reminders, got %d: %+v", len(reminders), reminders)
+	}
+	if reminders[0].Subscription.ID != "today" || reminders[0].DaysLeft != 0 {
+		t.Errorf("DueReminders() expected reminder for today, got %s in %d days", reminders[0].Subscription.ID, reminders[0].DaysLeft)
+	}
+	if reminders[1].Subscription.ID != "in3" || reminders[1].DaysLeft != 3 {
+		t.Errorf("DueReminders() expected reminder 3 days ahead, got %s in %d days", reminders[1].Subscription.ID, reminders[1].DaysLeft)
+	}
+	if key := reminders[1].Key(); key != "P1/in3/2026-10-20" {
+		t.Errorf("Key() expected P1/in3/2026-10-20, got %s", key)
+	}
+
+	if err := (Subscription{ReminderDays: MaxReminderDays + 1}).ValidateReminder(); err == nil {
+		t.Errorf("ValidateReminder() expected error for %d days", MaxReminderDays+1)
+	}
+}
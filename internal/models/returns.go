@@ -0,0 +1,305 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// PeriodReturn to stopy zwrotu jednej grupy aktywów (całego portfela, typu portfela lub aktywa) w okresie.
+// W przeciwieństwie do GetProfitLossPercentage uwzględniają, kiedy pieniądze zostały wpłacone lub wypłacone.
+type PeriodReturn struct {
+	Label string
+
+	StartValue Decimal // Wartość na koniec pierwszego dnia okresu (w walucie bazowej)
+	EndValue   Decimal // Wartość na koniec ostatniego dnia okresu
+	NetFlows   Decimal // Suma wpłat minus wypłaty w okresie (zakupy z prowizjami, sprzedaże, dywidendy, opłaty)
+
+	TWR    float64 // Stopa zwrotu ważona czasem za cały okres, w procentach
+	HasTWR bool    // false, gdy w okresie nic nie było zainwestowane
+
+	XIRR    float64 // Stopa zwrotu ważona kapitałem (wewnętrzna stopa zwrotu) w skali roku, w procentach
+	HasXIRR bool    // false, gdy przepływów jest za mało, aby ją wyznaczyć
+}
+
+// ReturnsReport zbiera stopy zwrotu portfela, jego typów portfela i aktywów w jednym okresie.
+type ReturnsReport struct {
+	Start    time.Time // Pierwszy dzień okresu (wartość początkowa to stan na jego koniec)
+	End      time.Time // Ostatni dzień okresu
+	Currency string
+
+	Portfolio   PeriodReturn
+	WalletTypes []PeriodReturn
+	Assets      []PeriodReturn
+}
+
+// CashFlow to pojedynczy przepływ pieniężny z punktu widzenia inwestora:
+// ujemny to pieniądze włożone w inwestycję, dodatni - wyjęte z niej.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Returns wylicza stopy zwrotu TWR i XIRR od start do end (dni, północ UTC) dla całego portfela,
+// każdego typu portfela i każdego aktywa. Zerowy start oznacza okres od dnia przed pierwszą transakcją.
+// prices to historia cen według symbolu (NormalizeSymbol), posortowana po dacie - służy do wyceny
+// pozycji w dniach przepływów; brakujące ceny uzupełniamy cenami z transakcji, a w ostateczności ceną bieżącą.
+// Kursy walut są bieżące - ich historii nie przechowujemy.
+func (p *InvestmentPortfolio) Returns(start, end time.Time, prices map[string][]PricePoint) ReturnsReport {
+	end = end.UTC().Truncate(24 * time.Hour)
+	if start.IsZero() {
+		start = firstTransactionDay(p.Assets).AddDate(0, 0, -1)
+	}
+	start = start.UTC().Truncate(24 * time.Hour)
+
+	report := ReturnsReport{
+		Start:     start,
+		End:       end,
+		Currency:  p.GetBaseCurrency(),
+		Portfolio: p.periodReturn("Cały portfel", p.Assets, start, end, prices),
+	}
+
+	byWalletType := make(map[string][]Asset)
+	var walletTypes []string
+	for _, a := range p.Assets {
+		if _, seen := byWalletType[a.WalletType]; !seen {
+			walletTypes = append(walletTypes, a.WalletType)
+		}
+		byWalletType[a.WalletType] = append(byWalletType[a.WalletType], a)
+	}
+	sort.Strings(walletTypes)
+	for _, walletType := range walletTypes {
+		label := walletType
+		if label == "" {
+			label = "Bez typu"
+		}
+		report.WalletTypes = append(report.WalletTypes, p.periodReturn(label, byWalletType[walletType], start, end, prices))
+	}
+
+	for _, a := range p.Assets {
+		label := a.Name
+		if a.Symbol != "" {
+			label += " (" + a.Symbol + ")"
+		}
+		report.Assets = append(report.Assets, p.periodReturn(label, []Asset{a}, start, end, prices))
+	}
+	return report
+}
+
+// periodReturn liczy stopy zwrotu grupy aktywów. TWR to iloczyn stóp z odcinków między dniami przepływów:
+// odcinek kończy się wyceną pozycji sprzed transakcji danego dnia (plus dywidendy, minus opłaty),
+// a następny zaczyna od tej wyceny powiększonej o wartość kupionych i pomniejszonej o wartość sprzedanych jednostek.
+func (p *InvestmentPortfolio) periodReturn(label string, assets []Asset, start, end time.Time, prices map[string][]PricePoint) PeriodReturn {
+	result := PeriodReturn{Label: label, NetFlows: Zero}
+
+	// valueAt wycenia pozycje z końca dnia positionDate po cenach z dnia priceDate
+	valueAt := func(positionDate, priceDate time.Time) Decimal {
+		total := Zero
+		for _, a := range assets {
+			quantity, _, ok := a.positionAt(positionDate, p.assetMethod(a))
+			if !ok || quantity.IsZero() {
+				continue
+			}
+			price := a.priceOn(priceDate, prices[NormalizeSymbol(a.Symbol)])
+			total = total.Add(p.ToBase(quantity.Mul(price), a.CurrencyCode()))
+		}
+		return total
+	}
+
+	trades := make(map[time.Time]Decimal)
+	incomes := make(map[time.Time]Decimal)
+	for _, a := range assets {
+		for _, t := range a.Transactions {
+			day := t.Date.UTC().Truncate(24 * time.Hour)
+			if day.After(start) && !day.After(end) {
+				trade, income := t.flowParts()
+				trades[day] = trades[day].Add(p.ToBase(trade, a.CurrencyCode()))
+				incomes[day] = incomes[day].Add(p.ToBase(income, a.CurrencyCode()))
+			}
+		}
+	}
+	days := make([]time.Time, 0, len(trades))
+	for day := range trades {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	result.StartValue = valueAt(start, start)
+	result.EndValue = valueAt(end, end)
+
+	var cashFlows []CashFlow
+	if result.StartValue.IsPositive() {
+		cashFlows = append(cashFlows, CashFlow{Date: start, Amount: -result.StartValue.Float64()})
+	}
+
+	growth := 1.0
+	previous := result.StartValue
+	for _, day := range days {
+		before := valueAt(day.Add(-time.Nanosecond), day)
+		if previous.IsPositive() {
+			growth *= before.Add(incomes[day]).Float64() / previous.Float64()
+			result.HasTWR = true
+		}
+		previous = before.Add(trades[day])
+
+		flow := trades[day].Sub(incomes[day])
+		result.NetFlows = result.NetFlows.Add(flow)
+		cashFlows = append(cashFlows, CashFlow{Date: day, Amount: -flow.Float64()})
+	}
+	if previous.IsPositive() {
+		growth *= result.EndValue.Float64() / previous.Float64()
+		result.HasTWR = true
+	}
+	if result.HasTWR {
+		result.TWR = (growth - 1) * 100
+	}
+
+	if result.EndValue.IsPositive() {
+		cashFlows = append(cashFlows, CashFlow{Date: end, Amount: result.EndValue.Float64()})
+	}
+	if rate, ok := XIRR(cashFlows); ok {
+		result.XIRR = rate * 100
+		result.HasXIRR = true
+	}
+	return result
+}
+
+// flowParts dzieli przepływ transakcji na wartość kupionych (dodatnią) lub sprzedanych (ujemną) jednostek
+// po cenie transakcji oraz dochód: dywidendy na plus, prowizje i opłaty na minus.
+// Kwota wniesiona przez inwestora to trade - income.
+func (t Transaction) flowParts() (trade, income Decimal) {
+	switch t.Type {
+	case TransactionBuy, TransactionDeposit:
+		return t.Quantity.Mul(t.Price), t.Fee.Neg()
+	case TransactionSell, TransactionWithdrawal:
+		return t.Quantity.Mul(t.Price).Neg(), t.Fee.Neg()
+	case TransactionDividend:
+		return Zero, t.Amount.Sub(t.Fee)
+	case TransactionFee:
+		return Zero, t.Fee.Neg()
+	default:
+		return Zero, Zero
+	}
+}
+
+// priceOn zwraca cenę aktywa na koniec podanego dnia: bieżącą dla dzisiaj i później, a wcześniej
+// najświeższą z historii cen lub z transakcji (przy remisie wygrywa transakcja). Bez żadnej z nich - bieżącą.
+func (a Asset) priceOn(date time.Time, points []PricePoint) Decimal {
+	if !date.Before(Today()) {
+		return a.CurrentPrice
+	}
+
+	var price Decimal
+	var priceDate time.Time
+	found := false
+	if point, ok := lastPricePoint(points, date); ok {
+		price, priceDate, found = point.Close, point.Date, true
+	}
+	for _, t := range a.Transactions {
+		if !t.Type.changesQuantity() || t.Date.After(date) {
+			continue
+		}
+		if !found || !t.Date.Before(priceDate) {
+			price, priceDate, found = t.Price, t.Date, true
+		}
+	}
+	if !found {
+		return a.CurrentPrice
+	}
+	return price
+}
+
+// firstTransactionDay zwraca dzień pierwszej transakcji aktywów (dzisiaj, jeśli nie ma transakcji).
+func firstTransactionDay(assets []Asset) time.Time {
+	first := Today()
+	for _, a := range assets {
+		for _, t := range a.Transactions {
+			if t.Date.Before(first) {
+				first = t.Date
+			}
+		}
+	}
+	return first.UTC().Truncate(24 * time.Hour)
+}
+
+// XIRR wyznacza roczną wewnętrzną stopę zwrotu dla nieregularnych przepływów (jako ułamek, 0.05 = 5%).
+// Zwraca false, gdy przepływy nie mają obu znaków albo mieszczą się w jednym dniu.
+func XIRR(flows []CashFlow) (float64, bool) {
+	if len(flows) < 2 {
+		return 0, false
+	}
+	first, last := flows[0].Date, flows[0].Date
+	hasIn, hasOut := false, false
+	for _, f := range flows {
+		if f.Date.Before(first) {
+			first = f.Date
+		}
+		if f.Date.After(last) {
+			last = f.Date
+		}
+		hasIn = hasIn || f.Amount < 0
+		hasOut = hasOut || f.Amount > 0
+	}
+	if !hasIn || !hasOut || !last.After(first) {
+		return 0, false
+	}
+
+	years := make([]float64, len(flows))
+	for i, f := range flows {
+		years[i] = f.Date.Sub(first).Hours() / 24 / 365
+	}
+	npv := func(rate float64) (float64, float64) {
+		value, derivative := 0.0, 0.0
+		for i, f := range flows {
+			discount := math.Pow(1+rate, -years[i])
+			value += f.Amount * discount
+			derivative -= years[i] * f.Amount * discount / (1 + rate)
+		}
+		return value, derivative
+	}
+
+	// Najpierw metoda Newtona, która zwykle zbiega w kilku krokach
+	rate := 0.1
+	for i := 0; i < 50; i++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < 1e-7 {
+			return rate, true
+		}
+		if derivative == 0 || math.IsNaN(derivative) {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, true
+		}
+		rate = next
+	}
+
+	// Gdy Newton zawiedzie, szukamy miejsca zerowego bisekcją
+	low, high := -0.999999, 1.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	for lowValue*highValue > 0 && high < 1e6 {
+		high *= 10
+		highValue, _ = npv(high)
+	}
+	if lowValue*highValue > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		midValue, _ := npv(mid)
+		if math.Abs(midValue) < 1e-7 || high-low < 1e-12 {
+			return mid, true
+		}
+		if lowValue*midValue < 0 {
+			high = mid
+		} else {
+			low, lowValue = mid, midValue
+		}
+	}
+	return (low + high) / 2, true
+}
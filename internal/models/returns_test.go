@@ -0,0 +1,52 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestReturns sprawdza stopy zwrotu ważone czasem (TWR) i kapitałem (XIRR).
+func TestReturns(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddAsset(Asset{ID: "R1", Name: "ETF", Symbol: "ETF", WalletType: "Długoterminowy", Transactions: []Transaction{
+		{ID: "T1", Type: TransactionBuy, Date: day(1), Quantity: dec(10), Price: dec(100)},
+		{ID: "T2", Type: TransactionBuy, Date: day(10), Quantity: dec(10), Price: dec(120)},
+	}})
+	prices := map[string][]PricePoint{"ETF": {{Symbol: "ETF", Date: day(15), Close: dec(132)}}}
+
+	// Cena rośnie o 20% do drugiego zakupu i o 10% po nim - TWR nie zależy od dokupionej kwoty
+	report := portfolio.Returns(time.Time{}, day(20), prices)
+	if !report.Portfolio.HasTWR || math.Abs(report.Portfolio.TWR-32) > 1e-9 {
+		t.Errorf("Returns() expected TWR 32%%, got %v (%v)", report.Portfolio.TWR, report.Portfolio.HasTWR)
+	}
+	if report.Portfolio.EndValue != dec(2640) || report.Portfolio.NetFlows != dec(2200) {
+		t.Errorf("Returns() expected end value 2640 and flows 2200, got %s and %s", report.Portfolio.EndValue, report.Portfolio.NetFlows)
+	}
+	if !report.Portfolio.HasXIRR || report.Portfolio.XIRR <= 0 {
+		t.Errorf("Returns() expected positive XIRR, got %v (%v)", report.Portfolio.XIRR, report.Portfolio.HasXIRR)
+	}
+	if len(report.WalletTypes) != 1 || len(report.Assets) != 1 || report.Assets[0].TWR != report.Portfolio.TWR {
+		t.Errorf("Returns() unexpected breakdown %+v / %+v", report.WalletTypes, report.Assets)
+	}
+
+	// Okres od drugiego zakupu: wartość początkowa 2400, końcowa 2640
+	fromSecond := portfolio.Returns(day(10), day(20), prices)
+	if fromSecond.Portfolio.StartValue != dec(2400) || math.Abs(fromSecond.Portfolio.TWR-10) > 1e-9 {
+		t.Errorf("Returns(from day 10) expected start 2400 and TWR 10%%, got %s and %v", fromSecond.Portfolio.StartValue, fromSecond.Portfolio.TWR)
+	}
+
+	// Roczna lokata z 10% zyskiem
+	rate, ok := XIRR([]CashFlow{
+		{Date: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC), Amount: -1000},
+		{Date: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), Amount: 1100},
+	})
+	if !ok || math.Abs(rate-0.1) > 1e-6 {
+		t.Errorf("XIRR() expected 0.1, got %v (%v)", rate, ok)
+	}
+	if _, ok := XIRR([]CashFlow{{Date: day(1), Amount: -1000}}); ok {
+		t.Error("XIRR() with a single flow should fail")
+	}
+}
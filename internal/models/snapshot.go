@@ -0,0 +1,164 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// PortfolioSnapshot to dzienny zapis wartości portfela - z takich zapisów powstaje wykres
+// wartości majątku w czasie. Kwoty są w walucie bazowej portfela z dnia zapisu (Currency).
+type PortfolioSnapshot struct {
+	PortfolioID  string             `json:"portfolioId" bson:"portfolioId"`
+	Date         time.Time          `json:"date" bson:"date"` // Dzień zapisu (północ UTC)
+	Currency     string             `json:"currency" bson:"currency"`
+	TotalValue   Decimal            `json:"totalValue" bson:"totalValue"`
+	TotalCost    Decimal            `json:"totalCost" bson:"totalCost"`
+	WalletValues map[string]Decimal `json:"walletValues" bson:"walletValues"` // Wartość aktywów według typu portfela (WalletType)
+	Backfilled   bool               `json:"backfilled" bson:"backfilled"`     // Odtworzony z historii cen, a nie zapisany na bieżąco
+}
+
+// SnapshotRanges to zakresy dat do wyboru na wykresie wartości portfela w czasie.
+var SnapshotRanges = []string{"1M", "YTD", "1Y", "all"}
+
+// DefaultSnapshotRange to zakres wykresu wartości w czasie wybrany domyślnie.
+const DefaultSnapshotRange = "1Y"
+
+// SnapshotRangeStart zwraca pierwszy dzień zakresu (np. "YTD" - 1 stycznia bieżącego roku).
+// Dla "all" i nieznanych zakresów zwraca zerowy czas, czyli brak ograniczenia.
+func SnapshotRangeStart(rangeKey string, today time.Time) time.Time {
+	switch rangeKey {
+	case "1M":
+		return today.AddDate(0, -1, 0)
+	case "YTD":
+		return time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	case "1Y":
+		return today.AddDate(-1, 0, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// newSnapshot tworzy pusty zapis portfela z podanego dnia.
+func (p *InvestmentPortfolio) newSnapshot(date time.Time) PortfolioSnapshot {
+	return PortfolioSnapshot{
+		PortfolioID:  p.ID,
+		Date:         date.UTC().Truncate(24 * time.Hour),
+		Currency:     p.GetBaseCurrency(),
+		TotalValue:   Zero,
+		TotalCost:    Zero,
+		WalletValues: make(map[string]Decimal),
+	}
+}
+
+// add dolicza do zapisu wartość i koszt jednego aktywa (już w walucie bazowej).
+func (s *PortfolioSnapshot) add(walletType string, value, cost Decimal) {
+	s.TotalValue = s.TotalValue.Add(value)
+	s.TotalCost = s.TotalCost.Add(cost)
+	if walletType != "" {
+		s.WalletValues[walletType] = s.WalletValues[walletType].Add(value)
+	}
+}
+
+// TakeSnapshot zapisuje bieżący stan portfela jako zapis z podanego dnia.
+func (p *InvestmentPortfolio) TakeSnapshot(date time.Time) PortfolioSnapshot {
+	snapshot := p.newSnapshot(date)
+	for _, a := range p.Assets {
+		currency := a.CurrencyCode()
+		snapshot.add(a.WalletType, p.ToBase(a.Quantity.Mul(a.CurrentPrice), currency), p.ToBase(a.Quantity.Mul(a.AvgCost), currency))
+	}
+	return snapshot
+}
+
+// ReconstructSnapshot odtwarza stan portfela z podanego dnia: ilości i koszty wylicza z transakcji
+// zawartych do tego dnia włącznie, a ceny bierze z historii (ostatnia cena nie późniejsza niż ten dzień).
+// prices to historia cen według symbolu (NormalizeSymbol), posortowana po dacie.
+// Aktywa bez ceny z historii są wyceniane po koszcie nabycia. Kursy walut są bieżące - ich historii nie przechowujemy.
+func (p *InvestmentPortfolio) ReconstructSnapshot(date time.Time, prices map[string][]PricePoint) PortfolioSnapshot {
+	snapshot := p.newSnapshot(date)
+	snapshot.Backfilled = true
+
+	for _, a := range p.Assets {
+		quantity, cost, ok := a.positionAt(snapshot.Date, p.assetMethod(a))
+		if !ok || quantity.IsZero() {
+			continue
+		}
+		value := cost
+		if price, found := priceAt(prices[NormalizeSymbol(a.Symbol)], snapshot.Date); found {
+			value = quantity.Mul(price)
+		}
+		currency := a.CurrencyCode()
+		snapshot.add(a.WalletType, p.ToBase(value, currency), p.ToBase(cost, currency))
+	}
+	return snapshot
+}
+
+// positionAt zwraca ilość i koszt nabycia pozycji na koniec podanego dnia.
+// Aktywa bez rejestru transakcji traktujemy tak, jakby bieżąca pozycja istniała od zawsze.
+func (a Asset) positionAt(date time.Time, method CostBasisMethod) (Decimal, Decimal, bool) {
+	if len(a.Transactions) == 0 {
+		return a.Quantity, a.Quantity.Mul(a.AvgCost), true
+	}
+
+	var txs []Transaction
+	for _, t := range a.Transactions {
+		if !t.Date.After(date) {
+			txs = append(txs, t)
+		}
+	}
+	if len(txs) == 0 {
+		return Zero, Zero, false
+	}
+	summary, err := ReplayLedger(txs, method)
+	if err != nil {
+		return Zero, Zero, false
+	}
+	return summary.Quantity, summary.CostBasis, true
+}
+
+// priceAt zwraca ostatnią cenę z historii nie późniejszą niż podany dzień.
+func priceAt(points []PricePoint, date time.Time) (Decimal, bool) {
+	point, ok := lastPricePoint(points, date)
+	return point.Close, ok
+}
+
+// lastPricePoint zwraca ostatni zapis historii cen nie późniejszy niż podany dzień.
+func lastPricePoint(points []PricePoint, date time.Time) (PricePoint, bool) {
+	i := sort.Search(len(points), func(i int) bool { return points[i].Date.After(date) })
+	if i == 0 {
+		return PricePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// MergeSnapshots sumuje zapisy kilku portfeli z tych samych dni (dla widoku "Wszystkie portfele").
+// toBase przelicza kwoty zapisów na wspólną walutę. Wynik jest posortowany po dacie.
+func MergeSnapshots(snapshots []PortfolioSnapshot, currency string, toBase func(amount Decimal, currency string) Decimal) []PortfolioSnapshot {
+	byDate := make(map[time.Time]*PortfolioSnapshot)
+	for _, s := range snapshots {
+		merged, ok := byDate[s.Date]
+		if !ok {
+			merged = &PortfolioSnapshot{
+				PortfolioID:  AllPortfoliosID,
+				Date:         s.Date,
+				Currency:     currency,
+				TotalValue:   Zero,
+				TotalCost:    Zero,
+				WalletValues: make(map[string]Decimal),
+			}
+			byDate[s.Date] = merged
+		}
+		merged.TotalValue = merged.TotalValue.Add(toBase(s.TotalValue, s.Currency))
+		merged.TotalCost = merged.TotalCost.Add(toBase(s.TotalCost, s.Currency))
+		for walletType, value := range s.WalletValues {
+			merged.WalletValues[walletType] = merged.WalletValues[walletType].Add(toBase(value, s.Currency))
+		}
+		merged.Backfilled = merged.Backfilled || s.Backfilled
+	}
+
+	result := make([]PortfolioSnapshot, 0, len(byDate))
+	for _, s := range byDate {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestReconstructSnapshot sprawdza odtwarzanie wartości portfela z przeszłego dnia z rejestru i historii cen.
+func TestReconstructSnapshot(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddAsset(Asset{ID: "R1", Name: "ETF", Symbol: "etf", WalletType: "Długoterminowy", Transactions: []Transaction{
+		{ID: "T1", Type: TransactionBuy, Date: day(1), Quantity: dec(10), Price: dec(100)},
+		{ID: "T2", Type: TransactionBuy, Date: day(10), Quantity: dec(10), Price: dec(120)},
+	}})
+	portfolio.AddAsset(Asset{ID: "R2", Name: "Lokata", WalletType: "Poduszka", Transactions: []Transaction{
+		{ID: "T3", Type: TransactionDeposit, Date: day(5), Quantity: dec(1000), Price: dec(1)},
+	}})
+	prices := map[string][]PricePoint{"ETF": {
+		{Symbol: "ETF", Date: day(2), Close: dec(105)},
+		{Symbol: "ETF", Date: day(8), Close: dec(115)},
+	}}
+
+	// Przed pierwszą ceną z historii pozycja jest wyceniana po koszcie, lokaty jeszcze nie ma
+	first := portfolio.ReconstructSnapshot(day(1), prices)
+	if first.TotalValue != dec(1000) || first.TotalCost != dec(1000) || !first.Backfilled {
+		t.Errorf("ReconstructSnapshot(day 1) expected value and cost 1000, got %s and %s", first.TotalValue, first.TotalCost)
+	}
+
+	// 9 marca: 10 jednostek po ostatniej znanej cenie 115 plus lokata
+	ninth := portfolio.ReconstructSnapshot(day(9), prices)
+	if expected := dec(10*115 + 1000); ninth.TotalValue != expected {
+		t.Errorf("ReconstructSnapshot(day 9) expected value %s, got %s", expected, ninth.TotalValue)
+	}
+	if ninth.WalletValues["Poduszka"] != dec(1000) || ninth.WalletValues["Długoterminowy"] != dec(1150) {
+		t.Errorf("ReconstructSnapshot(day 9) unexpected wallet values %v", ninth.WalletValues)
+	}
+
+	// Sumowanie zapisów dwóch portfeli z tego samego dnia
+	merged := MergeSnapshots([]PortfolioSnapshot{ninth, first}, DefaultCurrency, func(amount Decimal, _ string) Decimal { return amount })
+	if len(merged) != 2 || !merged[0].Date.Equal(day(1)) || merged[1].TotalValue != ninth.TotalValue {
+		t.Errorf("MergeSnapshots() expected 2 snapshots sorted by date, got %+v", merged)
+	}
+}
@@ -0,0 +1,333 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SubscriptionFrequency określa, jak często pobierana jest opłata za subskrypcję.
+type SubscriptionFrequency string
+
+const (
+	FrequencyWeekly     SubscriptionFrequency = "weekly"     // Co tydzień
+	FrequencyMonthly    SubscriptionFrequency = "monthly"    // Co miesiąc
+	FrequencyQuarterly  SubscriptionFrequency = "quarterly"  // Co kwartał
+	FrequencySemiAnnual SubscriptionFrequency = "semiannual" // Co pół roku
+	FrequencyYearly     SubscriptionFrequency = "yearly"     // Co rok
+	FrequencyCustom     SubscriptionFrequency = "custom"     // Co IntervalDays dni
+)
+
+// maxPastCharges ogranicza liczbę zapamiętanych dat minionych płatności jednej subskrypcji.
+const maxPastCharges = 120
+
+// SubscriptionFrequencies zwraca obsługiwane częstotliwości w kolejności wyświetlania.
+func SubscriptionFrequencies() []SubscriptionFrequency {
+	return []SubscriptionFrequency{
+		FrequencyWeekly,
+		FrequencyMonthly,
+		FrequencyQuarterly,
+		FrequencySemiAnnual,
+		FrequencyYearly,
+		FrequencyCustom,
+	}
+}
+
+// Label zwraca polską nazwę częstotliwości.
+func (f SubscriptionFrequency) Label() string {
+	switch f {
+	case FrequencyWeekly:
+		return "Tygodniowo"
+	case FrequencyMonthly:
+		return "Miesięcznie"
+	case FrequencyQuarterly:
+		return "Kwartalnie"
+	case FrequencySemiAnnual:
+		return "Półrocznie"
+	case FrequencyYearly:
+		return "Rocznie"
+	case FrequencyCustom:
+		return "Co N dni"
+	default:
+		return string(f)
+	}
+}
+
+// IsValid mówi, czy częstotliwość jest jedną z obsługiwanych.
+func (f SubscriptionFrequency) IsValid() bool {
+	for _, known := range SubscriptionFrequencies() {
+		if f == known {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize zamienia częstotliwość zapisaną przed wprowadzeniem typów (polska nazwa wpisana
+// ręcznie, np. "Miesięcznie") na kod. Nieznane wartości zwraca bez zmian.
+func (f SubscriptionFrequency) normalize() SubscriptionFrequency {
+	if f.IsValid() {
+		return f
+	}
+	value := strings.ToLower(strings.TrimSpace(string(f)))
+	for _, known := range SubscriptionFrequencies() {
+		if value == string(known) || value == strings.ToLower(known.Label()) {
+			return known
+		}
+	}
+	return f
+}
+
+// PaymentsPerYear zwraca liczbę płatności w roku (0 dla FrequencyCustom, której koszt liczymy z odstępu w dniach).
+func (f SubscriptionFrequency) PaymentsPerYear() int64 {
+	switch f {
+	case FrequencyWeekly:
+		return 52
+	case FrequencyMonthly:
+		return 12
+	case FrequencyQuarterly:
+		return 4
+	case FrequencySemiAnnual:
+		return 2
+	case FrequencyYearly:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// FrequencyCode zwraca typ częstotliwości subskrypcji, rozpoznając też polskie nazwy z danych
+// sprzed wprowadzenia typów (np. "Rocznie").
+func (s Subscription) FrequencyCode() SubscriptionFrequency {
+	return s.Frequency.normalize()
+}
+
+// FrequencyLabel zwraca opis częstotliwości do wyświetlenia, np. "Kwartalnie" albo "Co 10 dni".
+func (s Subscription) FrequencyLabel() string {
+	code := s.FrequencyCode()
+	switch {
+	case code == FrequencyCustom:
+		return fmt.Sprintf("Co %d dni", s.IntervalDays)
+	case code.IsValid():
+		return code.Label()
+	default:
+		return string(s.Frequency) + " (nieznana)"
+	}
+}
+
+// ValidateSchedule sprawdza częstotliwość i odstęp płatności oraz zapisuje częstotliwość jako kod.
+func (s *Subscription) ValidateSchedule() error {
+	s.Frequency = s.FrequencyCode()
+	if !s.Frequency.IsValid() {
+		return fmt.Errorf("unknown subscription frequency %q", s.Frequency)
+	}
+	if s.Frequency == FrequencyCustom {
+		if s.IntervalDays < 1 {
+			return fmt.Errorf("custom interval must be at least 1 day")
+		}
+	} else {
+		s.IntervalDays = 0
+	}
+	return nil
+}
+
+// MonthlyCost zwraca przeciętny miesięczny koszt subskrypcji w jej walucie
+// (koszt roczny podzielony przez 12). Nieznana częstotliwość daje 0.
+func (s Subscription) MonthlyCost() Decimal {
+	code := s.FrequencyCode()
+	if code == FrequencyCustom {
+		if s.IntervalDays < 1 {
+			return Zero
+		}
+		return s.Cost.MulInt(365).DivInt(12 * int64(s.IntervalDays))
+	}
+	perYear := code.PaymentsPerYear()
+	if perYear == 0 {
+		return Zero
+	}
+	return s.Cost.MulInt(perYear).DivInt(12)
+}
+
+// NextChargeAfter zwraca termin płatności następujący po podanym.
+// Terminy miesięczne przesunięte na koniec krótszego miesiąca (np. 31 → 28 lutego) wracają
+// w kolejnych miesiącach na dzień pierwszej zapamiętanej płatności.
+func (s Subscription) NextChargeAfter(due time.Time) time.Time {
+	switch s.FrequencyCode() {
+	case FrequencyWeekly:
+		return due.AddDate(0, 0, 7)
+	case FrequencyCustom:
+		return due.AddDate(0, 0, max(s.IntervalDays, 1))
+	case FrequencyMonthly:
+		return addMonths(due, 1, s.anchorDay(due))
+	case FrequencyQuarterly:
+		return addMonths(due, 3, s.anchorDay(due))
+	case FrequencySemiAnnual:
+		return addMonths(due, 6, s.anchorDay(due))
+	case FrequencyYearly:
+		return addMonths(due, 12, s.anchorDay(due))
+	default:
+		return time.Time{}
+	}
+}
+
+// anchorDay zwraca dzień miesiąca, na który przypadają płatności: dzień pierwszej zapamiętanej
+// płatności, jeśli termin due został przycięty do końca krótszego miesiąca, a w pozostałych przypadkach dzień due.
+func (s Subscription) anchorDay(due time.Time) int {
+	if len(s.PastCharges) > 0 && due.Day() == daysInMonth(due.Year(), due.Month()) {
+		if first := s.PastCharges[0].Day(); first > due.Day() {
+			return first
+		}
+	}
+	return due.Day()
+}
+
+// addMonths przesuwa datę o podaną liczbę miesięcy na wskazany dzień miesiąca,
+// przycinając go do długości miesiąca docelowego (time.AddDate przeniósłby 31 stycznia na 3 marca).
+func addMonths(date time.Time, months, day int) time.Time {
+	first := time.Date(date.Year(), date.Month(), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	target := first.AddDate(0, months, 0)
+	day = min(day, daysInMonth(target.Year(), target.Month()))
+	return target.AddDate(0, 0, day-1)
+}
+
+// daysInMonth zwraca liczbę dni w miesiącu.
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// RollOver przesuwa NextDue na pierwszy termin nie wcześniejszy niż today, dopisując minione
+// terminy do PastCharges. Zwraca true, jeśli coś się zmieniło.
+func (s *Subscription) RollOver(today time.Time) bool {
+	if s.NextDue.IsZero() || !s.NextDue.Before(today) {
+		return false
+	}
+	rolled := false
+	for s.NextDue.Before(today) {
+		next := s.NextChargeAfter(s.NextDue)
+		if !next.After(s.NextDue) {
+			break // Nieznana częstotliwość - nie wiemy, kiedy następna płatność
+		}
+		s.PastCharges = append(s.PastCharges, s.NextDue)
+		s.NextDue = next
+		rolled = true
+	}
+	if len(s.PastCharges) > maxPastCharges {
+		s.PastCharges = s.PastCharges[len(s.PastCharges)-maxPastCharges:]
+	}
+	return rolled
+}
+
+// RollOverSubscriptions przesuwa terminy wszystkich subskrypcji portfela i zwraca te, które się zmieniły.
+func (p *InvestmentPortfolio) RollOverSubscriptions(today time.Time) []Subscription {
+	var rolled []Subscription
+	for i := range p.Subscriptions {
+		if p.Subscriptions[i].RollOver(today) {
+			rolled = append(rolled, p.Subscriptions[i])
+		}
+	}
+	return rolled
+}
+
+// Edit przepisuje na subskrypcję pola zmieniane w formularzu (nazwa, koszt, waluta, harmonogram, termin
+// i przypomnienie) i przesuwa minione terminy. Historia terminów i płatności zostaje bez zmian.
+func (s *Subscription) Edit(edited Subscription, today time.Time) {
+	s.Name = edited.Name
+	s.Cost = edited.Cost
+	s.Currency = edited.Currency
+	s.Frequency = edited.Frequency
+	s.IntervalDays = edited.IntervalDays
+	s.NextDue = edited.NextDue
+	s.ReminderDays = edited.ReminderDays
+	s.RollOver(today)
+}
+
+// EditSubscription zmienia subskrypcję o ID edited.ID (zob. Subscription.Edit), przelicza sumy portfela
+// i zwraca subskrypcję po zmianie.
+func (p *InvestmentPortfolio) EditSubscription(edited Subscription, today time.Time) (Subscription, error) {
+	for i := range p.Subscriptions {
+		if p.Subscriptions[i].ID == edited.ID {
+			p.Subscriptions[i].Edit(edited, today)
+			p.CalculateTotals()
+			return p.Subscriptions[i], nil
+		}
+	}
+	return Subscription{}, fmt.Errorf("subscription with ID %s not found in portfolio", edited.ID)
+}
+
+// SubscriptionPayment to faktycznie zapłacona kwota subskrypcji - w odróżnieniu od prognozy z Cost i Frequency.
+type SubscriptionPayment struct {
+	ID     string    `json:"id" bson:"_id"`
+	Date   time.Time `json:"date" bson:"date"`
+	Amount Decimal   `json:"amount" bson:"amount"` // W walucie subskrypcji
+	Note   string    `json:"note" bson:"note"`
+}
+
+// Validate sprawdza, czy płatność ma datę i nieujemną kwotę.
+func (p SubscriptionPayment) Validate() error {
+	if p.Date.IsZero() {
+		return fmt.Errorf("payment date is required")
+	}
+	if p.Amount.IsNegative() {
+		return fmt.Errorf("payment amount cannot be negative")
+	}
+	return nil
+}
+
+// RecordPayment dopisuje płatność do historii subskrypcji, zachowując kolejność dat.
+// Gdy markPaid jest true, płatność rozlicza bieżący termin: NextDue trafia do minionych terminów,
+// a następnym terminem staje się kolejny termin z harmonogramu.
+func (s *Subscription) RecordPayment(payment SubscriptionPayment, markPaid bool) error {
+	if err := payment.Validate(); err != nil {
+		return err
+	}
+	if payment.ID == "" {
+		payment.ID = GenerateID()
+	}
+	i := sort.Search(len(s.Payments), func(i int) bool { return s.Payments[i].Date.After(payment.Date) })
+	s.Payments = slices.Insert(s.Payments, i, payment)
+
+	if markPaid && !s.NextDue.IsZero() {
+		if next := s.NextChargeAfter(s.NextDue); next.After(s.NextDue) {
+			s.PastCharges = append(s.PastCharges, s.NextDue)
+			if len(s.PastCharges) > maxPastCharges {
+				s.PastCharges = s.PastCharges[len(s.PastCharges)-maxPastCharges:]
+			}
+			s.NextDue = next
+		}
+	}
+	return nil
+}
+
+// RecordSubscriptionPayment zapisuje płatność subskrypcji o podanym ID (zob. Subscription.RecordPayment).
+func (p *InvestmentPortfolio) RecordSubscriptionPayment(subID string, payment SubscriptionPayment, markPaid bool) error {
+	for i := range p.Subscriptions {
+		if p.Subscriptions[i].ID == subID {
+			return p.Subscriptions[i].RecordPayment(payment, markPaid)
+		}
+	}
+	return fmt.Errorf("subscription with ID %s not found in portfolio", subID)
+}
+
+// ChargesBetween zwraca terminy płatności z przedziału [from, to): minione terminy z historii
+// oraz terminy wyliczone z harmonogramu od NextDue.
+func (s Subscription) ChargesBetween(from, to time.Time) []time.Time {
+	var charges []time.Time
+	for _, charge := range s.PastCharges {
+		if !charge.Before(from) && charge.Before(to) {
+			charges = append(charges, charge)
+		}
+	}
+	for due := s.NextDue; !due.IsZero() && due.Before(to); {
+		if !due.Before(from) {
+			charges = append(charges, due)
+		}
+		next := s.NextChargeAfter(due)
+		if !next.After(due) {
+			break
+		}
+		due = next
+	}
+	return charges
+}
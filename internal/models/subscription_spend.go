@@ -0,0 +1,77 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// SubscriptionSpend porównuje prognozowane i faktyczne wydatki na jedną subskrypcję w danym roku.
+type SubscriptionSpend struct {
+	SubscriptionID string
+	Name           string
+	Currency       string
+	Charges        int     // Liczba terminów płatności w roku według harmonogramu
+	Forecast       Decimal // Charges × Cost, w walucie subskrypcji
+	Actual         Decimal // Suma zapisanych płatności, w walucie subskrypcji
+	Payments       int
+	ForecastBase   Decimal // Forecast w walucie bazowej portfela
+	ActualBase     Decimal // Actual w walucie bazowej portfela
+}
+
+// Difference zwraca różnicę między faktycznymi a prognozowanymi wydatkami w walucie bazowej (dodatnia - wydano więcej).
+func (s SubscriptionSpend) Difference() Decimal {
+	return s.ActualBase.Sub(s.ForecastBase)
+}
+
+// SubscriptionSpendReport to roczne zestawienie prognozowanych i faktycznych wydatków na subskrypcje.
+type SubscriptionSpendReport struct {
+	Year     int
+	Currency string // Waluta bazowa portfela
+	Rows     []SubscriptionSpend
+	Forecast Decimal
+	Actual   Decimal
+	// MissingFXRates to waluty subskrypcji bez kursu do waluty bazowej - ich kwoty nie są wliczone do sum.
+	MissingFXRates []string
+}
+
+// Difference zwraca łączną różnicę między faktycznymi a prognozowanymi wydatkami.
+func (r SubscriptionSpendReport) Difference() Decimal {
+	return r.Actual.Sub(r.Forecast)
+}
+
+// SubscriptionSpendReport zestawia dla podanego roku prognozę wydatków na każdą subskrypcję
+// (terminy z harmonogramu pomnożone przez koszt) z sumą zapisanych płatności. Kwoty przeliczamy
+// na walutę bazową po bieżących kursach; kwoty w walutach bez kursu pomijamy w sumach. Wiersze są posortowane po nazwie subskrypcji.
+func (p *InvestmentPortfolio) SubscriptionSpendReport(year int) SubscriptionSpendReport {
+	report := SubscriptionSpendReport{Year: year, Currency: p.GetBaseCurrency(), Forecast: Zero, Actual: Zero}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	for _, s := range p.Subscriptions {
+		row := SubscriptionSpend{
+			SubscriptionID: s.ID,
+			Name:           s.Name,
+			Currency:       s.CurrencyCode(),
+			Charges:        len(s.ChargesBetween(from, to)),
+			Actual:         Zero,
+		}
+		row.Forecast = s.Cost.MulInt(int64(row.Charges))
+		for _, payment := range s.Payments {
+			if !payment.Date.Before(from) && payment.Date.Before(to) {
+				row.Actual = row.Actual.Add(payment.Amount)
+				row.Payments++
+			}
+		}
+		if !p.HasFXRate(row.Currency) && !isInSlice(row.Currency, report.MissingFXRates) {
+			report.MissingFXRates = append(report.MissingFXRates, row.Currency)
+		}
+		row.ForecastBase = p.ToBase(row.Forecast, row.Currency)
+		row.ActualBase = p.ToBase(row.Actual, row.Currency)
+
+		report.Forecast = report.Forecast.Add(row.ForecastBase)
+		report.Actual = report.Actual.Add(row.ActualBase)
+		report.Rows = append(report.Rows, row)
+	}
+	sort.SliceStable(report.Rows, func(i, j int) bool { return report.Rows[i].Name < report.Rows[j].Name })
+	return report
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSubscriptionSpendReport sprawdza zapis płatności, przesuwanie terminu i roczne porównanie prognozy z wydatkami.
+func TestSubscriptionSpendReport(t *testing.T) {
+	portfolio := NewInvestmentPortfolio()
+	portfolio.AddSubscription(Subscription{ID: "S1", Name: "Siłownia", Cost: dec(100), Frequency: FrequencyQuarterly,
+		NextDue: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)})
+
+	if err := portfolio.RecordSubscriptionPayment("S1", SubscriptionPayment{Date: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), Amount: dec(100)}, true); err != nil {
+		t.Fatalf("RecordSubscriptionPayment() unexpected error: %v", err)
+	}
+	if err := portfolio.RecordSubscriptionPayment("S1", SubscriptionPayment{Date: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), Amount: dec(20), Note: "Wpisowe"}, false); err != nil {
+		t.Fatalf("RecordSubscriptionPayment() unexpected error: %v", err)
+	}
+	sub := portfolio.Subscriptions[0]
+	if want := time.Date(2025, 5, 10, 0, 0, 0, 0, time.UTC); !sub.NextDue.Equal(want) || len(sub.PastCharges) != 1 {
+		t.Errorf("RecordSubscriptionPayment(markPaid) expected next due %v and 1 past charge, got %v %v", want, sub.NextDue, sub.PastCharges)
+	}
+	if len(sub.Payments) != 2 || sub.Payments[0].Note != "Wpisowe" {
+		t.Errorf("RecordSubscriptionPayment() expected payments sorted by date, got %+v", sub.Payments)
+	}
+	if err := portfolio.RecordSubscriptionPayment("S1", SubscriptionPayment{Amount: dec(10)}, false); err == nil {
+		t.Errorf("RecordSubscriptionPayment() expected error for a payment without date")
+	}
+
+	// Edycja z formularza (bez historii) nie może skasować zapisanych terminów i płatności
+	edited, err := portfolio.EditSubscription(Subscription{ID: "S1", Name: "Siłownia Plus", Cost: dec(100), Frequency: FrequencyQuarterly,
+		NextDue: sub.NextDue}, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("EditSubscription() unexpected error: %v", err)
+	}
+	if edited.Name != "Siłownia Plus" || len(edited.PastCharges) != 1 || len(edited.Payments) != 2 {
+		t.Errorf("EditSubscription() expected new name with history kept, got %+v", edited)
+	}
+
+	// Terminy w 2025: 10 lutego (miniony), 10 maja, 10 sierpnia, 10 listopada
+	report := portfolio.SubscriptionSpendReport(2025)
+	if len(report.Rows) != 1 || report.Rows[0].Charges != 4 || report.Forecast != dec(400) || report.Actual != dec(120) {
+		t.Errorf("SubscriptionSpendReport() expected forecast 400 over 4 charges and 120 paid, got %+v", report)
+	}
+	if report.Difference() != dec(-280) {
+		t.Errorf("SubscriptionSpendReport() expected difference -280, got %s", report.Difference())
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSubscriptionFrequency sprawdza miesięczny koszt różnych częstotliwości i przesuwanie minionych terminów.
+func TestSubscriptionFrequency(t *testing.T) {
+	costs := []struct {
+		sub  Subscription
+		want Decimal
+	}{
+		{Subscription{Cost: dec(300), Frequency: FrequencyQuarterly}, dec(100)},
+		{Subscription{Cost: dec(12), Frequency: FrequencyWeekly}, dec(52)},
+		{Subscription{Cost: dec(600), Frequency: FrequencySemiAnnual}, dec(100)},
+		{Subscription{Cost: dec(12), Frequency: FrequencyCustom, IntervalDays: 365}, dec(1)},
+		{Subscription{Cost: dec(120), Frequency: "Rocznie"}, dec(10)},
+	}
+	for _, c := range costs {
+		if got := c.sub.MonthlyCost(); got != c.want {
+			t.Errorf("MonthlyCost() of %s expected %s, got %s", c.sub.FrequencyLabel(), c.want, got)
+		}
+	}
+
+	invalid := Subscription{Frequency: FrequencyCustom}
+	if err := invalid.ValidateSchedule(); err == nil {
+		t.Errorf("ValidateSchedule() expected error for custom frequency without interval")
+	}
+
+	// Płatność 31-go przycinana do końca lutego wraca na 31 marca
+	sub := Subscription{Frequency: FrequencyMonthly, NextDue: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}
+	if !sub.RollOver(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("RollOver() expected the due date to move")
+	}
+	if want := time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC); !sub.NextDue.Equal(want) {
+		t.Errorf("RollOver() expected next due %v, got %v", want, sub.NextDue)
+	}
+	if len(sub.PastCharges) != 3 || sub.PastCharges[1].Day() != 29 || sub.PastCharges[2].Day() != 31 {
+		t.Errorf("RollOver() expected past charges Jan 31, Feb 29, Mar 31, got %v", sub.PastCharges)
+	}
+	if sub.RollOver(time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("RollOver() should not move a due date that has not passed")
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"time"
+)
+
+// CostBasisMethod określa, które partie są zużywane przy sprzedaży.
+type CostBasisMethod string
+
+const (
+	CostBasisFIFO    CostBasisMethod = "fifo"    // Pierwsze weszło, pierwsze wyszło (wymagane w PIT-38)
+	CostBasisLIFO    CostBasisMethod = "lifo"    // Ostatnie weszło, pierwsze wyszło
+	CostBasisAverage CostBasisMethod = "average" // Średni ważony koszt (wszystkie partie proporcjonalnie)
+)
+
+// CostBasisMethods zwraca wszystkie obsługiwane metody w kolejności wyświetlania.
+func CostBasisMethods() []CostBasisMethod {
+	return []CostBasisMethod{CostBasisFIFO, CostBasisLIFO, CostBasisAverage}
+}
+
+// Label zwraca polską nazwę metody do wyświetlenia w widokach.
+func (m CostBasisMethod) Label() string {
+	switch m {
+	case CostBasisFIFO:
+		return "FIFO (pierwsze weszło, pierwsze wyszło)"
+	case CostBasisLIFO:
+		return "LIFO (ostatnie weszło, pierwsze wyszło)"
+	case CostBasisAverage, "":
+		return "Średni koszt"
+	}
+	return string(m)
+}
+
+// IsValid sprawdza, czy metoda jest jedną z obsługiwanych.
+func (m CostBasisMethod) IsValid() bool {
+	for _, known := range CostBasisMethods() {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TaxLot to partia jednostek nabyta jedną transakcją (kupnem lub wpłatą).
+type TaxLot struct {
+	TransactionID    string    // Transakcja, która utworzyła partię
+	AcquiredAt       time.Time // Data nabycia
+	OriginalQuantity Decimal   // Ilość nabyta
+	Quantity         Decimal   // Ilość pozostała w portfelu
+	Cost             Decimal   // Koszt pozostałych jednostek z uwzględnieniem prowizji zakupu
+}
+
+// UnitCost zwraca koszt jednej jednostki partii.
+func (l TaxLot) UnitCost() Decimal {
+	return l.Cost.Div(l.Quantity)
+}
+
+// HoldingDays zwraca liczbę dni od nabycia partii do podanej daty.
+func (l TaxLot) HoldingDays(asOf time.Time) int {
+	return holdingDays(l.AcquiredAt, asOf)
+}
+
+// UnrealizedGain zwraca niezrealizowany zysk/stratę partii przy podanej cenie rynkowej.
+func (l TaxLot) UnrealizedGain(price Decimal) Decimal {
+	return l.Quantity.Mul(price).Sub(l.Cost)
+}
+
+// LotSale to część partii zamknięta konkretną transakcją sprzedaży.
+type LotSale struct {
+	LotTransactionID  string    // Transakcja, która utworzyła partię
+	SaleTransactionID string    // Transakcja sprzedaży
+	AcquiredAt        time.Time // Data nabycia partii
+	SoldAt            time.Time // Data sprzedaży
+	Quantity          Decimal   // Sprzedana ilość z tej partii
+	CostBasis         Decimal   // Koszt uzyskania przychodu
+	Proceeds          Decimal   // Przychód (po potrąceniu proporcjonalnej części prowizji)
+	Gain              Decimal   // Dochód (lub strata) ze sprzedaży
+}
+
+// HoldingDays zwraca liczbę dni, przez które sprzedana partia była w portfelu.
+func (s LotSale) HoldingDays() int {
+	return holdingDays(s.AcquiredAt, s.SoldAt)
+}
+
+// lotPortion opisuje, ile jednostek (i jakiego kosztu) zostało pobranych z danej partii.
+type lotPortion struct {
+	Lot      TaxLot
+	Quantity Decimal
+	Cost     Decimal
+}
+
+// takeFromLot zdejmuje ilość z partii, przenosząc proporcjonalną część kosztu.
+// Gdy partia jest zużywana w całości, przenoszony jest cały pozostały koszt (bez błędów zaokrągleń).
+func takeFromLot(lot *TaxLot, quantity Decimal) lotPortion {
+	cost := lot.Cost
+	if quantity.LessThan(lot.Quantity) {
+		cost = lot.Cost.Mul(quantity).Div(lot.Quantity)
+	}
+	portion := lotPortion{Lot: *lot, Quantity: quantity, Cost: cost}
+	lot.Quantity = lot.Quantity.Sub(quantity)
+	lot.Cost = lot.Cost.Sub(cost)
+	return portion
+}
+
+// consumeLots zdejmuje podaną ilość z partii zgodnie z metodą i zwraca pozostałe partie oraz zużyte części.
+func consumeLots(lots []TaxLot, quantity Decimal, method CostBasisMethod) ([]TaxLot, []lotPortion) {
+	var portions []lotPortion
+
+	switch method {
+	case CostBasisFIFO, CostBasisLIFO:
+		remaining := quantity
+		for step := 0; step < len(lots) && remaining.IsPositive(); step++ {
+			i := step
+			if method == CostBasisLIFO {
+				i = len(lots) - 1 - step
+			}
+			if !lots[i].Quantity.IsPositive() {
+				continue
+			}
+			portion := takeFromLot(&lots[i], lots[i].Quantity.Min(remaining))
+			portions = append(portions, portion)
+			remaining = remaining.Sub(portion.Quantity)
+		}
+	default:
+		// Średni koszt: każda partia jest pomniejszana o ten sam ułamek, więc średni koszt się nie zmienia.
+		// Ostatnia partia dostaje resztę, żeby suma sprzedanych jednostek była dokładna.
+		total := totalLotQuantity(lots)
+		remaining := quantity
+		for i := range lots {
+			taken := lots[i].Quantity.Mul(quantity).Div(total)
+			if i == len(lots)-1 {
+				taken = remaining
+			}
+			taken = taken.Min(lots[i].Quantity).Min(remaining)
+			if !taken.IsPositive() {
+				continue
+			}
+			portions = append(portions, takeFromLot(&lots[i], taken))
+			remaining = remaining.Sub(taken)
+		}
+		// Resztki po zaokrągleniach (pojedyncze jednostki 10^-8) zdejmujemy z pierwszej partii, która ma zapas
+		for i := range lots {
+			if !remaining.IsPositive() {
+				break
+			}
+			if taken := lots[i].Quantity.Min(remaining); taken.IsPositive() {
+				portions = append(portions, takeFromLot(&lots[i], taken))
+				remaining = remaining.Sub(taken)
+			}
+		}
+	}
+
+	// Usuń partie, które zostały całkowicie sprzedane
+	open := lots[:0]
+	for _, lot := range lots {
+		if lot.Quantity.IsPositive() {
+			open = append(open, lot)
+		}
+	}
+	return open, portions
+}
+
+// totalLotQuantity sumuje ilość pozostałą we wszystkich partiach.
+func totalLotQuantity(lots []TaxLot) Decimal {
+	total := Zero
+	for _, lot := range lots {
+		total = total.Add(lot.Quantity)
+	}
+	return total
+}
+
+// holdingDays liczy pełne dni pomiędzy dwiema datami.
+func holdingDays(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
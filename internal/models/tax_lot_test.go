@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTaxLotMethods sprawdza zużywanie partii przy sprzedaży metodami FIFO, LIFO i średniego kosztu.
+func TestTaxLotMethods(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	txs := []Transaction{
+		{ID: "B1", Type: TransactionBuy, Date: day(1), Quantity: dec(10), Price: dec(100)},
+		{ID: "B2", Type: TransactionBuy, Date: day(10), Quantity: dec(10), Price: dec(200)},
+		{ID: "S1", Type: TransactionSell, Date: day(20), Quantity: dec(15), Price: dec(300)},
+	}
+
+	tests := []struct {
+		method       CostBasisMethod
+		wantRealized Decimal
+		wantCost     Decimal
+		wantOpenLots int
+	}{
+		{CostBasisFIFO, dec(15*300 - (10*100 + 5*200)), dec(5 * 200), 1},
+		{CostBasisLIFO, dec(15*300 - (10*200 + 5*100)), dec(5 * 100), 1},
+		{CostBasisAverage, dec(15*300 - 15*150), dec(5 * 150), 2},
+	}
+
+	for _, tt := range tests {
+		summary, err := ReplayLedger(txs, tt.method)
+		if err != nil {
+			t.Fatalf("ReplayLedger(%s) unexpected error: %v", tt.method, err)
+		}
+		if summary.Quantity != dec(5) {
+			t.Errorf("ReplayLedger(%s) expected Quantity 5, got %s", tt.method, summary.Quantity)
+		}
+		if summary.RealizedPL != tt.wantRealized {
+			t.Errorf("ReplayLedger(%s) expected RealizedPL %s, got %s", tt.method, tt.wantRealized, summary.RealizedPL)
+		}
+		if summary.CostBasis != tt.wantCost {
+			t.Errorf("ReplayLedger(%s) expected CostBasis %s, got %s", tt.method, tt.wantCost, summary.CostBasis)
+		}
+		if len(summary.OpenLots) != tt.wantOpenLots {
+			t.Errorf("ReplayLedger(%s) expected %d open lots, got %d", tt.method, tt.wantOpenLots, len(summary.OpenLots))
+		}
+	}
+
+	// Okres posiadania pierwszej partii sprzedanej metodą FIFO
+	summary, _ := ReplayLedger(txs, CostBasisFIFO)
+	if days := summary.ClosedLots[0].HoldingDays(); days != 19 {
+		t.Errorf("FIFO first closed lot expected 19 holding days, got %d", days)
+	}
+}
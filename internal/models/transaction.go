@@ -0,0 +1,222 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TransactionType określa rodzaj operacji zapisanej w rejestrze aktywa.
+type TransactionType string
+
+const (
+	TransactionBuy        TransactionType = "buy"        // Zakup jednostek
+	TransactionSell       TransactionType = "sell"       // Sprzedaż jednostek
+	TransactionDeposit    TransactionType = "deposit"    // Wpłata (np. gotówki na konto)
+	TransactionWithdrawal TransactionType = "withdrawal" // Wypłata
+	TransactionFee        TransactionType = "fee"        // Samodzielna opłata (np. za prowadzenie rachunku)
+	TransactionDividend   TransactionType = "dividend"   // Dywidenda lub odsetki
+)
+
+// TransactionTypes zwraca wszystkie obsługiwane typy transakcji w kolejności wyświetlania.
+func TransactionTypes() []TransactionType {
+	return []TransactionType{
+		TransactionBuy,
+		TransactionSell,
+		TransactionDeposit,
+		TransactionWithdrawal,
+		TransactionFee,
+		TransactionDividend,
+	}
+}
+
+// Label zwraca polską nazwę typu transakcji do wyświetlenia w widokach.
+func (t TransactionType) Label() string {
+	switch t {
+	case TransactionBuy:
+		return "Kupno"
+	case TransactionSell:
+		return "Sprzedaż"
+	case TransactionDeposit:
+		return "Wpłata"
+	case TransactionWithdrawal:
+		return "Wypłata"
+	case TransactionFee:
+		return "Opłata"
+	case TransactionDividend:
+		return "Dywidenda"
+	}
+	return string(t)
+}
+
+// IsValid sprawdza, czy typ transakcji jest jednym z obsługiwanych.
+func (t TransactionType) IsValid() bool {
+	for _, known := range TransactionTypes() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// changesQuantity mówi, czy transakcja danego typu zmienia liczbę posiadanych jednostek.
+func (t TransactionType) changesQuantity() bool {
+	switch t {
+	case TransactionBuy, TransactionSell, TransactionDeposit, TransactionWithdrawal:
+		return true
+	}
+	return false
+}
+
+// Transaction reprezentuje pojedynczy wpis w rejestrze (ledgerze) aktywa.
+// Ilość i średni koszt aktywa są wyliczane z listy transakcji, a nie nadpisywane.
+type Transaction struct {
+	ID       string          `json:"id" bson:"_id"`
+	Type     TransactionType `json:"type" bson:"type"`
+	Date     time.Time       `json:"date" bson:"date"`
+	Quantity Decimal         `json:"quantity" bson:"quantity"` // Liczba jednostek (kupno, sprzedaż, wpłata, wypłata)
+	Price    Decimal         `json:"price" bson:"price"`       // Cena za jednostkę
+	Amount   Decimal         `json:"amount" bson:"amount"`     // Wartość pieniężna operacji (dla dywidend kwota wypłaty)
+	Fee      Decimal         `json:"fee" bson:"fee"`           // Prowizja lub opłata
+	Note     string          `json:"note" bson:"note"`
+}
+
+// Validate sprawdza spójność transakcji i uzupełnia kwotę dla operacji na jednostkach.
+func (t *Transaction) Validate() error {
+	if !t.Type.IsValid() {
+		return fmt.Errorf("unknown transaction type %q", t.Type)
+	}
+	if t.Fee.IsNegative() {
+		return fmt.Errorf("fee cannot be negative")
+	}
+
+	switch {
+	case t.Type.changesQuantity():
+		if !t.Quantity.IsPositive() {
+			return fmt.Errorf("quantity must be greater than zero")
+		}
+		if t.Price.IsNegative() {
+			return fmt.Errorf("price cannot be negative")
+		}
+		t.Amount = t.Quantity.Mul(t.Price)
+	case t.Type == TransactionDividend:
+		if !t.Amount.IsPositive() {
+			return fmt.Errorf("dividend amount must be greater than zero")
+		}
+		t.Quantity = Zero
+	case t.Type == TransactionFee:
+		if !t.Fee.IsPositive() {
+			return fmt.Errorf("fee must be greater than zero")
+		}
+		t.Quantity = Zero
+	}
+	return nil
+}
+
+// LedgerSummary to wynik przeliczenia rejestru transakcji aktywa.
+type LedgerSummary struct {
+	Quantity  Decimal // Aktualnie posiadana liczba jednostek
+	CostBasis Decimal // Łączny koszt nabycia posiadanych jednostek (z prowizjami zakupu)
+	Fees      Decimal // Suma wszystkich opłat i prowizji
+	Dividends Decimal // Suma otrzymanych dywidend
+
+	RealizedPL Decimal // Zrealizowany zysk/strata ze sprzedaży (przychód - prowizja - koszt sprzedanych jednostek)
+	Proceeds   Decimal // Łączny przychód ze sprzedaży po potrąceniu prowizji
+
+	OpenLots   []TaxLot  // Partie, które nadal są w portfelu
+	ClosedLots []LotSale // Sprzedane (zamknięte) części partii
+}
+
+// AvgCost zwraca średni koszt jednostki lub 0, jeśli pozycja jest zamknięta.
+func (s LedgerSummary) AvgCost() Decimal {
+	if s.Quantity.IsZero() {
+		return Zero
+	}
+	return s.CostBasis.Div(s.Quantity)
+}
+
+// Today zwraca dzisiejszą datę (północ UTC) - tak samo jak daty z formularzy, dzięki czemu
+// transakcje z tego samego dnia zachowują kolejność wprowadzania.
+func Today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// SortTransactions porządkuje transakcje chronologicznie (stabilnie, aby zachować kolejność wpisów z tego samego dnia).
+func SortTransactions(txs []Transaction) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Date.Before(txs[j].Date)
+	})
+}
+
+// ReplayLedger odtwarza stan pozycji na podstawie listy transakcji.
+// Każdy zakup i wpłata tworzą partię (lot), a sprzedaż i wypłata zużywają partie zgodnie z wybraną metodą.
+func ReplayLedger(txs []Transaction, method CostBasisMethod) (LedgerSummary, error) {
+	ordered := make([]Transaction, len(txs))
+	copy(ordered, txs)
+	SortTransactions(ordered)
+
+	var s LedgerSummary
+	var lots []TaxLot
+	for _, t := range ordered {
+		s.Fees = s.Fees.Add(t.Fee)
+
+		switch t.Type {
+		case TransactionBuy, TransactionDeposit:
+			lots = append(lots, TaxLot{
+				TransactionID:    t.ID,
+				AcquiredAt:       t.Date,
+				OriginalQuantity: t.Quantity,
+				Quantity:         t.Quantity,
+				Cost:             t.Quantity.Mul(t.Price).Add(t.Fee),
+			})
+		case TransactionSell, TransactionWithdrawal:
+			held := totalLotQuantity(lots)
+			if held.IsZero() || t.Quantity.GreaterThan(held) {
+				return s, fmt.Errorf("transaction %s on %s exceeds held quantity (%s > %s)",
+					t.ID, t.Date.Format("2006-01-02"), t.Quantity, held)
+			}
+
+			var portions []lotPortion
+			lots, portions = consumeLots(lots, t.Quantity, method)
+			if t.Type != TransactionSell {
+				// Wypłata zmniejsza pozycję, ale nie jest zdarzeniem podatkowym
+				continue
+			}
+
+			proceeds := t.Quantity.Mul(t.Price).Sub(t.Fee)
+			s.Proceeds = s.Proceeds.Add(proceeds)
+			allocated := Zero
+			for i, portion := range portions {
+				// Przychód i prowizję rozdzielamy na partie proporcjonalnie do sprzedanej ilości;
+				// ostatnia partia dostaje resztę, żeby suma zgadzała się co do grosza
+				lotProceeds := proceeds.Mul(portion.Quantity).Div(t.Quantity)
+				if i == len(portions)-1 {
+					lotProceeds = proceeds.Sub(allocated)
+				}
+				allocated = allocated.Add(lotProceeds)
+
+				sale := LotSale{
+					LotTransactionID:  portion.Lot.TransactionID,
+					SaleTransactionID: t.ID,
+					AcquiredAt:        portion.Lot.AcquiredAt,
+					SoldAt:            t.Date,
+					Quantity:          portion.Quantity,
+					CostBasis:         portion.Cost,
+					Proceeds:          lotProceeds,
+				}
+				sale.Gain = sale.Proceeds.Sub(sale.CostBasis)
+				s.RealizedPL = s.RealizedPL.Add(sale.Gain)
+				s.ClosedLots = append(s.ClosedLots, sale)
+			}
+		case TransactionDividend:
+			s.Dividends = s.Dividends.Add(t.Amount)
+		}
+	}
+
+	for _, lot := range lots {
+		s.Quantity = s.Quantity.Add(lot.Quantity)
+		s.CostBasis = s.CostBasis.Add(lot.Cost)
+	}
+	s.OpenLots = lots
+	return s, nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LegacyPortfolioID to identyfikator portfela zapisanego przed wprowadzeniem kont użytkowników.
+// Pierwsze zarejestrowane konto przejmuje ten portfel, dzięki czemu istniejące dane nie giną.
+const LegacyPortfolioID = "main_portfolio"
+
+// MinPasswordLength to minimalna długość hasła przy rejestracji.
+const MinPasswordLength = 8
+
+// SessionTTL określa, jak długo sesja pozostaje ważna od zalogowania.
+const SessionTTL = 30 * 24 * time.Hour
+
+// User reprezentuje konto użytkownika. Każdy użytkownik ma własny portfel (PortfolioID).
+type User struct {
+	ID           string    `json:"id" bson:"_id"`
+	Email        string    `json:"email" bson:"email"`
+	PasswordHash string    `json:"-" bson:"passwordHash"` // Skrót bcrypt - hasło nigdy nie jest przechowywane wprost
+	PortfolioID  string    `json:"portfolioId" bson:"portfolioId"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+
+	CalendarTokenHash string `json:"-" bson:"calendarTokenHash,omitempty"` // Skrót tokenu z adresu kanału iCalendar (pusty - kanał wyłączony)
+}
+
+// Session to zalogowana sesja przeglądarki. W bazie przechowujemy tylko skrót tokenu z ciasteczka,
+// więc wyciek bazy danych nie pozwala przejąć aktywnych sesji.
+type Session struct {
+	TokenHash string    `json:"-" bson:"_id"`
+	UserID    string    `json:"userId" bson:"userId"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt" bson:"expiresAt"`
+}
+
+// NormalizeEmail sprawdza adres e-mail i zwraca go małymi literami (adres służy jako login).
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address %q", email)
+	}
+	return email, nil
+}
+
+// NewUser tworzy konto z zaszyfrowanym hasłem i nowym, pustym portfelem.
+func NewUser(email, password string) (User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+	user := User{
+		ID:          GenerateID(),
+		Email:       email,
+		PortfolioID: GenerateID(),
+		CreatedAt:   time.Now(),
+	}
+	if err := user.SetPassword(password); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// SetPassword zapisuje skrót bcrypt podanego hasła.
+func (u *User) SetPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword sprawdza, czy hasło pasuje do zapisanego skrótu.
+func (u User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// NewSession tworzy sesję dla użytkownika. Zwraca sesję do zapisania w bazie oraz token
+// do umieszczenia w ciasteczku - token nie jest nigdzie przechowywany.
+func NewSession(userID string) (Session, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return Session{}, "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	now := time.Now()
+	return Session{
+		TokenHash: HashSessionToken(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionTTL),
+	}, token, nil
+}
+
+// HashSessionToken zwraca skrót tokenu sesji, pod którym sesja jest zapisana w bazie.
+func HashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsExpired mówi, czy sesja wygasła.
+func (s Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUserCredentials sprawdza hasła i tokeny sesji kont użytkowników.
+func TestUserCredentials(t *testing.T) {
+	if _, err := NewUser("a@example.com", "short"); err == nil {
+		t.Errorf("NewUser() expected error for too short password")
+	}
+	user, err := NewUser(" Jan@Example.com ", "tajnehaslo")
+	if err != nil {
+		t.Fatalf("NewUser() unexpected error: %v", err)
+	}
+	if user.Email != "jan@example.com" {
+		t.Errorf("NewUser() expected normalized email, got %q", user.Email)
+	}
+	if user.PasswordHash == "tajnehaslo" || !user.CheckPassword("tajnehaslo") || user.CheckPassword("innehaslo") {
+		t.Errorf("CheckPassword() does not match the stored password hash")
+	}
+
+	session, token, err := NewSession(user.ID)
+	if err != nil {
+		t.Fatalf("NewSession() unexpected error: %v", err)
+	}
+	if session.TokenHash == token || HashSessionToken(token) != session.TokenHash {
+		t.Errorf("NewSession() expected only the token hash to be stored")
+	}
+	if session.IsExpired(time.Now()) || !session.IsExpired(time.Now().Add(SessionTTL+time.Minute)) {
+		t.Errorf("IsExpired() returned unexpected result")
+	}
+}
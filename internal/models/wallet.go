@@ -13,28 +13,77 @@ type Asset struct {
 	Name         string  `json:"name" bson:"name"`
 	Symbol       string  `json:"symbol" bson:"symbol"`
 	Type         string  `json:"type" bson:"type"`
-	Quantity     float64 `json:"quantity" bson:"quantity"`
-	AvgCost      float64 `json:"avgCost" bson:"avgCost"`
-	CurrentPrice float64 `json:"currentPrice" bson:"currentPrice"`
+	Quantity     Decimal `json:"quantity" bson:"quantity"`
+	AvgCost      Decimal `json:"avgCost" bson:"avgCost"`
+	CurrentPrice Decimal `json:"currentPrice" bson:"currentPrice"`
 	WalletType   string  `json:"walletType" bson:"walletType"`
+	Currency     string  `json:"currency" bson:"currency"` // Waluta notowań aktywa (np. "USD"); pusta oznacza PLN
+
+	PriceSource    string    `json:"priceSource" bson:"priceSource"`       // Skąd pochodzi CurrentPrice (np. "manual", "file:ceny.json")
+	PriceUpdatedAt time.Time `json:"priceUpdatedAt" bson:"priceUpdatedAt"` // Kiedy CurrentPrice została ostatnio ustawiona
+
+	Transactions []Transaction `json:"transactions" bson:"transactions"` // Rejestr operacji, z którego wyliczane są Quantity i AvgCost
+	RealizedPL   Decimal       `json:"-" bson:"realizedPL"`              // Zrealizowany zysk/strata z rejestru - zapisywany, żeby MongoDB mogło przeliczać sumy portfela
+
+	Alerts []PriceAlert `json:"alerts" bson:"alerts"` // Alerty cenowe, oceniane przy każdej zmianie CurrentPrice
 }
 
 // Subscription reprezentuje pojedynczą subskrypcję lub stały koszt.
 type Subscription struct {
-	ID        string    `json:"id" bson:"_id"` // Dodaj tag bson:"_id"
-	Name      string    `json:"name" bson:"name"`
-	Cost      float64   `json:"cost" bson:"cost"`
-	Frequency string    `json:"frequency" bson:"frequency"` // np. "Miesięcznie", "Rocznie"
-	NextDue   time.Time `json:"nextDue" bson:"nextDue"`     // Następna data płatności
+	ID        string                `json:"id" bson:"_id"` // Dodaj tag bson:"_id"
+	Name      string                `json:"name" bson:"name"`
+	Cost      Decimal               `json:"cost" bson:"cost"`
+	Frequency SubscriptionFrequency `json:"frequency" bson:"frequency"` // Np. FrequencyMonthly; starsze dane mogą mieć polską nazwę, zob. FrequencyCode
+	NextDue   time.Time             `json:"nextDue" bson:"nextDue"`     // Następna data płatności
+	Currency  string                `json:"currency" bson:"currency"`   // Waluta płatności; pusta oznacza PLN
+
+	IntervalDays int         `json:"intervalDays" bson:"intervalDays"` // Odstęp między płatnościami dla FrequencyCustom
+	PastCharges  []time.Time `json:"pastCharges" bson:"pastCharges"`   // Minione terminy płatności (dopisywane przy przesuwaniu NextDue)
+
+	Payments []SubscriptionPayment `json:"payments" bson:"payments"` // Faktycznie zapłacone kwoty, posortowane po dacie
+
+	ReminderDays int `json:"reminderDays" bson:"reminderDays"` // Ile dni przed NextDue wysłać przypomnienie e-mail (0 - bez przypomnienia)
+}
+
+// CurrencyCode zwraca walutę aktywa, przyjmując PLN dla danych sprzed wprowadzenia walut.
+func (a Asset) CurrencyCode() string {
+	if a.Currency == "" {
+		return DefaultCurrency
+	}
+	return a.Currency
+}
+
+// CurrencyCode zwraca walutę subskrypcji, przyjmując PLN dla danych sprzed wprowadzenia walut.
+func (s Subscription) CurrencyCode() string {
+	if s.Currency == "" {
+		return DefaultCurrency
+	}
+	return s.Currency
 }
 
 // InvestmentPortfolio reprezentuje cały portfel inwestycyjny użytkownika.
 type InvestmentPortfolio struct {
-	Assets                  []Asset        // Lista posiadanych aktywów
-	Subscriptions           []Subscription // Lista subskrypcji
-	TotalValue              float64        // Całkowita szacowana wartość portfela
-	TotalCost               float64        // Całkowity koszt zakupu aktywów (bez subskrypcji)
-	MonthlySubscriptionCost float64        // Łączny miesięczny koszt subskrypcji
+	ID      string `bson:"-"`       // Identyfikator portfela (klucz dokumentu w bazie, uzupełniany przy wczytaniu)
+	Name    string `bson:"name"`    // Nazwa portfela widoczna w przełączniku (np. "IKE", "Maklerski")
+	OwnerID string `bson:"ownerId"` // ID użytkownika, do którego należy portfel
+	Version int64  `bson:"version"` // Wersja zapisu - rośnie przy każdej zmianie danych użytkownika (nie przy zmianie ceny bieżącej), zapis starszej wersji kończy się konfliktem
+
+	Assets                  []Asset         // Lista posiadanych aktywów
+	Subscriptions           []Subscription  // Lista subskrypcji
+	TotalValue              Decimal         // Całkowita szacowana wartość portfela
+	TotalCost               Decimal         // Całkowity koszt zakupu aktywów (bez subskrypcji)
+	MonthlySubscriptionCost Decimal         // Łączny miesięczny koszt subskrypcji
+	RealizedProfitLoss      Decimal         // Zrealizowany zysk/strata ze sprzedaży aktywów
+	CostBasisMethod         CostBasisMethod // Metoda rozliczania partii przy sprzedaży (FIFO, LIFO, średni koszt)
+	BaseCurrency            string          // Waluta, w której liczone są sumy portfela
+
+	AllocationTargets []AllocationTarget    `bson:"allocationTargets"` // Docelowy podział portfela według typu portfela i typu aktywa
+	EmergencyFund     EmergencyFundSettings `bson:"emergencyFund"`     // Koszty życia i próg ostrzeżenia poduszki finansowej
+
+	FXRates        FXRates  `bson:"-" json:"-"` // Kursy walut dołączane przy wczytaniu portfela (przechowywane osobno)
+	MissingFXRates []string `bson:"-" json:"-"` // Waluty, dla których zabrakło kursu przy ostatnim przeliczeniu
+
+	assetMethods map[string]CostBasisMethod // Widok zbiorczy: metoda rozliczania portfela, z którego pochodzi aktywo (ID aktywa -> metoda)
 }
 
 // NewInvestmentPortfolio tworzy i zwraca nową instancję pustego portfela inwestycyjnego.
@@ -42,54 +91,353 @@ func NewInvestmentPortfolio() *InvestmentPortfolio {
 	return &InvestmentPortfolio{
 		Assets:                  []Asset{},
 		Subscriptions:           []Subscription{},
-		TotalValue:              0.0,
-		TotalCost:               0.0,
-		MonthlySubscriptionCost: 0.0,
+		TotalValue:              Zero,
+		TotalCost:               Zero,
+		MonthlySubscriptionCost: Zero,
+		RealizedProfitLoss:      Zero,
+		BaseCurrency:            DefaultCurrency,
 	}
 }
 
-// AddAsset dodaje nowe aktywo do portfela.
-// To uproszczona wersja, która nie uwzględnia aktualizacji istniejących aktywów ani cen rynkowych.
-func (p *InvestmentPortfolio) AddAsset(a Asset) {
-	p.Assets = append(p.Assets, a)
-	// Na razie TotalValue będzie po prostu sumą (Quantity * AvgCost).
-	// W przyszłości będziemy pobierać aktualne ceny rynkowe.
-	if a.CurrentPrice == 0 {
+// ensureOpeningBalance zakłada transakcję otwarcia dla aktywów zapisanych przed wprowadzeniem rejestru.
+// Dzięki temu stara pozycja (sama Quantity i AvgCost) nie ginie przy pierwszej nowej transakcji.
+// Prawdziwa data zakupu starej pozycji nie jest znana, więc otwarcie datujemy na dzień przed pierwszą
+// transakcją wprowadzoną przez użytkownika (first) - wsteczna sprzedaż nadal ma z czego zejść.
+// Gdy takiej transakcji nie ma (first jest zerowe), otwarcie dostaje dzisiejszą datę.
+func (a *Asset) ensureOpeningBalance(first time.Time) {
+	if len(a.Transactions) > 0 || !a.Quantity.IsPositive() {
+		return
+	}
+	date := Today()
+	if !first.IsZero() {
+		date = first.AddDate(0, 0, -1)
+	}
+	a.Transactions = append(a.Transactions, Transaction{
+		ID:       GenerateID(),
+		Type:     TransactionBuy,
+		Date:     date,
+		Quantity: a.Quantity,
+		Price:    a.AvgCost,
+		Amount:   a.Quantity.Mul(a.AvgCost),
+		Note:     "Bilans otwarcia",
+	})
+}
+
+// RecalculateFromLedger wylicza Quantity, AvgCost i RealizedPL na podstawie rejestru transakcji i metody rozliczania partii.
+// Aktywa bez transakcji (zapisane przed wprowadzeniem rejestru) pozostają bez zmian.
+func (a *Asset) RecalculateFromLedger(method CostBasisMethod) error {
+	if len(a.Transactions) == 0 {
+		return nil
+	}
+	summary, err := ReplayLedger(a.Transactions, method)
+	if err != nil {
+		return err
+	}
+	a.Quantity = summary.Quantity
+	a.AvgCost = summary.AvgCost()
+	a.RealizedPL = summary.RealizedPL
+	return nil
+}
+
+// Ledger zwraca podsumowanie rejestru transakcji aktywa (wraz z partiami) dla podanej metody.
+func (a *Asset) Ledger(method CostBasisMethod) LedgerSummary {
+	if len(a.Transactions) == 0 {
+		return LedgerSummary{Quantity: a.Quantity, CostBasis: a.Quantity.Mul(a.AvgCost)}
+	}
+	summary, err := ReplayLedger(a.Transactions, method)
+	if err != nil {
+		return LedgerSummary{Quantity: a.Quantity, CostBasis: a.Quantity.Mul(a.AvgCost)}
+	}
+	return summary
+}
+
+// LedgerIssue zwraca błąd, jeśli rejestru transakcji nie da się przeliczyć (np. sprzedaż większej ilości niż
+// posiadana). Pozycja takiego aktywa pochodzi wtedy z zapisanych pól, a nie z rejestru, więc widoki ostrzegają o tym.
+func (a Asset) LedgerIssue() error {
+	if len(a.Transactions) == 0 {
+		return nil
+	}
+	_, err := ReplayLedger(a.Transactions, CostBasisAverage)
+	return err
+}
+
+// AddTransaction dopisuje transakcję do rejestru i przelicza pozycję.
+// Jeśli transakcja jest niepoprawna (np. sprzedaż większej ilości niż posiadana), rejestr nie jest zmieniany.
+func (a *Asset) AddTransaction(t Transaction, method CostBasisMethod) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if t.ID == "" {
+		t.ID = GenerateID()
+	}
+	if t.Date.IsZero() {
+		t.Date = Today()
+	}
+
+	a.ensureOpeningBalance(t.Date)
+	previous := a.Transactions
+	a.Transactions = append(append([]Transaction{}, previous...), t)
+	SortTransactions(a.Transactions)
+
+	if err := a.RecalculateFromLedger(method); err != nil {
+		a.Transactions = previous
+		return err
+	}
+	return nil
+}
+
+// RemoveTransaction usuwa transakcję z rejestru (np. błędnie wprowadzoną) i przelicza pozycję.
+func (a *Asset) RemoveTransaction(transactionID string, method CostBasisMethod) error {
+	var remaining []Transaction
+	found := false
+	for _, t := range a.Transactions {
+		if t.ID == transactionID {
+			found = true
+			continue
+		}
+		remaining = append(remaining, t)
+	}
+	if !found {
+		return fmt.Errorf("transaction with ID %s not found", transactionID)
+	}
+
+	if len(remaining) == 0 {
+		// Usunięto ostatnią transakcję - pozycja jest pusta
+		a.Transactions = []Transaction{}
+		a.Quantity = Zero
+		a.AvgCost = Zero
+		a.RealizedPL = Zero
+		return nil
+	}
+
+	previous := a.Transactions
+	a.Transactions = remaining
+	if err := a.RecalculateFromLedger(method); err != nil {
+		a.Transactions = previous
+		return fmt.Errorf("cannot remove transaction: %w", err)
+	}
+	return nil
+}
+
+// PrepareAsset przygotowuje nowe aktywo do zapisania w portfelu rozliczanym podaną metodą.
+// Jeśli aktywo nie ma jeszcze transakcji, jego początkowa ilość jest zapisywana jako transakcja otwarcia.
+// Aktywo z niespójnym rejestrem zachowuje wpisaną pozycję, a problem pokazuje widok (zob. LedgerIssue).
+func PrepareAsset(a Asset, method CostBasisMethod) Asset {
+	// Dopóki nie znamy ceny rynkowej, przyjmujemy średni koszt zakupu.
+	if a.CurrentPrice.IsZero() {
 		a.CurrentPrice = a.AvgCost
 	}
-	p.TotalValue += a.Quantity * a.CurrentPrice // Uproszczone obliczenie wartości na podstawie średniego kosztu
-	p.TotalCost += a.Quantity * a.AvgCost
+	a.ensureOpeningBalance(time.Time{})
+	_ = a.RecalculateFromLedger(method)
+	return a
+}
+
+// AddAsset dodaje nowe aktywo do portfela (przygotowane przez PrepareAsset).
+func (p *InvestmentPortfolio) AddAsset(a Asset) {
+	p.Assets = append(p.Assets, PrepareAsset(a, p.CostBasisMethod))
 	p.CalculateTotals() // Przelicz wszystko po dodaniu
 }
 
+// SetCostBasisMethod zmienia metodę rozliczania partii i przelicza wszystkie aktywa.
+func (p *InvestmentPortfolio) SetCostBasisMethod(method CostBasisMethod) error {
+	if !method.IsValid() {
+		return fmt.Errorf("unknown cost basis method %q", method)
+	}
+	for i := range p.Assets {
+		if err := p.Assets[i].RecalculateFromLedger(method); err != nil {
+			return fmt.Errorf("cannot recalculate asset %s: %w", p.Assets[i].Name, err)
+		}
+	}
+	p.CostBasisMethod = method
+	p.CalculateTotals()
+	return nil
+}
+
+// FindAsset zwraca wskaźnik do aktywa o podanym ID (lub false, jeśli go nie ma).
+func (p *InvestmentPortfolio) FindAsset(assetID string) (*Asset, bool) {
+	for i := range p.Assets {
+		if p.Assets[i].ID == assetID {
+			return &p.Assets[i], true
+		}
+	}
+	return nil, false
+}
+
+// RemoveAsset usuwa aktywo o podanym ID i przelicza sumy portfela.
+func (p *InvestmentPortfolio) RemoveAsset(assetID string) error {
+	for i, a := range p.Assets {
+		if a.ID == assetID {
+			p.Assets = append(p.Assets[:i:i], p.Assets[i+1:]...)
+			p.CalculateTotals()
+			return nil
+		}
+	}
+	return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+}
+
+// RecordTransaction dopisuje transakcję do rejestru aktywa o podanym ID i przelicza sumy portfela.
+func (p *InvestmentPortfolio) RecordTransaction(assetID string, tx Transaction) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	if err := asset.AddTransaction(tx, p.CostBasisMethod); err != nil {
+		return fmt.Errorf("cannot add transaction to asset %s: %w", asset.Name, err)
+	}
+	p.CalculateTotals()
+	return nil
+}
+
+// DeleteTransaction usuwa transakcję z rejestru aktywa o podanym ID i przelicza sumy portfela.
+func (p *InvestmentPortfolio) DeleteTransaction(assetID, transactionID string) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	if err := asset.RemoveTransaction(transactionID, p.CostBasisMethod); err != nil {
+		return err
+	}
+	p.CalculateTotals()
+	return nil
+}
+
+// SetAssetCurrentPrice ustawia cenę bieżącą aktywa (wraz ze źródłem notowania) i przelicza sumy portfela.
+func (p *InvestmentPortfolio) SetAssetCurrentPrice(assetID string, quote PriceQuote) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	asset.ApplyQuote(quote)
+	p.CalculateTotals()
+	return nil
+}
+
+// SetAssetWalletType przypisuje aktywo do podanego typu portfela (np. "Poduszka").
+func (p *InvestmentPortfolio) SetAssetWalletType(assetID, walletType string) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	asset.WalletType = walletType
+	return nil
+}
+
+// AssetPatch to zmiana aktywa wprowadzana jednym zapisem (PATCH w API). Pole nil zostaje bez zmian.
+type AssetPatch struct {
+	WalletType *string
+	Quote      *PriceQuote
+}
+
+// PatchAsset wprowadza zmiany aktywa z patch i przelicza sumy portfela.
+func (p *InvestmentPortfolio) PatchAsset(assetID string, patch AssetPatch) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	if patch.WalletType != nil {
+		asset.WalletType = *patch.WalletType
+	}
+	if patch.Quote != nil {
+		asset.ApplyQuote(*patch.Quote)
+	}
+	p.CalculateTotals()
+	return nil
+}
+
+// SetAssetAlerts zastępuje alerty cenowe aktywa.
+func (p *InvestmentPortfolio) SetAssetAlerts(assetID string, alerts []PriceAlert) error {
+	asset, found := p.FindAsset(assetID)
+	if !found {
+		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+	asset.Alerts = alerts
+	return nil
+}
+
 // AddSubscription dodaje nową subskrypcję do portfela.
 func (p *InvestmentPortfolio) AddSubscription(s Subscription) {
 	p.Subscriptions = append(p.Subscriptions, s)
 	p.CalculateTotals() // Przelicz wszystko po dodaniu
 }
 
+// RemoveSubscription usuwa subskrypcję o podanym ID i przelicza sumy portfela.
+func (p *InvestmentPortfolio) RemoveSubscription(subID string) error {
+	for i, s := range p.Subscriptions {
+		if s.ID == subID {
+			p.Subscriptions = append(p.Subscriptions[:i:i], p.Subscriptions[i+1:]...)
+			p.CalculateTotals()
+			return nil
+		}
+	}
+	return fmt.Errorf("subscription with ID %s not found in portfolio", subID)
+}
+
 // CalculateTotals przelicza sumaryczne wartości portfela.
 // POWINNO BYĆ WYWOŁYWANE PO KAŻDEJ ZMIANIE W ASSETACH LUB SUBSKRYPCJACH
+// Wszystkie kwoty są przeliczane na walutę bazową portfela według kursów z FXRates.
 func (p *InvestmentPortfolio) CalculateTotals() {
-	p.TotalValue = 0.0
-	p.TotalCost = 0.0
-	p.MonthlySubscriptionCost = 0.0
+	p.TotalValue = Zero
+	p.TotalCost = Zero
+	p.MonthlySubscriptionCost = Zero
+	p.RealizedProfitLoss = Zero
+	p.MissingFXRates = nil
 
 	for _, a := range p.Assets {
-		// Dla uproszczenia, wartość to ilość * średni koszt. W przyszłości będzie to ilość * cena rynkowa.
-		p.TotalValue += a.Quantity * a.CurrentPrice
-		p.TotalCost += a.Quantity * a.AvgCost
+		currency := a.CurrencyCode()
+		p.TotalValue = p.TotalValue.Add(p.ToBase(a.Quantity.Mul(a.CurrentPrice), currency))
+		p.TotalCost = p.TotalCost.Add(p.ToBase(a.Quantity.Mul(a.AvgCost), currency))
+		p.RealizedProfitLoss = p.RealizedProfitLoss.Add(p.ToBase(a.Ledger(p.assetMethod(a)).RealizedPL, currency))
 	}
 
 	for _, s := range p.Subscriptions {
-		switch s.Frequency {
-		case "Miesięcznie":
-			p.MonthlySubscriptionCost += s.Cost
-		case "Rocznie":
-			p.MonthlySubscriptionCost += s.Cost / 12.0
+		p.MonthlySubscriptionCost = p.MonthlySubscriptionCost.Add(p.ToBase(s.MonthlyCost(), s.CurrencyCode()))
+	}
+}
+
+// assetMethod zwraca metodę rozliczania partii aktywa - w widoku zbiorczym metodę portfela, z którego aktywo pochodzi.
+func (p *InvestmentPortfolio) assetMethod(a Asset) CostBasisMethod {
+	if method, ok := p.assetMethods[a.ID]; ok {
+		return method
+	}
+	return p.CostBasisMethod
+}
+
+// GetBaseCurrency zwraca walutę bazową portfela (PLN dla portfeli sprzed wprowadzenia walut).
+func (p *InvestmentPortfolio) GetBaseCurrency() string {
+	if p.BaseCurrency == "" {
+		return DefaultCurrency
+	}
+	return p.BaseCurrency
+}
+
+// ToBase przelicza kwotę z podanej waluty na walutę bazową portfela.
+// Brak kursu jest zapamiętywany w MissingFXRates, a kwota jest pomijana (zwracane jest zero),
+// więc sumy liczone bez niej są niepełne, ale nie mieszają walut.
+func (p *InvestmentPortfolio) ToBase(amount Decimal, currency string) Decimal {
+	converted, ok := p.FXRates.Convert(amount, currency, p.GetBaseCurrency())
+	if !ok {
+		if !isInSlice(currency, p.MissingFXRates) {
+			p.MissingFXRates = append(p.MissingFXRates, currency)
+		}
+		return Zero
+	}
+	return converted
+}
+
+// HasFXRate mówi, czy kwoty w podanej walucie da się przeliczyć na walutę bazową portfela.
+func (p *InvestmentPortfolio) HasFXRate(currency string) bool {
+	_, _, ok := p.FXRates.Factor(currency, p.GetBaseCurrency())
+	return ok
+}
+
+// isInSlice sprawdza, czy tekst znajduje się na liście.
+func isInSlice(s string, slice []string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
 		}
-		// Możesz dodać obsługę innych częstotliwości, np. "Kwartalnie"
 	}
+	return false
 }
 
 // GetAssets zwraca wszystkie aktywa.
@@ -103,39 +451,48 @@ func (p *InvestmentPortfolio) GetSubscriptions() []Subscription {
 }
 
 // GetTotalValue zwraca całkowitą wartość portfela.
-func (p *InvestmentPortfolio) GetTotalValue() float64 {
+func (p *InvestmentPortfolio) GetTotalValue() Decimal {
 	p.CalculateTotals() // Upewniamy się, że wartości są aktualne przed zwróceniem
 	return p.TotalValue
 }
 
 // GetTotalCost zwraca całkowity koszt zakupu aktywów.
-func (p *InvestmentPortfolio) GetTotalCost() float64 {
+func (p *InvestmentPortfolio) GetTotalCost() Decimal {
 	p.CalculateTotals()
 	return p.TotalCost
 }
 
 // GetMonthlySubscriptionCost zwraca łączny miesięczny koszt subskrypcji.
-func (p *InvestmentPortfolio) GetMonthlySubscriptionCost() float64 {
+func (p *InvestmentPortfolio) GetMonthlySubscriptionCost() Decimal {
 	p.CalculateTotals()
 	return p.MonthlySubscriptionCost
 }
 
-// GetProfitLoss oblicza zysk/stratę dla portfela (uproszczone: wartość bieżąca - koszt zakupu).
-func (p *InvestmentPortfolio) GetProfitLoss() float64 {
-	return p.GetTotalValue() - p.GetTotalCost()
+// GetProfitLoss oblicza niezrealizowany zysk/stratę dla portfela (wartość bieżąca - koszt zakupu posiadanych jednostek).
+func (p *InvestmentPortfolio) GetProfitLoss() Decimal {
+	return p.GetTotalValue().Sub(p.GetTotalCost())
+}
+
+// GetRealizedProfitLoss zwraca zysk/stratę zrealizowaną na sprzedażach (niezależnie od bieżących cen).
+func (p *InvestmentPortfolio) GetRealizedProfitLoss() Decimal {
+	p.CalculateTotals()
+	return p.RealizedProfitLoss
 }
 
 // GetProfitLossPercentage oblicza procentowy zysk/stratę.
 func (p *InvestmentPortfolio) GetProfitLossPercentage() float64 {
-	if p.GetTotalCost() == 0 {
+	if p.GetTotalCost().IsZero() {
 		return 0.0 // Zapobiega dzieleniu przez zero
 	}
-	return (p.GetProfitLoss() / p.GetTotalCost()) * 100.0
+	return p.GetProfitLoss().Div(p.GetTotalCost()).Float64() * 100.0
 }
 
-// FormatCurrency to pomocnicza funkcja do formatowania kwot.
-func FormatCurrency(amount float64) string {
-	return fmt.Sprintf("%.2f PLN", amount)
+// FormatCurrency to pomocnicza funkcja do formatowania kwot w podanej walucie.
+func FormatCurrency(amount Decimal, currency string) string {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return fmt.Sprintf("%s %s", amount.StringFixed(2), currency)
 }
 
 func GenerateID() string {
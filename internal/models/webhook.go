@@ -0,0 +1,249 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// WebhookEvent to rodzaj zdarzenia portfela wysyłanego na webhooki użytkownika.
+type WebhookEvent string
+
+const (
+	WebhookAssetAdded      WebhookEvent = "asset.added"
+	WebhookAssetUpdated    WebhookEvent = "asset.updated" // Zmiana ilości, sprzedaż, transakcja, typ portfela
+	WebhookAssetRemoved    WebhookEvent = "asset.removed"
+	WebhookPriceChanged    WebhookEvent = "price.changed" // Ręcznie albo z odświeżania notowań
+	WebhookSubscriptionDue WebhookEvent = "subscription.due"
+	WebhookAlertTriggered  WebhookEvent = "alert.triggered"
+	WebhookPing            WebhookEvent = "ping" // Wiadomość testowa z ekranu webhooków - trafia tylko do wybranego adresu
+)
+
+// MaxWebhookEndpoints to największa liczba adresów webhooków jednego użytkownika.
+const MaxWebhookEndpoints = 10
+
+// MaxWebhookAttempts to liczba prób doręczenia zdarzenia, po której uznajemy je za nieudane.
+const MaxWebhookAttempts = 6
+
+// MaxWebhookDeliveries to liczba ostatnich doręczeń pokazywanych w dzienniku.
+const MaxWebhookDeliveries = 100
+
+// WebhookEvents zwraca zdarzenia, które można subskrybować, w kolejności wyświetlania w formularzu.
+func WebhookEvents() []WebhookEvent {
+	return []WebhookEvent{WebhookAssetAdded, WebhookAssetUpdated, WebhookAssetRemoved, WebhookPriceChanged, WebhookSubscriptionDue, WebhookAlertTriggered}
+}
+
+// Label zwraca polską nazwę zdarzenia do wyświetlenia.
+func (e WebhookEvent) Label() string {
+	switch e {
+	case WebhookAssetAdded:
+		return "Dodanie aktywa"
+	case WebhookAssetUpdated:
+		return "Zmiana aktywa"
+	case WebhookAssetRemoved:
+		return "Usunięcie aktywa"
+	case WebhookPriceChanged:
+		return "Zmiana ceny"
+	case WebhookSubscriptionDue:
+		return "Termin płatności subskrypcji"
+	case WebhookAlertTriggered:
+		return "Alert cenowy"
+	case WebhookPing:
+		return "Test"
+	default:
+		return string(e)
+	}
+}
+
+// IsValid mówi, czy zdarzenie jest jednym z tych, które można subskrybować.
+func (e WebhookEvent) IsValid() bool {
+	for _, known := range WebhookEvents() {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEndpoint to adres, pod który wysyłamy zdarzenia portfeli użytkownika (POST z JSON-em).
+// Każde żądanie jest podpisane HMAC-SHA256 sekretem adresu (zob. SignWebhook).
+type WebhookEndpoint struct {
+	ID        string         `json:"id" bson:"_id"`
+	UserID    string         `json:"userId" bson:"userId"`
+	URL       string         `json:"url" bson:"url"`
+	Secret    string         `json:"-" bson:"secret"`
+	Events    []WebhookEvent `json:"events" bson:"events"` // Pusta lista - wszystkie zdarzenia
+	Active    bool           `json:"active" bson:"active"`
+	CreatedAt time.Time      `json:"createdAt" bson:"createdAt"`
+}
+
+// NewWebhookEndpoint tworzy aktywny adres webhooka z losowym sekretem do podpisywania żądań.
+func NewWebhookEndpoint(userID, url string, events []WebhookEvent) (WebhookEndpoint, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	endpoint := WebhookEndpoint{
+		ID:        GenerateID(),
+		UserID:    userID,
+		URL:       url,
+		Secret:    "whsec_" + hex.EncodeToString(buf),
+		Events:    events,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}
+	return endpoint, endpoint.Validate()
+}
+
+// Validate sprawdza adres i listę zdarzeń webhooka.
+func (e WebhookEndpoint) Validate() error {
+	if err := ValidateWebhookURL(e.URL); err != nil {
+		return err
+	}
+	for _, event := range e.Events {
+		if !event.IsValid() {
+			return fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+	return nil
+}
+
+// Accepts mówi, czy adres ma dostać zdarzenie danego rodzaju (wiadomości testowe dostaje zawsze).
+func (e WebhookEndpoint) Accepts(event WebhookEvent) bool {
+	if event == WebhookPing {
+		return true
+	}
+	if !e.Active {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, accepted := range e.Events {
+		if accepted == event {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhook zwraca wartość nagłówka z podpisem żądania webhooka: "sha256=" i HMAC-SHA256
+// (kluczem jest sekret adresu) z tekstu "<timestamp>.<treść żądania>". Odbiorca liczy ten sam podpis
+// i porównuje go z nagłówkiem; znacznik czasu w podpisie chroni przed powtórzeniem starego żądania.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookMessage to treść (JSON) każdego żądania webhooka. Data zależy od zdarzenia: aktywo (asset.*),
+// PriceChange (price.changed), subskrypcja (subscription.due) albo opis alertu (alert.triggered).
+type WebhookMessage struct {
+	ID          string       `json:"id"` // Wspólne dla wszystkich adresów, które dostały to zdarzenie
+	Event       WebhookEvent `json:"event"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	PortfolioID string       `json:"portfolioId,omitempty"`
+	Data        any          `json:"data"`
+}
+
+// PriceChange to dane zdarzenia price.changed.
+type PriceChange struct {
+	AssetID       string    `json:"assetId"`
+	Name          string    `json:"name"`
+	Symbol        string    `json:"symbol"`
+	Currency      string    `json:"currency"`
+	PreviousPrice Decimal   `json:"previousPrice"`
+	Price         Decimal   `json:"price"`
+	Source        string    `json:"source"`
+	QuotedAt      time.Time `json:"quotedAt"`
+}
+
+// NewPriceChange opisuje zmianę ceny aktywa (asset to stan sprzed zmiany) na podstawie nowego notowania.
+func NewPriceChange(asset Asset, quote PriceQuote) PriceChange {
+	return PriceChange{
+		AssetID:       asset.ID,
+		Name:          asset.Name,
+		Symbol:        asset.Symbol,
+		Currency:      asset.CurrencyCode(),
+		PreviousPrice: asset.CurrentPrice,
+		Price:         quote.Price,
+		Source:        quote.Source,
+		QuotedAt:      quote.Time,
+	}
+}
+
+// WebhookDeliveryStatus to stan doręczenia zdarzenia na jeden adres.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending" // Czeka na (kolejną) próbę
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	WebhookFailed    WebhookDeliveryStatus = "failed" // Wyczerpano próby albo adres usunięto
+)
+
+// Label zwraca polską nazwę stanu doręczenia.
+func (s WebhookDeliveryStatus) Label() string {
+	switch s {
+	case WebhookPending:
+		return "Oczekuje"
+	case WebhookDelivered:
+		return "Doręczono"
+	case WebhookFailed:
+		return "Nieudane"
+	default:
+		return string(s)
+	}
+}
+
+// WebhookDelivery to doręczenie jednego zdarzenia na jeden adres - wpis w dzienniku webhooków.
+// Payload to gotowa treść żądania, dzięki czemu kolejne próby wysyłają dokładnie to samo.
+type WebhookDelivery struct {
+	ID            string                `json:"id" bson:"_id"`
+	EndpointID    string                `json:"endpointId" bson:"endpointId"`
+	UserID        string                `json:"userId" bson:"userId"`
+	Event         WebhookEvent          `json:"event" bson:"event"`
+	Payload       string                `json:"payload" bson:"payload"`
+	Status        WebhookDeliveryStatus `json:"status" bson:"status"`
+	Attempts      int                   `json:"attempts" bson:"attempts"`
+	ResponseCode  int                   `json:"responseCode" bson:"responseCode"` // Kod HTTP ostatniej próby (0 - brak odpowiedzi)
+	Error         string                `json:"error" bson:"error"`               // Błąd ostatniej próby
+	CreatedAt     time.Time             `json:"createdAt" bson:"createdAt"`
+	NextAttemptAt time.Time             `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	DeliveredAt   time.Time             `json:"deliveredAt" bson:"deliveredAt"`
+}
+
+// WebhookRetryDelay zwraca odstęp przed kolejną próbą po podanej liczbie nieudanych prób:
+// 1, 4, 16, 64 i 256 minut.
+func WebhookRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return time.Minute << (2 * (attempts - 1))
+}
+
+// RecordAttempt zapisuje wynik próby doręczenia: sukces kończy doręczenie, a błąd planuje kolejną
+// próbę z rosnącym odstępem - albo, po MaxWebhookAttempts próbach, oznacza doręczenie jako nieudane.
+func (d *WebhookDelivery) RecordAttempt(now time.Time, responseCode int, err error) {
+	d.Attempts++
+	d.ResponseCode = responseCode
+	if err == nil {
+		d.Status = WebhookDelivered
+		d.Error = ""
+		d.DeliveredAt = now
+		d.NextAttemptAt = time.Time{}
+		return
+	}
+	d.Error = err.Error()
+	if d.Attempts >= MaxWebhookAttempts {
+		d.Status = WebhookFailed
+		d.NextAttemptAt = time.Time{}
+		return
+	}
+	d.NextAttemptAt = now.Add(WebhookRetryDelay(d.Attempts))
+}
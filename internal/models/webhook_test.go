@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWebhooks sprawdza podpis żądania, filtrowanie zdarzeń adresu oraz ponawianie doręczeń z rosnącym odstępem.
+func TestWebhooks(t *testing.T) {
+	signature := SignWebhook("whsec_test", 1700000000, []byte(`{"event":"ping"}`))
+	if signature != "sha256=aa8efe37b751e71157c508c5ac4acb1e9fe5225db98355dfc00f4b680afbc447" {
+		t.Errorf("SignWebhook() unexpected signature %s", signature)
+	}
+
+	endpoint, err := NewWebhookEndpoint("U1", "https://example.com/hook", []WebhookEvent{WebhookPriceChanged})
+	if err != nil {
+		t.Fatalf("NewWebhookEndpoint() unexpected error: %v", err)
+	}
+	if !endpoint.Accepts(WebhookPriceChanged) || endpoint.Accepts(WebhookAssetAdded) {
+		t.Errorf("Accepts() expected only price.changed for %v", endpoint.Events)
+	}
+	endpoint.Active = false
+	if endpoint.Accepts(WebhookPriceChanged) || !endpoint.Accepts(WebhookPing) {
+		t.Errorf("Accepts() expected disabled endpoint to get only test messages")
+	}
+	if _, err := NewWebhookEndpoint("U1", "https://example.com/hook", []WebhookEvent{"asset.sold"}); err == nil {
+		t.Errorf("NewWebhookEndpoint() expected error for unknown event")
+	}
+	// Adresy lokalne i prywatne są odrzucane, żeby webhook nie sięgał do sieci wewnętrznej serwera
+	for _, url := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://10.1.2.3/hook", "http://169.254.169.254/latest", "http://[::1]/hook", "http://0.0.0.0/"} {
+		if err := ValidateWebhookURL(url); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) expected error for non-public address", url)
+		}
+	}
+	if err := ValidateWebhookURL("https://93.184.216.34/hook"); err != nil {
+		t.Errorf("ValidateWebhookURL() unexpected error for public address: %v", err)
+	}
+
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	delivery := WebhookDelivery{Status: WebhookPending}
+	delivery.RecordAttempt(now, 500, errors.New("server error"))
+	if delivery.Status != WebhookPending || !delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("RecordAttempt() expected retry after 1 minute, got %s at %s", delivery.Status, delivery.NextAttemptAt)
+	}
+	delivery.RecordAttempt(now, 500, errors.New("server error"))
+	if !delivery.NextAttemptAt.Equal(now.Add(4 * time.Minute)) {
+		t.Errorf("RecordAttempt() expected retry after 4 minutes, got %s", delivery.NextAttemptAt)
+	}
+	for delivery.Status == WebhookPending {
+		delivery.RecordAttempt(now, 0, errors.New("timeout"))
+	}
+	if delivery.Status != WebhookFailed || delivery.Attempts != MaxWebhookAttempts {
+		t.Errorf("RecordAttempt() expected failure after %d attempts, got %s after %d", MaxWebhookAttempts, delivery.Status, delivery.Attempts)
+	}
+
+	delivery = WebhookDelivery{Status: WebhookPending, Attempts: 1, Error: "timeout"}
+	delivery.RecordAttempt(now, 204, nil)
+	if delivery.Status != WebhookDelivered || delivery.Error != "" || !delivery.DeliveredAt.Equal(now) {
+		t.Errorf("RecordAttempt() expected delivered, got %+v", delivery)
+	}
+}
@@ -0,0 +1,18 @@
+// Package notify wysyła powiadomienia do użytkowników (np. przypomnienia o płatnościach subskrypcji).
+package notify
+
+import "context"
+
+// Message to powiadomienie dla jednego odbiorcy.
+type Message struct {
+	To      string // Adres e-mail odbiorcy
+	Subject string
+	Body    string // Zwykły tekst (UTF-8)
+}
+
+// Notifier dostarcza powiadomienia. Dzięki interfejsowi zadania w tle nie zależą od sposobu
+// wysyłki - SMTPNotifier wysyła e-maile, a w testach można podstawić własną implementację.
+type Notifier interface {
+	// Notify wysyła wiadomość. Błąd oznacza, że wiadomość nie została przyjęta do doręczenia.
+	Notify(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout ogranicza czas wysyłki, gdy kontekst wywołującego nie ma własnego terminu.
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier wysyła powiadomienia e-mailem przez serwer SMTP. Jeśli serwer to obsługuje,
+// połączenie jest szyfrowane (STARTTLS), a logowanie odbywa się tylko, gdy podano użytkownika.
+// Bez logowania i szyfrowania działa też z lokalnym serwerem testowym (np. MailHog lub Mailpit).
+type SMTPNotifier struct {
+	addr     string // host:port serwera
+	host     string
+	from     *mail.Address
+	username string
+	password string
+}
+
+// NewSMTPNotifier tworzy notyfikator wysyłający e-maile przez serwer addr (host:port) z adresu from
+// (np. "Webwallet <noreply@example.com>"). Pusty username oznacza serwer bez logowania.
+func NewSMTPNotifier(addr, from, username, password string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q (expected host:port): %w", addr, err)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+	return &SMTPNotifier{addr: addr, host: host, from: sender, username: username, password: password}, nil
+}
+
+// Notify wysyła wiadomość jako e-mail tekstowy w UTF-8.
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", msg.To, err)
+	}
+	body, err := n.compose(to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", n.addr, err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session with %s: %w", n.addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("failed to start TLS with %s: %w", n.addr, err)
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(n.from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", n.from.Address, err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected recipient %s: %w", to.Address, err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", to.Address, err)
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to send email to %s: %w", to.Address, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", to.Address, err)
+	}
+	return client.Quit()
+}
+
+// compose buduje treść e-maila (nagłówki i treść w kodowaniu quoted-printable).
+// Temat kodujemy zgodnie z RFC 2047, więc polskie znaki i znaki nowej linii nie psują nagłówków.
+func (n *SMTPNotifier) compose(to *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	_, domain, _ := strings.Cut(n.from.Address, "@")
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, fmt.Errorf("failed to encode email body: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode email body: %w", err)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// webhookDialer łączy się tylko z adresami publicznymi (models.IsPublicAddr). Adres sprawdzamy już po
+// rozwiązaniu nazwy, tuż przed połączeniem, więc nazwa, która po rejestracji zaczęła wskazywać
+// na sieć wewnętrzną (np. przez zmianę rekordu DNS), też zostanie odrzucona.
+var webhookDialer = &net.Dialer{
+	Timeout: 10 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("invalid webhook address %q: %w", address, err)
+		}
+		if !models.IsPublicAddr(addrPort.Addr()) {
+			return fmt.Errorf("%s: %w", addrPort.Addr(), models.ErrNonPublicAddress)
+		}
+		return nil
+	},
+}
+
+// webhookClient wysyła żądania webhooków - z limitem czasu, żeby wolny odbiorca nie blokował wysyłki.
+// Nie korzysta z proxy (sprawdzalibyśmy wtedy adres proxy, a nie odbiorcy) i nie podąża za przekierowaniami,
+// bo przekierowanie mogłoby wskazać adres, którego nie sprawdzono przy rejestracji.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         webhookDialer.DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// CheckWebhookURL sprawdza adres webhooka przy rejestracji: poprawność (models.ValidateWebhookURL)
+// oraz to, czy nazwa hosta rozwiązuje się wyłącznie na adresy publiczne (inaczej błąd models.ErrNonPublicAddress).
+func CheckWebhookURL(ctx context.Context, rawURL string) error {
+	if err := models.ValidateWebhookURL(rawURL); err != nil {
+		return err
+	}
+	u, _ := url.Parse(rawURL)
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %s: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !models.IsPublicAddr(addr) {
+			return fmt.Errorf("webhook host %s resolves to %s: %w", u.Hostname(), addr, models.ErrNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// Post wysyła gotową treść JSON (POST) pod podany adres z dodatkowymi nagłówkami i zwraca kod odpowiedzi
+// (0, gdy odpowiedzi nie było). Odpowiedź spoza zakresu 2xx (także przekierowanie) jest błędem.
+func Post(ctx context.Context, url string, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook URL %q: %w", url, err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "webwallet")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("webhook %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook %s returned status %s", url, resp.Status)
+	}
+	return resp.StatusCode, nil
+}
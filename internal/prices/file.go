@@ -0,0 +1,46 @@
+package prices
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// FileProvider czyta notowania z lokalnego pliku JSON (tablica obiektów
+// {"symbol", "type", "price", "time"}). Działa bez dostępu do sieci - wystarczy
+// podmienić plik, a kolejne odświeżenie cen go wczyta.
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider tworzy dostawcę czytającego notowania z pliku o podanej ścieżce.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Name zwraca nazwę źródła, np. "file:ceny.json".
+func (p *FileProvider) Name() string {
+	return "file:" + p.path
+}
+
+// Quote wczytuje plik i zwraca notowanie symbolu. Plik jest czytany przy każdym zapytaniu,
+// więc zmiany są widoczne bez restartu aplikacji.
+func (p *FileProvider) Quote(ctx context.Context, symbol, assetType string) (models.PriceQuote, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("failed to read quote file: %w", err)
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("failed to read quote file: %w", err)
+	}
+	table, err := parseQuoteTable(data)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("%s: %w", p.path, err)
+	}
+	// Wpisy bez czasu traktujemy jako aktualne na chwilę ostatniej zmiany pliku
+	return table.lookup(p.Name(), symbol, assetType, info.ModTime().Truncate(time.Second))
+}
@@ -0,0 +1,76 @@
+package prices
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// maxQuoteResponseSize ogranicza rozmiar odpowiedzi serwera notowań.
+const maxQuoteResponseSize = 1 << 20
+
+// HTTPProvider pobiera notowania z serwera HTTP. Zapytanie GET trafia pod skonfigurowany adres
+// z parametrami "symbol" i "type", a odpowiedź ma ten sam format JSON co plik FileProvider
+// (tablica notowań). Dzięki temu w trybie offline można użyć dowolnego lokalnego serwera
+// plików (np. stuba serwującego ceny.json) zamiast prawdziwego serwisu z notowaniami.
+type HTTPProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPProvider tworzy dostawcę pytającego serwer pod podanym adresem.
+func NewHTTPProvider(baseURL string) *HTTPProvider {
+	return &HTTPProvider{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name zwraca adres serwera notowań jako nazwę źródła.
+func (p *HTTPProvider) Name() string {
+	return p.baseURL
+}
+
+// Quote pyta serwer o notowanie symbolu.
+func (p *HTTPProvider) Quote(ctx context.Context, symbol, assetType string) (models.PriceQuote, error) {
+	u, err := url.Parse(p.baseURL)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("invalid quote server URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("symbol", symbol)
+	query.Set("type", assetType)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("failed to build quote request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("failed to fetch quote for %s: %w", symbol, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return models.PriceQuote{}, ErrNoQuote
+	}
+	if resp.StatusCode != http.StatusOK {
+		return models.PriceQuote{}, fmt.Errorf("quote server returned %s for %s", resp.Status, symbol)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxQuoteResponseSize))
+	if err != nil {
+		return models.PriceQuote{}, fmt.Errorf("failed to read quote for %s: %w", symbol, err)
+	}
+	table, err := parseQuoteTable(data)
+	if err != nil {
+		return models.PriceQuote{}, err
+	}
+	return table.lookup(p.Name(), symbol, assetType, time.Now())
+}
@@ -0,0 +1,75 @@
+// Package prices pobiera notowania rynkowe aktywów i okresowo aktualizuje nimi ceny bieżące w portfelach.
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// ErrNoQuote oznacza, że dostawca nie zna notowania dla podanego symbolu.
+var ErrNoQuote = errors.New("no quote for symbol")
+
+// PriceProvider dostarcza bieżące notowanie aktywa na podstawie jego symbolu (Asset.Symbol)
+// i typu (Asset.Type) - ten sam symbol może oznaczać np. akcję i kryptowalutę.
+type PriceProvider interface {
+	// Name zwraca nazwę źródła zapisywaną przy cenie (np. "file:ceny.json").
+	Name() string
+	// Quote zwraca notowanie albo ErrNoQuote, jeśli dostawca nie zna symbolu.
+	Quote(ctx context.Context, symbol, assetType string) (models.PriceQuote, error)
+}
+
+// quoteEntry to pojedyncza pozycja tabeli notowań w formacie JSON używanym przez
+// FileProvider i HTTPProvider, np. {"symbol": "CDR", "type": "Akcje", "price": "123.45"}.
+// Typ i czas są opcjonalne - wpis bez typu pasuje do aktywa każdego typu.
+type quoteEntry struct {
+	Symbol string         `json:"symbol"`
+	Type   string         `json:"type"`
+	Price  models.Decimal `json:"price"`
+	Time   time.Time      `json:"time"`
+}
+
+// quoteTable to wczytana tabela notowań.
+type quoteTable []quoteEntry
+
+// parseQuoteTable odczytuje tabelę notowań z dokumentu JSON (tablicy obiektów quoteEntry).
+func parseQuoteTable(data []byte) (quoteTable, error) {
+	var table quoteTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("invalid quote table: %w", err)
+	}
+	return table, nil
+}
+
+// lookup wyszukuje notowanie symbolu (bez rozróżniania wielkości liter). Wpis z pasującym typem
+// ma pierwszeństwo przed wpisem bez typu. Brak czasu notowania oznacza chwilę odczytu (fetchedAt).
+func (t quoteTable) lookup(source, symbol, assetType string, fetchedAt time.Time) (models.PriceQuote, error) {
+	var match *quoteEntry
+	for i := range t {
+		entry := &t[i]
+		if !strings.EqualFold(entry.Symbol, symbol) {
+			continue
+		}
+		if strings.EqualFold(entry.Type, assetType) {
+			match = entry
+			break
+		}
+		if entry.Type == "" && match == nil {
+			match = entry
+		}
+	}
+	if match == nil {
+		return models.PriceQuote{}, ErrNoQuote
+	}
+
+	quote := models.PriceQuote{Price: match.Price, Source: source, Time: match.Time}
+	if quote.Time.IsZero() {
+		quote.Time = fetchedAt
+	}
+	return quote, nil
+}
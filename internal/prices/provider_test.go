@@ -0,0 +1,49 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// TestFileProvider sprawdza, że wpis z pasującym typem ma pierwszeństwo, wpis bez typu jest
+// zapasowym notowaniem dla każdego typu, a nieznany symbol daje ErrNoQuote.
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ceny.json")
+	table := `[
+		{"symbol": "BTC", "price": "1"},
+		{"symbol": "btc", "type": "Kryptowaluty", "price": "250000.50", "time": "2026-10-17T10:00:00Z"},
+		{"symbol": "CDR", "price": "120"}
+	]`
+	if err := os.WriteFile(path, []byte(table), 0o600); err != nil {
+		t.Fatalf("failed to write quote file: %v", err)
+	}
+	provider := NewFileProvider(path)
+	ctx := context.Background()
+
+	quote, err := provider.Quote(ctx, "BTC", "Kryptowaluty")
+	if err != nil {
+		t.Fatalf("Quote() error: %v", err)
+	}
+	if quote.Price != models.NewDecimalFromFloat(250000.50) || quote.Source != "file:"+path ||
+		!quote.Time.Equal(time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Quote(BTC, Kryptowaluty) = %+v", quote)
+	}
+	if quote, err := provider.Quote(ctx, "btc", "ETF"); err != nil || quote.Price != models.NewDecimal(1) {
+		t.Errorf("Quote(btc, ETF) = %+v, %v, want the untyped entry", quote, err)
+	}
+	if quote, err := provider.Quote(ctx, "cdr", "Akcje"); err != nil || quote.Time.IsZero() {
+		t.Errorf("Quote(cdr) = %+v, %v, want the file modification time", quote, err)
+	}
+	if _, err := provider.Quote(ctx, "XYZ", "Akcje"); !errors.Is(err, ErrNoQuote) {
+		t.Errorf("Quote(XYZ) expected ErrNoQuote, got %v", err)
+	}
+	if _, err := NewFileProvider(filepath.Join(t.TempDir(), "brak.json")).Quote(ctx, "CDR", ""); err == nil || errors.Is(err, ErrNoQuote) {
+		t.Errorf("Quote() from a missing file expected a read error, got %v", err)
+	}
+}
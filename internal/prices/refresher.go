@@ -0,0 +1,111 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/webhooks"
+)
+
+// Refresher okresowo pobiera notowania dla wszystkich aktywów z podanym symbolem
+// i zapisuje je przez PortfolioStore.UpdateAssetCurrentPrice (wraz ze źródłem i czasem notowania).
+// Każde pobrane notowanie trafia też do historii cen symbolu.
+// Każda zmiana ceny trafia na webhooki właściciela portfela jako zdarzenie price.changed.
+type Refresher struct {
+	store    repository.PortfolioStore
+	history  repository.PriceHistoryStore
+	events   *webhooks.Dispatcher
+	provider PriceProvider
+	interval time.Duration
+}
+
+// NewRefresher tworzy odświeżacz cen działający co podany interwał.
+func NewRefresher(store repository.Store, events *webhooks.Dispatcher, provider PriceProvider, interval time.Duration) *Refresher {
+	return &Refresher{store: store, history: store, events: events, provider: provider, interval: interval}
+}
+
+// Run odświeża ceny od razu po starcie, a potem co interwał - aż do anulowania kontekstu.
+func (r *Refresher) Run(ctx context.Context) {
+	log.Printf("Price refresher started (source %s, every %s).", r.provider.Name(), r.interval)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.RefreshAll(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Price refresher stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// quoteResult to zapamiętany wynik zapytania do dostawcy (także nieudanego).
+type quoteResult struct {
+	quote models.PriceQuote
+	err   error
+}
+
+// RefreshAll wykonuje jedno odświeżenie cen we wszystkich portfelach. O każdy symbol pytamy
+// dostawcę tylko raz, nawet jeśli to samo aktywo jest w wielu portfelach.
+func (r *Refresher) RefreshAll(ctx context.Context) {
+	portfolios, err := r.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Price refresh failed: %v", err)
+		return
+	}
+
+	quotes := make(map[string]quoteResult)
+	updated := 0
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		portfolio, err := r.store.LoadPortfolio(ctx, info.ID)
+		if err != nil {
+			log.Printf("Price refresh: failed to load portfolio %s: %v", info.ID, err)
+			continue
+		}
+
+		for _, asset := range portfolio.Assets {
+			if asset.Symbol == "" {
+				continue
+			}
+			key := strings.ToUpper(asset.Symbol) + "|" + asset.Type
+			result, ok := quotes[key]
+			if !ok {
+				result.quote, result.err = r.provider.Quote(ctx, asset.Symbol, asset.Type)
+				quotes[key] = result
+				if result.err != nil && !errors.Is(result.err, ErrNoQuote) {
+					log.Printf("Price refresh: %v", result.err)
+				}
+				if result.err == nil {
+					if err := r.history.RecordPrice(ctx, models.NewPricePoint(asset.Symbol, result.quote)); err != nil {
+						log.Printf("Price refresh: %v", err)
+					}
+				}
+			}
+			if result.err != nil {
+				continue
+			}
+			// Niezmienionej ceny nie zapisujemy - każdy zapis podbija wersję portfela,
+			// a to unieważniłoby formularze otwarte właśnie przez użytkownika.
+			if result.quote.Price.Cmp(asset.CurrentPrice) == 0 && result.quote.Source == asset.PriceSource {
+				continue
+			}
+			if err := r.store.UpdateAssetCurrentPrice(ctx, info.ID, asset.ID, result.quote); err != nil {
+				log.Printf("Price refresh: failed to update %s in portfolio %s: %v", asset.Symbol, info.ID, err)
+				continue
+			}
+			r.events.Emit(ctx, info.OwnerID, info.ID, models.WebhookPriceChanged, models.NewPriceChange(asset, result.quote))
+			updated++
+		}
+	}
+	log.Printf("Price refresh finished: %d prices updated from %s.", updated, r.provider.Name())
+}
@@ -0,0 +1,167 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+	"webwallet/internal/webhooks"
+)
+
+// fakeProvider zwraca notowania z mapy (klucz: symbol wielkimi literami) i liczy zapytania.
+type fakeProvider struct {
+	mu     sync.Mutex
+	quotes map[string]models.PriceQuote
+	errs   map[string]error
+	calls  map[string]int
+	called chan struct{} // Jeśli ustawiony, dostaje sygnał po każdym zapytaniu
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Quote(ctx context.Context, symbol, assetType string) (models.PriceQuote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	if p.calls == nil {
+		p.calls = make(map[string]int)
+	}
+	p.calls[symbol]++
+	if p.called != nil {
+		select {
+		case p.called <- struct{}{}:
+		default:
+		}
+	}
+	if err, ok := p.errs[symbol]; ok {
+		return models.PriceQuote{}, err
+	}
+	quote, ok := p.quotes[symbol]
+	if !ok {
+		return models.PriceQuote{}, ErrNoQuote
+	}
+	return quote, nil
+}
+
+// callCount zwraca liczbę zapytań o symbol.
+func (p *fakeProvider) callCount(symbol string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls[symbol]
+}
+
+// newRefresherStore tworzy magazyn z dwoma portfelami, które mają to samo aktywo CDR,
+// oraz aktywa, dla których dostawca nie ma notowania albo zwraca błąd.
+func newRefresherStore(t *testing.T) *repository.MemoryPortfolioRepo {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryPortfolioRepo()
+	for _, id := range []string{"p1", "p2"} {
+		if err := store.CreatePortfolio(ctx, models.PortfolioInfo{ID: id, Name: id, OwnerID: "u-" + id}); err != nil {
+			t.Fatalf("CreatePortfolio() error: %v", err)
+		}
+		assets := []models.Asset{
+			{ID: id + "-cdr", Name: "CD Projekt", Type: "Akcje", Symbol: "cdr", Quantity: models.NewDecimal(1), CurrentPrice: models.NewDecimal(100)},
+			{ID: id + "-xyz", Name: "Nieznane", Type: "Akcje", Symbol: "XYZ", Quantity: models.NewDecimal(1), CurrentPrice: models.NewDecimal(50)},
+			{ID: id + "-err", Name: "Awaria", Type: "Akcje", Symbol: "ERR", Quantity: models.NewDecimal(1), CurrentPrice: models.NewDecimal(70)},
+			{ID: id + "-cash", Name: "Gotówka", Type: "Gotówka", Quantity: models.NewDecimal(1), CurrentPrice: models.NewDecimal(1)},
+		}
+		for _, asset := range assets {
+			if err := store.AddAsset(ctx, id, repository.AnyVersion, asset); err != nil {
+				t.Fatalf("AddAsset() error: %v", err)
+			}
+		}
+	}
+	return store
+}
+
+// TestRefreshAll sprawdza, że odświeżenie pyta dostawcę o każdy symbol raz, zapisuje ceny we wszystkich
+// portfelach bez podbijania wersji i dopisuje historię, a brak notowania lub błąd dostawcy zostawia starą cenę.
+func TestRefreshAll(t *testing.T) {
+	ctx := context.Background()
+	store := newRefresherStore(t)
+	quoteTime := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	provider := &fakeProvider{
+		quotes: map[string]models.PriceQuote{"CDR": {Price: models.NewDecimal(120), Source: "fake", Time: quoteTime}},
+		errs:   map[string]error{"ERR": errors.New("provider unavailable")},
+	}
+	versions := map[string]int64{}
+	for _, id := range []string{"p1", "p2"} {
+		portfolio, _ := store.LoadPortfolio(ctx, id)
+		versions[id] = portfolio.Version
+	}
+
+	refresher := NewRefresher(store, webhooks.NewDispatcher(store, time.Hour), provider, time.Hour)
+	refresher.RefreshAll(ctx)
+
+	if calls := provider.callCount("CDR"); calls != 1 {
+		t.Errorf("RefreshAll() asked for CDR %d times, want 1", calls)
+	}
+	if calls := provider.callCount(""); calls != 0 {
+		t.Errorf("RefreshAll() asked for assets without symbol %d times", calls)
+	}
+	for _, id := range []string{"p1", "p2"} {
+		portfolio, _ := store.LoadPortfolio(ctx, id)
+		if portfolio.Version != versions[id] {
+			t.Errorf("RefreshAll() changed version of %s from %d to %d", id, versions[id], portfolio.Version)
+		}
+		want := map[string]models.Decimal{
+			id + "-cdr": models.NewDecimal(120),
+			id + "-xyz": models.NewDecimal(50),
+			id + "-err": models.NewDecimal(70),
+		}
+		for assetID, price := range want {
+			asset, _ := portfolio.FindAsset(assetID)
+			if asset.CurrentPrice != price {
+				t.Errorf("price of %s = %s, want %s", assetID, asset.CurrentPrice, price)
+			}
+		}
+		if asset, _ := portfolio.FindAsset(id + "-cdr"); asset.PriceSource != "fake" || !asset.PriceUpdatedAt.Equal(quoteTime) {
+			t.Errorf("quote origin of %s = %q at %s", asset.ID, asset.PriceSource, asset.PriceUpdatedAt)
+		}
+	}
+
+	history, err := store.PriceHistory(ctx, "CDR", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("PriceHistory() error: %v", err)
+	}
+	if len(history) != 1 || history[0].Close != models.NewDecimal(120) || !history[0].Date.Equal(quoteTime.Truncate(24*time.Hour)) {
+		t.Errorf("PriceHistory() = %+v", history)
+	}
+}
+
+// TestRefresherRun sprawdza, że Run odświeża ceny od razu po starcie, potem co interwał,
+// i kończy się po anulowaniu kontekstu.
+func TestRefresherRun(t *testing.T) {
+	store := newRefresherStore(t)
+	provider := &fakeProvider{called: make(chan struct{}, 1)}
+	refresher := NewRefresher(store, webhooks.NewDispatcher(store, time.Hour), provider, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refresher.Run(ctx)
+		close(done)
+	}()
+
+	// Każde odświeżenie pyta o CDR, XYZ i ERR - czekamy, aż zacznie się co najmniej drugie
+	deadline := time.After(5 * time.Second)
+	for provider.callCount("CDR") < 2 {
+		select {
+		case <-provider.called:
+		case <-deadline:
+			t.Fatalf("Run() refreshed CDR %d times within 5s, want at least 2", provider.callCount("CDR"))
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not stop after the context was cancelled")
+	}
+}
@@ -0,0 +1,163 @@
+// Package reminders wysyła przypomnienia o zbliżających się płatnościach subskrypcji.
+package reminders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/notify"
+	"webwallet/internal/repository"
+)
+
+// notifyTimeout ogranicza czas wysyłki jednego przypomnienia.
+const notifyTimeout = 30 * time.Second
+
+// Scheduler okresowo przegląda NextDue subskrypcji wszystkich portfeli i wysyła właścicielowi portfela
+// przypomnienie ReminderDays dni przed terminem. Wysłane przypomnienia zapisuje w ReminderStore,
+// więc restart aplikacji (ani kolejne przebiegi tego samego dnia) nie powtarza wysyłki.
+type Scheduler struct {
+	store    repository.Store
+	notifier notify.Notifier
+	interval time.Duration
+}
+
+// NewScheduler tworzy zadanie wysyłające przypomnienia co podany interwał.
+func NewScheduler(store repository.Store, notifier notify.Notifier, interval time.Duration) *Scheduler {
+	return &Scheduler{store: store, notifier: notifier, interval: interval}
+}
+
+// Run wysyła przypomnienia od razu po starcie i potem co interwał - aż do anulowania kontekstu.
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("Subscription reminders started (every %s).", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.SendDue(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Subscription reminders stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue wysyła przypomnienia o płatnościach, które przypadają w najbliższych ReminderDays dniach
+// i o których jeszcze nie przypominaliśmy. Nieudana wysyłka nie trafia do dziennika,
+// więc zostanie ponowiona przy następnym przebiegu.
+func (s *Scheduler) SendDue(ctx context.Context) {
+	portfolios, err := s.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Subscription reminders failed: %v", err)
+		return
+	}
+
+	today := models.Today()
+	owners := make(map[string]*models.User)
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		if info.OwnerID == "" {
+			continue
+		}
+		portfolio, err := s.store.LoadPortfolio(ctx, info.ID)
+		if err != nil {
+			log.Printf("Subscription reminders: failed to load portfolio %s: %v", info.ID, err)
+			continue
+		}
+		portfolio.ID, portfolio.Name = info.ID, info.Name
+		reminders := portfolio.DueReminders(today)
+		if len(reminders) == 0 {
+			continue
+		}
+
+		owner, ok := owners[info.OwnerID]
+		if !ok {
+			owner, err = s.store.GetUser(ctx, info.OwnerID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				log.Printf("Subscription reminders: failed to load owner of portfolio %s: %v", info.ID, err)
+				continue
+			}
+			owners[info.OwnerID] = owner
+		}
+		if owner == nil {
+			continue
+		}
+
+		for _, reminder := range reminders {
+			if err := s.send(ctx, owner, reminder); err != nil {
+				log.Printf("Subscription reminders: %v", err)
+			}
+		}
+	}
+}
+
+// send wysyła jedno przypomnienie i zapisuje je w dzienniku (pomijając już wysłane).
+func (s *Scheduler) send(ctx context.Context, owner *models.User, reminder models.SubscriptionReminder) error {
+	key := reminder.Key()
+	sent, err := s.store.ReminderSent(ctx, key)
+	if err != nil {
+		return err
+	}
+	if sent {
+		return nil
+	}
+
+	notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	if err := s.notifier.Notify(notifyCtx, reminderMessage(owner.Email, reminder)); err != nil {
+		return fmt.Errorf("failed to send reminder about %s to %s: %w", reminder.Subscription.Name, owner.Email, err)
+	}
+
+	err = s.store.SaveSentReminder(ctx, models.SentReminder{
+		Key:            key,
+		PortfolioID:    reminder.PortfolioID,
+		SubscriptionID: reminder.Subscription.ID,
+		DueDate:        reminder.Subscription.NextDue,
+		Email:          owner.Email,
+		SentAt:         time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Reminder about %s due %s sent to %s.", reminder.Subscription.Name, reminder.Subscription.NextDue.Format("2006-01-02"), owner.Email)
+	return nil
+}
+
+// reminderMessage układa treść e-maila z przypomnieniem o płatności.
+func reminderMessage(email string, reminder models.SubscriptionReminder) notify.Message {
+	sub := reminder.Subscription
+	due := sub.NextDue.Format("2006-01-02")
+	cost := models.FormatCurrency(sub.Cost, sub.CurrencyCode())
+
+	var when string
+	switch reminder.DaysLeft {
+	case 0:
+		when = "Dzisiaj"
+	case 1:
+		when = "Jutro"
+	default:
+		when = fmt.Sprintf("Za %d dni", reminder.DaysLeft)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s (%s) przypada płatność subskrypcji %s: %s.\n\n", when, due, sub.Name, cost)
+	fmt.Fprintf(&body, "Częstotliwość: %s\n", sub.FrequencyLabel())
+	if reminder.PortfolioName != "" {
+		fmt.Fprintf(&body, "Portfel: %s\n", reminder.PortfolioName)
+	}
+	body.WriteString("\nPrzypomnienia ustawisz (lub wyłączysz) w edycji subskrypcji.\n")
+
+	return notify.Message{
+		To:      email,
+		Subject: fmt.Sprintf("Przypomnienie: %s - płatność %s", sub.Name, due),
+		Body:    body.String(),
+	}
+}
@@ -0,0 +1,808 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"webwallet/internal/models"
+)
+
+// MemoryPortfolioRepo przechowuje portfele, konta i sesje w pamięci procesu zamiast w MongoDB.
+// Przydaje się do demo (dane znikają po restarcie) oraz do testów handlerów bez bazy danych.
+// Jest bezpieczny dla wielu goroutine - każda operacja odbywa się pod blokadą.
+type MemoryPortfolioRepo struct {
+	mu         sync.RWMutex
+	portfolios map[string]*models.InvestmentPortfolio // Klucz: ID portfela
+	fxRates    map[string]models.FXRate
+	users      map[string]models.User                // Klucz: ID użytkownika
+	sessions   map[string]models.Session             // Klucz: skrót tokenu sesji
+	prices     map[string][]models.PricePoint        // Klucz: symbol; punkty posortowane po dacie
+	snapshots  map[string][]models.PortfolioSnapshot // Klucz: ID portfela; zapisy posortowane po dacie
+	reminders  map[string]models.SentReminder        // Klucz: SubscriptionReminder.Key
+	notices    []models.Notification                 // Powiadomienia wszystkich użytkowników, od najstarszego
+	endpoints  []models.WebhookEndpoint              // Adresy webhooków wszystkich użytkowników, od najstarszego
+	deliveries []models.WebhookDelivery              // Doręczenia webhooków, od najstarszego
+}
+
+// NewMemoryPortfolioRepo tworzy puste repozytorium w pamięci.
+func NewMemoryPortfolioRepo() *MemoryPortfolioRepo {
+	return &MemoryPortfolioRepo{
+		portfolios: make(map[string]*models.InvestmentPortfolio),
+		fxRates:    make(map[string]models.FXRate),
+		users:      make(map[string]models.User),
+		sessions:   make(map[string]models.Session),
+		prices:     make(map[string][]models.PricePoint),
+		snapshots:  make(map[string][]models.PortfolioSnapshot),
+		reminders:  make(map[string]models.SentReminder),
+	}
+}
+
+// Disconnect nic nie robi - repozytorium w pamięci nie ma połączenia do zamknięcia.
+func (r *MemoryPortfolioRepo) Disconnect(ctx context.Context) error {
+	return nil
+}
+
+// clonePortfolio tworzy głęboką kopię portfela, żeby wywołujący nie modyfikował stanu repozytorium.
+// Kopiujemy przez BSON, więc zachowanie jest takie samo jak przy zapisie do MongoDB
+// (np. pola oznaczone bson:"-" nie są przechowywane).
+func clonePortfolio(portfolio *models.InvestmentPortfolio) (*models.InvestmentPortfolio, error) {
+	data, err := bson.Marshal(portfolio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy portfolio: %w", err)
+	}
+	var clone models.InvestmentPortfolio
+	if err := bson.Unmarshal(data, &clone); err != nil {
+		return nil, fmt.Errorf("failed to copy portfolio: %w", err)
+	}
+	return &clone, nil
+}
+
+// loadLocked zwraca kopię portfela z dołączonymi kursami walut. Wymaga trzymania blokady.
+func (r *MemoryPortfolioRepo) loadLocked(portfolioID string) (*models.InvestmentPortfolio, error) {
+	portfolio := models.NewInvestmentPortfolio()
+	if stored, ok := r.portfolios[portfolioID]; ok {
+		clone, err := clonePortfolio(stored)
+		if err != nil {
+			return nil, err
+		}
+		portfolio = clone
+	}
+	portfolio.ID = portfolioID
+	portfolio.FXRates = models.NewFXRates(r.fxRatesLocked())
+	return portfolio, nil
+}
+
+// fxRatesLocked zwraca listę zapisanych kursów. Wymaga trzymania blokady.
+func (r *MemoryPortfolioRepo) fxRatesLocked() []models.FXRate {
+	rates := make([]models.FXRate, 0, len(r.fxRates))
+	for _, rate := range r.fxRates {
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+// modify wczytuje portfel, wykonuje na nim zmianę i zapisuje wynik - całość pod jedną blokadą,
+// więc równoległe żądania nie nadpisują sobie nawzajem zmian.
+func (r *MemoryPortfolioRepo) modify(portfolioID string, change func(portfolio *models.InvestmentPortfolio) error) error {
+	return r.modifyVersion(portfolioID, AnyVersion, change)
+}
+
+// modifyVersion działa jak modify, ale zmienia portfel tylko wtedy, gdy ma on oczekiwaną wersję
+// (AnyVersion - dowolną). W przeciwnym razie zwraca ErrConflict.
+func (r *MemoryPortfolioRepo) modifyVersion(portfolioID string, version int64, change func(portfolio *models.InvestmentPortfolio) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	portfolio, err := r.loadLocked(portfolioID)
+	if err != nil {
+		return err
+	}
+	if version != AnyVersion && portfolio.Version != version {
+		return ErrConflict
+	}
+	if err := change(portfolio); err != nil {
+		return err
+	}
+	return r.saveLocked(portfolioID, portfolio)
+}
+
+// modifyState działa jak modify, ale nie podbija wersji portfela. Służy do zmian, których użytkownik
+// nie wprowadza w formularzach (np. cena bieżąca), żeby nie unieważniały formularzy otwartych w przeglądarce.
+// Nie giną one przy innych zapisach, bo każdy zapis wczytuje portfel pod tą samą blokadą.
+func (r *MemoryPortfolioRepo) modifyState(portfolioID string, change func(portfolio *models.InvestmentPortfolio) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	portfolio, err := r.loadLocked(portfolioID)
+	if err != nil {
+		return err
+	}
+	if err := change(portfolio); err != nil {
+		return err
+	}
+	clone, err := clonePortfolio(portfolio)
+	if err != nil {
+		return err
+	}
+	r.portfolios[portfolioID] = clone
+	return nil
+}
+
+// saveLocked zapisuje kopię portfela, jeśli w repozytorium nadal jest wersja, którą wczytano.
+// Wymaga trzymania blokady do zapisu.
+func (r *MemoryPortfolioRepo) saveLocked(portfolioID string, portfolio *models.InvestmentPortfolio) error {
+	var current int64
+	if stored, ok := r.portfolios[portfolioID]; ok {
+		current = stored.Version
+	}
+	if portfolio.Version != current {
+		return ErrConflict
+	}
+
+	clone, err := clonePortfolio(portfolio)
+	if err != nil {
+		return err
+	}
+	clone.Version = current + 1
+	r.portfolios[portfolioID] = clone
+	portfolio.Version = clone.Version
+	return nil
+}
+
+// LoadPortfolio zwraca kopię przechowywanego portfela (lub nowy, pusty portfel).
+func (r *MemoryPortfolioRepo) LoadPortfolio(ctx context.Context, portfolioID string) (*models.InvestmentPortfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loadLocked(portfolioID)
+}
+
+// ListPortfolios zwraca portfele należące do użytkownika (posortowane po nazwie).
+func (r *MemoryPortfolioRepo) ListPortfolios(ctx context.Context, ownerID string) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(func(portfolio *models.InvestmentPortfolio) bool {
+		return portfolio.OwnerID == ownerID
+	}), nil
+}
+
+// AllPortfolios zwraca wszystkie portfele (posortowane po nazwie).
+func (r *MemoryPortfolioRepo) AllPortfolios(ctx context.Context) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(func(*models.InvestmentPortfolio) bool { return true }), nil
+}
+
+// listPortfolios zwraca posortowaną listę portfeli spełniających warunek.
+func (r *MemoryPortfolioRepo) listPortfolios(match func(portfolio *models.InvestmentPortfolio) bool) []models.PortfolioInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := []models.PortfolioInfo{}
+	for id, portfolio := range r.portfolios {
+		if match(portfolio) {
+			infos = append(infos, models.PortfolioInfo{ID: id, Name: portfolio.Name, OwnerID: portfolio.OwnerID})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// CreatePortfolio zakłada nowy, pusty portfel (albo nadaje nazwę i właściciela istniejącemu).
+func (r *MemoryPortfolioRepo) CreatePortfolio(ctx context.Context, info models.PortfolioInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	portfolio, ok := r.portfolios[info.ID]
+	if !ok {
+		portfolio = models.NewInvestmentPortfolio()
+		r.portfolios[info.ID] = portfolio
+	}
+	portfolio.Name = info.Name
+	portfolio.OwnerID = info.OwnerID
+	return nil
+}
+
+// ClaimPortfolio nadaje nazwę i właściciela portfelowi, który jeszcze nie ma właściciela.
+func (r *MemoryPortfolioRepo) ClaimPortfolio(ctx context.Context, info models.PortfolioInfo) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	portfolio, ok := r.portfolios[info.ID]
+	if !ok || portfolio.OwnerID != "" {
+		return false, nil
+	}
+	portfolio.Name = info.Name
+	portfolio.OwnerID = info.OwnerID
+	return true, nil
+}
+
+// RenamePortfolio zmienia nazwę portfela.
+func (r *MemoryPortfolioRepo) RenamePortfolio(ctx context.Context, portfolioID, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	portfolio, ok := r.portfolios[portfolioID]
+	if !ok {
+		return ErrNotFound
+	}
+	portfolio.Name = name
+	return nil
+}
+
+// DeletePortfolio usuwa portfel.
+func (r *MemoryPortfolioRepo) DeletePortfolio(ctx context.Context, portfolioID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[portfolioID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.portfolios, portfolioID)
+	delete(r.snapshots, portfolioID)
+	return nil
+}
+
+// AddAsset dodaje nowe aktywo do portfela.
+func (r *MemoryPortfolioRepo) AddAsset(ctx context.Context, portfolioID string, version int64, asset models.Asset) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.AddAsset(asset)
+		return nil
+	})
+}
+
+// RemoveAsset usuwa aktywo o podanym ID.
+func (r *MemoryPortfolioRepo) RemoveAsset(ctx context.Context, portfolioID string, version int64, assetID string) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RemoveAsset(assetID)
+	})
+}
+
+// UpdateAsset dokupuje jednostki aktywa, zapisując transakcję kupna w rejestrze.
+func (r *MemoryPortfolioRepo) UpdateAsset(ctx context.Context, portfolioID string, version int64, assetID string, additionalQuantity, newPurchasePrice models.Decimal) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordTransaction(assetID, models.Transaction{
+			Type:     models.TransactionBuy,
+			Date:     models.Today(),
+			Quantity: additionalQuantity,
+			Price:    newPurchasePrice,
+		})
+	})
+}
+
+// SellAsset sprzedaje (częściowo lub całkowicie) pozycję, zapisując transakcję sprzedaży z prowizją.
+func (r *MemoryPortfolioRepo) SellAsset(ctx context.Context, portfolioID string, version int64, assetID string, quantity, price, fee models.Decimal, date time.Time) error {
+	return r.AddTransaction(ctx, portfolioID, version, assetID, models.Transaction{
+		Type:     models.TransactionSell,
+		Date:     date,
+		Quantity: quantity,
+		Price:    price,
+		Fee:      fee,
+	})
+}
+
+// UpdateAssetCurrentPrice aktualizuje cenę bieżącą dla danego aktywa (bez zmiany wersji portfela).
+func (r *MemoryPortfolioRepo) UpdateAssetCurrentPrice(ctx context.Context, portfolioID, assetID string, quote models.PriceQuote) error {
+	return r.modifyState(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAssetCurrentPrice(assetID, quote)
+	})
+}
+
+// UpdateAssetWalletType aktualizuje przypisanie aktywa do typu portfela.
+func (r *MemoryPortfolioRepo) UpdateAssetWalletType(ctx context.Context, portfolioID string, version int64, assetID string, newWalletType string) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAssetWalletType(assetID, newWalletType)
+	})
+}
+
+// PatchAsset zmienia typ portfela i cenę bieżącą aktywa.
+func (r *MemoryPortfolioRepo) PatchAsset(ctx context.Context, portfolioID string, version int64, assetID string, patch models.AssetPatch) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.PatchAsset(assetID, patch)
+	})
+}
+
+// UpdateAssetAlerts zastępuje alerty cenowe aktywa.
+func (r *MemoryPortfolioRepo) UpdateAssetAlerts(ctx context.Context, portfolioID string, version int64, assetID string, alerts []models.PriceAlert) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAssetAlerts(assetID, alerts)
+	})
+}
+
+// UpdateAlertState zapisuje stan alertu, jeśli nie zmienił się od oceny.
+func (r *MemoryPortfolioRepo) UpdateAlertState(ctx context.Context, portfolioID, assetID string, previous, next models.PriceAlert) (bool, error) {
+	updated := false
+	err := r.modifyState(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		asset, found := portfolio.FindAsset(assetID)
+		if !found {
+			return nil
+		}
+		for i := range asset.Alerts {
+			alert := &asset.Alerts[i]
+			if alert.ID == next.ID && alert.SameState(previous) {
+				alert.Triggered = next.Triggered
+				alert.LastTriggeredAt = next.LastTriggeredAt
+				updated = true
+			}
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// AddTransaction dopisuje transakcję do rejestru aktywa i przelicza portfel.
+func (r *MemoryPortfolioRepo) AddTransaction(ctx context.Context, portfolioID string, version int64, assetID string, tx models.Transaction) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordTransaction(assetID, tx)
+	})
+}
+
+// RemoveTransaction usuwa transakcję z rejestru aktywa.
+func (r *MemoryPortfolioRepo) RemoveTransaction(ctx context.Context, portfolioID string, version int64, assetID, transactionID string) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.DeleteTransaction(assetID, transactionID)
+	})
+}
+
+// UpdateCostBasisMethod zmienia metodę rozliczania partii i przelicza pozycje.
+func (r *MemoryPortfolioRepo) UpdateCostBasisMethod(ctx context.Context, portfolioID string, method models.CostBasisMethod) error {
+	return r.modify(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetCostBasisMethod(method)
+	})
+}
+
+// AddSubscription dodaje nową subskrypcję do portfela.
+func (r *MemoryPortfolioRepo) AddSubscription(ctx context.Context, portfolioID string, version int64, sub models.Subscription) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.AddSubscription(sub)
+		return nil
+	})
+}
+
+// RemoveSubscription usuwa subskrypcję o podanym ID.
+func (r *MemoryPortfolioRepo) RemoveSubscription(ctx context.Context, portfolioID string, version int64, subID string) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RemoveSubscription(subID)
+	})
+}
+
+// UpdateSubscription zmienia edytowalne pola subskrypcji o tym samym ID.
+func (r *MemoryPortfolioRepo) UpdateSubscription(ctx context.Context, portfolioID string, version int64, updatedSub models.Subscription) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		_, err := portfolio.EditSubscription(updatedSub, models.Today())
+		return err
+	})
+}
+
+// RollOverSubscriptions przesuwa minione terminy subskrypcji portfela.
+func (r *MemoryPortfolioRepo) RollOverSubscriptions(ctx context.Context, portfolioID string, today time.Time) ([]models.Subscription, error) {
+	var rolled []models.Subscription
+	err := r.modify(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		if rolled = portfolio.RollOverSubscriptions(today); len(rolled) == 0 {
+			return errNothingRolled
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingRolled) {
+		return nil, nil
+	}
+	return rolled, err
+}
+
+// LoadFXRates zwraca wszystkie zapisane kursy walut.
+func (r *MemoryPortfolioRepo) LoadFXRates(ctx context.Context) ([]models.FXRate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fxRatesLocked(), nil
+}
+
+// SaveFXRate zapisuje (lub nadpisuje) kurs dla pary walutowej.
+func (r *MemoryPortfolioRepo) SaveFXRate(ctx context.Context, rate models.FXRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fxRates[rate.Pair()] = rate
+	return nil
+}
+
+// RemoveFXRate usuwa kurs dla pary walutowej (np. "USD/PLN").
+func (r *MemoryPortfolioRepo) RemoveFXRate(ctx context.Context, pair string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.fxRates[pair]; !ok {
+		return fmt.Errorf("fx rate %s not found", pair)
+	}
+	delete(r.fxRates, pair)
+	return nil
+}
+
+// UpdateBaseCurrency zmienia walutę bazową portfela i przelicza sumy.
+func (r *MemoryPortfolioRepo) UpdateBaseCurrency(ctx context.Context, portfolioID, currency string) error {
+	return r.modify(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.BaseCurrency = currency
+		portfolio.CalculateTotals()
+		return nil
+	})
+}
+
+// UpdateAllocationTargets zastępuje docelowy podział portfela w podanej grupie.
+func (r *MemoryPortfolioRepo) UpdateAllocationTargets(ctx context.Context, portfolioID, group string, targets []models.AllocationTarget) error {
+	return r.modify(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAllocationTargets(group, targets)
+	})
+}
+
+// RecordSubscriptionPayment zapisuje płatność subskrypcji i opcjonalnie przesuwa jej termin (markPaid).
+func (r *MemoryPortfolioRepo) RecordSubscriptionPayment(ctx context.Context, portfolioID string, version int64, subID string, payment models.SubscriptionPayment, markPaid bool) error {
+	return r.modifyVersion(portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordSubscriptionPayment(subID, payment, markPaid)
+	})
+}
+
+// UpdateEmergencyFundSettings zapisuje ustawienia poduszki finansowej portfela.
+func (r *MemoryPortfolioRepo) UpdateEmergencyFundSettings(ctx context.Context, portfolioID string, settings models.EmergencyFundSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return r.modify(portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.EmergencyFund = settings
+		return nil
+	})
+}
+
+// CreateUser zapisuje nowe konto użytkownika.
+func (r *MemoryPortfolioRepo) CreateUser(ctx context.Context, user models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return ErrUserExists
+		}
+	}
+	r.users[user.ID] = user
+	return nil
+}
+
+// GetUser zwraca użytkownika o podanym ID.
+func (r *MemoryPortfolioRepo) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// GetUserByEmail zwraca użytkownika o podanym adresie e-mail.
+func (r *MemoryPortfolioRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// GetUserByCalendarToken zwraca użytkownika o podanym skrócie tokenu kanału iCalendar.
+func (r *MemoryPortfolioRepo) GetUserByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if tokenHash == "" {
+		return nil, ErrNotFound
+	}
+	for _, user := range r.users {
+		if user.CalendarTokenHash == tokenHash {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SetUserPortfolio zmienia portfel główny użytkownika.
+func (r *MemoryPortfolioRepo) SetUserPortfolio(ctx context.Context, userID, portfolioID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.PortfolioID = portfolioID
+	r.users[userID] = user
+	return nil
+}
+
+// SetCalendarToken zapisuje skrót tokenu kanału iCalendar użytkownika.
+func (r *MemoryPortfolioRepo) SetCalendarToken(ctx context.Context, userID, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.CalendarTokenHash = tokenHash
+	r.users[userID] = user
+	return nil
+}
+
+// CountUsers zwraca liczbę zarejestrowanych kont.
+func (r *MemoryPortfolioRepo) CountUsers(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.users)), nil
+}
+
+// CreateSession zapisuje nową sesję logowania.
+func (r *MemoryPortfolioRepo) CreateSession(ctx context.Context, session models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.TokenHash] = session
+	return nil
+}
+
+// GetSession zwraca ważną sesję o podanym skrócie tokenu.
+func (r *MemoryPortfolioRepo) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	session, ok := r.sessions[tokenHash]
+	if !ok || session.IsExpired(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// DeleteSession usuwa sesję (wylogowanie).
+func (r *MemoryPortfolioRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, tokenHash)
+	return nil
+}
+
+// RecordPrice zapisuje cenę symbolu, nadpisując cenę zapisaną wcześniej tego samego dnia.
+func (r *MemoryPortfolioRepo) RecordPrice(ctx context.Context, point models.PricePoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	points := r.prices[point.Symbol]
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(point.Date) })
+	if i < len(points) && points[i].Date.Equal(point.Date) {
+		points[i] = point
+		return nil
+	}
+	points = append(points, models.PricePoint{})
+	copy(points[i+1:], points[i:])
+	points[i] = point
+	r.prices[point.Symbol] = points
+	return nil
+}
+
+// PriceHistory zwraca historię cen symbolu z podanego przedziału dat.
+func (r *MemoryPortfolioRepo) PriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]models.PricePoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	points := []models.PricePoint{}
+	for _, p := range r.prices[models.NormalizeSymbol(symbol)] {
+		if (!from.IsZero() && p.Date.Before(from)) || (!to.IsZero() && p.Date.After(to)) {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// SaveSnapshot zapisuje stan portfela z danego dnia, nadpisując wcześniejszy zapis z tego dnia.
+func (r *MemoryPortfolioRepo) SaveSnapshot(ctx context.Context, snapshot models.PortfolioSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshots := r.snapshots[snapshot.PortfolioID]
+	i := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Date.Before(snapshot.Date) })
+	if i < len(snapshots) && snapshots[i].Date.Equal(snapshot.Date) {
+		snapshots[i] = snapshot
+		return nil
+	}
+	snapshots = append(snapshots, models.PortfolioSnapshot{})
+	copy(snapshots[i+1:], snapshots[i:])
+	snapshots[i] = snapshot
+	r.snapshots[snapshot.PortfolioID] = snapshots
+	return nil
+}
+
+// Snapshots zwraca zapisy wartości portfela z podanego przedziału dat.
+func (r *MemoryPortfolioRepo) Snapshots(ctx context.Context, portfolioID string, from, to time.Time) ([]models.PortfolioSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshots := []models.PortfolioSnapshot{}
+	for _, s := range r.snapshots[portfolioID] {
+		if (!from.IsZero() && s.Date.Before(from)) || (!to.IsZero() && s.Date.After(to)) {
+			continue
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+// ReminderSent sprawdza, czy przypomnienie o podanym kluczu zostało już wysłane.
+func (r *MemoryPortfolioRepo) ReminderSent(ctx context.Context, key string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.reminders[key]
+	return ok, nil
+}
+
+// SaveSentReminder zapisuje wysłane przypomnienie.
+func (r *MemoryPortfolioRepo) SaveSentReminder(ctx context.Context, reminder models.SentReminder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reminders[reminder.Key] = reminder
+	return nil
+}
+
+// AddNotification zapisuje nowe powiadomienie.
+func (r *MemoryPortfolioRepo) AddNotification(ctx context.Context, notification models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notices = append(r.notices, notification)
+	return nil
+}
+
+// Notifications zwraca najnowsze powiadomienia użytkownika (od najnowszego).
+func (r *MemoryPortfolioRepo) Notifications(ctx context.Context, userID string, limit int) ([]models.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifications := []models.Notification{}
+	for i := len(r.notices) - 1; i >= 0 && len(notifications) < limit; i-- {
+		if r.notices[i].UserID == userID {
+			notifications = append(notifications, r.notices[i])
+		}
+	}
+	return notifications, nil
+}
+
+// UnreadNotifications zwraca liczbę nieprzeczytanych powiadomień użytkownika.
+func (r *MemoryPortfolioRepo) UnreadNotifications(ctx context.Context, userID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, n := range r.notices {
+		if n.UserID == userID && !n.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkNotificationsRead oznacza wszystkie powiadomienia użytkownika jako przeczytane.
+func (r *MemoryPortfolioRepo) MarkNotificationsRead(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.notices {
+		if r.notices[i].UserID == userID {
+			r.notices[i].Read = true
+		}
+	}
+	return nil
+}
+
+// WebhookEndpoints zwraca adresy webhooków użytkownika (od najstarszego).
+func (r *MemoryPortfolioRepo) WebhookEndpoints(ctx context.Context, userID string) ([]models.WebhookEndpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	endpoints := []models.WebhookEndpoint{}
+	for _, e := range r.endpoints {
+		if e.UserID == userID {
+			e.Events = slices.Clone(e.Events)
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints, nil
+}
+
+// GetWebhookEndpoint zwraca adres webhooka o podanym ID.
+func (r *MemoryPortfolioRepo) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.endpoints {
+		if e.ID == endpointID {
+			e.Events = slices.Clone(e.Events)
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SaveWebhookEndpoint zapisuje nowy adres webhooka albo zastępuje zapisany adres o tym samym ID.
+func (r *MemoryPortfolioRepo) SaveWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endpoint.Events = slices.Clone(endpoint.Events)
+	for i := range r.endpoints {
+		if r.endpoints[i].ID == endpoint.ID {
+			r.endpoints[i] = endpoint
+			return nil
+		}
+	}
+	r.endpoints = append(r.endpoints, endpoint)
+	return nil
+}
+
+// DeleteWebhookEndpoint usuwa adres webhooka użytkownika i jego doręczenia.
+func (r *MemoryPortfolioRepo) DeleteWebhookEndpoint(ctx context.Context, userID, endpointID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := slices.IndexFunc(r.endpoints, func(e models.WebhookEndpoint) bool { return e.ID == endpointID && e.UserID == userID })
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.endpoints = slices.Delete(r.endpoints, i, i+1)
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(d models.WebhookDelivery) bool { return d.EndpointID == endpointID })
+	return nil
+}
+
+// SaveWebhookDelivery zapisuje nowe doręczenie albo zastępuje zapisane doręczenie o tym samym ID.
+func (r *MemoryPortfolioRepo) SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = delivery
+			return nil
+		}
+	}
+	r.deliveries = append(r.deliveries, delivery)
+	return nil
+}
+
+// WebhookDeliveries zwraca najnowsze doręczenia użytkownika (od najnowszego).
+func (r *MemoryPortfolioRepo) WebhookDeliveries(ctx context.Context, userID string, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if r.deliveries[i].UserID == userID {
+			deliveries = append(deliveries, r.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// DueWebhookDeliveries zwraca oczekujące doręczenia, których kolejna próba już przypadła.
+func (r *MemoryPortfolioRepo) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, d := range r.deliveries {
+		if d.Status == models.WebhookPending && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool { return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt) })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// migrationsCollection to kolekcja, w której zapisujemy, które migracje zostały już wykonane.
+const migrationsCollection = "migrations"
+
+// decimalMigrationID identyfikuje migrację zamieniającą liczby zmiennoprzecinkowe (double) na Decimal128.
+const decimalMigrationID = "2024_decimal_money"
+
+// realizedPLMigrationID identyfikuje migrację zapisującą zrealizowany zysk/stratę przy każdym aktywie.
+const realizedPLMigrationID = "2026_asset_realized_pl"
+
+// MigrateDecimalFields przepisuje zapisane portfele i kursy walut tak, aby kwoty i ilości
+// były przechowywane jako Decimal128 zamiast double. Migracja wykonuje się tylko raz -
+// po zakończeniu zapisuje znacznik w kolekcji "migrations".
+// Odczyt starych dokumentów działa także bez migracji (models.Decimal rozumie double),
+// ale dopiero po niej baza przechowuje dokładne wartości.
+func (r *PortfolioRepo) MigrateDecimalFields(ctx context.Context) error {
+	migrations := r.collection.Database().Collection(migrationsCollection)
+
+	err := migrations.FindOne(ctx, bson.M{"_id": decimalMigrationID}).Err()
+	if err == nil {
+		return nil // Migracja została już wykonana
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to check migration %s: %w", decimalMigrationID, err)
+	}
+
+	// 1. Portfele - dekodujemy każdy dokument do modelu i zapisujemy go z powrotem
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to list portfolios for migration: %w", err)
+	}
+	var portfolios []bson.Raw
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return fmt.Errorf("failed to read portfolios for migration: %w", err)
+	}
+	for _, raw := range portfolios {
+		var portfolio models.InvestmentPortfolio
+		if err := bson.Unmarshal(raw, &portfolio); err != nil {
+			return fmt.Errorf("failed to decode portfolio for migration: %w", err)
+		}
+		id := raw.Lookup("_id")
+		if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": &portfolio}); err != nil {
+			return fmt.Errorf("failed to migrate portfolio %s: %w", id, err)
+		}
+	}
+
+	// 2. Kursy walut
+	rates, err := r.LoadFXRates(ctx)
+	if err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		if err := r.SaveFXRate(ctx, rate); err != nil {
+			return err
+		}
+	}
+
+	// 3. Znacznik wykonanej migracji
+	opts := options.Update().SetUpsert(true)
+	marker := bson.M{"$set": bson.M{"appliedAt": time.Now()}}
+	if _, err := migrations.UpdateOne(ctx, bson.M{"_id": decimalMigrationID}, marker, opts); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", decimalMigrationID, err)
+	}
+
+	log.Printf("Migration %s applied: %d portfolio(s), %d fx rate(s) converted to Decimal128.", decimalMigrationID, len(portfolios), len(rates))
+	return nil
+}
+
+// MigrateRealizedPL zapisuje przy aktywach zrealizowany zysk/stratę wyliczony z rejestru transakcji (pole "realizedPL")
+// i przelicza sumy portfeli - od tej migracji baza liczy sumy sama, w tym samym zapisie co zmiana tablic.
+// Portfel zmieniony w trakcie migracji pomijamy: jego aktywa dostaną pole przy następnej zmianie rejestru.
+func (r *PortfolioRepo) MigrateRealizedPL(ctx context.Context) error {
+	migrations := r.collection.Database().Collection(migrationsCollection)
+
+	err := migrations.FindOne(ctx, bson.M{"_id": realizedPLMigrationID}).Err()
+	if err == nil {
+		return nil // Migracja została już wykonana
+	}
+	if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to check migration %s: %w", realizedPLMigrationID, err)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to list portfolios for migration: %w", err)
+	}
+	var portfolios []bson.Raw
+	if err := cursor.All(ctx, &portfolios); err != nil {
+		return fmt.Errorf("failed to read portfolios for migration: %w", err)
+	}
+	for _, raw := range portfolios {
+		var portfolio models.InvestmentPortfolio
+		if err := bson.Unmarshal(raw, &portfolio); err != nil {
+			return fmt.Errorf("failed to decode portfolio for migration: %w", err)
+		}
+		id, _ := raw.Lookup("_id").StringValueOK()
+		stages := make([]bson.M, 0, len(portfolio.Assets))
+		for _, asset := range portfolio.Assets {
+			realized := asset.Ledger(portfolio.CostBasisMethod).RealizedPL
+			stages = append(stages, setElementFields("assets", asset.ID, bson.M{"realizedPL": realized}))
+		}
+		result, err := r.arrayUpdate(ctx, versionFilter(id, portfolio.Version), false, stages...)
+		if err != nil {
+			return fmt.Errorf("failed to migrate portfolio %s: %w", id, err)
+		}
+		if result.MatchedCount == 0 {
+			log.Printf("Portfolio %s changed during migration %s, skipped.", id, realizedPLMigrationID)
+		}
+	}
+
+	opts := options.Update().SetUpsert(true)
+	marker := bson.M{"$set": bson.M{"appliedAt": time.Now()}}
+	if _, err := migrations.UpdateOne(ctx, bson.M{"_id": realizedPLMigrationID}, marker, opts); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", realizedPLMigrationID, err)
+	}
+
+	log.Printf("Migration %s applied: %d portfolio(s) updated.", realizedPLMigrationID, len(portfolios))
+	return nil
+}
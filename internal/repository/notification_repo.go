@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ensureNotificationIndexes zakłada indeks (użytkownik, data) - centrum powiadomień pokazuje najnowsze wpisy użytkownika.
+func (r *PortfolioRepo) ensureNotificationIndexes(ctx context.Context) error {
+	_, err := r.notificationsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create notifications index: %w", err)
+	}
+	return nil
+}
+
+// AddNotification zapisuje nowe powiadomienie.
+func (r *PortfolioRepo) AddNotification(ctx context.Context, notification models.Notification) error {
+	if _, err := r.notificationsCollection.InsertOne(ctx, notification); err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	return nil
+}
+
+// Notifications zwraca najnowsze powiadomienia użytkownika (od najnowszego).
+func (r *PortfolioRepo) Notifications(ctx context.Context, userID string, limit int) ([]models.Notification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.notificationsCollection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notifications: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, fmt.Errorf("failed to decode notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// UnreadNotifications zwraca liczbę nieprzeczytanych powiadomień użytkownika.
+func (r *PortfolioRepo) UnreadNotifications(ctx context.Context, userID string) (int, error) {
+	count, err := r.notificationsCollection.CountDocuments(ctx, bson.M{"userId": userID, "read": false})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return int(count), nil
+}
+
+// MarkNotificationsRead oznacza wszystkie powiadomienia użytkownika jako przeczytane.
+func (r *PortfolioRepo) MarkNotificationsRead(ctx context.Context, userID string) error {
+	_, err := r.notificationsCollection.UpdateMany(ctx, bson.M{"userId": userID, "read": false}, bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return nil
+}
@@ -18,12 +18,38 @@ type RepoConfiguration struct {
 	URI        string
 	Database   string
 	Collection string
+	// FXCollection to kolekcja z kursami walut (domyślnie "fx_rates")
+	FXCollection string
+	// UsersCollection i SessionsCollection przechowują konta i sesje logowania (domyślnie "users" i "sessions")
+	UsersCollection    string
+	SessionsCollection string
+	// PriceHistoryCollection przechowuje historię cen symboli (domyślnie "price_history")
+	PriceHistoryCollection string
+	// SnapshotsCollection przechowuje dzienne zapisy wartości portfeli (domyślnie "snapshots")
+	SnapshotsCollection string
+	// RemindersCollection przechowuje wysłane przypomnienia o płatnościach (domyślnie "sent_reminders")
+	RemindersCollection string
+	// NotificationsCollection przechowuje centrum powiadomień użytkowników (domyślnie "notifications")
+	NotificationsCollection string
+	// WebhookEndpointsCollection i WebhookDeliveriesCollection przechowują adresy webhooków i dziennik doręczeń
+	// (domyślnie "webhook_endpoints" i "webhook_deliveries")
+	WebhookEndpointsCollection  string
+	WebhookDeliveriesCollection string
 }
 
 // PortfolioRepo implementuje operacje CRUD dla InvestmentPortfolio.
 type PortfolioRepo struct {
-	client     *mongo.Client
-	collection *mongo.Collection
+	client                  *mongo.Client
+	collection              *mongo.Collection
+	fxCollection            *mongo.Collection
+	usersCollection         *mongo.Collection
+	sessionsCollection      *mongo.Collection
+	priceHistoryCollection  *mongo.Collection
+	snapshotsCollection     *mongo.Collection
+	remindersCollection     *mongo.Collection
+	notificationsCollection *mongo.Collection
+	webhookEndpoints        *mongo.Collection
+	webhookDeliveries       *mongo.Collection
 }
 
 // NewPortfolioRepo tworzy nową instancję PortfolioRepo i łączy się z MongoDB.
@@ -51,10 +77,79 @@ func NewPortfolioRepo(config RepoConfiguration) (*PortfolioRepo, error) {
 
 	collection := client.Database(config.Database).Collection(config.Collection)
 
-	return &PortfolioRepo{
-		client:     client,
-		collection: collection,
-	}, nil
+	fxCollectionName := config.FXCollection
+	if fxCollectionName == "" {
+		fxCollectionName = "fx_rates"
+	}
+	fxCollection := client.Database(config.Database).Collection(fxCollectionName)
+
+	usersCollectionName := config.UsersCollection
+	if usersCollectionName == "" {
+		usersCollectionName = "users"
+	}
+	sessionsCollectionName := config.SessionsCollection
+	if sessionsCollectionName == "" {
+		sessionsCollectionName = "sessions"
+	}
+
+	priceHistoryCollectionName := config.PriceHistoryCollection
+	if priceHistoryCollectionName == "" {
+		priceHistoryCollectionName = "price_history"
+	}
+
+	snapshotsCollectionName := config.SnapshotsCollection
+	if snapshotsCollectionName == "" {
+		snapshotsCollectionName = "snapshots"
+	}
+
+	remindersCollectionName := config.RemindersCollection
+	if remindersCollectionName == "" {
+		remindersCollectionName = "sent_reminders"
+	}
+
+	notificationsCollectionName := config.NotificationsCollection
+	if notificationsCollectionName == "" {
+		notificationsCollectionName = "notifications"
+	}
+
+	webhookEndpointsCollectionName := config.WebhookEndpointsCollection
+	if webhookEndpointsCollectionName == "" {
+		webhookEndpointsCollectionName = "webhook_endpoints"
+	}
+	webhookDeliveriesCollectionName := config.WebhookDeliveriesCollection
+	if webhookDeliveriesCollectionName == "" {
+		webhookDeliveriesCollectionName = "webhook_deliveries"
+	}
+
+	repo := &PortfolioRepo{
+		client:                  client,
+		collection:              collection,
+		fxCollection:            fxCollection,
+		usersCollection:         client.Database(config.Database).Collection(usersCollectionName),
+		sessionsCollection:      client.Database(config.Database).Collection(sessionsCollectionName),
+		priceHistoryCollection:  client.Database(config.Database).Collection(priceHistoryCollectionName),
+		snapshotsCollection:     client.Database(config.Database).Collection(snapshotsCollectionName),
+		remindersCollection:     client.Database(config.Database).Collection(remindersCollectionName),
+		notificationsCollection: client.Database(config.Database).Collection(notificationsCollectionName),
+		webhookEndpoints:        client.Database(config.Database).Collection(webhookEndpointsCollectionName),
+		webhookDeliveries:       client.Database(config.Database).Collection(webhookDeliveriesCollectionName),
+	}
+	if err := repo.ensureUserIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := repo.ensurePriceHistoryIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := repo.ensureSnapshotIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := repo.ensureNotificationIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := repo.ensureWebhookIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return repo, nil
 }
 
 // Disconnect zamyka połączenie z bazą danych.
@@ -65,156 +160,522 @@ func (r *PortfolioRepo) Disconnect(ctx context.Context) error {
 	return r.client.Disconnect(ctx)
 }
 
-// SavePortfolio zapisuje lub aktualizuje portfel w bazie danych.
-// Na razie zakładamy, że mamy tylko jeden portfel (lub będziemy używać stałego ID).
-func (r *PortfolioRepo) SavePortfolio(ctx context.Context, portfolio *models.InvestmentPortfolio) error {
-	// Przykład: zawsze zapisujemy portfel z ID "main_portfolio"
-	// W prawdziwej aplikacji ID portfela byłoby związane z użytkownikiem
-	filter := bson.M{"_id": "main_portfolio"}
+// versionFilter wybiera dokument portfela w podanej wersji.
+func versionFilter(portfolioID string, version int64) bson.M {
+	filter := bson.M{"_id": portfolioID, "version": version}
+	if version == 0 {
+		// Dokumenty zapisane przed wprowadzeniem wersji nie mają pola "version"
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	return filter
+}
 
-	// Opcje aktualizacji: Upsert = true oznacza "wstaw, jeśli nie istnieje"
-	opts := options.Update().SetUpsert(true)
+// expectedVersionFilter wybiera dokument portfela w oczekiwanej wersji (AnyVersion - w dowolnej).
+func expectedVersionFilter(portfolioID string, version int64) bson.M {
+	if version == AnyVersion {
+		return bson.M{"_id": portfolioID}
+	}
+	return versionFilter(portfolioID, version)
+}
 
-	// Aktualizacja dokumentu
-	// Używamy $set, aby zaktualizować tylko podane pola, nie nadpisując całego dokumentu
-	// (choć w tym przypadku nadpisanie całego dokumentu też byłoby ok).
-	// Zauważ, że InvestmentPortfolio jest zapisywany bezpośrednio, MongoDB zajmie się mapowaniem.
-	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": portfolio}, opts)
+// unmatchedError wyjaśnia, dlaczego warunkowy zapis nie dopasował dokumentu: jeśli portfel nie ma już
+// oczekiwanej wersji, zmienił się w międzyczasie (ErrConflict), a w przeciwnym razie brakuje zmienianego elementu.
+func (r *PortfolioRepo) unmatchedError(ctx context.Context, portfolioID string, version int64, notFound error) error {
+	if version == AnyVersion {
+		return notFound
+	}
+	count, err := r.collection.CountDocuments(ctx, versionFilter(portfolioID, version), options.Count().SetLimit(1))
 	if err != nil {
-		return fmt.Errorf("failed to save portfolio: %w", err)
+		return fmt.Errorf("failed to check portfolio version: %w", err)
 	}
-	return nil
+	if count == 0 {
+		return ErrConflict
+	}
+	return notFound
 }
 
 // LoadPortfolio ładuje portfel z bazy danych.
-func (r *PortfolioRepo) LoadPortfolio(ctx context.Context) (*models.InvestmentPortfolio, error) {
+func (r *PortfolioRepo) LoadPortfolio(ctx context.Context, portfolioID string) (*models.InvestmentPortfolio, error) {
 	var portfolio models.InvestmentPortfolio
-	filter := bson.M{"_id": "main_portfolio"} // Ładujemy portfel o stałym ID
+	filter := bson.M{"_id": portfolioID} // Ładujemy portfel zalogowanego użytkownika
 
-	err := r.collection.FindOne(ctx, filter).Decode(&portfolio)
+	// Kursy walut są potrzebne do przeliczenia sum na walutę bazową
+	rates, err := r.LoadFXRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.collection.FindOne(ctx, filter).Decode(&portfolio)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			log.Println("No existing portfolio found. Creating a new one.")
-			return models.NewInvestmentPortfolio(), nil // Zwróć nowy, pusty portfel
+			newPortfolio := models.NewInvestmentPortfolio() // Zwróć nowy, pusty portfel
+			newPortfolio.ID = portfolioID
+			newPortfolio.FXRates = models.NewFXRates(rates)
+			return newPortfolio, nil
 		}
 		return nil, fmt.Errorf("failed to load portfolio: %w", err)
 	}
+	portfolio.ID = portfolioID
+	portfolio.FXRates = models.NewFXRates(rates)
 	return &portfolio, nil
 }
 
-// RemoveAsset usuwa aktywo o podanym ID z portfela w bazie danych.
-func (r *PortfolioRepo) RemoveAsset(ctx context.Context, assetID string) error {
-	// 1. Załaduj aktualny portfel
-	portfolio, err := r.LoadPortfolio(ctx)
+// ListPortfolios zwraca portfele należące do użytkownika (posortowane po nazwie).
+func (r *PortfolioRepo) ListPortfolios(ctx context.Context, ownerID string) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(ctx, bson.M{"ownerId": ownerID})
+}
+
+// AllPortfolios zwraca wszystkie portfele (posortowane po nazwie).
+func (r *PortfolioRepo) AllPortfolios(ctx context.Context) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(ctx, bson.M{})
+}
+
+// listPortfolios zwraca portfele pasujące do filtra.
+// Wczytujemy tylko nazwę i właściciela - aktywa i subskrypcje nie są potrzebne w przełączniku.
+func (r *PortfolioRepo) listPortfolios(ctx context.Context, filter bson.M) ([]models.PortfolioInfo, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"name": 1, "ownerId": 1}).
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return fmt.Errorf("failed to load portfolio for asset removal: %w", err)
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
 	}
+	defer cursor.Close(ctx)
 
-	// 2. Znajdź i usuń aktywo z tablicy w pamięci
-	found := false
-	var updatedAssets []models.Asset
-	for _, asset := range portfolio.Assets {
-		if asset.ID == assetID {
-			found = true
-			log.Printf("Found asset to remove: %s", asset.Name)
-		} else {
-			updatedAssets = append(updatedAssets, asset)
-		}
+	infos := []models.PortfolioInfo{}
+	if err := cursor.All(ctx, &infos); err != nil {
+		return nil, fmt.Errorf("failed to decode portfolios: %w", err)
 	}
+	return infos, nil
+}
 
-	if !found {
-		return fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+// CreatePortfolio zakłada nowy, pusty portfel (albo nadaje nazwę i właściciela istniejącemu).
+func (r *PortfolioRepo) CreatePortfolio(ctx context.Context, info models.PortfolioInfo) error {
+	empty := models.NewInvestmentPortfolio()
+	update := bson.M{
+		"$set": bson.M{"name": info.Name, "ownerId": info.OwnerID},
+		"$setOnInsert": bson.M{
+			"assets":        empty.Assets,
+			"subscriptions": empty.Subscriptions,
+			"basecurrency":  empty.BaseCurrency,
+		},
 	}
+	opts := options.Update().SetUpsert(true)
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": info.ID}, update, opts); err != nil {
+		return fmt.Errorf("failed to create portfolio %s: %w", info.Name, err)
+	}
+	log.Printf("Portfolio %s (%s) created.", info.Name, info.ID)
+	return nil
+}
 
-	portfolio.Assets = updatedAssets
-	portfolio.CalculateTotals() // Przelicz wartości portfela po usunięciu
+// ClaimPortfolio nadaje nazwę i właściciela portfelowi, który jeszcze nie ma właściciela.
+func (r *PortfolioRepo) ClaimPortfolio(ctx context.Context, info models.PortfolioInfo) (bool, error) {
+	filter := bson.M{"_id": info.ID, "ownerId": bson.M{"$in": bson.A{"", nil}}}
+	update := bson.M{"$set": bson.M{"name": info.Name, "ownerId": info.OwnerID}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim portfolio %s: %w", info.ID, err)
+	}
+	return result.MatchedCount > 0, nil
+}
 
-	// 3. Zapisz zaktualizowany portfel z powrotem do bazy danych
-	// Używamy SavePortfolio, które jest upsertem i zaktualizuje cały dokument portfela.
-	if err := r.SavePortfolio(ctx, portfolio); err != nil {
-		return fmt.Errorf("failed to save portfolio after asset removal: %w", err)
+// RenamePortfolio zmienia nazwę portfela.
+func (r *PortfolioRepo) RenamePortfolio(ctx context.Context, portfolioID, name string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": portfolioID}, bson.M{"$set": bson.M{"name": name}})
+	if err != nil {
+		return fmt.Errorf("failed to rename portfolio: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
 	}
+	return nil
+}
 
-	log.Printf("Asset with ID %s successfully removed from portfolio.", assetID)
+// DeletePortfolio usuwa dokument portfela (razem z aktywami i subskrypcjami, które są jego częścią)
+// oraz zapisy jego wartości.
+func (r *PortfolioRepo) DeletePortfolio(ctx context.Context, portfolioID string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": portfolioID})
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	if _, err := r.snapshotsCollection.DeleteMany(ctx, bson.M{"portfolioId": portfolioID}); err != nil {
+		return fmt.Errorf("failed to delete portfolio snapshots: %w", err)
+	}
+	log.Printf("Portfolio %s deleted.", portfolioID)
 	return nil
 }
 
-// UpdateAsset aktualizuje aktywo o podanym ID, dodając nową ilość i przeliczając średni koszt zakupu.
-func (r *PortfolioRepo) UpdateAsset(ctx context.Context, assetID string, additionalQuantity, newPurchasePrice float64) error {
-	// 1. Załaduj aktualny portfel
-	portfolio, err := r.LoadPortfolio(ctx)
+// LoadFXRates wczytuje wszystkie zapisane kursy walut.
+func (r *PortfolioRepo) LoadFXRates(ctx context.Context) ([]models.FXRate, error) {
+	cursor, err := r.fxCollection.Find(ctx, bson.M{})
 	if err != nil {
-		return fmt.Errorf("failed to load portfolio for asset update: %w", err)
+		return nil, fmt.Errorf("failed to load fx rates: %w", err)
 	}
+	defer cursor.Close(ctx)
 
-	// 2. Znajdź aktywo do zaktualizowania
-	found := false
-	for i, asset := range portfolio.Assets {
-		if asset.ID == assetID {
-			found = true
+	var rates []models.FXRate
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, fmt.Errorf("failed to decode fx rates: %w", err)
+	}
+	return rates, nil
+}
 
-			// Przelicz nową średnią cenę zakupu
-			// Nowy_AvgCost = ((Stara_Ilosc * Stary_AvgCost) + (Nowa_Ilosc * Nowa_Cena_Zakupu)) / (Stara_Ilosc + Nowa_Ilosc)
-			oldTotalCost := asset.Quantity * asset.AvgCost
-			newTotalCostForAdditional := additionalQuantity * newPurchasePrice
+// SaveFXRate zapisuje (lub nadpisuje) kurs dla pary walutowej.
+func (r *PortfolioRepo) SaveFXRate(ctx context.Context, rate models.FXRate) error {
+	filter := bson.M{"_id": rate.Pair()}
+	opts := options.Update().SetUpsert(true)
 
-			newQuantity := asset.Quantity + additionalQuantity
+	_, err := r.fxCollection.UpdateOne(ctx, filter, bson.M{"$set": rate}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save fx rate %s: %w", rate.Pair(), err)
+	}
+	log.Printf("FX rate %s saved: %s", rate.Pair(), rate.Rate)
+	return nil
+}
 
-			// Unikaj dzielenia przez zero, choć przy quantity > 0 i additionalQuantity > 0 nie powinno się zdarzyć
-			if newQuantity == 0 {
-				return fmt.Errorf("cannot update asset: total quantity would be zero")
-			}
+// RemoveFXRate usuwa kurs dla pary walutowej (np. "USD/PLN").
+func (r *PortfolioRepo) RemoveFXRate(ctx context.Context, pair string) error {
+	result, err := r.fxCollection.DeleteOne(ctx, bson.M{"_id": pair})
+	if err != nil {
+		return fmt.Errorf("failed to remove fx rate %s: %w", pair, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("fx rate %s not found", pair)
+	}
+	return nil
+}
 
-			newAvgCost := (oldTotalCost + newTotalCostForAdditional) / newQuantity
+// UpdateBaseCurrency zmienia walutę bazową portfela (tylko pole "basecurrency") i przelicza sumy w tym samym zapisie.
+func (r *PortfolioRepo) UpdateBaseCurrency(ctx context.Context, portfolioID, currency string) error {
+	_, err := r.arrayUpdate(ctx, bson.M{"_id": portfolioID}, true, bson.M{"$set": bson.M{"basecurrency": currency}}, bumpVersionStage())
+	if err != nil {
+		return fmt.Errorf("failed to update base currency: %w", err)
+	}
 
-			// Zaktualizuj aktywo w pamięci
-			portfolio.Assets[i].Quantity = newQuantity
-			portfolio.Assets[i].AvgCost = newAvgCost
-			//portfolio.Assets[i].CurrentPrice = newAvgCost // Na razie CurrentPrice = AvgCost
+	log.Printf("Base currency changed to %s.", currency)
+	return nil
+}
+
+// UpdateAllocationTargets zastępuje docelowy podział portfela w podanej grupie (tylko elementy tej grupy
+// w tablicy "allocationTargets"), więc cele drugiej grupy zapisane w międzyczasie nie giną.
+func (r *PortfolioRepo) UpdateAllocationTargets(ctx context.Context, portfolioID, group string, targets []models.AllocationTarget) error {
+	// Model sprawdza cele i wybiera te do zapisania - na pustym portfelu zostają tylko cele tej grupy
+	validated := models.NewInvestmentPortfolio()
+	if err := validated.SetAllocationTargets(group, targets); err != nil {
+		return err
+	}
+	kept := validated.AllocationTargets
+	if kept == nil {
+		kept = []models.AllocationTarget{}
+	}
+
+	replaceGroup := bson.M{"$set": bson.M{"allocationTargets": bson.M{"$concatArrays": bson.A{
+		bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$allocationTargets", bson.A{}}},
+			"as":    "target",
+			"cond":  bson.M{"$ne": bson.A{"$$target.group", group}},
+		}},
+		bson.M{"$literal": kept},
+	}}}}
+	if _, err := r.arrayUpdate(ctx, bson.M{"_id": portfolioID}, true, replaceGroup, bumpVersionStage()); err != nil {
+		return fmt.Errorf("failed to update allocation targets: %w", err)
+	}
+
+	log.Printf("Allocation targets by %s updated.", group)
+	return nil
+}
+
+// RecordSubscriptionPayment zapisuje płatność subskrypcji i opcjonalnie przesuwa jej termin (markPaid).
+// Zapisuje tylko pola "payments", "nextDue" i "pastCharges" tej subskrypcji, pod warunkiem, że nadal ma
+// wczytany termin i liczbę płatności - inaczej ktoś w międzyczasie ją rozliczył i zwracamy ErrConflict.
+func (r *PortfolioRepo) RecordSubscriptionPayment(ctx context.Context, portfolioID string, version int64, subID string, payment models.SubscriptionPayment, markPaid bool) error {
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to load portfolio for subscription payment: %w", err)
+	}
+	if version != AnyVersion && portfolio.Version != version {
+		return ErrConflict
+	}
+	previousDue := subscriptionDueDates(portfolio)[subID]
+	previousPayments := 0
+	for _, sub := range portfolio.Subscriptions {
+		if sub.ID == subID {
+			previousPayments = len(sub.Payments)
+		}
+	}
+	if err := portfolio.RecordSubscriptionPayment(subID, payment, markPaid); err != nil {
+		return err
+	}
 
-			log.Printf("Asset %s updated: New Quantity=%.2f, New AvgCost=%.2f", asset.Name, newQuantity, newAvgCost)
-			break
+	for _, sub := range portfolio.Subscriptions {
+		if sub.ID != subID {
+			continue
+		}
+		guard := sizeGuard("payments", previousPayments)
+		guard["_id"] = subID
+		guard["nextDue"] = previousDue
+		filter := expectedVersionFilter(portfolioID, version)
+		filter["subscriptions"] = bson.M{"$elemMatch": guard}
+		fields := bson.M{
+			"payments":    sub.Payments,
+			"nextDue":     sub.NextDue,
+			"pastCharges": sub.PastCharges,
+		}
+		result, err := r.arrayUpdate(ctx, filter, false, setElementFields("subscriptions", subID, fields), bumpVersionStage())
+		if err != nil {
+			return fmt.Errorf("failed to save subscription payment: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return ErrConflict
 		}
 	}
+	return nil
+}
 
-	if !found {
-		return fmt.Errorf("asset with ID %s not found in portfolio for update", assetID)
+// UpdateEmergencyFundSettings zapisuje ustawienia poduszki finansowej portfela (tylko pole "emergencyFund").
+func (r *PortfolioRepo) UpdateEmergencyFundSettings(ctx context.Context, portfolioID string, settings models.EmergencyFundSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	update := bson.M{
+		"$set": bson.M{"emergencyFund": settings},
+		"$inc": bson.M{"version": 1},
 	}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": portfolioID}, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to update emergency fund settings: %w", err)
+	}
+	return nil
+}
 
-	portfolio.CalculateTotals() // Przelicz wartości portfela po aktualizacji
+// AddAsset dopisuje aktywo na koniec tablicy "assets", nie przepisując reszty dokumentu.
+// Przy oczekiwanej wersji portfel w innej wersji nie pasuje do filtra, a upsert próbuje wstawić drugi
+// dokument o tym samym _id - błąd duplikatu klucza oznacza więc konflikt.
+func (r *PortfolioRepo) AddAsset(ctx context.Context, portfolioID string, version int64, asset models.Asset) error {
+	// Metoda rozliczania partii jest potrzebna do wyliczenia pozycji z transakcji otwarcia
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to load portfolio for asset addition: %w", err)
+	}
+	if version != AnyVersion && portfolio.Version != version {
+		return ErrConflict
+	}
+	asset = models.PrepareAsset(asset, portfolio.CostBasisMethod)
 
-	// 3. Zapisz zaktualizowany portfel z powrotem do bazy danych
-	if err := r.SavePortfolio(ctx, portfolio); err != nil {
-		return fmt.Errorf("failed to save portfolio after asset update: %w", err)
+	_, err = r.arrayUpdate(ctx, expectedVersionFilter(portfolioID, version), true, appendElement("assets", asset), bumpVersionStage())
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to add asset: %w", err)
 	}
 
+	log.Printf("Asset %s added to portfolio.", asset.Name)
 	return nil
 }
 
-// UpdateAssetCurrentPrice aktualizuje cenę bieżącą dla danego aktywa.
-func (r *PortfolioRepo) UpdateAssetCurrentPrice(ctx context.Context, assetID string, newPrice float64) error {
-	// The filter to find the main portfolio document.
-	filter := bson.M{"_id": "main_portfolio"}
+// RemoveAsset usuwa aktywo o podanym ID z tablicy "assets".
+func (r *PortfolioRepo) RemoveAsset(ctx context.Context, portfolioID string, version int64, assetID string) error {
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["assets._id"] = assetID
 
-	// The filter to identify the specific asset within the 'assets' array.
-	// and the update operation to set the new 'currentPrice'.
-	update := bson.M{
-		"$set": bson.M{"assets.$[elem].currentPrice": newPrice},
+	result, err := r.arrayUpdate(ctx, filter, false, removeElement("assets", assetID), bumpVersionStage())
+	if err != nil {
+		return fmt.Errorf("failed to remove asset: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, portfolioID, version, fmt.Errorf("asset with ID %s not found in portfolio", assetID))
 	}
 
-	// arrayFilters specifies the condition to find the correct element in the array.
-	// In this case, it finds the asset with the matching _id.
-	opts := options.Update().SetArrayFilters(options.ArrayFilters{
-		Filters: []interface{}{bson.M{"elem._id": assetID}},
+	log.Printf("Asset with ID %s successfully removed from portfolio.", assetID)
+	return nil
+}
+
+// modifyAsset wczytuje portfel, zmienia rejestr transakcji jednego aktywa i zapisuje tylko pola wyliczane
+// z rejestru ("transactions", "quantity", "avgCost" i "realizedPL" wskazanego aktywa), więc równoległa
+// zmiana ceny, typu portfela czy alertów tego aktywa nie zostaje nadpisana. Zapis jest warunkowy: aktywo musi
+// mieć w bazie dokładnie te transakcje, które wczytano - inaczej ktoś w międzyczasie zmienił jego rejestr
+// i zwracamy ErrConflict. Zmiany innych aktywów i subskrypcji nie powodują konfliktu, chyba że podano
+// oczekiwaną wersję portfela (AnyVersion - bez tego warunku).
+func (r *PortfolioRepo) modifyAsset(ctx context.Context, portfolioID string, version int64, assetID string, change func(asset *models.Asset, method models.CostBasisMethod) error) (*models.Asset, error) {
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load portfolio: %w", err)
+	}
+	if version != AnyVersion && portfolio.Version != version {
+		return nil, ErrConflict
+	}
+	asset, found := portfolio.FindAsset(assetID)
+	if !found {
+		return nil, fmt.Errorf("asset with ID %s not found in portfolio", assetID)
+	}
+
+	ledger := ledgerGuard(*asset)
+
+	if err := change(asset, portfolio.CostBasisMethod); err != nil {
+		return nil, err
+	}
+
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["assets"] = bson.M{"$elemMatch": ledger}
+	fields := bson.M{
+		"transactions": asset.Transactions,
+		"quantity":     asset.Quantity,
+		"avgCost":      asset.AvgCost,
+		"realizedPL":   asset.RealizedPL,
+	}
+	result, err := r.arrayUpdate(ctx, filter, false, setElementFields("assets", assetID, fields), bumpVersionStage())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save asset %s: %w", asset.Name, err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrConflict
+	}
+	return asset, nil
+}
+
+// UpdateAsset dokupuje jednostki aktywa, zapisując transakcję kupna w rejestrze.
+// Ilość i średni koszt zakupu są wyliczane z rejestru, więc historia zakupów nie ginie.
+func (r *PortfolioRepo) UpdateAsset(ctx context.Context, portfolioID string, version int64, assetID string, additionalQuantity, newPurchasePrice models.Decimal) error {
+	return r.AddTransaction(ctx, portfolioID, version, assetID, models.Transaction{
+		Type:     models.TransactionBuy,
+		Date:     models.Today(),
+		Quantity: additionalQuantity,
+		Price:    newPurchasePrice,
 	})
+}
 
-	result, err := r.collection.UpdateOne(ctx, filter, update, opts)
+// SellAsset sprzedaje (częściowo lub całkowicie) pozycję, zapisując transakcję sprzedaży z prowizją.
+// Zrealizowany zysk/strata jest wyliczany z rejestru, więc nie trzeba go tu przechowywać.
+func (r *PortfolioRepo) SellAsset(ctx context.Context, portfolioID string, version int64, assetID string, quantity, price, fee models.Decimal, date time.Time) error {
+	return r.AddTransaction(ctx, portfolioID, version, assetID, models.Transaction{
+		Type:     models.TransactionSell,
+		Date:     date,
+		Quantity: quantity,
+		Price:    price,
+		Fee:      fee,
+	})
+}
+
+// AddTransaction dopisuje transakcję do rejestru aktywa i przelicza jego pozycję, o ile portfel ma oczekiwaną wersję.
+func (r *PortfolioRepo) AddTransaction(ctx context.Context, portfolioID string, version int64, assetID string, tx models.Transaction) error {
+	asset, err := r.modifyAsset(ctx, portfolioID, version, assetID, func(asset *models.Asset, method models.CostBasisMethod) error {
+		if err := asset.AddTransaction(tx, method); err != nil {
+			return fmt.Errorf("cannot add transaction to asset %s: %w", asset.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Asset %s updated with %s transaction: New Quantity=%s, New AvgCost=%s", asset.Name, tx.Type, asset.Quantity, asset.AvgCost)
+	return nil
+}
+
+// RemoveTransaction usuwa transakcję z rejestru aktywa (np. w celu poprawienia pomyłki).
+func (r *PortfolioRepo) RemoveTransaction(ctx context.Context, portfolioID string, version int64, assetID, transactionID string) error {
+	asset, err := r.modifyAsset(ctx, portfolioID, version, assetID, func(asset *models.Asset, method models.CostBasisMethod) error {
+		return asset.RemoveTransaction(transactionID, method)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Transaction with ID %s removed from asset %s.", transactionID, asset.Name)
+	return nil
+}
+
+// UpdateCostBasisMethod zmienia metodę rozliczania partii portfela i przelicza pozycje. Zapisuje tylko metodę
+// i pola wyliczane z rejestrów ("quantity", "avgCost", "realizedPL" każdego aktywa), przeliczając sumy
+// w tym samym zapisie. Zapis jest warunkowy: portfel musi mieć nadal te same aktywa z tymi samymi
+// transakcjami - inaczej pozycje wyliczono z nieaktualnych rejestrów i zwracamy ErrConflict.
+func (r *PortfolioRepo) UpdateCostBasisMethod(ctx context.Context, portfolioID string, method models.CostBasisMethod) error {
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to load portfolio for cost basis method update: %w", err)
+	}
+
+	guards := bson.A{sizeGuard("assets", len(portfolio.Assets))}
+	for _, asset := range portfolio.Assets {
+		guards = append(guards, bson.M{"assets": bson.M{"$elemMatch": ledgerGuard(asset)}})
+	}
+
+	if err := portfolio.SetCostBasisMethod(method); err != nil {
+		return err
+	}
+
+	stages := []bson.M{{"$set": bson.M{"costbasismethod": method}}}
+	for _, asset := range portfolio.Assets {
+		stages = append(stages, setElementFields("assets", asset.ID, bson.M{
+			"quantity":   asset.Quantity,
+			"avgCost":    asset.AvgCost,
+			"realizedPL": asset.RealizedPL,
+		}))
+	}
+	stages = append(stages, bumpVersionStage())
+
+	// Jak w AddAsset: upsert zakłada brakujący portfel, a portfel o innych aktywach kończy się duplikatem klucza
+	if _, err := r.arrayUpdate(ctx, bson.M{"_id": portfolioID, "$and": guards}, true, stages...); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to update cost basis method: %w", err)
+	}
+
+	log.Printf("Cost basis method changed to %s.", method)
+	return nil
+}
+
+// ledgerGuard dopasowuje element tablicy "assets" z dokładnie tymi transakcjami (liczba i ID), które ma asset.
+func ledgerGuard(asset models.Asset) bson.M {
+	guard := sizeGuard("transactions", len(asset.Transactions))
+	guard["_id"] = asset.ID
+	if len(asset.Transactions) > 0 {
+		// Ta sama liczba transakcji to za mało (usunięcie jednej i dodanie innej) - sprawdzamy też ich ID
+		ids := make(bson.A, 0, len(asset.Transactions))
+		for _, t := range asset.Transactions {
+			ids = append(ids, t.ID)
+		}
+		guard["transactions._id"] = bson.M{"$all": ids}
+	}
+	return guard
+}
+
+// sizeGuard dopasowuje dokument, którego tablica field ma n elementów. Pusta tablica pasuje także
+// do brakującego pola (dane zapisane przed jego wprowadzeniem).
+func sizeGuard(field string, n int) bson.M {
+	if n == 0 {
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$size": 0}},
+			bson.M{field: nil},
+		}}
+	}
+	return bson.M{field: bson.M{"$size": n}}
+}
+
+// UpdateAssetCurrentPrice aktualizuje cenę bieżącą dla danego aktywa (bez zmiany wersji portfela).
+func (r *PortfolioRepo) UpdateAssetCurrentPrice(ctx context.Context, portfolioID, assetID string, quote models.PriceQuote) error {
+	// The filter to find the user's portfolio document containing the asset.
+	filter := bson.M{"_id": portfolioID, "assets._id": assetID}
+
+	// Set the quote fields of the matching asset and recalculate the totals in the same update.
+	fields := bson.M{
+		"currentPrice":   quote.Price,
+		"priceSource":    quote.Source,
+		"priceUpdatedAt": quote.Time,
+	}
+	result, err := r.arrayUpdate(ctx, filter, false, setElementFields("assets", assetID, fields))
 	if err != nil {
 		return fmt.Errorf("failed to update asset price in db: %w", err)
 	}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("asset with ID %s not found or price is already the same", assetID)
+	// Ta sama cena nie zmienia dokumentu, więc brak aktywa rozpoznajemy po MatchedCount
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("asset with ID %s not found", assetID)
 	}
 
 	log.Printf("Successfully updated current price for asset ID %s", assetID)
@@ -222,14 +683,16 @@ func (r *PortfolioRepo) UpdateAssetCurrentPrice(ctx context.Context, assetID str
 }
 
 // UpdateAssetWalletType aktualizuje przypisanie do danego typu portfela dla danego aktywa.
-func (r *PortfolioRepo) UpdateAssetWalletType(ctx context.Context, assetID string, newWalletType string) error {
-	// The filter to find the main portfolio document.
-	filter := bson.M{"_id": "main_portfolio"}
+func (r *PortfolioRepo) UpdateAssetWalletType(ctx context.Context, portfolioID string, version int64, assetID string, newWalletType string) error {
+	// The filter to find the user's portfolio document (in the expected version) containing the asset.
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["assets._id"] = assetID
 
 	// The filter to identify the specific asset within the 'assets' array.
-	// and the update operation to set the new 'currentPrice'.
+	// and the update operation to set the new 'walletType'.
 	update := bson.M{
 		"$set": bson.M{"assets.$[elem].walletType": newWalletType},
+		"$inc": bson.M{"version": 1},
 	}
 
 	// arrayFilters specifies the condition to find the correct element in the array.
@@ -243,74 +706,194 @@ func (r *PortfolioRepo) UpdateAssetWalletType(ctx context.Context, assetID strin
 		return fmt.Errorf("failed to update asset wallet type in db: %w", err)
 	}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("asset with ID %s not found or type is already the same", assetID)
+	if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, portfolioID, version, fmt.Errorf("asset with ID %s not found", assetID))
 	}
 
 	log.Printf("Successfully updated wallet type for asset ID %s", assetID)
 	return nil
 }
 
-// RemoveSubscription usuwa subskrypcję o podanym ID z portfela w bazie danych.
-func (r *PortfolioRepo) RemoveSubscription(ctx context.Context, subID string) error {
-	portfolio, err := r.LoadPortfolio(ctx)
+// PatchAsset zmienia typ portfela i cenę bieżącą aktywa jednym zapisem (tylko te pola wskazanego aktywa),
+// przeliczając w nim także sumy portfela.
+func (r *PortfolioRepo) PatchAsset(ctx context.Context, portfolioID string, version int64, assetID string, patch models.AssetPatch) error {
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["assets._id"] = assetID
+
+	fields := bson.M{}
+	if patch.WalletType != nil {
+		fields["walletType"] = *patch.WalletType
+	}
+	if patch.Quote != nil {
+		fields["currentPrice"] = patch.Quote.Price
+		fields["priceSource"] = patch.Quote.Source
+		fields["priceUpdatedAt"] = patch.Quote.Time
+	}
+	result, err := r.arrayUpdate(ctx, filter, false, setElementFields("assets", assetID, fields), bumpVersionStage())
 	if err != nil {
-		return fmt.Errorf("failed to load portfolio for subscription removal: %w", err)
+		return fmt.Errorf("failed to patch asset in db: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, portfolioID, version, fmt.Errorf("asset with ID %s not found", assetID))
 	}
+	return nil
+}
 
-	found := false
-	var updatedSubscriptions []models.Subscription
-	for _, sub := range portfolio.Subscriptions {
-		if sub.ID == subID {
-			found = true
-			log.Printf("Found subscription to remove: %s", sub.Name)
-		} else {
-			updatedSubscriptions = append(updatedSubscriptions, sub)
-		}
+// UpdateAssetAlerts zastępuje alerty cenowe aktywa (tylko pole "alerts" wskazanego aktywa).
+func (r *PortfolioRepo) UpdateAssetAlerts(ctx context.Context, portfolioID string, version int64, assetID string, alerts []models.PriceAlert) error {
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["assets._id"] = assetID
+	update := bson.M{
+		"$set": bson.M{"assets.$.alerts": alerts},
+		"$inc": bson.M{"version": 1},
 	}
 
-	if !found {
-		return fmt.Errorf("subscription with ID %s not found in portfolio", subID)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update asset alerts: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, portfolioID, version, fmt.Errorf("asset with ID %s not found", assetID))
+	}
+	return nil
+}
+
+// UpdateAlertState zapisuje stan alertu (pola w tablicy "alerts" aktywa), jeśli nie zmienił się od oceny.
+// Warunek na poprzedni stan jest częścią filtra, więc z równoległych ocen zapis udaje się tylko jednej.
+func (r *PortfolioRepo) UpdateAlertState(ctx context.Context, portfolioID, assetID string, previous, next models.PriceAlert) (bool, error) {
+	filter := bson.M{
+		"_id": portfolioID,
+		"assets": bson.M{"$elemMatch": bson.M{
+			"_id": assetID,
+			"alerts": bson.M{"$elemMatch": bson.M{
+				"_id":             next.ID,
+				"triggered":       previous.Triggered,
+				"lastTriggeredAt": previous.LastTriggeredAt,
+			}},
+		}},
+	}
+	update := bson.M{"$set": bson.M{
+		"assets.$[asset].alerts.$[alert].triggered":       next.Triggered,
+		"assets.$[asset].alerts.$[alert].lastTriggeredAt": next.LastTriggeredAt,
+	}}
+	arrayFilters := options.ArrayFilters{Filters: bson.A{bson.M{"asset._id": assetID}, bson.M{"alert._id": next.ID}}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetArrayFilters(arrayFilters))
+	if err != nil {
+		return false, fmt.Errorf("failed to update alert state: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// AddSubscription dopisuje subskrypcję na koniec tablicy "subscriptions".
+func (r *PortfolioRepo) AddSubscription(ctx context.Context, portfolioID string, version int64, sub models.Subscription) error {
+	_, err := r.arrayUpdate(ctx, expectedVersionFilter(portfolioID, version), true, appendElement("subscriptions", sub), bumpVersionStage())
+	if err != nil {
+		// Jak w AddAsset: portfel w innej wersji niż oczekiwana kończy upsert duplikatem klucza
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to add subscription: %w", err)
 	}
 
-	portfolio.Subscriptions = updatedSubscriptions
-	portfolio.CalculateTotals() // Przelicz wartości portfela po usunięciu
+	log.Printf("Subscription %s added to portfolio.", sub.Name)
+	return nil
+}
 
-	if err := r.SavePortfolio(ctx, portfolio); err != nil {
-		return fmt.Errorf("failed to save portfolio after subscription removal: %w", err)
+// RemoveSubscription usuwa subskrypcję o podanym ID z tablicy "subscriptions".
+func (r *PortfolioRepo) RemoveSubscription(ctx context.Context, portfolioID string, version int64, subID string) error {
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["subscriptions._id"] = subID
+
+	result, err := r.arrayUpdate(ctx, filter, false, removeElement("subscriptions", subID), bumpVersionStage())
+	if err != nil {
+		return fmt.Errorf("failed to remove subscription: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return r.unmatchedError(ctx, portfolioID, version, fmt.Errorf("subscription with ID %s not found in portfolio", subID))
 	}
 
 	log.Printf("Subscription with ID %s successfully removed from portfolio.", subID)
 	return nil
 }
 
-// UpdateSubscription aktualizuje subskrypcję o podanym ID.
-func (r *PortfolioRepo) UpdateSubscription(ctx context.Context, updatedSub models.Subscription) error {
-	portfolio, err := r.LoadPortfolio(ctx)
+// UpdateSubscription zmienia edytowalne pola subskrypcji o podanym ID (jeden element tablicy "subscriptions").
+// Przesunięcie minionych terminów dopisuje je do zapisanej historii, więc zapis jest warunkowy: subskrypcja
+// musi mieć nadal wczytany termin (historię zmieniają tylko zapisy, które przesuwają też termin).
+func (r *PortfolioRepo) UpdateSubscription(ctx context.Context, portfolioID string, version int64, updatedSub models.Subscription) error {
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
 	if err != nil {
 		return fmt.Errorf("failed to load portfolio for subscription update: %w", err)
 	}
+	if version != AnyVersion && portfolio.Version != version {
+		return ErrConflict
+	}
+	previousDue := subscriptionDueDates(portfolio)[updatedSub.ID]
+	sub, err := portfolio.EditSubscription(updatedSub, models.Today())
+	if err != nil {
+		return err
+	}
 
-	found := false
-	for i, sub := range portfolio.Subscriptions {
-		if sub.ID == updatedSub.ID {
-			// Zastąp starą subskrypcję nową
-			portfolio.Subscriptions[i] = updatedSub
-			found = true
-			log.Printf("Subscription %s updated.", updatedSub.Name)
-			break
-		}
+	filter := expectedVersionFilter(portfolioID, version)
+	filter["subscriptions"] = bson.M{"$elemMatch": bson.M{"_id": sub.ID, "nextDue": previousDue}}
+	fields := bson.M{
+		"name":         sub.Name,
+		"cost":         sub.Cost,
+		"currency":     sub.Currency,
+		"frequency":    sub.Frequency,
+		"intervalDays": sub.IntervalDays,
+		"nextDue":      sub.NextDue,
+		"reminderDays": sub.ReminderDays,
+		"pastCharges":  sub.PastCharges,
+	}
+	result, err := r.arrayUpdate(ctx, filter, false, setElementFields("subscriptions", sub.ID, fields), bumpVersionStage())
+	if err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrConflict
 	}
 
-	if !found {
-		return fmt.Errorf("subscription with ID %s not found in portfolio for update", updatedSub.ID)
+	log.Printf("Subscription %s updated.", sub.Name)
+	return nil
+}
+
+// RollOverSubscriptions przesuwa minione terminy subskrypcji portfela jednym zapisem,
+// pod warunkiem, że żaden z przesuwanych terminów nie zmienił się od wczytania.
+func (r *PortfolioRepo) RollOverSubscriptions(ctx context.Context, portfolioID string, today time.Time) ([]models.Subscription, error) {
+	portfolio, err := r.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load portfolio for subscription rollover: %w", err)
+	}
+	previousDue := subscriptionDueDates(portfolio)
+	rolled := portfolio.RollOverSubscriptions(today)
+	if len(rolled) == 0 {
+		return nil, nil
 	}
 
-	portfolio.CalculateTotals() // Przelicz wartości portfela po aktualizacji
+	guards := bson.A{}
+	stages := []bson.M{}
+	for _, sub := range rolled {
+		guards = append(guards, bson.M{"subscriptions": bson.M{"$elemMatch": bson.M{"_id": sub.ID, "nextDue": previousDue[sub.ID]}}})
+		stages = append(stages, setElementFields("subscriptions", sub.ID, bson.M{"nextDue": sub.NextDue, "pastCharges": sub.PastCharges}))
+	}
+	stages = append(stages, bumpVersionStage())
 
-	if err := r.SavePortfolio(ctx, portfolio); err != nil {
-		return fmt.Errorf("failed to save portfolio after subscription update: %w", err)
+	result, err := r.arrayUpdate(ctx, bson.M{"_id": portfolioID, "$and": guards}, false, stages...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll over subscriptions: %w", err)
 	}
+	if result.MatchedCount == 0 {
+		return nil, ErrConflict
+	}
+	return rolled, nil
+}
 
-	return nil
+// subscriptionDueDates zwraca terminy subskrypcji portfela według ID.
+func subscriptionDueDates(portfolio *models.InvestmentPortfolio) map[string]time.Time {
+	due := make(map[string]time.Time, len(portfolio.Subscriptions))
+	for _, sub := range portfolio.Subscriptions {
+		due[sub.ID] = sub.NextDue
+	}
+	return due
 }
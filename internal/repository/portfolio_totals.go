@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// Sumy portfela (totalvalue, totalcost, monthlysubscriptioncost, realizedprofitloss) są zapisywane w dokumencie
+// portfela. Operacje na tablicach aktywów i subskrypcji zmieniają dokument potokiem agregacji, którego ostatni
+// etap przelicza sumy z tablic po zmianie - w tym samym zapisie, więc równoległy zapis nie może ich rozjechać.
+// Wzory odpowiadają models.InvestmentPortfolio.CalculateTotals (kwoty zaokrąglane do models.DecimalPlaces miejsc;
+// MongoDB zaokrągla połówki do parzystej, więc wynik może różnić się o jednostkę na ostatnim miejscu).
+
+// arrayUpdate zmienia dokument portfela potokiem: etapy stages zmieniają tablice, a ostatni etap przelicza sumy.
+// Zwraca wynik UpdateOne, żeby wywołujący mógł rozpoznać brak dopasowania.
+func (r *PortfolioRepo) arrayUpdate(ctx context.Context, filter bson.M, upsert bool, stages ...bson.M) (*mongo.UpdateResult, error) {
+	rates, err := r.LoadFXRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := bson.A{}
+	for _, stage := range stages {
+		pipeline = append(pipeline, stage)
+	}
+	pipeline = append(pipeline, totalsStage(rates))
+	return r.collection.UpdateOne(ctx, filter, pipeline, options.Update().SetUpsert(upsert))
+}
+
+// bumpVersionStage podbija wersję portfela (dokumenty sprzed wprowadzenia wersji mają wersję 0).
+func bumpVersionStage() bson.M {
+	return bson.M{"$set": bson.M{"version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}}}
+}
+
+// appendElement dopisuje dokument na koniec tablicy (odpowiednik $push).
+func appendElement(array string, element any) bson.M {
+	return bson.M{"$set": bson.M{array: bson.M{"$concatArrays": bson.A{
+		bson.M{"$ifNull": bson.A{"$" + array, bson.A{}}},
+		bson.A{bson.M{"$literal": element}},
+	}}}}
+}
+
+// removeElement usuwa z tablicy element o podanym _id (odpowiednik $pull).
+func removeElement(array, id string) bson.M {
+	return bson.M{"$set": bson.M{array: bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$" + array, bson.A{}}},
+		"as":    "element",
+		"cond":  bson.M{"$ne": bson.A{"$$element._id", id}},
+	}}}}
+}
+
+// replaceElement zastępuje element tablicy o podanym _id nowym dokumentem.
+func replaceElement(array, id string, element any) bson.M {
+	return bson.M{"$set": bson.M{array: bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$" + array, bson.A{}}},
+		"as":    "element",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$$element._id", id}},
+			bson.M{"$literal": element},
+			"$$element",
+		}},
+	}}}}
+}
+
+// setElementFields ustawia pola elementu tablicy o podanym _id, nie zmieniając pozostałych
+// (odpowiednik pozycyjnego $set; w potoku nie ma operatora $).
+func setElementFields(array, id string, fields bson.M) bson.M {
+	return bson.M{"$set": bson.M{array: bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$" + array, bson.A{}}},
+		"as":    "element",
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$$element._id", id}},
+			bson.M{"$mergeObjects": bson.A{"$$element", bson.M{"$literal": fields}}},
+			"$$element",
+		}},
+	}}}}
+}
+
+// totalsStage przelicza sumy portfela z tablic "assets" i "subscriptions" na walutę bazową.
+// Kursy walut są przechowywane osobno, więc dołączamy je do potoku jako tabelę przeliczników
+// wyliczoną z tych samych kursów co models.FXRates.Convert.
+func totalsStage(rates []models.FXRate) bson.M {
+	asset := func(field string) bson.M {
+		return bson.M{"$ifNull": bson.A{"$$asset." + field, 0}}
+	}
+	assetSum := func(amount any) bson.M {
+		return bson.M{"$sum": bson.M{"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$assets", bson.A{}}},
+			"as":    "asset",
+			"in":    toBaseExpr(amount, currencyExpr("$$asset.currency")),
+		}}}
+	}
+	return bson.M{"$set": bson.M{
+		"totalvalue":         withFXTable(rates, assetSum(roundExpr(bson.M{"$multiply": bson.A{asset("quantity"), asset("currentPrice")}}))),
+		"totalcost":          withFXTable(rates, assetSum(roundExpr(bson.M{"$multiply": bson.A{asset("quantity"), asset("avgCost")}}))),
+		"realizedprofitloss": withFXTable(rates, assetSum(asset("realizedPL"))),
+		"monthlysubscriptioncost": withFXTable(rates, bson.M{"$sum": bson.M{"$map": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$subscriptions", bson.A{}}},
+			"as":    "sub",
+			"in":    toBaseExpr(monthlyCostExpr(), currencyExpr("$$sub.currency")),
+		}}}),
+	}}
+}
+
+// withFXTable udostępnia wyrażeniu zmienne $$base (waluta bazowa portfela) i $$fx (tabela przeliczników).
+func withFXTable(rates []models.FXRate, expr any) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{
+			"base": currencyExpr("$basecurrency"),
+			"fx":   bson.M{"$literal": fxTable(rates)},
+		},
+		"in": expr,
+	}}
+}
+
+// fxTable wylicza przeliczniki pomiędzy każdą parą walut występujących w kursach (i PLN).
+func fxTable(rates []models.FXRate) bson.A {
+	fx := models.NewFXRates(rates)
+	set := map[string]bool{models.DefaultCurrency: true}
+	for _, rate := range rates {
+		set[rate.Base] = true
+		set[rate.Quote] = true
+	}
+	currencies := make([]string, 0, len(set))
+	for currency := range set {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	table := bson.A{}
+	for _, from := range currencies {
+		for _, to := range currencies {
+			if from == to {
+				continue
+			}
+			rate, divide, ok := fx.Factor(from, to)
+			if !ok {
+				continue
+			}
+			table = append(table, bson.M{"from": from, "to": to, "rate": rate, "divide": divide})
+		}
+	}
+	return table
+}
+
+// toBaseExpr przelicza kwotę z waluty currency na walutę bazową $$base tak jak InvestmentPortfolio.ToBase:
+// kwota bez kursu jest pomijana (liczona jako 0).
+func toBaseExpr(amount, currency any) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{
+			"amount":   amount,
+			"currency": currency,
+		},
+		"in": bson.M{"$let": bson.M{
+			"vars": bson.M{"factor": bson.M{"$arrayElemAt": bson.A{
+				bson.M{"$filter": bson.M{
+					"input": "$$fx",
+					"as":    "rate",
+					"cond": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$$rate.from", "$$currency"}},
+						bson.M{"$eq": bson.A{"$$rate.to", "$$base"}},
+					}},
+				}},
+				0,
+			}}},
+			"in": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$eq": bson.A{"$$currency", "$$base"}}, "then": "$$amount"},
+					bson.M{"case": bson.M{"$eq": bson.A{"$$factor.divide", true}}, "then": roundExpr(bson.M{"$divide": bson.A{"$$amount", "$$factor.rate"}})},
+					bson.M{"case": bson.M{"$eq": bson.A{"$$factor.divide", false}}, "then": roundExpr(bson.M{"$multiply": bson.A{"$$amount", "$$factor.rate"}})},
+				},
+				"default": 0,
+			}},
+		}},
+	}}
+}
+
+// monthlyCostExpr wylicza miesięczny koszt subskrypcji $$sub tak jak models.Subscription.MonthlyCost,
+// rozpoznając także polskie nazwy częstotliwości z danych sprzed wprowadzenia typów.
+func monthlyCostExpr() bson.M {
+	cost := bson.M{"$ifNull": bson.A{"$$sub.cost", 0}}
+	days := bson.M{"$ifNull": bson.A{"$$sub.intervalDays", 0}}
+	frequency := bson.M{"$toLower": bson.M{"$trim": bson.M{"input": bson.M{"$ifNull": bson.A{"$$sub.frequency", ""}}}}}
+
+	branches := bson.A{}
+	for _, known := range models.SubscriptionFrequencies() {
+		names := bson.A{string(known), strings.ToLower(known.Label())}
+		matches := bson.M{"$in": bson.A{"$$frequency", names}}
+		if known == models.FrequencyCustom {
+			branches = append(branches, bson.M{
+				"case": matches,
+				"then": bson.M{"$cond": bson.A{
+					bson.M{"$lt": bson.A{days, 1}},
+					0,
+					roundExpr(bson.M{"$divide": bson.A{bson.M{"$multiply": bson.A{cost, 365}}, bson.M{"$multiply": bson.A{12, days}}}}),
+				}},
+			})
+			continue
+		}
+		branches = append(branches, bson.M{
+			"case": matches,
+			"then": roundExpr(bson.M{"$divide": bson.A{bson.M{"$multiply": bson.A{cost, known.PaymentsPerYear()}}, 12}}),
+		})
+	}
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"frequency": frequency},
+		"in":   bson.M{"$switch": bson.M{"branches": branches, "default": 0}},
+	}}
+}
+
+// currencyExpr zwraca kod waluty z pola, przyjmując PLN dla pustego pola (dane sprzed wprowadzenia walut).
+func currencyExpr(field string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{field, ""}}, ""}},
+		models.DefaultCurrency,
+		field,
+	}}
+}
+
+// roundExpr zaokrągla kwotę do tylu miejsc po przecinku, ile przechowuje models.Decimal.
+func roundExpr(amount any) bson.M {
+	return bson.M{"$round": bson.A{amount, models.DecimalPlaces}}
+}
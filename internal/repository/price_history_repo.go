@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ensurePriceHistoryIndexes zakłada unikalny indeks (symbol, dzień) - jeden punkt historii na dzień,
+// który służy też do zapytań o przedział dat.
+func (r *PortfolioRepo) ensurePriceHistoryIndexes(ctx context.Context) error {
+	_, err := r.priceHistoryCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "symbol", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create price history index: %w", err)
+	}
+	return nil
+}
+
+// RecordPrice zapisuje cenę symbolu (upsert po symbolu i dniu).
+func (r *PortfolioRepo) RecordPrice(ctx context.Context, point models.PricePoint) error {
+	filter := bson.M{"symbol": point.Symbol, "date": point.Date}
+	opts := options.Update().SetUpsert(true)
+	if _, err := r.priceHistoryCollection.UpdateOne(ctx, filter, bson.M{"$set": point}, opts); err != nil {
+		return fmt.Errorf("failed to record price of %s: %w", point.Symbol, err)
+	}
+	return nil
+}
+
+// PriceHistory zwraca historię cen symbolu z podanego przedziału dat.
+func (r *PortfolioRepo) PriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]models.PricePoint, error) {
+	filter := bson.M{"symbol": models.NormalizeSymbol(symbol)}
+	dateRange := bson.M{}
+	if !from.IsZero() {
+		dateRange["$gte"] = from
+	}
+	if !to.IsZero() {
+		dateRange["$lte"] = to
+	}
+	if len(dateRange) > 0 {
+		filter["date"] = dateRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := r.priceHistoryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price history of %s: %w", symbol, err)
+	}
+	defer cursor.Close(ctx)
+
+	points := []models.PricePoint{}
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode price history of %s: %w", symbol, err)
+	}
+	return points, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ReminderSent sprawdza, czy przypomnienie o podanym kluczu zostało już wysłane.
+func (r *PortfolioRepo) ReminderSent(ctx context.Context, key string) (bool, error) {
+	count, err := r.remindersCollection.CountDocuments(ctx, bson.M{"_id": key}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check reminder %s: %w", key, err)
+	}
+	return count > 0, nil
+}
+
+// SaveSentReminder zapisuje wysłane przypomnienie (upsert po kluczu).
+func (r *PortfolioRepo) SaveSentReminder(ctx context.Context, reminder models.SentReminder) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.remindersCollection.ReplaceOne(ctx, bson.M{"_id": reminder.Key}, reminder, opts); err != nil {
+		return fmt.Errorf("failed to save reminder %s: %w", reminder.Key, err)
+	}
+	return nil
+}
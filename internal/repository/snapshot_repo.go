@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ensureSnapshotIndexes zakłada unikalny indeks (portfel, dzień) - jeden zapis wartości portfela na dzień.
+func (r *PortfolioRepo) ensureSnapshotIndexes(ctx context.Context) error {
+	_, err := r.snapshotsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "portfolioId", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create snapshots index: %w", err)
+	}
+	return nil
+}
+
+// SaveSnapshot zapisuje stan portfela z danego dnia (upsert po portfelu i dniu).
+func (r *PortfolioRepo) SaveSnapshot(ctx context.Context, snapshot models.PortfolioSnapshot) error {
+	filter := bson.M{"portfolioId": snapshot.PortfolioID, "date": snapshot.Date}
+	opts := options.Update().SetUpsert(true)
+	if _, err := r.snapshotsCollection.UpdateOne(ctx, filter, bson.M{"$set": snapshot}, opts); err != nil {
+		return fmt.Errorf("failed to save snapshot of portfolio %s: %w", snapshot.PortfolioID, err)
+	}
+	return nil
+}
+
+// Snapshots zwraca zapisy wartości portfela z podanego przedziału dat.
+func (r *PortfolioRepo) Snapshots(ctx context.Context, portfolioID string, from, to time.Time) ([]models.PortfolioSnapshot, error) {
+	filter := bson.M{"portfolioId": portfolioID}
+	dateRange := bson.M{}
+	if !from.IsZero() {
+		dateRange["$gte"] = from
+	}
+	if !to.IsZero() {
+		dateRange["$lte"] = to
+	}
+	if len(dateRange) > 0 {
+		filter["date"] = dateRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cursor, err := r.snapshotsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshots of portfolio %s: %w", portfolioID, err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := []models.PortfolioSnapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshots of portfolio %s: %w", portfolioID, err)
+	}
+	return snapshots, nil
+}
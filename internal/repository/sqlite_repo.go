@@ -0,0 +1,1533 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // Sterownik SQLite w czystym Go (bez cgo), rejestruje się jako "sqlite"
+
+	"webwallet/internal/models"
+)
+
+// sqliteTimeLayout to format zapisu dat w kolumnach tekstowych. Ma stałą szerokość (zawsze 9 cyfr
+// ułamka sekundy, zawsze UTC), więc porównanie i sortowanie tekstu daje kolejność chronologiczną.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// sqliteTimeColumns to kolumny (tabela.kolumna) z datami zapisanymi przez formatSQLiteTime lub formatPriceTime.
+var sqliteTimeColumns = []string{
+	"transactions.date", "subscriptions.next_due", "fx_rates.updated_at",
+	"users.created_at", "sessions.created_at", "sessions.expires_at",
+	"assets.price_updated_at", "price_history.date", "portfolio_snapshots.date",
+	"subscription_payments.date", "sent_reminders.due_date", "sent_reminders.sent_at",
+	"asset_alerts.snoozed_until", "asset_alerts.last_triggered_at", "asset_alerts.created_at",
+	"notifications.created_at", "webhook_endpoints.created_at",
+	"webhook_deliveries.created_at", "webhook_deliveries.next_attempt_at", "webhook_deliveries.delivered_at",
+	"schema_migrations.applied_at",
+}
+
+// fixedWidthTimesSQL zwraca polecenia przepisujące daty zapisane wcześniej jako RFC3339Nano (o zmiennej
+// szerokości, np. "...:05Z" i "...:05.5Z") na sqliteTimeLayout - dopisuje zera do 9 cyfr ułamka sekundy.
+func fixedWidthTimesSQL(columns []string) string {
+	var b strings.Builder
+	for _, column := range columns {
+		table, name, _ := strings.Cut(column, ".")
+		fmt.Fprintf(&b, `UPDATE %[1]s SET %[2]s = CASE
+			WHEN instr(%[2]s, '.') = 0 THEN substr(%[2]s, 1, 19) || '.000000000Z'
+			ELSE substr(%[2]s, 1, 20) || substr(substr(%[2]s, 21, length(%[2]s) - 21) || '000000000', 1, 9) || 'Z'
+		END
+		WHERE %[2]s GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]*Z';
+`, table, name)
+	}
+	return b.String()
+}
+
+// sqliteMigrations to kolejne wersje schematu bazy. Migracja o indeksie i ma numer wersji i+1.
+// Nowe zmiany schematu dopisujemy zawsze na końcu listy - nigdy nie zmieniamy już wykonanych.
+var sqliteMigrations = []string{
+	// 1: schemat początkowy - portfel, aktywa z rejestrem transakcji, subskrypcje i kursy walut
+	`CREATE TABLE portfolios (
+		id                TEXT PRIMARY KEY,
+		base_currency     TEXT NOT NULL DEFAULT '',
+		cost_basis_method TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE assets (
+		id            TEXT PRIMARY KEY,
+		portfolio_id  TEXT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+		position      INTEGER NOT NULL,
+		name          TEXT NOT NULL,
+		symbol        TEXT NOT NULL DEFAULT '',
+		type          TEXT NOT NULL DEFAULT '',
+		quantity      TEXT NOT NULL DEFAULT '0',
+		avg_cost      TEXT NOT NULL DEFAULT '0',
+		current_price TEXT NOT NULL DEFAULT '0',
+		wallet_type   TEXT NOT NULL DEFAULT '',
+		currency      TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_assets_portfolio ON assets(portfolio_id, position);
+	CREATE TABLE transactions (
+		id       TEXT PRIMARY KEY,
+		asset_id TEXT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		type     TEXT NOT NULL,
+		date     TEXT NOT NULL,
+		quantity TEXT NOT NULL DEFAULT '0',
+		price    TEXT NOT NULL DEFAULT '0',
+		amount   TEXT NOT NULL DEFAULT '0',
+		fee      TEXT NOT NULL DEFAULT '0',
+		note     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_transactions_asset ON transactions(asset_id, position);
+	CREATE TABLE subscriptions (
+		id           TEXT PRIMARY KEY,
+		portfolio_id TEXT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+		position     INTEGER NOT NULL,
+		name         TEXT NOT NULL,
+		cost         TEXT NOT NULL DEFAULT '0',
+		frequency    TEXT NOT NULL DEFAULT '',
+		next_due     TEXT NOT NULL,
+		currency     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_subscriptions_portfolio ON subscriptions(portfolio_id, position);
+	CREATE TABLE fx_rates (
+		pair       TEXT PRIMARY KEY,
+		base       TEXT NOT NULL,
+		quote      TEXT NOT NULL,
+		rate       TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);`,
+	// 2: konta użytkowników i sesje logowania
+	`CREATE TABLE users (
+		id            TEXT PRIMARY KEY,
+		email         TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		portfolio_id  TEXT NOT NULL,
+		created_at    TEXT NOT NULL
+	);
+	CREATE TABLE sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		created_at TEXT NOT NULL,
+		expires_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_sessions_expires ON sessions(expires_at);`,
+	// 3: wiele nazwanych portfeli na użytkownika
+	`ALTER TABLE portfolios ADD COLUMN name TEXT NOT NULL DEFAULT '';
+	ALTER TABLE portfolios ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_portfolios_owner ON portfolios(owner_id, name);`,
+	// 4: wersja portfela do optymistycznej kontroli współbieżności
+	`ALTER TABLE portfolios ADD COLUMN version INTEGER NOT NULL DEFAULT 0;`,
+	// 5: źródło i czas notowania ceny bieżącej aktywa
+	`ALTER TABLE assets ADD COLUMN price_source TEXT NOT NULL DEFAULT '';
+	ALTER TABLE assets ADD COLUMN price_updated_at TEXT NOT NULL DEFAULT '';`,
+	// 6: historia cen zamknięcia symboli
+	`CREATE TABLE price_history (
+		symbol TEXT NOT NULL,
+		date   TEXT NOT NULL,
+		close  TEXT NOT NULL,
+		source TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (symbol, date)
+	);`,
+	// 7: dzienne zapisy wartości portfeli (wartości według typu portfela jako JSON)
+	`CREATE TABLE portfolio_snapshots (
+		portfolio_id  TEXT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+		date          TEXT NOT NULL,
+		currency      TEXT NOT NULL DEFAULT '',
+		total_value   TEXT NOT NULL DEFAULT '0',
+		total_cost    TEXT NOT NULL DEFAULT '0',
+		wallet_values TEXT NOT NULL DEFAULT '{}',
+		backfilled    INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (portfolio_id, date)
+	);`,
+	// 8: docelowy podział portfela według typu portfela i typu aktywa
+	`CREATE TABLE allocation_targets (
+		portfolio_id TEXT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+		grp          TEXT NOT NULL,
+		category     TEXT NOT NULL,
+		percent      TEXT NOT NULL,
+		PRIMARY KEY (portfolio_id, grp, category)
+	);`,
+	// 9: koszty życia i próg ostrzeżenia poduszki finansowej
+	`ALTER TABLE portfolios ADD COLUMN living_cost TEXT NOT NULL DEFAULT '0';
+	ALTER TABLE portfolios ADD COLUMN emergency_min_months TEXT NOT NULL DEFAULT '0';`,
+	// 10: typowana częstotliwość subskrypcji, odstęp w dniach i minione terminy płatności (JSON)
+	`ALTER TABLE subscriptions ADD COLUMN interval_days INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE subscriptions ADD COLUMN past_charges TEXT NOT NULL DEFAULT '[]';
+	UPDATE subscriptions SET frequency = 'monthly' WHERE frequency = 'Miesięcznie';
+	UPDATE subscriptions SET frequency = 'yearly' WHERE frequency = 'Rocznie';`,
+	// 11: faktycznie zapłacone kwoty subskrypcji
+	`CREATE TABLE subscription_payments (
+		id              TEXT PRIMARY KEY,
+		subscription_id TEXT NOT NULL REFERENCES subscriptions(id) ON DELETE CASCADE,
+		position        INTEGER NOT NULL,
+		date            TEXT NOT NULL,
+		amount          TEXT NOT NULL DEFAULT '0',
+		note            TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_subscription_payments_subscription ON subscription_payments(subscription_id, position);`,
+	// 12: token kanału iCalendar z terminami subskrypcji
+	`ALTER TABLE users ADD COLUMN calendar_token_hash TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_users_calendar_token ON users(calendar_token_hash);`,
+	// 13: przypomnienia e-mail o płatnościach subskrypcji i dziennik wysłanych przypomnień
+	`ALTER TABLE subscriptions ADD COLUMN reminder_days INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE sent_reminders (
+		key             TEXT PRIMARY KEY,
+		portfolio_id    TEXT NOT NULL,
+		subscription_id TEXT NOT NULL,
+		due_date        TEXT NOT NULL,
+		email           TEXT NOT NULL DEFAULT '',
+		sent_at         TEXT NOT NULL
+	);`,
+	// 14: alerty cenowe aktywów i centrum powiadomień użytkowników
+	`CREATE TABLE asset_alerts (
+		id                TEXT PRIMARY KEY,
+		asset_id          TEXT NOT NULL REFERENCES assets(id) ON DELETE CASCADE,
+		position          INTEGER NOT NULL,
+		condition         TEXT NOT NULL,
+		threshold         TEXT NOT NULL DEFAULT '0',
+		email             INTEGER NOT NULL DEFAULT 0,
+		webhook_url       TEXT NOT NULL DEFAULT '',
+		snoozed_until     TEXT NOT NULL DEFAULT '',
+		triggered         INTEGER NOT NULL DEFAULT 0,
+		last_triggered_at TEXT NOT NULL DEFAULT '',
+		created_at        TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_asset_alerts_asset ON asset_alerts(asset_id, position);
+	CREATE TABLE notifications (
+		id           TEXT PRIMARY KEY,
+		user_id      TEXT NOT NULL,
+		portfolio_id TEXT NOT NULL DEFAULT '',
+		asset_id     TEXT NOT NULL DEFAULT '',
+		alert_id     TEXT NOT NULL DEFAULT '',
+		title        TEXT NOT NULL,
+		message      TEXT NOT NULL DEFAULT '',
+		created_at   TEXT NOT NULL,
+		read         INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX idx_notifications_user ON notifications(user_id, created_at);`,
+	// 15: webhooki użytkowników i dziennik doręczeń zdarzeń
+	`CREATE TABLE webhook_endpoints (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		url        TEXT NOT NULL,
+		secret     TEXT NOT NULL,
+		events     TEXT NOT NULL DEFAULT '[]',
+		active     INTEGER NOT NULL DEFAULT 1,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_webhook_endpoints_user ON webhook_endpoints(user_id, created_at);
+	CREATE TABLE webhook_deliveries (
+		id              TEXT PRIMARY KEY,
+		endpoint_id     TEXT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+		user_id         TEXT NOT NULL,
+		event           TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		response_code   INTEGER NOT NULL DEFAULT 0,
+		error           TEXT NOT NULL DEFAULT '',
+		created_at      TEXT NOT NULL,
+		next_attempt_at TEXT NOT NULL DEFAULT '',
+		delivered_at    TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_webhook_deliveries_user ON webhook_deliveries(user_id, created_at);
+	CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);`,
+	// 16: daty o stałej szerokości (sqliteTimeLayout), żeby porównania tekstu nie myliły kolejności w obrębie sekundy
+	fixedWidthTimesSQL(sqliteTimeColumns),
+}
+
+// SQLitePortfolioRepo przechowuje portfel w pliku SQLite - aplikacja działa wtedy jako jeden plik
+// wykonywalny, bez Dockera i MongoDB. Schemat jest znormalizowany (osobne tabele dla aktywów,
+// transakcji i subskrypcji), a kwoty zapisywane są jako tekst, żeby nie tracić precyzji.
+type SQLitePortfolioRepo struct {
+	db *sql.DB
+}
+
+// sqlQuerier to wspólna część *sql.DB i *sql.Tx, dzięki czemu odczyt działa w transakcji i poza nią.
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// NewSQLitePortfolioRepo otwiera (lub tworzy) plik bazy SQLite i wykonuje brakujące migracje schematu.
+func NewSQLitePortfolioRepo(path string) (*SQLitePortfolioRepo, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+	// SQLite pozwala na jednego pisarza naraz - jedno połączenie eliminuje błędy "database is locked"
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+
+	repo := &SQLitePortfolioRepo{db: db}
+	if err := repo.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Printf("Opened SQLite database %s", path)
+	return repo, nil
+}
+
+// migrate wykonuje migracje schematu, których jeszcze nie zastosowano (każdą w osobnej transakcji).
+func (r *SQLitePortfolioRepo) migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		version := i + 1
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, formatSQLiteTime(time.Now())); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+		log.Printf("Applied SQLite schema migration %d", version)
+	}
+	return nil
+}
+
+// Disconnect zamyka plik bazy danych.
+func (r *SQLitePortfolioRepo) Disconnect(ctx context.Context) error {
+	if r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+// formatSQLiteTime zapisuje datę w UTC w formacie tekstowym.
+func formatSQLiteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// formatPriceTime zapisuje czas notowania (lub inny czas, który może nie być ustawiony);
+// zerowy czas zapisujemy jako pusty tekst.
+func formatPriceTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatSQLiteTime(t)
+}
+
+// parseSQLiteTime odczytuje datę zapisaną przez formatSQLiteTime. Przyjmuje dowolny RFC3339,
+// więc odczyta także daty zapisane przed migracją 16.
+func parseSQLiteTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q in database: %w", s, err)
+	}
+	return t, nil
+}
+
+// parseOptionalSQLiteTime odczytuje datę zapisaną przez formatPriceTime (pusty tekst to zerowy czas).
+func parseOptionalSQLiteTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return parseSQLiteTime(s)
+}
+
+// LoadPortfolio ładuje portfel z bazy danych (lub zwraca nowy, pusty portfel).
+func (r *SQLitePortfolioRepo) LoadPortfolio(ctx context.Context, portfolioID string) (*models.InvestmentPortfolio, error) {
+	return r.loadPortfolio(ctx, r.db, portfolioID)
+}
+
+// loadPortfolio składa portfel z tabel portfolios, assets, transactions i subscriptions.
+func (r *SQLitePortfolioRepo) loadPortfolio(ctx context.Context, q sqlQuerier, portfolioID string) (*models.InvestmentPortfolio, error) {
+	rates, err := r.loadFXRates(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := models.NewInvestmentPortfolio()
+	portfolio.ID = portfolioID
+	portfolio.FXRates = models.NewFXRates(rates)
+
+	var baseCurrency, method string
+	err = q.QueryRowContext(ctx, `SELECT name, owner_id, version, base_currency, cost_basis_method, living_cost, emergency_min_months
+		FROM portfolios WHERE id = ?`, portfolioID).
+		Scan(&portfolio.Name, &portfolio.OwnerID, &portfolio.Version, &baseCurrency, &method,
+			&portfolio.EmergencyFund.MonthlyLivingCost, &portfolio.EmergencyFund.MinMonths)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Println("No existing portfolio found. Creating a new one.")
+		return portfolio, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load portfolio: %w", err)
+	}
+	if baseCurrency != "" {
+		portfolio.BaseCurrency = baseCurrency
+	}
+	portfolio.CostBasisMethod = models.CostBasisMethod(method)
+
+	if portfolio.Assets, err = r.loadAssets(ctx, q, portfolioID); err != nil {
+		return nil, err
+	}
+	if portfolio.Subscriptions, err = r.loadSubscriptions(ctx, q, portfolioID); err != nil {
+		return nil, err
+	}
+	if portfolio.AllocationTargets, err = r.loadAllocationTargets(ctx, q, portfolioID); err != nil {
+		return nil, err
+	}
+
+	// Sumy nie są przechowywane w bazie - wyliczamy je z aktywów i subskrypcji
+	portfolio.CalculateTotals()
+	return portfolio, nil
+}
+
+// loadAssets wczytuje aktywa portfela wraz z ich rejestrami transakcji.
+func (r *SQLitePortfolioRepo) loadAssets(ctx context.Context, q sqlQuerier, portfolioID string) ([]models.Asset, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, name, symbol, type, quantity, avg_cost, current_price, wallet_type, currency, price_source, price_updated_at
+		FROM assets WHERE portfolio_id = ? ORDER BY position`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+	defer rows.Close()
+
+	assets := []models.Asset{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var a models.Asset
+		var priceUpdatedAt string
+		if err := rows.Scan(&a.ID, &a.Name, &a.Symbol, &a.Type, &a.Quantity, &a.AvgCost, &a.CurrentPrice, &a.WalletType, &a.Currency, &a.PriceSource, &priceUpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to decode asset: %w", err)
+		}
+		// Aktywa sprzed migracji 5 nie mają czasu notowania
+		if priceUpdatedAt != "" {
+			if a.PriceUpdatedAt, err = parseSQLiteTime(priceUpdatedAt); err != nil {
+				return nil, err
+			}
+		}
+		index[a.ID] = len(assets)
+		assets = append(assets, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	txRows, err := q.QueryContext(ctx, `SELECT t.asset_id, t.id, t.type, t.date, t.quantity, t.price, t.amount, t.fee, t.note
+		FROM transactions t JOIN assets a ON a.id = t.asset_id
+		WHERE a.portfolio_id = ? ORDER BY t.asset_id, t.position`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+	defer txRows.Close()
+
+	for txRows.Next() {
+		var assetID, txType, date string
+		var t models.Transaction
+		if err := txRows.Scan(&assetID, &t.ID, &txType, &date, &t.Quantity, &t.Price, &t.Amount, &t.Fee, &t.Note); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		t.Type = models.TransactionType(txType)
+		if t.Date, err = parseSQLiteTime(date); err != nil {
+			return nil, err
+		}
+		if i, ok := index[assetID]; ok {
+			assets[i].Transactions = append(assets[i].Transactions, t)
+		}
+	}
+	if err := txRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %w", err)
+	}
+
+	alertRows, err := q.QueryContext(ctx, `SELECT l.asset_id, l.id, l.condition, l.threshold, l.email,
+			l.snoozed_until, l.triggered, l.last_triggered_at, l.created_at
+		FROM asset_alerts l JOIN assets a ON a.id = l.asset_id
+		WHERE a.portfolio_id = ? ORDER BY l.asset_id, l.position`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load asset alerts: %w", err)
+	}
+	defer alertRows.Close()
+
+	for alertRows.Next() {
+		var assetID, condition, snoozedUntil, lastTriggeredAt, createdAt string
+		var alert models.PriceAlert
+		if err := alertRows.Scan(&assetID, &alert.ID, &condition, &alert.Threshold, &alert.Email,
+			&snoozedUntil, &alert.Triggered, &lastTriggeredAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to decode asset alert: %w", err)
+		}
+		alert.Condition = models.AlertCondition(condition)
+		if alert.SnoozedUntil, err = parseOptionalSQLiteTime(snoozedUntil); err != nil {
+			return nil, err
+		}
+		if alert.LastTriggeredAt, err = parseOptionalSQLiteTime(lastTriggeredAt); err != nil {
+			return nil, err
+		}
+		if alert.CreatedAt, err = parseOptionalSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[assetID]; ok {
+			assets[i].Alerts = append(assets[i].Alerts, alert)
+		}
+	}
+	if err := alertRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load asset alerts: %w", err)
+	}
+	return assets, nil
+}
+
+// loadSubscriptions wczytuje subskrypcje portfela.
+func (r *SQLitePortfolioRepo) loadSubscriptions(ctx context.Context, q sqlQuerier, portfolioID string) ([]models.Subscription, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, name, cost, frequency, next_due, currency, interval_days, past_charges, reminder_days
+		FROM subscriptions WHERE portfolio_id = ? ORDER BY position`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var s models.Subscription
+		var nextDue, pastCharges string
+		if err := rows.Scan(&s.ID, &s.Name, &s.Cost, &s.Frequency, &nextDue, &s.Currency, &s.IntervalDays, &pastCharges, &s.ReminderDays); err != nil {
+			return nil, fmt.Errorf("failed to decode subscription: %w", err)
+		}
+		if s.NextDue, err = parseSQLiteTime(nextDue); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(pastCharges), &s.PastCharges); err != nil {
+			return nil, fmt.Errorf("failed to decode past charges of subscription %s: %w", s.ID, err)
+		}
+		index[s.ID] = len(subscriptions)
+		subscriptions = append(subscriptions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	paymentRows, err := q.QueryContext(ctx, `SELECT p.subscription_id, p.id, p.date, p.amount, p.note
+		FROM subscription_payments p JOIN subscriptions s ON s.id = p.subscription_id
+		WHERE s.portfolio_id = ? ORDER BY p.subscription_id, p.position`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscription payments: %w", err)
+	}
+	defer paymentRows.Close()
+
+	for paymentRows.Next() {
+		var subID, date string
+		var payment models.SubscriptionPayment
+		if err := paymentRows.Scan(&subID, &payment.ID, &date, &payment.Amount, &payment.Note); err != nil {
+			return nil, fmt.Errorf("failed to decode subscription payment: %w", err)
+		}
+		if payment.Date, err = parseSQLiteTime(date); err != nil {
+			return nil, err
+		}
+		if i, ok := index[subID]; ok {
+			subscriptions[i].Payments = append(subscriptions[i].Payments, payment)
+		}
+	}
+	if err := paymentRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load subscription payments: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// marshalPastCharges zapisuje minione terminy płatności subskrypcji jako tablicę JSON (pusta lista to "[]").
+func marshalPastCharges(charges []time.Time) (string, error) {
+	if charges == nil {
+		charges = []time.Time{}
+	}
+	data, err := json.Marshal(charges)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode past charges: %w", err)
+	}
+	return string(data), nil
+}
+
+// loadAllocationTargets wczytuje docelowy podział portfela.
+func (r *SQLitePortfolioRepo) loadAllocationTargets(ctx context.Context, q sqlQuerier, portfolioID string) ([]models.AllocationTarget, error) {
+	rows, err := q.QueryContext(ctx, `SELECT grp, category, percent FROM allocation_targets WHERE portfolio_id = ? ORDER BY grp, category`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load allocation targets: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []models.AllocationTarget
+	for rows.Next() {
+		var t models.AllocationTarget
+		if err := rows.Scan(&t.Group, &t.Category, &t.Percent); err != nil {
+			return nil, fmt.Errorf("failed to decode allocation target: %w", err)
+		}
+		targets = append(targets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load allocation targets: %w", err)
+	}
+	return targets, nil
+}
+
+// savePortfolio zastępuje zawartość tabel portfela bieżącym stanem z pamięci. Wywołujemy go tylko w modifyVersion,
+// na portfelu wczytanym w tej samej transakcji - inaczej cofnąłby ceny zapisane w międzyczasie przez UpdateAssetCurrentPrice.
+// Wiersz portfela jest aktualizowany tylko wtedy, gdy w bazie jest wersja, którą wczytano - w przeciwnym razie ErrConflict.
+func (r *SQLitePortfolioRepo) savePortfolio(ctx context.Context, tx *sql.Tx, portfolioID string, portfolio *models.InvestmentPortfolio) error {
+	result, err := tx.ExecContext(ctx, `UPDATE portfolios SET name = ?, owner_id = ?, base_currency = ?, cost_basis_method = ?,
+		living_cost = ?, emergency_min_months = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		portfolio.Name, portfolio.OwnerID, portfolio.BaseCurrency, string(portfolio.CostBasisMethod),
+		portfolio.EmergencyFund.MonthlyLivingCost, portfolio.EmergencyFund.MinMonths, portfolioID, portfolio.Version)
+	if err != nil {
+		return fmt.Errorf("failed to save portfolio: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Brak wiersza w tej wersji: albo portfel zapisujemy pierwszy raz, albo ktoś zapisał go przed nami
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM portfolios WHERE id = ?`, portfolioID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to save portfolio: %w", err)
+		}
+		if exists > 0 || portfolio.Version != 0 {
+			return ErrConflict
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO portfolios (id, name, owner_id, version, base_currency, cost_basis_method, living_cost, emergency_min_months)
+			VALUES (?, ?, ?, 1, ?, ?, ?, ?)`,
+			portfolioID, portfolio.Name, portfolio.OwnerID, portfolio.BaseCurrency, string(portfolio.CostBasisMethod),
+			portfolio.EmergencyFund.MonthlyLivingCost, portfolio.EmergencyFund.MinMonths)
+		if err != nil {
+			return fmt.Errorf("failed to save portfolio: %w", err)
+		}
+	}
+
+	// Usunięcie aktywów usuwa też ich transakcje (ON DELETE CASCADE)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM assets WHERE portfolio_id = ?`, portfolioID); err != nil {
+		return fmt.Errorf("failed to save assets: %w", err)
+	}
+	for i, a := range portfolio.Assets {
+		_, err := tx.ExecContext(ctx, `INSERT INTO assets (id, portfolio_id, position, name, symbol, type, quantity, avg_cost, current_price, wallet_type, currency, price_source, price_updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.ID, portfolioID, i, a.Name, a.Symbol, a.Type, a.Quantity, a.AvgCost, a.CurrentPrice, a.WalletType, a.Currency, a.PriceSource, formatPriceTime(a.PriceUpdatedAt))
+		if err != nil {
+			return fmt.Errorf("failed to save asset %s: %w", a.Name, err)
+		}
+		for j, t := range a.Transactions {
+			_, err := tx.ExecContext(ctx, `INSERT INTO transactions (id, asset_id, position, type, date, quantity, price, amount, fee, note)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				t.ID, a.ID, j, string(t.Type), formatSQLiteTime(t.Date), t.Quantity, t.Price, t.Amount, t.Fee, t.Note)
+			if err != nil {
+				return fmt.Errorf("failed to save transaction of asset %s: %w", a.Name, err)
+			}
+		}
+		for j, alert := range a.Alerts {
+			_, err := tx.ExecContext(ctx, `INSERT INTO asset_alerts (id, asset_id, position, condition, threshold, email,
+					snoozed_until, triggered, last_triggered_at, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				alert.ID, a.ID, j, string(alert.Condition), alert.Threshold, alert.Email,
+				formatPriceTime(alert.SnoozedUntil), alert.Triggered, formatPriceTime(alert.LastTriggeredAt), formatPriceTime(alert.CreatedAt))
+			if err != nil {
+				return fmt.Errorf("failed to save alert of asset %s: %w", a.Name, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE portfolio_id = ?`, portfolioID); err != nil {
+		return fmt.Errorf("failed to save subscriptions: %w", err)
+	}
+	for i, s := range portfolio.Subscriptions {
+		pastCharges, err := marshalPastCharges(s.PastCharges)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO subscriptions (id, portfolio_id, position, name, cost, frequency, next_due, currency, interval_days, past_charges, reminder_days)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.ID, portfolioID, i, s.Name, s.Cost, string(s.Frequency), formatSQLiteTime(s.NextDue), s.Currency, s.IntervalDays, pastCharges, s.ReminderDays)
+		if err != nil {
+			return fmt.Errorf("failed to save subscription %s: %w", s.Name, err)
+		}
+		for j, payment := range s.Payments {
+			_, err := tx.ExecContext(ctx, `INSERT INTO subscription_payments (id, subscription_id, position, date, amount, note)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				payment.ID, s.ID, j, formatSQLiteTime(payment.Date), payment.Amount, payment.Note)
+			if err != nil {
+				return fmt.Errorf("failed to save payment of subscription %s: %w", s.Name, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM allocation_targets WHERE portfolio_id = ?`, portfolioID); err != nil {
+		return fmt.Errorf("failed to save allocation targets: %w", err)
+	}
+	for _, t := range portfolio.AllocationTargets {
+		_, err := tx.ExecContext(ctx, `INSERT INTO allocation_targets (portfolio_id, grp, category, percent) VALUES (?, ?, ?, ?)`,
+			portfolioID, t.Group, t.Category, t.Percent)
+		if err != nil {
+			return fmt.Errorf("failed to save allocation target %s: %w", t.Category, err)
+		}
+	}
+	return nil
+}
+
+// ListPortfolios zwraca portfele należące do użytkownika (posortowane po nazwie).
+func (r *SQLitePortfolioRepo) ListPortfolios(ctx context.Context, ownerID string) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(ctx, `SELECT id, name, owner_id FROM portfolios WHERE owner_id = ? ORDER BY name, id`, ownerID)
+}
+
+// AllPortfolios zwraca wszystkie portfele (posortowane po nazwie).
+func (r *SQLitePortfolioRepo) AllPortfolios(ctx context.Context) ([]models.PortfolioInfo, error) {
+	return r.listPortfolios(ctx, `SELECT id, name, owner_id FROM portfolios ORDER BY name, id`)
+}
+
+// listPortfolios wykonuje zapytanie zwracające kolumny id, name i owner_id portfeli.
+func (r *SQLitePortfolioRepo) listPortfolios(ctx context.Context, query string, args ...any) ([]models.PortfolioInfo, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	infos := []models.PortfolioInfo{}
+	for rows.Next() {
+		var info models.PortfolioInfo
+		if err := rows.Scan(&info.ID, &info.Name, &info.OwnerID); err != nil {
+			return nil, fmt.Errorf("failed to decode portfolio: %w", err)
+		}
+		infos = append(infos, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list portfolios: %w", err)
+	}
+	return infos, nil
+}
+
+// CreatePortfolio zakłada nowy, pusty portfel (albo nadaje nazwę i właściciela istniejącemu).
+func (r *SQLitePortfolioRepo) CreatePortfolio(ctx context.Context, info models.PortfolioInfo) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO portfolios (id, name, owner_id, base_currency) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, owner_id = excluded.owner_id`,
+		info.ID, info.Name, info.OwnerID, models.DefaultCurrency)
+	if err != nil {
+		return fmt.Errorf("failed to create portfolio %s: %w", info.Name, err)
+	}
+	return nil
+}
+
+// ClaimPortfolio nadaje nazwę i właściciela portfelowi, który jeszcze nie ma właściciela.
+func (r *SQLitePortfolioRepo) ClaimPortfolio(ctx context.Context, info models.PortfolioInfo) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE portfolios SET name = ?, owner_id = ? WHERE id = ? AND owner_id = ''`,
+		info.Name, info.OwnerID, info.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim portfolio %s: %w", info.ID, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// RenamePortfolio zmienia nazwę portfela.
+func (r *SQLitePortfolioRepo) RenamePortfolio(ctx context.Context, portfolioID, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE portfolios SET name = ? WHERE id = ?`, name, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to rename portfolio: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeletePortfolio usuwa portfel. Aktywa, ich transakcje i subskrypcje znikają razem z nim (ON DELETE CASCADE).
+func (r *SQLitePortfolioRepo) DeletePortfolio(ctx context.Context, portfolioID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM portfolios WHERE id = ?`, portfolioID)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// inTx wykonuje funkcję w transakcji bazy danych - przy błędzie wszystkie zmiany są wycofywane.
+func (r *SQLitePortfolioRepo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// bumpVersion podbija wersję portfela przy zmianie wykonanej bezpośrednim zapytaniem (bez savePortfolio),
+// żeby formularze zbudowane na starszej wersji wykryły konflikt. Gdy portfel nie ma oczekiwanej wersji
+// (AnyVersion - dowolnej), zwraca ErrConflict, a transakcja wycofuje zmianę.
+func (r *SQLitePortfolioRepo) bumpVersion(ctx context.Context, tx *sql.Tx, portfolioID string, version int64) error {
+	result, err := tx.ExecContext(ctx, `UPDATE portfolios SET version = version + 1 WHERE id = ? AND (? = -1 OR version = ?)`,
+		portfolioID, version, version)
+	if err != nil {
+		return fmt.Errorf("failed to update portfolio version: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 && version != AnyVersion {
+		return ErrConflict
+	}
+	return nil
+}
+
+// modify wczytuje portfel, wykonuje na nim zmianę i zapisuje wynik w jednej transakcji bazy danych.
+func (r *SQLitePortfolioRepo) modify(ctx context.Context, portfolioID string, change func(portfolio *models.InvestmentPortfolio) error) error {
+	return r.modifyVersion(ctx, portfolioID, AnyVersion, change)
+}
+
+// modifyVersion działa jak modify, ale zmienia portfel tylko wtedy, gdy ma on oczekiwaną wersję
+// (AnyVersion - dowolną). W przeciwnym razie zwraca ErrConflict.
+func (r *SQLitePortfolioRepo) modifyVersion(ctx context.Context, portfolioID string, version int64, change func(portfolio *models.InvestmentPortfolio) error) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		portfolio, err := r.loadPortfolio(ctx, tx, portfolioID)
+		if err != nil {
+			return err
+		}
+		if version != AnyVersion && portfolio.Version != version {
+			return ErrConflict
+		}
+		if err := change(portfolio); err != nil {
+			return err
+		}
+		return r.savePortfolio(ctx, tx, portfolioID, portfolio)
+	})
+}
+
+// AddAsset dodaje nowe aktywo do portfela.
+func (r *SQLitePortfolioRepo) AddAsset(ctx context.Context, portfolioID string, version int64, asset models.Asset) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.AddAsset(asset)
+		return nil
+	})
+}
+
+// RemoveAsset usuwa aktywo o podanym ID.
+func (r *SQLitePortfolioRepo) RemoveAsset(ctx context.Context, portfolioID string, version int64, assetID string) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RemoveAsset(assetID)
+	})
+}
+
+// UpdateAsset dokupuje jednostki aktywa, zapisując transakcję kupna w rejestrze.
+func (r *SQLitePortfolioRepo) UpdateAsset(ctx context.Context, portfolioID string, version int64, assetID string, additionalQuantity, newPurchasePrice models.Decimal) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordTransaction(assetID, models.Transaction{
+			Type:     models.TransactionBuy,
+			Date:     models.Today(),
+			Quantity: additionalQuantity,
+			Price:    newPurchasePrice,
+		})
+	})
+}
+
+// SellAsset sprzedaje (częściowo lub całkowicie) pozycję, zapisując transakcję sprzedaży z prowizją.
+func (r *SQLitePortfolioRepo) SellAsset(ctx context.Context, portfolioID string, version int64, assetID string, quantity, price, fee models.Decimal, date time.Time) error {
+	return r.AddTransaction(ctx, portfolioID, version, assetID, models.Transaction{
+		Type:     models.TransactionSell,
+		Date:     date,
+		Quantity: quantity,
+		Price:    price,
+		Fee:      fee,
+	})
+}
+
+// AddTransaction dopisuje transakcję do rejestru aktywa i przelicza pozycję.
+func (r *SQLitePortfolioRepo) AddTransaction(ctx context.Context, portfolioID string, version int64, assetID string, tx models.Transaction) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordTransaction(assetID, tx)
+	})
+}
+
+// RemoveTransaction usuwa transakcję z rejestru aktywa.
+func (r *SQLitePortfolioRepo) RemoveTransaction(ctx context.Context, portfolioID string, version int64, assetID, transactionID string) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.DeleteTransaction(assetID, transactionID)
+	})
+}
+
+// UpdateCostBasisMethod zmienia metodę rozliczania partii i przelicza pozycje.
+func (r *SQLitePortfolioRepo) UpdateCostBasisMethod(ctx context.Context, portfolioID string, method models.CostBasisMethod) error {
+	return r.modify(ctx, portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetCostBasisMethod(method)
+	})
+}
+
+// UpdateAssetCurrentPrice aktualizuje cenę bieżącą dla danego aktywa (bez zmiany wersji portfela).
+func (r *SQLitePortfolioRepo) UpdateAssetCurrentPrice(ctx context.Context, portfolioID, assetID string, quote models.PriceQuote) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE assets SET current_price = ?, price_source = ?, price_updated_at = ? WHERE id = ? AND portfolio_id = ?`,
+			quote.Price, quote.Source, formatPriceTime(quote.Time), assetID, portfolioID)
+		if err != nil {
+			return fmt.Errorf("failed to update asset price in db: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("asset with ID %s not found", assetID)
+		}
+		return nil
+	})
+}
+
+// PatchAsset zmienia typ portfela i cenę bieżącą aktywa w jednej transakcji.
+func (r *SQLitePortfolioRepo) PatchAsset(ctx context.Context, portfolioID string, version int64, assetID string, patch models.AssetPatch) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.PatchAsset(assetID, patch)
+	})
+}
+
+// UpdateAssetAlerts zastępuje alerty cenowe aktywa.
+func (r *SQLitePortfolioRepo) UpdateAssetAlerts(ctx context.Context, portfolioID string, version int64, assetID string, alerts []models.PriceAlert) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAssetAlerts(assetID, alerts)
+	})
+}
+
+// UpdateAlertState zapisuje stan alertu, jeśli nie zmienił się od oceny.
+func (r *SQLitePortfolioRepo) UpdateAlertState(ctx context.Context, portfolioID, assetID string, previous, next models.PriceAlert) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE asset_alerts SET triggered = ?, last_triggered_at = ?
+		WHERE id = ? AND triggered = ? AND last_triggered_at = ?
+			AND asset_id IN (SELECT id FROM assets WHERE id = ? AND portfolio_id = ?)`,
+		next.Triggered, formatPriceTime(next.LastTriggeredAt),
+		next.ID, previous.Triggered, formatPriceTime(previous.LastTriggeredAt), assetID, portfolioID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update alert state in db: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// UpdateAssetWalletType aktualizuje przypisanie aktywa do typu portfela.
+func (r *SQLitePortfolioRepo) UpdateAssetWalletType(ctx context.Context, portfolioID string, version int64, assetID string, newWalletType string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.bumpVersion(ctx, tx, portfolioID, version); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `UPDATE assets SET wallet_type = ? WHERE id = ? AND portfolio_id = ?`, newWalletType, assetID, portfolioID)
+		if err != nil {
+			return fmt.Errorf("failed to update asset wallet type in db: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("asset with ID %s not found", assetID)
+		}
+		return nil
+	})
+}
+
+// AddSubscription dodaje nową subskrypcję do portfela.
+func (r *SQLitePortfolioRepo) AddSubscription(ctx context.Context, portfolioID string, version int64, sub models.Subscription) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.AddSubscription(sub)
+		return nil
+	})
+}
+
+// RemoveSubscription usuwa subskrypcję o podanym ID.
+func (r *SQLitePortfolioRepo) RemoveSubscription(ctx context.Context, portfolioID string, version int64, subID string) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := r.bumpVersion(ctx, tx, portfolioID, version); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = ? AND portfolio_id = ?`, subID, portfolioID)
+		if err != nil {
+			return fmt.Errorf("failed to remove subscription: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("subscription with ID %s not found in portfolio", subID)
+		}
+		return nil
+	})
+}
+
+// UpdateSubscription zmienia edytowalne pola subskrypcji o tym samym ID.
+func (r *SQLitePortfolioRepo) UpdateSubscription(ctx context.Context, portfolioID string, version int64, updatedSub models.Subscription) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		_, err := portfolio.EditSubscription(updatedSub, models.Today())
+		return err
+	})
+}
+
+// RollOverSubscriptions przesuwa minione terminy subskrypcji portfela.
+func (r *SQLitePortfolioRepo) RollOverSubscriptions(ctx context.Context, portfolioID string, today time.Time) ([]models.Subscription, error) {
+	var rolled []models.Subscription
+	err := r.modify(ctx, portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		if rolled = portfolio.RollOverSubscriptions(today); len(rolled) == 0 {
+			return errNothingRolled
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingRolled) {
+		return nil, nil
+	}
+	return rolled, err
+}
+
+// LoadFXRates wczytuje wszystkie zapisane kursy walut.
+func (r *SQLitePortfolioRepo) LoadFXRates(ctx context.Context) ([]models.FXRate, error) {
+	return r.loadFXRates(ctx, r.db)
+}
+
+func (r *SQLitePortfolioRepo) loadFXRates(ctx context.Context, q sqlQuerier) ([]models.FXRate, error) {
+	rows, err := q.QueryContext(ctx, `SELECT base, quote, rate, updated_at FROM fx_rates ORDER BY pair`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fx rates: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []models.FXRate
+	for rows.Next() {
+		var rate models.FXRate
+		var updatedAt string
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Rate, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to decode fx rate: %w", err)
+		}
+		if rate.UpdatedAt, err = parseSQLiteTime(updatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load fx rates: %w", err)
+	}
+	return rates, nil
+}
+
+// SaveFXRate zapisuje (lub nadpisuje) kurs dla pary walutowej.
+func (r *SQLitePortfolioRepo) SaveFXRate(ctx context.Context, rate models.FXRate) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO fx_rates (pair, base, quote, rate, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(pair) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`,
+		rate.Pair(), rate.Base, rate.Quote, rate.Rate, formatSQLiteTime(rate.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save fx rate %s: %w", rate.Pair(), err)
+	}
+	return nil
+}
+
+// RemoveFXRate usuwa kurs dla pary walutowej (np. "USD/PLN").
+func (r *SQLitePortfolioRepo) RemoveFXRate(ctx context.Context, pair string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM fx_rates WHERE pair = ?`, pair)
+	if err != nil {
+		return fmt.Errorf("failed to remove fx rate %s: %w", pair, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("fx rate %s not found", pair)
+	}
+	return nil
+}
+
+// UpdateBaseCurrency zmienia walutę bazową portfela.
+func (r *SQLitePortfolioRepo) UpdateBaseCurrency(ctx context.Context, portfolioID, currency string) error {
+	return r.modify(ctx, portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.BaseCurrency = currency
+		return nil
+	})
+}
+
+// UpdateAllocationTargets zastępuje docelowy podział portfela w podanej grupie.
+func (r *SQLitePortfolioRepo) UpdateAllocationTargets(ctx context.Context, portfolioID, group string, targets []models.AllocationTarget) error {
+	return r.modify(ctx, portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.SetAllocationTargets(group, targets)
+	})
+}
+
+// RecordSubscriptionPayment zapisuje płatność subskrypcji i opcjonalnie przesuwa jej termin (markPaid).
+func (r *SQLitePortfolioRepo) RecordSubscriptionPayment(ctx context.Context, portfolioID string, version int64, subID string, payment models.SubscriptionPayment, markPaid bool) error {
+	return r.modifyVersion(ctx, portfolioID, version, func(portfolio *models.InvestmentPortfolio) error {
+		return portfolio.RecordSubscriptionPayment(subID, payment, markPaid)
+	})
+}
+
+// UpdateEmergencyFundSettings zapisuje ustawienia poduszki finansowej portfela.
+func (r *SQLitePortfolioRepo) UpdateEmergencyFundSettings(ctx context.Context, portfolioID string, settings models.EmergencyFundSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	return r.modify(ctx, portfolioID, func(portfolio *models.InvestmentPortfolio) error {
+		portfolio.EmergencyFund = settings
+		return nil
+	})
+}
+
+// CreateUser zapisuje nowe konto użytkownika.
+func (r *SQLitePortfolioRepo) CreateUser(ctx context.Context, user models.User) error {
+	if _, err := r.GetUserByEmail(ctx, user.Email); err == nil {
+		return ErrUserExists
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (id, email, password_hash, portfolio_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.PasswordHash, user.PortfolioID, formatSQLiteTime(user.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUser zwraca użytkownika o podanym ID.
+func (r *SQLitePortfolioRepo) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return r.findUser(ctx, `WHERE id = ?`, userID)
+}
+
+// GetUserByEmail zwraca użytkownika o podanym adresie e-mail.
+func (r *SQLitePortfolioRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findUser(ctx, `WHERE email = ?`, email)
+}
+
+// GetUserByCalendarToken zwraca użytkownika o podanym skrócie tokenu kanału iCalendar.
+func (r *SQLitePortfolioRepo) GetUserByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	if tokenHash == "" {
+		return nil, ErrNotFound
+	}
+	return r.findUser(ctx, `WHERE calendar_token_hash = ?`, tokenHash)
+}
+
+// SetUserPortfolio zmienia portfel główny użytkownika.
+func (r *SQLitePortfolioRepo) SetUserPortfolio(ctx context.Context, userID, portfolioID string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET portfolio_id = ? WHERE id = ?`, portfolioID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save user portfolio: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetCalendarToken zapisuje skrót tokenu kanału iCalendar użytkownika.
+func (r *SQLitePortfolioRepo) SetCalendarToken(ctx context.Context, userID, tokenHash string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE users SET calendar_token_hash = ? WHERE id = ?`, tokenHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *SQLitePortfolioRepo) findUser(ctx context.Context, where string, arg string) (*models.User, error) {
+	var user models.User
+	var createdAt string
+	err := r.db.QueryRowContext(ctx, `SELECT id, email, password_hash, portfolio_id, created_at, calendar_token_hash FROM users `+where, arg).
+		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.PortfolioID, &createdAt, &user.CalendarTokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CountUsers zwraca liczbę zarejestrowanych kont.
+func (r *SQLitePortfolioRepo) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// CreateSession zapisuje nową sesję logowania (przy okazji usuwa sesje wygasłe).
+func (r *SQLitePortfolioRepo) CreateSession(ctx context.Context, session models.Session) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, formatSQLiteTime(time.Now())); err != nil {
+		return fmt.Errorf("failed to clean up sessions: %w", err)
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+		session.TokenHash, session.UserID, formatSQLiteTime(session.CreatedAt), formatSQLiteTime(session.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession zwraca ważną sesję o podanym skrócie tokenu.
+func (r *SQLitePortfolioRepo) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	var createdAt, expiresAt string
+	err := r.db.QueryRowContext(ctx, `SELECT token_hash, user_id, created_at, expires_at FROM sessions WHERE token_hash = ?`, tokenHash).
+		Scan(&session.TokenHash, &session.UserID, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	if session.ExpiresAt, err = parseSQLiteTime(expiresAt); err != nil {
+		return nil, err
+	}
+	if session.IsExpired(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// DeleteSession usuwa sesję (wylogowanie).
+func (r *SQLitePortfolioRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// RecordPrice zapisuje cenę symbolu, nadpisując cenę zapisaną wcześniej tego samego dnia.
+func (r *SQLitePortfolioRepo) RecordPrice(ctx context.Context, point models.PricePoint) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO price_history (symbol, date, close, source) VALUES (?, ?, ?, ?)
+		ON CONFLICT(symbol, date) DO UPDATE SET close = excluded.close, source = excluded.source`,
+		point.Symbol, formatSQLiteTime(point.Date), point.Close, point.Source)
+	if err != nil {
+		return fmt.Errorf("failed to record price of %s: %w", point.Symbol, err)
+	}
+	return nil
+}
+
+// PriceHistory zwraca historię cen symbolu z podanego przedziału dat.
+func (r *SQLitePortfolioRepo) PriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]models.PricePoint, error) {
+	query := `SELECT symbol, date, close, source FROM price_history WHERE symbol = ?`
+	args := []any{models.NormalizeSymbol(symbol)}
+	// Punkty historii mają daty o północy, więc granice też zaokrąglamy do dnia -
+	// dzięki temu porównanie tekstowe nie potyka się o ułamki sekund w formacie RFC 3339
+	if !from.IsZero() {
+		query += ` AND date >= ?`
+		args = append(args, formatSQLiteTime(from.UTC().Truncate(24*time.Hour)))
+	}
+	if !to.IsZero() {
+		query += ` AND date <= ?`
+		args = append(args, formatSQLiteTime(to.UTC().Truncate(24*time.Hour)))
+	}
+	query += ` ORDER BY date`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load price history of %s: %w", symbol, err)
+	}
+	defer rows.Close()
+
+	points := []models.PricePoint{}
+	for rows.Next() {
+		var p models.PricePoint
+		var date string
+		if err := rows.Scan(&p.Symbol, &date, &p.Close, &p.Source); err != nil {
+			return nil, fmt.Errorf("failed to decode price history: %w", err)
+		}
+		if p.Date, err = parseSQLiteTime(date); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load price history of %s: %w", symbol, err)
+	}
+	return points, nil
+}
+
+// SaveSnapshot zapisuje stan portfela z danego dnia, nadpisując wcześniejszy zapis z tego dnia.
+func (r *SQLitePortfolioRepo) SaveSnapshot(ctx context.Context, snapshot models.PortfolioSnapshot) error {
+	walletValues, err := json.Marshal(snapshot.WalletValues)
+	if err != nil {
+		return fmt.Errorf("failed to encode wallet values: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO portfolio_snapshots (portfolio_id, date, currency, total_value, total_cost, wallet_values, backfilled)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(portfolio_id, date) DO UPDATE SET currency = excluded.currency, total_value = excluded.total_value,
+			total_cost = excluded.total_cost, wallet_values = excluded.wallet_values, backfilled = excluded.backfilled`,
+		snapshot.PortfolioID, formatSQLiteTime(snapshot.Date), snapshot.Currency, snapshot.TotalValue, snapshot.TotalCost, string(walletValues), snapshot.Backfilled)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot of portfolio %s: %w", snapshot.PortfolioID, err)
+	}
+	return nil
+}
+
+// Snapshots zwraca zapisy wartości portfela z podanego przedziału dat.
+func (r *SQLitePortfolioRepo) Snapshots(ctx context.Context, portfolioID string, from, to time.Time) ([]models.PortfolioSnapshot, error) {
+	query := `SELECT portfolio_id, date, currency, total_value, total_cost, wallet_values, backfilled FROM portfolio_snapshots WHERE portfolio_id = ?`
+	args := []any{portfolioID}
+	// Zapisy mają daty o północy - granice zaokrąglamy do dnia (jak w PriceHistory)
+	if !from.IsZero() {
+		query += ` AND date >= ?`
+		args = append(args, formatSQLiteTime(from.UTC().Truncate(24*time.Hour)))
+	}
+	if !to.IsZero() {
+		query += ` AND date <= ?`
+		args = append(args, formatSQLiteTime(to.UTC().Truncate(24*time.Hour)))
+	}
+	query += ` ORDER BY date`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshots of portfolio %s: %w", portfolioID, err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.PortfolioSnapshot{}
+	for rows.Next() {
+		var s models.PortfolioSnapshot
+		var date, walletValues string
+		if err := rows.Scan(&s.PortfolioID, &date, &s.Currency, &s.TotalValue, &s.TotalCost, &walletValues, &s.Backfilled); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		if s.Date, err = parseSQLiteTime(date); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(walletValues), &s.WalletValues); err != nil {
+			return nil, fmt.Errorf("invalid wallet values of snapshot %s: %w", date, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load snapshots of portfolio %s: %w", portfolioID, err)
+	}
+	return snapshots, nil
+}
+
+// ReminderSent sprawdza, czy przypomnienie o podanym kluczu zostało już wysłane.
+func (r *SQLitePortfolioRepo) ReminderSent(ctx context.Context, key string) (bool, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sent_reminders WHERE key = ?`, key).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check reminder %s: %w", key, err)
+	}
+	return count > 0, nil
+}
+
+// SaveSentReminder zapisuje wysłane przypomnienie, nadpisując wcześniejszy zapis o tym samym kluczu.
+func (r *SQLitePortfolioRepo) SaveSentReminder(ctx context.Context, reminder models.SentReminder) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO sent_reminders (key, portfolio_id, subscription_id, due_date, email, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET email = excluded.email, sent_at = excluded.sent_at`,
+		reminder.Key, reminder.PortfolioID, reminder.SubscriptionID, formatSQLiteTime(reminder.DueDate), reminder.Email, formatSQLiteTime(reminder.SentAt))
+	if err != nil {
+		return fmt.Errorf("failed to save reminder %s: %w", reminder.Key, err)
+	}
+	return nil
+}
+
+// AddNotification zapisuje nowe powiadomienie.
+func (r *SQLitePortfolioRepo) AddNotification(ctx context.Context, n models.Notification) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO notifications (id, user_id, portfolio_id, asset_id, alert_id, title, message, created_at, read)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.ID, n.UserID, n.PortfolioID, n.AssetID, n.AlertID, n.Title, n.Message, formatSQLiteTime(n.CreatedAt), n.Read)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	return nil
+}
+
+// Notifications zwraca najnowsze powiadomienia użytkownika (od najnowszego).
+func (r *SQLitePortfolioRepo) Notifications(ctx context.Context, userID string, limit int) ([]models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, portfolio_id, asset_id, alert_id, title, message, created_at, read
+		FROM notifications WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.UserID, &n.PortfolioID, &n.AssetID, &n.AlertID, &n.Title, &n.Message, &createdAt, &n.Read); err != nil {
+			return nil, fmt.Errorf("failed to decode notification: %w", err)
+		}
+		if n.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// UnreadNotifications zwraca liczbę nieprzeczytanych powiadomień użytkownika.
+func (r *SQLitePortfolioRepo) UnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read = 0`, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationsRead oznacza wszystkie powiadomienia użytkownika jako przeczytane.
+func (r *SQLitePortfolioRepo) MarkNotificationsRead(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE notifications SET read = 1 WHERE user_id = ? AND read = 0`, userID); err != nil {
+		return fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+	return nil
+}
+
+// WebhookEndpoints zwraca adresy webhooków użytkownika (od najstarszego).
+func (r *SQLitePortfolioRepo) WebhookEndpoints(ctx context.Context, userID string) ([]models.WebhookEndpoint, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, url, secret, events, active, created_at
+		FROM webhook_endpoints WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// GetWebhookEndpoint zwraca adres webhooka o podanym ID.
+func (r *SQLitePortfolioRepo) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, url, secret, events, active, created_at
+		FROM webhook_endpoints WHERE id = ?`, endpointID)
+	endpoint, err := scanWebhookEndpoint(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return endpoint, err
+}
+
+// scanWebhookEndpoint odczytuje adres webhooka z wiersza zapytania.
+func scanWebhookEndpoint(row interface{ Scan(...any) error }) (*models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	var events, createdAt string
+	if err := row.Scan(&e.ID, &e.UserID, &e.URL, &e.Secret, &events, &e.Active, &createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to decode webhook endpoint: %w", err)
+	}
+	if err := json.Unmarshal([]byte(events), &e.Events); err != nil {
+		return nil, fmt.Errorf("invalid events of webhook endpoint %s: %w", e.ID, err)
+	}
+	var err error
+	if e.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveWebhookEndpoint zapisuje adres webhooka, nadpisując zapisany adres o tym samym ID.
+func (r *SQLitePortfolioRepo) SaveWebhookEndpoint(ctx context.Context, e models.WebhookEndpoint) error {
+	if e.Events == nil {
+		e.Events = []models.WebhookEvent{}
+	}
+	events, err := json.Marshal(e.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO webhook_endpoints (id, user_id, url, secret, events, active, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET url = excluded.url, secret = excluded.secret, events = excluded.events, active = excluded.active`,
+		e.ID, e.UserID, e.URL, e.Secret, string(events), e.Active, formatSQLiteTime(e.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to save webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint usuwa adres webhooka użytkownika (doręczenia usuwa klucz obcy ON DELETE CASCADE).
+func (r *SQLitePortfolioRepo) DeleteWebhookEndpoint(ctx context.Context, userID, endpointID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = ? AND user_id = ?`, endpointID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SaveWebhookDelivery zapisuje doręczenie, nadpisując zapisane doręczenie o tym samym ID.
+func (r *SQLitePortfolioRepo) SaveWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (id, endpoint_id, user_id, event, payload, status, attempts,
+			response_code, error, created_at, next_attempt_at, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET status = excluded.status, attempts = excluded.attempts, response_code = excluded.response_code,
+			error = excluded.error, next_attempt_at = excluded.next_attempt_at, delivered_at = excluded.delivered_at`,
+		d.ID, d.EndpointID, d.UserID, string(d.Event), d.Payload, string(d.Status), d.Attempts, d.ResponseCode, d.Error,
+		formatSQLiteTime(d.CreatedAt), formatPriceTime(d.NextAttemptAt), formatPriceTime(d.DeliveredAt))
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// WebhookDeliveries zwraca najnowsze doręczenia użytkownika (od najnowszego).
+func (r *SQLitePortfolioRepo) WebhookDeliveries(ctx context.Context, userID string, limit int) ([]models.WebhookDelivery, error) {
+	return r.queryWebhookDeliveries(ctx, `WHERE user_id = ? ORDER BY created_at DESC LIMIT ?`, userID, limit)
+}
+
+// DueWebhookDeliveries zwraca oczekujące doręczenia, których kolejna próba już przypadła.
+func (r *SQLitePortfolioRepo) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return r.queryWebhookDeliveries(ctx, `WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`,
+		string(models.WebhookPending), formatSQLiteTime(now), limit)
+}
+
+// queryWebhookDeliveries odczytuje doręczenia spełniające warunek (klauzule WHERE, ORDER BY i LIMIT).
+func (r *SQLitePortfolioRepo) queryWebhookDeliveries(ctx context.Context, where string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, endpoint_id, user_id, event, payload, status, attempts, response_code, error,
+		created_at, next_attempt_at, delivered_at FROM webhook_deliveries `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var createdAt, nextAttemptAt, deliveredAt string
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.UserID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error,
+			&createdAt, &nextAttemptAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook delivery: %w", err)
+		}
+		if d.CreatedAt, err = parseSQLiteTime(createdAt); err != nil {
+			return nil, err
+		}
+		if d.NextAttemptAt, err = parseOptionalSQLiteTime(nextAttemptAt); err != nil {
+			return nil, err
+		}
+		if d.DeliveredAt, err = parseOptionalSQLiteTime(deliveredAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// TestSQLiteMigrations sprawdza, że migracje można uruchomić ponownie (przy każdym otwarciu bazy)
+// bez błędów i bez ponownego stosowania już wykonanych.
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "webwallet.db")
+	repo := openTestSQLite(t, path)
+	if err := repo.migrate(ctx); err != nil {
+		t.Fatalf("migrate() second run error: %v", err)
+	}
+	repo.Disconnect(ctx)
+
+	repo = openTestSQLite(t, path)
+	var count, latest int
+	if err := repo.db.QueryRowContext(ctx, `SELECT COUNT(*), MAX(version) FROM schema_migrations`).Scan(&count, &latest); err != nil {
+		t.Fatalf("failed to read schema_migrations: %v", err)
+	}
+	if count != len(sqliteMigrations) || latest != len(sqliteMigrations) {
+		t.Errorf("schema_migrations has %d rows up to version %d, want %d", count, latest, len(sqliteMigrations))
+	}
+}
+
+// TestSQLiteFixedWidthTimesMigration sprawdza, że migracja 16 dopełnia daty zapisane ze zmienną
+// liczbą cyfr ułamka sekundy do sqliteTimeLayout, więc porównania tekstu znów dają kolejność chronologiczną.
+func TestSQLiteFixedWidthTimesMigration(t *testing.T) {
+	ctx := context.Background()
+	repo := openTestSQLite(t, filepath.Join(t.TempDir(), "webwallet.db"))
+
+	// Daty w starym formacie RFC 3339 - bez ułamka albo z jego skróconą postacią
+	legacy := map[string]string{
+		"AAA": "2026-01-02T00:00:00Z",
+		"BBB": "2026-01-02T03:04:05.5Z",
+		"CCC": "2026-01-02T03:04:05.123456789Z",
+	}
+	for symbol, date := range legacy {
+		if _, err := repo.db.ExecContext(ctx, `INSERT INTO price_history (symbol, date, close, source) VALUES (?, ?, '1', 'test')`, symbol, date); err != nil {
+			t.Fatalf("failed to insert legacy price: %v", err)
+		}
+	}
+	if _, err := repo.db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = 16`); err != nil {
+		t.Fatalf("failed to rewind schema version: %v", err)
+	}
+	if err := repo.migrate(ctx); err != nil {
+		t.Fatalf("migrate() error: %v", err)
+	}
+
+	want := map[string]string{
+		"AAA": "2026-01-02T00:00:00.000000000Z",
+		"BBB": "2026-01-02T03:04:05.500000000Z",
+		"CCC": "2026-01-02T03:04:05.123456789Z",
+	}
+	for symbol, expected := range want {
+		var date string
+		if err := repo.db.QueryRowContext(ctx, `SELECT date FROM price_history WHERE symbol = ?`, symbol).Scan(&date); err != nil {
+			t.Fatalf("failed to read migrated date: %v", err)
+		}
+		if date != expected {
+			t.Errorf("migrated date of %s = %q, want %q", symbol, date, expected)
+		}
+		parsed, err := parseSQLiteTime(date)
+		if err != nil {
+			t.Fatalf("parseSQLiteTime(%q) error: %v", date, err)
+		}
+		if formatSQLiteTime(parsed) != date {
+			t.Errorf("migrated date %q does not match sqliteTimeLayout", date)
+		}
+	}
+}
+
+// TestSQLiteRoundTrip sprawdza, że portfel z aktywami, transakcjami, alertami, subskrypcjami
+// i płatnościami wczytuje się po ponownym otwarciu bazy taki sam, jak został zapisany.
+func TestSQLiteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "webwallet.db")
+	repo := openTestSQLite(t, path)
+
+	const id = "p1"
+	if err := repo.CreatePortfolio(ctx, models.PortfolioInfo{ID: id, Name: "Emerytura", OwnerID: "u1"}); err != nil {
+		t.Fatalf("CreatePortfolio() error: %v", err)
+	}
+	asset := models.Asset{ID: "a1", Name: "ETF USD", Type: "ETF", WalletType: "IKE", Currency: "USD",
+		Quantity: models.NewDecimal(10), AvgCost: models.NewDecimalFromFloat(100.25), CurrentPrice: models.NewDecimal(110)}
+	if err := repo.AddAsset(ctx, id, 0, asset); err != nil {
+		t.Fatalf("AddAsset() error: %v", err)
+	}
+	dividend := models.Transaction{ID: "t-div", Type: models.TransactionDividend, Date: models.Today(), Amount: models.NewDecimalFromFloat(12.34), Note: "Dywidenda"}
+	if err := repo.AddTransaction(ctx, id, 1, "a1", dividend); err != nil {
+		t.Fatalf("AddTransaction() error: %v", err)
+	}
+	alertTime := time.Date(2026, 10, 17, 9, 30, 0, 500, time.UTC)
+	alert := models.PriceAlert{ID: "al1", Condition: models.AlertPriceBelow, Threshold: models.NewDecimal(90), Email: true,
+		SnoozedUntil: alertTime.Add(time.Hour), CreatedAt: alertTime}
+	if err := repo.UpdateAssetAlerts(ctx, id, 2, "a1", []models.PriceAlert{alert}); err != nil {
+		t.Fatalf("UpdateAssetAlerts() error: %v", err)
+	}
+	nextDue := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	sub := models.Subscription{ID: "s1", Name: "Chmura", Cost: models.NewDecimalFromFloat(9.99), Currency: "EUR",
+		Frequency: models.FrequencyMonthly, NextDue: nextDue, ReminderDays: 3}
+	if err := repo.AddSubscription(ctx, id, 3, sub); err != nil {
+		t.Fatalf("AddSubscription() error: %v", err)
+	}
+	payment := models.SubscriptionPayment{ID: "pay1", Date: nextDue.AddDate(0, 0, -1), Amount: models.NewDecimalFromFloat(10.49), Note: "Podwyżka"}
+	if err := repo.RecordSubscriptionPayment(ctx, id, 4, "s1", payment, false); err != nil {
+		t.Fatalf("RecordSubscriptionPayment() error: %v", err)
+	}
+	if err := repo.RemoveAsset(ctx, id, 4, "a1"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("RemoveAsset() with stale version expected ErrConflict, got %v", err)
+	}
+
+	repo.Disconnect(ctx)
+	repo = openTestSQLite(t, path)
+	portfolio, err := repo.LoadPortfolio(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadPortfolio() error: %v", err)
+	}
+	if portfolio.Version != 5 || portfolio.Name != "Emerytura" || portfolio.OwnerID != "u1" {
+		t.Errorf("loaded portfolio version %d, name %q, owner %q", portfolio.Version, portfolio.Name, portfolio.OwnerID)
+	}
+
+	stored, found := portfolio.FindAsset("a1")
+	if !found {
+		t.Fatalf("loaded portfolio has no asset a1: %+v", portfolio.Assets)
+	}
+	if stored.Name != asset.Name || stored.Currency != "USD" || stored.WalletType != "IKE" ||
+		stored.Quantity != asset.Quantity || stored.AvgCost != asset.AvgCost || stored.CurrentPrice != asset.CurrentPrice {
+		t.Errorf("loaded asset = %+v", stored)
+	}
+	var loadedDividend *models.Transaction
+	for i := range stored.Transactions {
+		if stored.Transactions[i].ID == dividend.ID {
+			loadedDividend = &stored.Transactions[i]
+		}
+	}
+	if loadedDividend == nil || loadedDividend.Amount != dividend.Amount || !loadedDividend.Date.Equal(dividend.Date) || loadedDividend.Note != dividend.Note {
+		t.Errorf("loaded transactions = %+v", stored.Transactions)
+	}
+	if len(stored.Alerts) != 1 {
+		t.Fatalf("loaded alerts = %+v", stored.Alerts)
+	}
+	if got := stored.Alerts[0]; got.Condition != alert.Condition || got.Threshold != alert.Threshold || !got.Email ||
+		!got.SnoozedUntil.Equal(alert.SnoozedUntil) || !got.CreatedAt.Equal(alert.CreatedAt) {
+		t.Errorf("loaded alert = %+v", got)
+	}
+
+	if len(portfolio.Subscriptions) != 1 {
+		t.Fatalf("loaded subscriptions = %+v", portfolio.Subscriptions)
+	}
+	gotSub := portfolio.Subscriptions[0]
+	if gotSub.Name != sub.Name || gotSub.Cost != sub.Cost || gotSub.Currency != "EUR" || gotSub.Frequency != sub.Frequency ||
+		!gotSub.NextDue.Equal(nextDue) || gotSub.ReminderDays != 3 {
+		t.Errorf("loaded subscription = %+v", gotSub)
+	}
+	if len(gotSub.Payments) != 1 || gotSub.Payments[0].Amount != payment.Amount || !gotSub.Payments[0].Date.Equal(payment.Date) || gotSub.Payments[0].Note != payment.Note {
+		t.Errorf("loaded payments = %+v", gotSub.Payments)
+	}
+}
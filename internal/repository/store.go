@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// ErrNotFound oznacza, że szukany rekord (np. użytkownik, sesja lub portfel) nie istnieje.
+var ErrNotFound = errors.New("not found")
+
+// ErrUserExists oznacza, że konto z podanym adresem e-mail już istnieje.
+var ErrUserExists = errors.New("user already exists")
+
+// ErrConflict oznacza, że portfel zmienił się od chwili wczytania (np. w innej karcie przeglądarki),
+// więc zapis został odrzucony, żeby nie nadpisać cudzych zmian. Należy wczytać portfel ponownie i powtórzyć operację.
+var ErrConflict = errors.New("portfolio was modified concurrently")
+
+// errNothingRolled przerywa zapis portfela, gdy żaden termin subskrypcji nie minął (bez podbijania wersji).
+var errNothingRolled = errors.New("no subscription to roll over")
+
+// AnyVersion podana jako oczekiwana wersja portfela wyłącza jej sprawdzanie (np. dla formularzy bez pola wersji).
+const AnyVersion int64 = -1
+
+// PortfolioStore opisuje operacje na portfelu, z których korzystają handlery.
+// Dzięki temu handlery nie zależą od konkretnej bazy danych - mogą pracować na MongoDB
+// (PortfolioRepo), pliku SQLite (SQLitePortfolioRepo) albo na pamięci (MemoryPortfolioRepo), np. w demo i testach.
+// Każda operacja dotyczy portfela o podanym ID (portfela zalogowanego użytkownika).
+type PortfolioStore interface {
+	// LoadPortfolio zwraca portfel (lub nowy, pusty portfel, jeśli jeszcze nic nie zapisano).
+	// Portfela nie zapisuje się w całości - każda zmiana ma własną operację, która zapisuje tylko zmieniane pola.
+	// Cena bieżąca i stan alertów zmieniają się bez podbijania wersji, więc zapis całego wczytanego portfela
+	// mógłby je po cichu cofnąć.
+	LoadPortfolio(ctx context.Context, portfolioID string) (*models.InvestmentPortfolio, error)
+
+	// ListPortfolios zwraca portfele należące do użytkownika (posortowane po nazwie).
+	ListPortfolios(ctx context.Context, ownerID string) ([]models.PortfolioInfo, error)
+	// AllPortfolios zwraca wszystkie portfele wszystkich użytkowników (np. dla zadań w tle, jak odświeżanie cen).
+	AllPortfolios(ctx context.Context) ([]models.PortfolioInfo, error)
+	// CreatePortfolio zakłada nowy, pusty portfel. Jeśli portfel o tym ID już istnieje
+	// (np. portfel zapisany przed wprowadzeniem wielu portfeli), zapisuje tylko jego nazwę i właściciela.
+	CreatePortfolio(ctx context.Context, info models.PortfolioInfo) error
+	// ClaimPortfolio nadaje nazwę i właściciela istniejącemu portfelowi, który nie ma jeszcze właściciela
+	// (np. portfelowi zapisanemu przed wprowadzeniem kont). Warunek jest sprawdzany w tym samym zapisie,
+	// więc z równoczesnych prób portfel dostaje tylko jedna. Zwraca false, gdy portfela nie ma albo ma już właściciela.
+	ClaimPortfolio(ctx context.Context, info models.PortfolioInfo) (bool, error)
+	// RenamePortfolio zmienia nazwę portfela. Zwraca ErrNotFound, jeśli portfel nie istnieje.
+	RenamePortfolio(ctx context.Context, portfolioID, name string) error
+	// DeletePortfolio usuwa portfel wraz z aktywami, subskrypcjami i zapisami wartości. Zwraca ErrNotFound, jeśli portfel nie istnieje.
+	DeletePortfolio(ctx context.Context, portfolioID string) error
+
+	// Operacje na aktywach i subskrypcjach zmieniają tylko dotknięty element portfela i podbijają jego wersję,
+	// więc równoległe zmiany różnych aktywów nie nadpisują się nawzajem. Mogą zwrócić ErrConflict,
+	// jeśli zmieniany element zmienił się między wczytaniem a zapisem. Operacje z parametrem version
+	// zapisują zmianę tylko wtedy, gdy portfel ma nadal tę wersję (warunek jest częścią zapisu),
+	// a w przeciwnym razie zwracają ErrConflict; AnyVersion wyłącza ten warunek.
+	AddAsset(ctx context.Context, portfolioID string, version int64, asset models.Asset) error
+	RemoveAsset(ctx context.Context, portfolioID string, version int64, assetID string) error
+	UpdateAsset(ctx context.Context, portfolioID string, version int64, assetID string, additionalQuantity, newPurchasePrice models.Decimal) error
+	SellAsset(ctx context.Context, portfolioID string, version int64, assetID string, quantity, price, fee models.Decimal, date time.Time) error
+	// UpdateAssetCurrentPrice ustawia cenę bieżącą aktywa i zapamiętuje, skąd i kiedy pochodzi notowanie.
+	// Nie podbija wersji portfela - odświeżanie cen w tle nie może unieważniać formularzy otwartych przez użytkownika.
+	UpdateAssetCurrentPrice(ctx context.Context, portfolioID, assetID string, quote models.PriceQuote) error
+	UpdateAssetWalletType(ctx context.Context, portfolioID string, version int64, assetID string, newWalletType string) error
+	// PatchAsset zmienia typ portfela i cenę bieżącą aktywa (pola ustawione w patch) jednym zapisem,
+	// więc albo zapisują się obie zmiany, albo żadna.
+	PatchAsset(ctx context.Context, portfolioID string, version int64, assetID string, patch models.AssetPatch) error
+	// UpdateAssetAlerts zastępuje alerty cenowe aktywa (także ich stan po ocenie nowej ceny).
+	UpdateAssetAlerts(ctx context.Context, portfolioID string, version int64, assetID string, alerts []models.PriceAlert) error
+	// UpdateAlertState zapisuje stan alertu po ocenie nowej ceny (Triggered, LastTriggeredAt), o ile alert ma nadal
+	// stan previous - z równoległych ocen tej samej zmiany ceny zapisuje się tylko jedna. Zwraca false, gdy stan
+	// zmienił się w międzyczasie albo alertu już nie ma. Jak UpdateAssetCurrentPrice nie podbija wersji portfela.
+	UpdateAlertState(ctx context.Context, portfolioID, assetID string, previous, next models.PriceAlert) (bool, error)
+
+	AddTransaction(ctx context.Context, portfolioID string, version int64, assetID string, tx models.Transaction) error
+	RemoveTransaction(ctx context.Context, portfolioID string, version int64, assetID, transactionID string) error
+	UpdateCostBasisMethod(ctx context.Context, portfolioID string, method models.CostBasisMethod) error
+
+	AddSubscription(ctx context.Context, portfolioID string, version int64, sub models.Subscription) error
+	RemoveSubscription(ctx context.Context, portfolioID string, version int64, subID string) error
+	// UpdateSubscription zmienia pola subskrypcji edytowane w formularzu (zob. models.Subscription.Edit)
+	// i przesuwa jej minione terminy. Historii terminów i płatności nie przepisuje z updatedSub.
+	UpdateSubscription(ctx context.Context, portfolioID string, version int64, updatedSub models.Subscription) error
+	// RollOverSubscriptions przesuwa minione terminy subskrypcji portfela (zob. models.Subscription.RollOver)
+	// i zwraca subskrypcje, które się zmieniły.
+	RollOverSubscriptions(ctx context.Context, portfolioID string, today time.Time) ([]models.Subscription, error)
+	// RecordSubscriptionPayment dopisuje faktyczną płatność do historii subskrypcji; gdy markPaid jest true,
+	// płatność rozlicza bieżący termin i NextDue przesuwa się na kolejny.
+	RecordSubscriptionPayment(ctx context.Context, portfolioID string, version int64, subID string, payment models.SubscriptionPayment, markPaid bool) error
+
+	// Kursy walut są wspólne dla wszystkich portfeli, a waluta bazowa jest ustawieniem portfela.
+	LoadFXRates(ctx context.Context) ([]models.FXRate, error)
+	SaveFXRate(ctx context.Context, rate models.FXRate) error
+	RemoveFXRate(ctx context.Context, pair string) error
+	UpdateBaseCurrency(ctx context.Context, portfolioID, currency string) error
+
+	// UpdateAllocationTargets zastępuje docelowy podział portfela w podanej grupie (typ portfela lub typ aktywa).
+	UpdateAllocationTargets(ctx context.Context, portfolioID, group string, targets []models.AllocationTarget) error
+	// UpdateEmergencyFundSettings zapisuje koszty życia i próg ostrzeżenia poduszki finansowej portfela.
+	UpdateEmergencyFundSettings(ctx context.Context, portfolioID string, settings models.EmergencyFundSettings) error
+
+	// Disconnect zwalnia zasoby (połączenie z bazą danych) przy zamykaniu aplikacji.
+	Disconnect(ctx context.Context) error
+}
+
+// UserStore przechowuje konta użytkowników i ich sesje logowania.
+type UserStore interface {
+	// CreateUser zapisuje nowe konto. Zwraca ErrUserExists, jeśli adres e-mail jest zajęty.
+	CreateUser(ctx context.Context, user models.User) error
+	// GetUser zwraca użytkownika o podanym ID albo ErrNotFound.
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	// GetUserByEmail zwraca użytkownika o podanym adresie e-mail albo ErrNotFound.
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// CountUsers zwraca liczbę zarejestrowanych kont.
+	CountUsers(ctx context.Context) (int64, error)
+	// SetUserPortfolio zmienia portfel główny użytkownika. Zwraca ErrNotFound, jeśli użytkownik nie istnieje.
+	SetUserPortfolio(ctx context.Context, userID, portfolioID string) error
+
+	// CreateSession zapisuje nową sesję logowania.
+	CreateSession(ctx context.Context, session models.Session) error
+	// GetSession zwraca sesję o podanym skrócie tokenu albo ErrNotFound (także dla sesji wygasłej).
+	GetSession(ctx context.Context, tokenHash string) (*models.Session, error)
+	// DeleteSession usuwa sesję (wylogowanie). Brak sesji nie jest błędem.
+	DeleteSession(ctx context.Context, tokenHash string) error
+
+	// SetCalendarToken zapisuje skrót tokenu kanału iCalendar użytkownika (zastępując poprzedni).
+	SetCalendarToken(ctx context.Context, userID, tokenHash string) error
+	// GetUserByCalendarToken zwraca użytkownika o podanym skrócie tokenu kanału iCalendar albo ErrNotFound.
+	GetUserByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error)
+}
+
+// PriceHistoryStore przechowuje historię cen zamknięcia symboli (jeden punkt na symbol i dzień).
+// Historia jest wspólna dla wszystkich portfeli - cena symbolu nie zależy od tego, kto go posiada.
+type PriceHistoryStore interface {
+	// RecordPrice zapisuje cenę symbolu, nadpisując cenę zapisaną wcześniej tego samego dnia.
+	RecordPrice(ctx context.Context, point models.PricePoint) error
+	// PriceHistory zwraca punkty historii symbolu z przedziału [from, to], posortowane po dacie.
+	// Zerowa wartość from lub to oznacza brak ograniczenia z tej strony.
+	PriceHistory(ctx context.Context, symbol string, from, to time.Time) ([]models.PricePoint, error)
+}
+
+// SnapshotStore przechowuje dzienne zapisy wartości portfeli (jeden zapis na portfel i dzień).
+type SnapshotStore interface {
+	// SaveSnapshot zapisuje stan portfela z danego dnia, nadpisując wcześniejszy zapis z tego dnia.
+	SaveSnapshot(ctx context.Context, snapshot models.PortfolioSnapshot) error
+	// Snapshots zwraca zapisy portfela z przedziału [from, to], posortowane po dacie.
+	// Zerowa wartość from lub to oznacza brak ograniczenia z tej strony.
+	Snapshots(ctx context.Context, portfolioID string, from, to time.Time) ([]models.PortfolioSnapshot, error)
+}
+
+// ReminderStore zapamiętuje wysłane przypomnienia o płatnościach, żeby restart aplikacji nie powtarzał wysyłki.
+type ReminderStore interface {
+	// ReminderSent mówi, czy przypomnienie o podanym kluczu (SubscriptionReminder.Key) zostało już wysłane.
+	ReminderSent(ctx context.Context, key string) (bool, error)
+	// SaveSentReminder zapisuje wysłane przypomnienie (ponowny zapis tego samego klucza nie jest błędem).
+	SaveSentReminder(ctx context.Context, reminder models.SentReminder) error
+}
+
+// NotificationStore przechowuje centrum powiadomień użytkowników (m.in. historię alertów cenowych).
+type NotificationStore interface {
+	// AddNotification zapisuje nowe powiadomienie.
+	AddNotification(ctx context.Context, notification models.Notification) error
+	// Notifications zwraca najnowsze powiadomienia użytkownika (od najnowszego), najwyżej limit.
+	Notifications(ctx context.Context, userID string, limit int) ([]models.Notification, error)
+	// UnreadNotifications zwraca liczbę nieprzeczytanych powiadomień użytkownika.
+	UnreadNotifications(ctx context.Context, userID string) (int, error)
+	// MarkNotificationsRead oznacza wszystkie powiadomienia użytkownika jako przeczytane.
+	MarkNotificationsRead(ctx context.Context, userID string) error
+}
+
+// WebhookStore przechowuje adresy webhooków użytkowników i dziennik doręczeń zdarzeń.
+type WebhookStore interface {
+	// WebhookEndpoints zwraca adresy webhooków użytkownika (od najstarszego).
+	WebhookEndpoints(ctx context.Context, userID string) ([]models.WebhookEndpoint, error)
+	// GetWebhookEndpoint zwraca adres webhooka o podanym ID albo ErrNotFound.
+	GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error)
+	// SaveWebhookEndpoint zapisuje nowy adres albo zastępuje zapisany adres o tym samym ID.
+	SaveWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) error
+	// DeleteWebhookEndpoint usuwa adres webhooka użytkownika wraz z jego doręczeniami. Zwraca ErrNotFound,
+	// jeśli użytkownik nie ma takiego adresu.
+	DeleteWebhookEndpoint(ctx context.Context, userID, endpointID string) error
+
+	// SaveWebhookDelivery zapisuje nowe doręczenie albo zastępuje zapisane doręczenie o tym samym ID (wynik kolejnej próby).
+	SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// WebhookDeliveries zwraca najnowsze doręczenia użytkownika (od najnowszego), najwyżej limit.
+	WebhookDeliveries(ctx context.Context, userID string, limit int) ([]models.WebhookDelivery, error)
+	// DueWebhookDeliveries zwraca oczekujące doręczenia, których kolejna próba przypada najpóźniej na now
+	// (od najdawniej zaplanowanej), najwyżej limit.
+	DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+}
+
+// Store łączy wszystkie magazyny danych aplikacji - każda implementacja (MongoDB, SQLite, pamięć)
+// udostępnia je wszystkie.
+type Store interface {
+	PortfolioStore
+	UserStore
+	PriceHistoryStore
+	SnapshotStore
+	ReminderStore
+	NotificationStore
+	WebhookStore
+}
+
+// Sprawdzenie w czasie kompilacji, że wszystkie implementacje spełniają interfejs.
+var (
+	_ Store = (*PortfolioRepo)(nil)
+	_ Store = (*MemoryPortfolioRepo)(nil)
+	_ Store = (*SQLitePortfolioRepo)(nil)
+)
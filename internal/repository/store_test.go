@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"webwallet/internal/models"
+)
+
+// storeFactories to implementacje Store sprawdzane wspólnym testem kontraktu. MongoDB wymaga serwera,
+// więc testujemy ją tylko przez kompilację (zob. sprawdzenie interfejsu w store.go).
+var storeFactories = map[string]func(t *testing.T) Store{
+	"memory": func(t *testing.T) Store {
+		return NewMemoryPortfolioRepo()
+	},
+	"sqlite": func(t *testing.T) Store {
+		return openTestSQLite(t, filepath.Join(t.TempDir(), "webwallet.db"))
+	},
+}
+
+// openTestSQLite otwiera bazę SQLite pod podaną ścieżką i zamyka ją po zakończeniu testu.
+func openTestSQLite(t *testing.T, path string) *SQLitePortfolioRepo {
+	t.Helper()
+	repo, err := NewSQLitePortfolioRepo(path)
+	if err != nil {
+		t.Fatalf("NewSQLitePortfolioRepo() error: %v", err)
+	}
+	t.Cleanup(func() { repo.Disconnect(context.Background()) })
+	return repo
+}
+
+// TestStoreContract sprawdza, że każda implementacja Store tak samo pilnuje wersji portfela,
+// zapisuje zmiany aktywów, transakcji, alertów i subskrypcji oraz zgłasza brakujące rekordy.
+func TestStoreContract(t *testing.T) {
+	for name, newStore := range storeFactories {
+		t.Run(name, func(t *testing.T) {
+			testPortfolioContract(t, newStore(t))
+			testUserContract(t, newStore(t))
+		})
+	}
+}
+
+// loadVersion wczytuje portfel i zwraca jego bieżącą wersję.
+func loadVersion(t *testing.T, store Store, portfolioID string) int64 {
+	t.Helper()
+	portfolio, err := store.LoadPortfolio(context.Background(), portfolioID)
+	if err != nil {
+		t.Fatalf("LoadPortfolio() error: %v", err)
+	}
+	return portfolio.Version
+}
+
+func testPortfolioContract(t *testing.T, store Store) {
+	ctx := context.Background()
+	const id = "p1"
+	if err := store.CreatePortfolio(ctx, models.PortfolioInfo{ID: id, Name: "Główny", OwnerID: "u1"}); err != nil {
+		t.Fatalf("CreatePortfolio() error: %v", err)
+	}
+	if infos, err := store.ListPortfolios(ctx, "u1"); err != nil || len(infos) != 1 || infos[0].Name != "Główny" {
+		t.Fatalf("ListPortfolios() = %+v, %v", infos, err)
+	}
+
+	version := loadVersion(t, store, id)
+	asset := models.Asset{ID: "a1", Name: "ETF", Type: "ETF", WalletType: "IKE", Quantity: models.NewDecimal(10),
+		AvgCost: models.NewDecimal(100), CurrentPrice: models.NewDecimal(110)}
+	if err := store.AddAsset(ctx, id, version, asset); err != nil {
+		t.Fatalf("AddAsset() error: %v", err)
+	}
+	if err := store.AddAsset(ctx, id, version, models.Asset{ID: "a2", Name: "Stary formularz", Quantity: models.NewDecimal(1)}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("AddAsset() with stale version expected ErrConflict, got %v", err)
+	}
+	if next := loadVersion(t, store, id); next != version+1 {
+		t.Fatalf("AddAsset() expected version %d, got %d", version+1, next)
+	}
+
+	// Cena bieżąca i stan alertów nie podbijają wersji - nie mogą unieważniać otwartych formularzy
+	version = loadVersion(t, store, id)
+	quote := models.PriceQuote{Price: models.NewDecimal(120), Source: "test", Time: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)}
+	if err := store.UpdateAssetCurrentPrice(ctx, id, "a1", quote); err != nil {
+		t.Fatalf("UpdateAssetCurrentPrice() error: %v", err)
+	}
+	alert := models.PriceAlert{ID: "al1", Condition: models.AlertPriceAbove, Threshold: models.NewDecimal(115)}
+	if err := store.UpdateAssetAlerts(ctx, id, version, "a1", []models.PriceAlert{alert}); err != nil {
+		t.Fatalf("UpdateAssetAlerts() error: %v", err)
+	}
+	version++
+	triggered := alert
+	triggered.Triggered = true
+	triggered.LastTriggeredAt = quote.Time
+	if ok, err := store.UpdateAlertState(ctx, id, "a1", alert, triggered); err != nil || !ok {
+		t.Fatalf("UpdateAlertState() = %v, %v, want true", ok, err)
+	}
+	if ok, err := store.UpdateAlertState(ctx, id, "a1", alert, triggered); err != nil || ok {
+		t.Fatalf("UpdateAlertState() with stale state = %v, %v, want false", ok, err)
+	}
+	if next := loadVersion(t, store, id); next != version {
+		t.Fatalf("price and alert state changes should keep version %d, got %d", version, next)
+	}
+
+	sellDate := models.Today()
+	if err := store.SellAsset(ctx, id, version, "a1", models.NewDecimal(4), models.NewDecimal(130), models.Zero, sellDate); err != nil {
+		t.Fatalf("SellAsset() error: %v", err)
+	}
+	if err := store.SellAsset(ctx, id, version, "a1", models.NewDecimal(1), models.NewDecimal(130), models.Zero, sellDate); !errors.Is(err, ErrConflict) {
+		t.Fatalf("SellAsset() with stale version expected ErrConflict, got %v", err)
+	}
+
+	portfolio, err := store.LoadPortfolio(ctx, id)
+	if err != nil {
+		t.Fatalf("LoadPortfolio() error: %v", err)
+	}
+	stored, found := portfolio.FindAsset("a1")
+	if !found {
+		t.Fatalf("LoadPortfolio() expected asset a1, got %+v", portfolio.Assets)
+	}
+	if stored.Quantity != models.NewDecimal(6) || stored.CurrentPrice != models.NewDecimal(120) || stored.PriceSource != "test" {
+		t.Errorf("stored asset = quantity %s, price %s from %q", stored.Quantity, stored.CurrentPrice, stored.PriceSource)
+	}
+	if len(stored.Alerts) != 1 || !stored.Alerts[0].Triggered || !stored.Alerts[0].LastTriggeredAt.Equal(quote.Time) {
+		t.Errorf("stored alerts = %+v", stored.Alerts)
+	}
+	var sellID string
+	for _, tx := range stored.Transactions {
+		if tx.Type == models.TransactionSell {
+			sellID = tx.ID
+		}
+	}
+	if sellID == "" {
+		t.Fatalf("stored transactions have no sell: %+v", stored.Transactions)
+	}
+	if err := store.RemoveTransaction(ctx, id, portfolio.Version, "a1", sellID); err != nil {
+		t.Fatalf("RemoveTransaction() error: %v", err)
+	}
+	portfolio, _ = store.LoadPortfolio(ctx, id)
+	if stored, _ := portfolio.FindAsset("a1"); stored.Quantity != models.NewDecimal(10) {
+		t.Errorf("RemoveTransaction() expected quantity 10, got %s", stored.Quantity)
+	}
+
+	nextDue := time.Date(2026, 11, 10, 0, 0, 0, 0, time.UTC)
+	sub := models.Subscription{ID: "s1", Name: "Muzyka", Cost: models.NewDecimal(20), Frequency: models.FrequencyMonthly, NextDue: nextDue}
+	if err := store.AddSubscription(ctx, id, portfolio.Version, sub); err != nil {
+		t.Fatalf("AddSubscription() error: %v", err)
+	}
+	payment := models.SubscriptionPayment{ID: "pay1", Date: nextDue, Amount: models.NewDecimal(21)}
+	if err := store.RecordSubscriptionPayment(ctx, id, portfolio.Version+1, "s1", payment, true); err != nil {
+		t.Fatalf("RecordSubscriptionPayment() error: %v", err)
+	}
+	if err := store.RemoveSubscription(ctx, id, portfolio.Version, "s1"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("RemoveSubscription() with stale version expected ErrConflict, got %v", err)
+	}
+	portfolio, _ = store.LoadPortfolio(ctx, id)
+	if len(portfolio.Subscriptions) != 1 {
+		t.Fatalf("expected one subscription, got %+v", portfolio.Subscriptions)
+	}
+	if got := portfolio.Subscriptions[0]; len(got.Payments) != 1 || got.Payments[0].Amount != payment.Amount || !got.NextDue.After(nextDue) {
+		t.Errorf("stored subscription = %+v", got)
+	}
+	if err := store.RemoveSubscription(ctx, id, portfolio.Version, "s1"); err != nil {
+		t.Fatalf("RemoveSubscription() error: %v", err)
+	}
+
+	if err := store.DeletePortfolio(ctx, id); err != nil {
+		t.Fatalf("DeletePortfolio() error: %v", err)
+	}
+	if err := store.RenamePortfolio(ctx, id, "Nowa nazwa"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RenamePortfolio() of deleted portfolio expected ErrNotFound, got %v", err)
+	}
+}
+
+func testUserContract(t *testing.T, store Store) {
+	ctx := context.Background()
+	user := models.User{ID: "u1", Email: "a@b.pl", PasswordHash: "hash", PortfolioID: "p1", CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := store.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if err := store.CreateUser(ctx, models.User{ID: "u2", Email: "a@b.pl"}); !errors.Is(err, ErrUserExists) {
+		t.Errorf("CreateUser() with taken email expected ErrUserExists, got %v", err)
+	}
+	if got, err := store.GetUserByEmail(ctx, "a@b.pl"); err != nil || got.ID != "u1" || got.PasswordHash != "hash" {
+		t.Errorf("GetUserByEmail() = %+v, %v", got, err)
+	}
+	if _, err := store.GetUser(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUser() of missing user expected ErrNotFound, got %v", err)
+	}
+	if err := store.SetUserPortfolio(ctx, "missing", "p2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetUserPortfolio() of missing user expected ErrNotFound, got %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	session := models.Session{TokenHash: "t1", UserID: "u1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := store.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession() error: %v", err)
+	}
+	if got, err := store.GetSession(ctx, "t1"); err != nil || got.UserID != "u1" {
+		t.Errorf("GetSession() = %+v, %v", got, err)
+	}
+	if err := store.DeleteSession(ctx, "t1"); err != nil {
+		t.Fatalf("DeleteSession() error: %v", err)
+	}
+	if _, err := store.GetSession(ctx, "t1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSession() of deleted session expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestPriceHistoryStore sprawdza, że ponowny zapis ceny z tego samego dnia nadpisuje punkt historii,
+// a zapytanie o przedział [from, to] zwraca punkty z obu granic, posortowane po dacie.
+func TestPriceHistoryStore(t *testing.T) {
+	for name, newStore := range storeFactories {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+			record := func(d int, price int64, source string) {
+				t.Helper()
+				quote := models.PriceQuote{Price: models.NewDecimal(price), Source: source, Time: day(d).Add(15 * time.Hour)}
+				if err := store.RecordPrice(ctx, models.NewPricePoint("cdr", quote)); err != nil {
+					t.Fatalf("RecordPrice() error: %v", err)
+				}
+			}
+			record(3, 103, "file")
+			record(1, 101, "file")
+			record(2, 102, "file")
+			record(2, 112, "http") // Nadpisuje cenę z 2 października
+			record(1, 1, "other")
+			if err := store.RecordPrice(ctx, models.PricePoint{Symbol: "PKN", Date: day(2), Close: models.NewDecimal(60)}); err != nil {
+				t.Fatalf("RecordPrice() error: %v", err)
+			}
+
+			all, err := store.PriceHistory(ctx, "CDR", time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("PriceHistory() error: %v", err)
+			}
+			want := []models.PricePoint{
+				{Symbol: "CDR", Date: day(1), Close: models.NewDecimal(1), Source: "other"},
+				{Symbol: "CDR", Date: day(2), Close: models.NewDecimal(112), Source: "http"},
+				{Symbol: "CDR", Date: day(3), Close: models.NewDecimal(103), Source: "file"},
+			}
+			if len(all) != len(want) {
+				t.Fatalf("PriceHistory() = %+v, want %+v", all, want)
+			}
+			for i := range want {
+				if all[i].Symbol != want[i].Symbol || !all[i].Date.Equal(want[i].Date) || all[i].Close != want[i].Close || all[i].Source != want[i].Source {
+					t.Errorf("PriceHistory()[%d] = %+v, want %+v", i, all[i], want[i])
+				}
+			}
+
+			ranged, err := store.PriceHistory(ctx, "cdr", day(2), day(3))
+			if err != nil {
+				t.Fatalf("PriceHistory() error: %v", err)
+			}
+			if len(ranged) != 2 || !ranged[0].Date.Equal(day(2)) || !ranged[1].Date.Equal(day(3)) {
+				t.Errorf("PriceHistory(2..3 Oct) = %+v", ranged)
+			}
+			if from, _ := store.PriceHistory(ctx, "CDR", day(3), time.Time{}); len(from) != 1 {
+				t.Errorf("PriceHistory(from 3 Oct) = %+v", from)
+			}
+			if to, _ := store.PriceHistory(ctx, "CDR", time.Time{}, day(1)); len(to) != 1 {
+				t.Errorf("PriceHistory(to 1 Oct) = %+v", to)
+			}
+			if none, err := store.PriceHistory(ctx, "XYZ", time.Time{}, time.Time{}); err != nil || len(none) != 0 {
+				t.Errorf("PriceHistory(XYZ) = %+v, %v, want empty", none, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ensureUserIndexes zakłada indeksy kolekcji użytkowników i sesji:
+// unikalny adres e-mail oraz automatyczne usuwanie wygasłych sesji (indeks TTL).
+func (r *PortfolioRepo) ensureUserIndexes(ctx context.Context) error {
+	_, err := r.usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create users index: %w", err)
+	}
+	_, err = r.usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "calendarTokenHash", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create users calendar token index: %w", err)
+	}
+	_, err = r.sessionsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create sessions index: %w", err)
+	}
+	return nil
+}
+
+// CreateUser zapisuje nowe konto użytkownika.
+func (r *PortfolioRepo) CreateUser(ctx context.Context, user models.User) error {
+	_, err := r.usersCollection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrUserExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetUser zwraca użytkownika o podanym ID.
+func (r *PortfolioRepo) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return r.findUser(ctx, bson.M{"_id": userID})
+}
+
+// GetUserByEmail zwraca użytkownika o podanym adresie e-mail.
+func (r *PortfolioRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findUser(ctx, bson.M{"email": email})
+}
+
+// GetUserByCalendarToken zwraca użytkownika o podanym skrócie tokenu kanału iCalendar.
+func (r *PortfolioRepo) GetUserByCalendarToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	if tokenHash == "" {
+		return nil, ErrNotFound
+	}
+	return r.findUser(ctx, bson.M{"calendarTokenHash": tokenHash})
+}
+
+// SetUserPortfolio zmienia portfel główny użytkownika.
+func (r *PortfolioRepo) SetUserPortfolio(ctx context.Context, userID, portfolioID string) error {
+	result, err := r.usersCollection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"portfolioId": portfolioID}})
+	if err != nil {
+		return fmt.Errorf("failed to save user portfolio: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetCalendarToken zapisuje skrót tokenu kanału iCalendar użytkownika.
+func (r *PortfolioRepo) SetCalendarToken(ctx context.Context, userID, tokenHash string) error {
+	result, err := r.usersCollection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"calendarTokenHash": tokenHash}})
+	if err != nil {
+		return fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PortfolioRepo) findUser(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := r.usersCollection.FindOne(ctx, filter).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
+
+// CountUsers zwraca liczbę zarejestrowanych kont.
+func (r *PortfolioRepo) CountUsers(ctx context.Context) (int64, error) {
+	count, err := r.usersCollection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// CreateSession zapisuje nową sesję logowania.
+func (r *PortfolioRepo) CreateSession(ctx context.Context, session models.Session) error {
+	if _, err := r.sessionsCollection.InsertOne(ctx, session); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession zwraca ważną sesję o podanym skrócie tokenu.
+// Indeks TTL usuwa wygasłe sesje z opóźnieniem, więc datę wygaśnięcia sprawdzamy także tutaj.
+func (r *PortfolioRepo) GetSession(ctx context.Context, tokenHash string) (*models.Session, error) {
+	var session models.Session
+	err := r.sessionsCollection.FindOne(ctx, bson.M{"_id": tokenHash}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.IsExpired(time.Now()) {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// DeleteSession usuwa sesję (wylogowanie).
+func (r *PortfolioRepo) DeleteSession(ctx context.Context, tokenHash string) error {
+	if _, err := r.sessionsCollection.DeleteOne(ctx, bson.M{"_id": tokenHash}); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
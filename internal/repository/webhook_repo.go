@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"webwallet/internal/models"
+)
+
+// ensureWebhookIndexes zakłada indeksy doręczeń: (użytkownik, data) dla dziennika oraz (stan, termin próby)
+// dla ponawiania nieudanych doręczeń.
+func (r *PortfolioRepo) ensureWebhookIndexes(ctx context.Context) error {
+	_, err := r.webhookDeliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery indexes: %w", err)
+	}
+	return nil
+}
+
+// WebhookEndpoints zwraca adresy webhooków użytkownika (od najstarszego).
+func (r *PortfolioRepo) WebhookEndpoints(ctx context.Context, userID string) ([]models.WebhookEndpoint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := r.webhookEndpoints.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	endpoints := []models.WebhookEndpoint{}
+	if err := cursor.All(ctx, &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// GetWebhookEndpoint zwraca adres webhooka o podanym ID.
+func (r *PortfolioRepo) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	err := r.webhookEndpoints.FindOne(ctx, bson.M{"_id": endpointID}).Decode(&endpoint)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// SaveWebhookEndpoint zapisuje adres webhooka (upsert po ID).
+func (r *PortfolioRepo) SaveWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.webhookEndpoints.ReplaceOne(ctx, bson.M{"_id": endpoint.ID}, endpoint, opts); err != nil {
+		return fmt.Errorf("failed to save webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookEndpoint usuwa adres webhooka użytkownika i jego doręczenia.
+func (r *PortfolioRepo) DeleteWebhookEndpoint(ctx context.Context, userID, endpointID string) error {
+	result, err := r.webhookEndpoints.DeleteOne(ctx, bson.M{"_id": endpointID, "userId": userID})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	if _, err := r.webhookDeliveries.DeleteMany(ctx, bson.M{"endpointId": endpointID}); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// SaveWebhookDelivery zapisuje doręczenie (upsert po ID).
+func (r *PortfolioRepo) SaveWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.webhookDeliveries.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery, opts); err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// WebhookDeliveries zwraca najnowsze doręczenia użytkownika (od najnowszego).
+func (r *PortfolioRepo) WebhookDeliveries(ctx context.Context, userID string, limit int) ([]models.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+	return r.findWebhookDeliveries(ctx, bson.M{"userId": userID}, opts)
+}
+
+// DueWebhookDeliveries zwraca oczekujące doręczenia, których kolejna próba już przypadła.
+func (r *PortfolioRepo) DueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	filter := bson.M{"status": models.WebhookPending, "nextAttemptAt": bson.M{"$lte": now}}
+	opts := options.Find().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).SetLimit(int64(limit))
+	return r.findWebhookDeliveries(ctx, filter, opts)
+}
+
+func (r *PortfolioRepo) findWebhookDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.WebhookDelivery, error) {
+	cursor, err := r.webhookDeliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,165 @@
+// Package snapshots zapisuje dzienne stany wartości portfeli, z których powstaje wykres wartości majątku w czasie.
+package snapshots
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+// maxBackfillDays ogranicza, jak daleko wstecz odtwarzamy brakujące zapisy z historii cen.
+const maxBackfillDays = 5 * 365
+
+// Recorder okresowo zapisuje bieżący stan wszystkich portfeli jako zapis z dzisiejszego dnia.
+// Kolejne zapisy z tego samego dnia nadpisują poprzednie, więc zostaje stan z końca dnia.
+type Recorder struct {
+	store    repository.Store
+	interval time.Duration
+}
+
+// NewRecorder tworzy rejestrator zapisujący stan portfeli co podany interwał.
+func NewRecorder(store repository.Store, interval time.Duration) *Recorder {
+	return &Recorder{store: store, interval: interval}
+}
+
+// Run po starcie uzupełnia brakujące zapisy z przeszłości (Backfill), a potem zapisuje stan
+// portfeli od razu i co interwał - aż do anulowania kontekstu.
+func (r *Recorder) Run(ctx context.Context) {
+	log.Printf("Snapshot recorder started (every %s).", r.interval)
+	r.BackfillAll(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.RecordAll(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Snapshot recorder stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecordAll zapisuje dzisiejszy stan każdego portfela.
+func (r *Recorder) RecordAll(ctx context.Context) {
+	portfolios, err := r.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Snapshot recording failed: %v", err)
+		return
+	}
+
+	today := models.Today()
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		portfolio, err := r.store.LoadPortfolio(ctx, info.ID)
+		if err != nil {
+			log.Printf("Snapshot recording: failed to load portfolio %s: %v", info.ID, err)
+			continue
+		}
+		if err := r.store.SaveSnapshot(ctx, portfolio.TakeSnapshot(today)); err != nil {
+			log.Printf("Snapshot recording: %v", err)
+		}
+	}
+}
+
+// BackfillAll uzupełnia brakujące zapisy wszystkich portfeli.
+func (r *Recorder) BackfillAll(ctx context.Context) {
+	portfolios, err := r.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Snapshot backfill failed: %v", err)
+		return
+	}
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := r.Backfill(ctx, info.ID); err != nil {
+			log.Printf("Snapshot backfill of portfolio %s failed: %v", info.ID, err)
+		}
+	}
+}
+
+// Backfill odtwarza brakujące zapisy portfela z dni, dla których mamy historię cen jego aktywów:
+// od pierwszej zapisanej ceny (ale nie wcześniej niż pierwsza transakcja) do wczoraj.
+// Dni, które mają już zapis, pomijamy.
+func (r *Recorder) Backfill(ctx context.Context, portfolioID string) error {
+	portfolio, err := r.store.LoadPortfolio(ctx, portfolioID)
+	if err != nil {
+		return err
+	}
+
+	prices := make(map[string][]models.PricePoint)
+	var start time.Time
+	for _, asset := range portfolio.Assets {
+		symbol := models.NormalizeSymbol(asset.Symbol)
+		if symbol == "" {
+			continue
+		}
+		if _, loaded := prices[symbol]; loaded {
+			continue
+		}
+		points, err := r.store.PriceHistory(ctx, symbol, time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		prices[symbol] = points
+		if len(points) > 0 && (start.IsZero() || points[0].Date.Before(start)) {
+			start = points[0].Date
+		}
+	}
+	if start.IsZero() {
+		return nil // Brak historii cen - nie ma z czego odtwarzać
+	}
+
+	firstTransaction := firstTransactionDate(portfolio)
+	if !firstTransaction.IsZero() && firstTransaction.After(start) {
+		start = firstTransaction
+	}
+	yesterday := models.Today().AddDate(0, 0, -1)
+	if limit := yesterday.AddDate(0, 0, -maxBackfillDays); start.Before(limit) {
+		start = limit
+	}
+
+	existing, err := r.store.Snapshots(ctx, portfolioID, start, yesterday)
+	if err != nil {
+		return err
+	}
+	recorded := make(map[time.Time]bool, len(existing))
+	for _, s := range existing {
+		recorded[s.Date] = true
+	}
+
+	added := 0
+	for day := start; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		if recorded[day] {
+			continue
+		}
+		if err := r.store.SaveSnapshot(ctx, portfolio.ReconstructSnapshot(day, prices)); err != nil {
+			return err
+		}
+		added++
+	}
+	if added > 0 {
+		log.Printf("Backfilled %d snapshots of portfolio %s from price history.", added, portfolioID)
+	}
+	return nil
+}
+
+// firstTransactionDate zwraca dzień pierwszej transakcji w portfelu (zerowy czas, jeśli nie ma transakcji).
+func firstTransactionDate(portfolio *models.InvestmentPortfolio) time.Time {
+	var first time.Time
+	for _, asset := range portfolio.Assets {
+		for _, t := range asset.Transactions {
+			if first.IsZero() || t.Date.Before(first) {
+				first = t.Date
+			}
+		}
+	}
+	return first.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,64 @@
+// Package subscriptions przesuwa terminy płatności subskrypcji, które już minęły.
+package subscriptions
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/repository"
+)
+
+// Roller okresowo przesuwa NextDue subskrypcji wszystkich portfeli na najbliższy przyszły termin,
+// zapisując minione terminy w historii płatności subskrypcji.
+type Roller struct {
+	store    repository.Store
+	interval time.Duration
+}
+
+// NewRoller tworzy zadanie przesuwające terminy subskrypcji co podany interwał.
+func NewRoller(store repository.Store, interval time.Duration) *Roller {
+	return &Roller{store: store, interval: interval}
+}
+
+// Run przesuwa terminy od razu po starcie i potem co interwał - aż do anulowania kontekstu.
+func (r *Roller) Run(ctx context.Context) {
+	log.Printf("Subscription roller started (every %s).", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.RollAll(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Subscription roller stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RollAll przesuwa minione terminy subskrypcji w każdym portfelu.
+func (r *Roller) RollAll(ctx context.Context) {
+	portfolios, err := r.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Subscription rollover failed: %v", err)
+		return
+	}
+
+	today := models.Today()
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		rolled, err := r.store.RollOverSubscriptions(ctx, info.ID, today)
+		if err != nil {
+			log.Printf("Subscription rollover: failed to update portfolio %s: %v", info.ID, err)
+			continue
+		}
+		for _, sub := range rolled {
+			log.Printf("Subscription %s rolled over to %s.", sub.Name, sub.NextDue.Format("2006-01-02"))
+		}
+	}
+}
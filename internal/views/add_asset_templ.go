@@ -10,10 +10,11 @@ package views
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
+import "strconv"
 import "webwallet/internal/models" // Potrzebne, bo Layout oczekuje danych portfolio
 
-// AddAssetForm przyjmuje komunikat o błędzie lub sukcesie do wyświetlenia użytkownikowi.
-func AddAssetForm(message string) templ.Component {
+// AddAssetForm przyjmuje wersję portfela i komunikat o błędzie lub sukcesie do wyświetlenia użytkownikowi.
+func AddAssetForm(version int64, message string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -34,7 +35,7 @@ func AddAssetForm(message string) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = Layout("Dodaj Nowe Aktywo", RenderAddAssetContent(message), &models.InvestmentPortfolio{}, "", "", "", 0, 0).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = Layout("Dodaj Nowe Aktywo", RenderAddAssetContent(version, message), &models.InvestmentPortfolio{}, "", "", "", 0, 0).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -44,7 +45,7 @@ func AddAssetForm(message string) templ.Component {
 
 // RenderAddAssetContent to pomocniczy komponent renderujący samą zawartość formularza.
 // Jest oddzielony, aby można go było łatwo przekazać do Layout.
-func RenderAddAssetContent(message string) templ.Component {
+func RenderAddAssetContent(version int64, message string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -77,7 +78,7 @@ func RenderAddAssetContent(message string) templ.Component {
 			var templ_7745c5c3_Var3 string
 			templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 			if templ_7745c5c3_Err != nil {
-				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/add_asset.templ`, Line: 20, Col: 40}
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/add_asset.templ`, Line: 21, Col: 40}
 			}
 			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 			if templ_7745c5c3_Err != nil {
@@ -88,7 +89,20 @@ func RenderAddAssetContent(message string) templ.Component {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<form action=\"/add-asset\" method=\"POST\"><div class=\"form-group\"><label for=\"name\">Nazwa Aktywa:</label> <input type=\"text\" id=\"name\" name=\"name\" required></div><div class=\"form-group\"><label for=\"symbol\">Symbol (np. SPX):</label> <input type=\"text\" id=\"symbol\" name=\"symbol\" required></div><div class=\"form-group\"><label for=\"type\">Typ (np. Akcje, Gotówka, ETF, Obligacje):</label> <input type=\"text\" id=\"type\" name=\"type\" required></div><div class=\"form-group\"><label for=\"quantity\">Ilość:</label> <input type=\"number\" id=\"quantity\" name=\"quantity\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"avgCost\">Średni Koszt Zakupu (za jednostkę):</label> <input type=\"number\" id=\"avgCost\" name=\"avgCost\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"currentPrice\">Obecna wartość rynkowa (za jednostkę):</label> <input type=\"number\" id=\"currentPrice\" name=\"currentPrice\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"walletType\">Rodzaj portfela (Poduszka Finansowa, Portfel Długoterminowy lub Portfel Krótkoterminowy):</label> <input type=\"text\" id=\"walletType\" name=\"walletType\" required></div><button type=\"submit\">Dodaj Aktywo</button></form><p><a href=\"/\" class=\"update-button\">Powrót do portfela</a></p></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<form action=\"/add-asset\" method=\"POST\"><input type=\"hidden\" name=\"version\" value=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var4 string
+		templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.FormatInt(version, 10))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/add_asset.templ`, Line: 25, Col: 86}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\"><div class=\"form-group\"><label for=\"name\">Nazwa Aktywa:</label> <input type=\"text\" id=\"name\" name=\"name\" required></div><div class=\"form-group\"><label for=\"symbol\">Symbol (np. SPX):</label> <input type=\"text\" id=\"symbol\" name=\"symbol\" required></div><div class=\"form-group\"><label for=\"type\">Typ (np. Akcje, Gotówka, ETF, Obligacje):</label> <input type=\"text\" id=\"type\" name=\"type\" required></div><div class=\"form-group\"><label for=\"currency\">Waluta notowań (np. PLN, USD, EUR):</label> <input type=\"text\" id=\"currency\" name=\"currency\" maxlength=\"3\" value=\"PLN\" required></div><div class=\"form-group\"><label for=\"quantity\">Ilość:</label> <input type=\"number\" id=\"quantity\" name=\"quantity\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"avgCost\">Średni Koszt Zakupu (za jednostkę):</label> <input type=\"number\" id=\"avgCost\" name=\"avgCost\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"currentPrice\">Obecna wartość rynkowa (za jednostkę):</label> <input type=\"number\" id=\"currentPrice\" name=\"currentPrice\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"walletType\">Rodzaj portfela (Poduszka Finansowa, Portfel Długoterminowy lub Portfel Krótkoterminowy):</label> <input type=\"text\" id=\"walletType\" name=\"walletType\" required></div><button type=\"submit\">Dodaj Aktywo</button></form><p><a href=\"/\" class=\"update-button\">Powrót do portfela</a></p></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -10,11 +10,11 @@ package views
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-import "fmt"
+import "strconv"
 import "webwallet/internal/models"
 
-// UpdateAssetForm przyjmuje dane aktywa do wyświetlenia i komunikat.
-func UpdateAssetForm(asset models.Asset, message string) templ.Component {
+// UpdateAssetForm przyjmuje dane aktywa do wyświetlenia, wersję portfela (do wykrywania konfliktów) i komunikat.
+func UpdateAssetForm(asset models.Asset, version int64, message string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -35,7 +35,7 @@ func UpdateAssetForm(asset models.Asset, message string) templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = Layout("Aktualizuj Aktywo", RenderUpdateAssetContent(asset, message), &models.InvestmentPortfolio{}, "", "", "", 0, 0).Render(ctx, templ_7745c5c3_Buffer)
+		templ_7745c5c3_Err = Layout("Aktualizuj Aktywo", RenderUpdateAssetContent(asset, version, message), &models.InvestmentPortfolio{}, "", "", "", 0, 0).Render(ctx, templ_7745c5c3_Buffer)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -44,7 +44,7 @@ func UpdateAssetForm(asset models.Asset, message string) templ.Component {
 }
 
 // RenderUpdateAssetContent to pomocniczy komponent renderujący samą zawartość formularza.
-func RenderUpdateAssetContent(asset models.Asset, message string) templ.Component {
+func RenderUpdateAssetContent(asset models.Asset, version int64, message string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -96,9 +96,9 @@ func RenderUpdateAssetContent(asset models.Asset, message string) templ.Componen
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var5 string
-		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%.2f", asset.Quantity))
+		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(asset.Quantity.String())
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/update_asset.templ`, Line: 16, Col: 64}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/update_asset.templ`, Line: 16, Col: 52}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 		if templ_7745c5c3_Err != nil {
@@ -109,9 +109,9 @@ func RenderUpdateAssetContent(asset models.Asset, message string) templ.Componen
 			return templ_7745c5c3_Err
 		}
 		var templ_7745c5c3_Var6 string
-		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("%.2f PLN", asset.AvgCost))
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(models.FormatCurrency(asset.AvgCost, asset.CurrencyCode()))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/update_asset.templ`, Line: 17, Col: 73}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/update_asset.templ`, Line: 17, Col: 93}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
@@ -153,7 +153,20 @@ func RenderUpdateAssetContent(asset models.Asset, message string) templ.Componen
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\"><div class=\"form-group\"><label for=\"additionalQuantity\">Dodatkowa Ilość:</label> <input type=\"number\" id=\"additionalQuantity\" name=\"additional_quantity\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"newPurchasePrice\">Cena Zakupu dla Nowej Ilości:</label> <input type=\"number\" id=\"newPurchasePrice\" name=\"new_purchase_price\" step=\"0.01\" min=\"0\" required></div><button type=\"submit\">Aktualizuj Aktywo</button></form><p><a href=\"/\" class=\"update-button\">Powrót do portfela</a></p></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "\"> <input type=\"hidden\" name=\"version\" value=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.FormatInt(version, 10))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/views/update_asset.templ`, Line: 25, Col: 86}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "\"><div class=\"form-group\"><label for=\"additionalQuantity\">Dodatkowa Ilość:</label> <input type=\"number\" id=\"additionalQuantity\" name=\"additional_quantity\" step=\"0.01\" min=\"0\" required></div><div class=\"form-group\"><label for=\"newPurchasePrice\">Cena Zakupu dla Nowej Ilości:</label> <input type=\"number\" id=\"newPurchasePrice\" name=\"new_purchase_price\" step=\"0.01\" min=\"0\" required></div><button type=\"submit\">Aktualizuj Aktywo</button></form><p><a href=\"/\" class=\"update-button\">Powrót do portfela</a></p></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,280 @@
+// Package webhooks wysyła zdarzenia portfeli (zmiany aktywów i cen, terminy subskrypcji, alerty)
+// na adresy webhooków skonfigurowane przez użytkowników.
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"webwallet/internal/models"
+	"webwallet/internal/notify"
+	"webwallet/internal/repository"
+)
+
+// attemptTimeout ogranicza czas jednej próby doręczenia (zapis wyniku w bazie i żądanie HTTP).
+const attemptTimeout = 30 * time.Second
+
+// retryBatch to największa liczba doręczeń ponawianych w jednym przebiegu.
+const retryBatch = 50
+
+// Nagłówki żądań webhooków. Podpis liczymy z X-Webwallet-Timestamp i treści żądania (zob. models.SignWebhook).
+const (
+	headerEvent     = "X-Webwallet-Event"
+	headerDelivery  = "X-Webwallet-Delivery"
+	headerTimestamp = "X-Webwallet-Timestamp"
+	headerSignature = "X-Webwallet-Signature"
+)
+
+// Dispatcher zapisuje zdarzenia w dzienniku doręczeń i wysyła je na adresy webhooków. Pierwsza próba
+// idzie od razu w tle; nieudane doręczenia ponawia Run z rosnącym odstępem (models.WebhookRetryDelay).
+type Dispatcher struct {
+	store    repository.Store
+	interval time.Duration
+
+	attempts     sync.WaitGroup
+	lastDueCheck time.Time // Dzień ostatniego sprawdzenia terminów subskrypcji (tylko w Run)
+}
+
+// NewDispatcher tworzy nadawcę webhooków, który co podany interwał ponawia nieudane doręczenia.
+func NewDispatcher(store repository.Store, interval time.Duration) *Dispatcher {
+	return &Dispatcher{store: store, interval: interval}
+}
+
+// Run ponawia zaległe doręczenia i raz dziennie wysyła zdarzenia subscription.due - od razu po starcie,
+// a potem co interwał, aż do anulowania kontekstu.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("Webhook dispatcher started (every %s).", d.interval)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.RetryDue(ctx)
+		if today := models.Today(); !today.Equal(d.lastDueCheck) {
+			d.EmitDueSubscriptions(ctx, today)
+			d.lastDueCheck = today
+		}
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook dispatcher stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait czeka na zakończenie prób doręczenia uruchomionych w tle (przy zamykaniu aplikacji).
+func (d *Dispatcher) Wait() {
+	d.attempts.Wait()
+}
+
+// Emit wysyła zdarzenie na wszystkie aktywne adresy użytkownika, które je subskrybują. Błędy tylko
+// logujemy - zdarzenie jest skutkiem ubocznym udanej zmiany portfela i nie może jej cofnąć.
+func (d *Dispatcher) Emit(ctx context.Context, userID, portfolioID string, event models.WebhookEvent, data any) {
+	if userID == "" {
+		return
+	}
+	endpoints, err := d.store.WebhookEndpoints(ctx, userID)
+	if err != nil {
+		log.Printf("Webhooks: %v", err)
+		return
+	}
+	var targets []models.WebhookEndpoint
+	for _, endpoint := range endpoints {
+		if event != models.WebhookPing && endpoint.Accepts(event) {
+			targets = append(targets, endpoint)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	if err := d.enqueue(ctx, targets, portfolioID, event, data); err != nil {
+		log.Printf("Webhooks: %v", err)
+	}
+}
+
+// Ping wysyła wiadomość testową na jeden adres (także wyłączony).
+func (d *Dispatcher) Ping(ctx context.Context, endpoint models.WebhookEndpoint) error {
+	data := map[string]string{"message": "Webhook działa."}
+	return d.enqueue(ctx, []models.WebhookEndpoint{endpoint}, "", models.WebhookPing, data)
+}
+
+// enqueue zapisuje doręczenie zdarzenia na każdy z adresów i uruchamia w tle pierwszą próbę wysyłki.
+func (d *Dispatcher) enqueue(ctx context.Context, endpoints []models.WebhookEndpoint, portfolioID string, event models.WebhookEvent, data any) error {
+	now := time.Now()
+	payload, err := json.Marshal(models.WebhookMessage{
+		ID:          models.GenerateID(),
+		Event:       event,
+		CreatedAt:   now,
+		PortfolioID: portfolioID,
+		Data:        data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+
+	for _, endpoint := range endpoints {
+		delivery := models.WebhookDelivery{
+			ID:         models.GenerateID(),
+			EndpointID: endpoint.ID,
+			UserID:     endpoint.UserID,
+			Event:      event,
+			Payload:    string(payload),
+			Status:     models.WebhookPending,
+			CreatedAt:  now,
+			// Do zakończenia pierwszej próby Run nie może wziąć tego doręczenia do ponowienia
+			NextAttemptAt: now.Add(attemptTimeout),
+		}
+		if err := d.store.SaveWebhookDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to queue %s event: %w", event, err)
+		}
+
+		d.attempts.Add(1)
+		go func() {
+			defer d.attempts.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), attemptTimeout)
+			defer cancel()
+			d.attempt(ctx, endpoint, delivery)
+		}()
+	}
+	return nil
+}
+
+// RetryDue ponawia doręczenia, których kolejna próba już przypadła.
+func (d *Dispatcher) RetryDue(ctx context.Context) {
+	deliveries, err := d.store.DueWebhookDeliveries(ctx, time.Now(), retryBatch)
+	if err != nil {
+		log.Printf("Webhook retry failed: %v", err)
+		return
+	}
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+		endpoint, err := d.store.GetWebhookEndpoint(attemptCtx, delivery.EndpointID)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			delivery.RecordAttempt(time.Now(), 0, errors.New("webhook endpoint was removed"))
+			delivery.Status = models.WebhookFailed
+			d.save(attemptCtx, delivery)
+		case err != nil:
+			log.Printf("Webhook retry: %v", err)
+		case !endpoint.Active && delivery.Event != models.WebhookPing:
+			delivery.RecordAttempt(time.Now(), 0, errors.New("webhook endpoint is disabled"))
+			delivery.Status = models.WebhookFailed
+			d.save(attemptCtx, delivery)
+		default:
+			d.attempt(attemptCtx, *endpoint, delivery)
+		}
+		cancel()
+	}
+}
+
+// attempt wysyła podpisane żądanie z treścią doręczenia i zapisuje wynik próby.
+func (d *Dispatcher) attempt(ctx context.Context, endpoint models.WebhookEndpoint, delivery models.WebhookDelivery) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+	header := http.Header{}
+	header.Set(headerEvent, string(delivery.Event))
+	header.Set(headerDelivery, delivery.ID)
+	header.Set(headerTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(headerSignature, models.SignWebhook(endpoint.Secret, timestamp, body))
+
+	code, err := notify.Post(ctx, endpoint.URL, body, header)
+	delivery.RecordAttempt(time.Now(), code, err)
+	switch {
+	case err == nil:
+		log.Printf("Webhook %s delivered to %s.", delivery.Event, endpoint.URL)
+	case delivery.Status == models.WebhookFailed:
+		log.Printf("Webhook %s to %s failed after %d attempts: %v", delivery.Event, endpoint.URL, delivery.Attempts, err)
+	default:
+		log.Printf("Webhook %s to %s failed (attempt %d, next at %s): %v", delivery.Event, endpoint.URL,
+			delivery.Attempts, delivery.NextAttemptAt.Format(time.RFC3339), err)
+	}
+	d.save(ctx, delivery)
+}
+
+// save zapisuje wynik próby doręczenia.
+func (d *Dispatcher) save(ctx context.Context, delivery models.WebhookDelivery) {
+	if err := d.store.SaveWebhookDelivery(ctx, delivery); err != nil {
+		log.Printf("Webhooks: %v", err)
+	}
+}
+
+// EmitDueSubscriptions wysyła zdarzenia subscription.due dla subskrypcji z terminem płatności w podanym dniu.
+// Wysłane zdarzenia trafiają do dziennika przypomnień, więc restart aplikacji ich nie powtarza.
+func (d *Dispatcher) EmitDueSubscriptions(ctx context.Context, today time.Time) {
+	portfolios, err := d.store.AllPortfolios(ctx)
+	if err != nil {
+		log.Printf("Webhooks: subscription due check failed: %v", err)
+		return
+	}
+
+	subscribed := make(map[string]bool) // Klucz: ID właściciela portfela
+	for _, info := range portfolios {
+		if ctx.Err() != nil {
+			return
+		}
+		if info.OwnerID == "" {
+			continue
+		}
+		wanted, checked := subscribed[info.OwnerID]
+		if !checked {
+			wanted = d.wants(ctx, info.OwnerID, models.WebhookSubscriptionDue)
+			subscribed[info.OwnerID] = wanted
+		}
+		if !wanted {
+			continue
+		}
+
+		portfolio, err := d.store.LoadPortfolio(ctx, info.ID)
+		if err != nil {
+			log.Printf("Webhooks: failed to load portfolio %s: %v", info.ID, err)
+			continue
+		}
+		for _, due := range portfolio.SubscriptionsDue(today) {
+			key := due.WebhookKey()
+			sent, err := d.store.ReminderSent(ctx, key)
+			if err != nil {
+				log.Printf("Webhooks: %v", err)
+				continue
+			}
+			if sent {
+				continue
+			}
+			d.Emit(ctx, info.OwnerID, info.ID, models.WebhookSubscriptionDue, due.Subscription)
+			err = d.store.SaveSentReminder(ctx, models.SentReminder{
+				Key:            key,
+				PortfolioID:    info.ID,
+				SubscriptionID: due.Subscription.ID,
+				DueDate:        due.Subscription.NextDue,
+				SentAt:         time.Now(),
+			})
+			if err != nil {
+				log.Printf("Webhooks: %v", err)
+			}
+		}
+	}
+}
+
+// wants mówi, czy użytkownik ma aktywny adres subskrybujący zdarzenie.
+func (d *Dispatcher) wants(ctx context.Context, userID string, event models.WebhookEvent) bool {
+	endpoints, err := d.store.WebhookEndpoints(ctx, userID)
+	if err != nil {
+		log.Printf("Webhooks: %v", err)
+		return false
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Accepts(event) {
+			return true
+		}
+	}
+	return false
+}